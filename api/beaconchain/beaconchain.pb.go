@@ -157,30 +157,152 @@ func (m *FetchLeadersResponse_Leader) GetPeerID() string {
 	return ""
 }
 
+// FetchCrosslinkRequest is the request to fetch the crosslink of a shard at a block number.
+type FetchCrosslinkRequest struct {
+	ShardId uint32 `protobuf:"varint,1,opt,name=shardId,proto3" json:"shardId,omitempty"`
+	// The number of the block, or 0 for the latest crosslink of the shard.
+	BlockNumber          uint64   `protobuf:"varint,2,opt,name=blockNumber,proto3" json:"blockNumber,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FetchCrosslinkRequest) Reset()         { *m = FetchCrosslinkRequest{} }
+func (m *FetchCrosslinkRequest) String() string { return proto.CompactTextString(m) }
+func (*FetchCrosslinkRequest) ProtoMessage()    {}
+func (*FetchCrosslinkRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_474fd8061d1037cf, []int{2}
+}
+
+func (m *FetchCrosslinkRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FetchCrosslinkRequest.Unmarshal(m, b)
+}
+func (m *FetchCrosslinkRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FetchCrosslinkRequest.Marshal(b, m, deterministic)
+}
+func (m *FetchCrosslinkRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchCrosslinkRequest.Merge(m, src)
+}
+func (m *FetchCrosslinkRequest) XXX_Size() int {
+	return xxx_messageInfo_FetchCrosslinkRequest.Size(m)
+}
+func (m *FetchCrosslinkRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchCrosslinkRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchCrosslinkRequest proto.InternalMessageInfo
+
+func (m *FetchCrosslinkRequest) GetShardId() uint32 {
+	if m != nil {
+		return m.ShardId
+	}
+	return 0
+}
+
+func (m *FetchCrosslinkRequest) GetBlockNumber() uint64 {
+	if m != nil {
+		return m.BlockNumber
+	}
+	return 0
+}
+
+// FetchCrosslinkResponse is the response of FetchCrosslinkRequest, with the signed header of the block.
+type FetchCrosslinkResponse struct {
+	ShardId     uint32 `protobuf:"varint,1,opt,name=shardId,proto3" json:"shardId,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,2,opt,name=blockNumber,proto3" json:"blockNumber,omitempty"`
+	BlockHash   []byte `protobuf:"bytes,3,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	// The RLP encoded header, with the signatures of the committee of the shard.
+	Header               []byte   `protobuf:"bytes,4,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FetchCrosslinkResponse) Reset()         { *m = FetchCrosslinkResponse{} }
+func (m *FetchCrosslinkResponse) String() string { return proto.CompactTextString(m) }
+func (*FetchCrosslinkResponse) ProtoMessage()    {}
+func (*FetchCrosslinkResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_474fd8061d1037cf, []int{3}
+}
+
+func (m *FetchCrosslinkResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FetchCrosslinkResponse.Unmarshal(m, b)
+}
+func (m *FetchCrosslinkResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FetchCrosslinkResponse.Marshal(b, m, deterministic)
+}
+func (m *FetchCrosslinkResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchCrosslinkResponse.Merge(m, src)
+}
+func (m *FetchCrosslinkResponse) XXX_Size() int {
+	return xxx_messageInfo_FetchCrosslinkResponse.Size(m)
+}
+func (m *FetchCrosslinkResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchCrosslinkResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchCrosslinkResponse proto.InternalMessageInfo
+
+func (m *FetchCrosslinkResponse) GetShardId() uint32 {
+	if m != nil {
+		return m.ShardId
+	}
+	return 0
+}
+
+func (m *FetchCrosslinkResponse) GetBlockNumber() uint64 {
+	if m != nil {
+		return m.BlockNumber
+	}
+	return 0
+}
+
+func (m *FetchCrosslinkResponse) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *FetchCrosslinkResponse) GetHeader() []byte {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*FetchLeadersRequest)(nil), "beaconchain.FetchLeadersRequest")
 	proto.RegisterType((*FetchLeadersResponse)(nil), "beaconchain.FetchLeadersResponse")
 	proto.RegisterType((*FetchLeadersResponse_Leader)(nil), "beaconchain.FetchLeadersResponse.Leader")
+	proto.RegisterType((*FetchCrosslinkRequest)(nil), "beaconchain.FetchCrosslinkRequest")
+	proto.RegisterType((*FetchCrosslinkResponse)(nil), "beaconchain.FetchCrosslinkResponse")
 }
 
 func init() { proto.RegisterFile("beaconchain.proto", fileDescriptor_474fd8061d1037cf) }
 
 var fileDescriptor_474fd8061d1037cf = []byte{
-	// 222 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x90, 0xcd, 0x4a, 0xc4, 0x30,
-	0x14, 0x85, 0x4d, 0x67, 0xe8, 0xe0, 0x1d, 0x15, 0xbc, 0xfe, 0x10, 0x66, 0x15, 0xbb, 0xca, 0xaa,
-	0x8b, 0xf1, 0x0d, 0xaa, 0x08, 0x05, 0x57, 0x11, 0xb7, 0x42, 0x9a, 0x5e, 0x68, 0x50, 0x9a, 0x98,
-	0x44, 0x1f, 0xce, 0xa7, 0x13, 0x53, 0x0b, 0x15, 0x44, 0x77, 0x39, 0x1f, 0x39, 0xe4, 0xcb, 0x81,
-	0xd3, 0x8e, 0xb4, 0x71, 0xa3, 0x19, 0xb4, 0x1d, 0x6b, 0x1f, 0x5c, 0x72, 0xb8, 0x5d, 0xa0, 0xea,
-	0x02, 0xce, 0xee, 0x28, 0x99, 0xe1, 0x9e, 0x74, 0x4f, 0x21, 0x2a, 0x7a, 0x7d, 0xa3, 0x98, 0xaa,
-	0x0f, 0x06, 0xe7, 0x3f, 0x79, 0xf4, 0x6e, 0x8c, 0x84, 0x0d, 0x6c, 0x5e, 0x26, 0xc4, 0x99, 0x58,
-	0xc9, 0xed, 0x5e, 0xd6, 0xcb, 0x17, 0x7e, 0xeb, 0xd4, 0x53, 0x56, 0x73, 0x71, 0xf7, 0x04, 0xe5,
-	0x84, 0xf0, 0x04, 0x0a, 0xeb, 0x39, 0x13, 0x4c, 0x1e, 0xaa, 0xc2, 0x7a, 0x44, 0x58, 0x7b, 0x17,
-	0x12, 0x2f, 0x32, 0xc9, 0x67, 0xe4, 0xb0, 0x89, 0x83, 0x0e, 0x7d, 0xdb, 0xf3, 0x95, 0x60, 0xf2,
-	0x58, 0xcd, 0x11, 0x2f, 0xa1, 0xf4, 0x44, 0xa1, 0xbd, 0xe5, 0xeb, 0x7c, 0xff, 0x3b, 0xed, 0x9f,
-	0x01, 0x9b, 0xec, 0x74, 0xf3, 0xe5, 0xf4, 0x40, 0xe1, 0xdd, 0x1a, 0xc2, 0x47, 0x38, 0x5a, 0xda,
-	0xa1, 0xf8, 0x43, 0x3c, 0x8f, 0xb0, 0xbb, 0xfa, 0xf7, 0x6b, 0xd5, 0x41, 0x57, 0xe6, 0x51, 0xaf,
-	0x3f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x35, 0x50, 0x26, 0x86, 0x69, 0x01, 0x00, 0x00,
+	// 312 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa5, 0x52, 0x4d, 0x4f, 0x83, 0x40,
+	0x10, 0x75, 0x5b, 0x42, 0xd3, 0x01, 0x9b, 0x38, 0xda, 0x86, 0x10, 0x0f, 0x88, 0x17, 0x4e, 0x1c,
+	0xea, 0x3f, 0x68, 0x8d, 0xb1, 0x89, 0xf1, 0xb0, 0x8d, 0x27, 0x13, 0x13, 0x3e, 0x36, 0x81, 0x14,
+	0x59, 0xdc, 0xa5, 0xfe, 0x08, 0x7f, 0x52, 0x7f, 0x81, 0x3f, 0x4b, 0xba, 0x40, 0x84, 0x6a, 0xea,
+	0xc1, 0xdb, 0xbc, 0x97, 0x99, 0x37, 0xfb, 0xde, 0x2c, 0x9c, 0x85, 0x2c, 0x88, 0x78, 0x1e, 0x25,
+	0x41, 0x9a, 0xfb, 0x85, 0xe0, 0x25, 0x47, 0xa3, 0x43, 0xb9, 0x53, 0x38, 0xbf, 0x63, 0x65, 0x94,
+	0x3c, 0xb0, 0x20, 0x66, 0x42, 0x52, 0xf6, 0xb6, 0x65, 0xb2, 0x74, 0x77, 0x04, 0x2e, 0xfa, 0xbc,
+	0x2c, 0x78, 0x2e, 0x19, 0x2e, 0x60, 0x94, 0xd5, 0x94, 0x45, 0x9c, 0xa1, 0x67, 0xcc, 0x3d, 0xbf,
+	0xbb, 0xe1, 0xb7, 0x19, 0xbf, 0xc6, 0xb4, 0x1d, 0xb4, 0x5f, 0x40, 0xaf, 0x29, 0x9c, 0xc0, 0x20,
+	0x2d, 0x2a, 0x21, 0xe2, 0x8d, 0x69, 0x55, 0x21, 0x82, 0x56, 0x70, 0x51, 0x5a, 0x03, 0xc5, 0xa8,
+	0x1a, 0x2d, 0x18, 0xc9, 0x24, 0x10, 0xf1, 0x2a, 0xb6, 0x86, 0x15, 0x7d, 0x4a, 0x5b, 0x88, 0x33,
+	0xd0, 0x0b, 0xc6, 0xc4, 0xea, 0xd6, 0xd2, 0x54, 0x7f, 0x83, 0xdc, 0x35, 0x4c, 0xd5, 0x3b, 0x96,
+	0x82, 0x4b, 0x99, 0xa5, 0xf9, 0xa6, 0x71, 0xd5, 0x95, 0x22, 0x7d, 0x29, 0x07, 0x8c, 0x30, 0xe3,
+	0xd1, 0xe6, 0x71, 0xfb, 0x1a, 0x32, 0xa1, 0xf6, 0x6b, 0xb4, 0x4b, 0xb9, 0x1f, 0x04, 0x66, 0x87,
+	0xaa, 0x4d, 0x26, 0xff, 0x90, 0xc5, 0x4b, 0x18, 0x2b, 0x78, 0x1f, 0xc8, 0x44, 0xf9, 0x33, 0xe9,
+	0x37, 0xb1, 0x77, 0x98, 0xa8, 0xa4, 0x94, 0x43, 0x93, 0x36, 0x68, 0xfe, 0x49, 0x00, 0x17, 0x2a,
+	0xf6, 0xe5, 0x3e, 0xf6, 0x35, 0x13, 0xef, 0x69, 0xc4, 0xf0, 0x09, 0xcc, 0xee, 0x01, 0xd0, 0x39,
+	0x72, 0x1b, 0x95, 0x88, 0x7d, 0xf5, 0xe7, 0xf5, 0xdc, 0x13, 0x7c, 0x86, 0x49, 0xdf, 0x39, 0xba,
+	0x3f, 0xc7, 0x0e, 0xc3, 0xb6, 0xaf, 0x8f, 0xf6, 0xb4, 0xe2, 0xa1, 0xae, 0x3e, 0xe5, 0xcd, 0x17,
+	0x63, 0x52, 0xa9, 0x5f, 0xa9, 0x02, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -196,6 +318,7 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type BeaconChainServiceClient interface {
 	FetchLeaders(ctx context.Context, in *FetchLeadersRequest, opts ...grpc.CallOption) (*FetchLeadersResponse, error)
+	FetchCrosslink(ctx context.Context, in *FetchCrosslinkRequest, opts ...grpc.CallOption) (*FetchCrosslinkResponse, error)
 }
 
 type beaconChainServiceClient struct {
@@ -215,9 +338,19 @@ func (c *beaconChainServiceClient) FetchLeaders(ctx context.Context, in *FetchLe
 	return out, nil
 }
 
+func (c *beaconChainServiceClient) FetchCrosslink(ctx context.Context, in *FetchCrosslinkRequest, opts ...grpc.CallOption) (*FetchCrosslinkResponse, error) {
+	out := new(FetchCrosslinkResponse)
+	err := c.cc.Invoke(ctx, "/beaconchain.BeaconChainService/FetchCrosslink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BeaconChainServiceServer is the server API for BeaconChainService service.
 type BeaconChainServiceServer interface {
 	FetchLeaders(context.Context, *FetchLeadersRequest) (*FetchLeadersResponse, error)
+	FetchCrosslink(context.Context, *FetchCrosslinkRequest) (*FetchCrosslinkResponse, error)
 }
 
 func RegisterBeaconChainServiceServer(s *grpc.Server, srv BeaconChainServiceServer) {
@@ -242,6 +375,24 @@ func _BeaconChainService_FetchLeaders_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BeaconChainService_FetchCrosslink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchCrosslinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconChainServiceServer).FetchCrosslink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/beaconchain.BeaconChainService/FetchCrosslink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconChainServiceServer).FetchCrosslink(ctx, req.(*FetchCrosslinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _BeaconChainService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "beaconchain.BeaconChainService",
 	HandlerType: (*BeaconChainServiceServer)(nil),
@@ -250,6 +401,10 @@ var _BeaconChainService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FetchLeaders",
 			Handler:    _BeaconChainService_FetchLeaders_Handler,
 		},
+		{
+			MethodName: "FetchCrosslink",
+			Handler:    _BeaconChainService_FetchCrosslink_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "beaconchain.proto",
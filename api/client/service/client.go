@@ -74,3 +74,11 @@ func (client *Client) GetStakingContractInfo(address common.Address) *proto.Stak
 	}
 	return response
 }
+
+// GetRandomness gets the randomness of an epoch, or of the epoch of the block number if not 0.
+func (client *Client) GetRandomness(epoch uint64, blockNumber uint64) (*proto.GetRandomnessResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &proto.GetRandomnessRequest{Epoch: epoch, BlockNumber: blockNumber}
+	return client.clientServiceClient.GetRandomness(ctx, request)
+}
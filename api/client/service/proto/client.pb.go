@@ -295,6 +295,178 @@ func (m *StakingContractInfoResponse) GetNonce() uint64 {
 	return 0
 }
 
+// GetRandomnessRequest is the request to get the randomness of an epoch.
+type GetRandomnessRequest struct {
+	// The epoch of the randomness.
+	Epoch uint64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// The number of a block in the epoch of the randomness, used instead of the epoch if not 0.
+	BlockNumber          uint64   `protobuf:"varint,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRandomnessRequest) Reset()         { *m = GetRandomnessRequest{} }
+func (m *GetRandomnessRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRandomnessRequest) ProtoMessage()    {}
+func (*GetRandomnessRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_014de31d7ac8c57c, []int{6}
+}
+
+func (m *GetRandomnessRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRandomnessRequest.Unmarshal(m, b)
+}
+func (m *GetRandomnessRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRandomnessRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRandomnessRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRandomnessRequest.Merge(m, src)
+}
+func (m *GetRandomnessRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRandomnessRequest.Size(m)
+}
+func (m *GetRandomnessRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRandomnessRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRandomnessRequest proto.InternalMessageInfo
+
+func (m *GetRandomnessRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *GetRandomnessRequest) GetBlockNumber() uint64 {
+	if m != nil {
+		return m.BlockNumber
+	}
+	return 0
+}
+
+// GetRandomnessResponse is the response of GetRandomness, with the material to verify the randomness.
+type GetRandomnessResponse struct {
+	// The epoch of the randomness.
+	Epoch uint64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// The number of the block committing pRnd, the first block of the epoch.
+	PrndBlockNumber uint64 `protobuf:"varint,2,opt,name=prnd_block_number,json=prndBlockNumber,proto3" json:"prnd_block_number,omitempty"`
+	// pRnd, the XOR of the vrfs committed by the committee.
+	Prnd []byte `protobuf:"bytes,3,opt,name=prnd,proto3" json:"prnd,omitempty"`
+	// The bitmap of the committee members who committed their vrf.
+	Bitmap []byte `protobuf:"bytes,4,opt,name=bitmap,proto3" json:"bitmap,omitempty"`
+	// The signed drand commit messages, each one with its vrf and the BLS signature proving it.
+	Commits [][]byte `protobuf:"bytes,5,rep,name=commits,proto3" json:"commits,omitempty"`
+	// The number of the block committing the final randomness, the last block of the epoch.
+	RandBlockNumber uint64 `protobuf:"varint,6,opt,name=rand_block_number,json=randBlockNumber,proto3" json:"rand_block_number,omitempty"`
+	// The final randomness, the hash of the VDF output. Empty until the last block of the epoch.
+	Rand []byte `protobuf:"bytes,7,opt,name=rand,proto3" json:"rand,omitempty"`
+	// The output of the VDF over pRnd.
+	VdfOutput []byte `protobuf:"bytes,8,opt,name=vdf_output,json=vdfOutput,proto3" json:"vdf_output,omitempty"`
+	// The proof of the VDF output.
+	VdfProof []byte `protobuf:"bytes,9,opt,name=vdf_proof,json=vdfProof,proto3" json:"vdf_proof,omitempty"`
+	// The number of sequential squarings of the VDF.
+	VdfDifficulty        uint64   `protobuf:"varint,10,opt,name=vdf_difficulty,json=vdfDifficulty,proto3" json:"vdf_difficulty,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRandomnessResponse) Reset()         { *m = GetRandomnessResponse{} }
+func (m *GetRandomnessResponse) String() string { return proto.CompactTextString(m) }
+func (*GetRandomnessResponse) ProtoMessage()    {}
+func (*GetRandomnessResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_014de31d7ac8c57c, []int{7}
+}
+
+func (m *GetRandomnessResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRandomnessResponse.Unmarshal(m, b)
+}
+func (m *GetRandomnessResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRandomnessResponse.Marshal(b, m, deterministic)
+}
+func (m *GetRandomnessResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRandomnessResponse.Merge(m, src)
+}
+func (m *GetRandomnessResponse) XXX_Size() int {
+	return xxx_messageInfo_GetRandomnessResponse.Size(m)
+}
+func (m *GetRandomnessResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRandomnessResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRandomnessResponse proto.InternalMessageInfo
+
+func (m *GetRandomnessResponse) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *GetRandomnessResponse) GetPrndBlockNumber() uint64 {
+	if m != nil {
+		return m.PrndBlockNumber
+	}
+	return 0
+}
+
+func (m *GetRandomnessResponse) GetPrnd() []byte {
+	if m != nil {
+		return m.Prnd
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetBitmap() []byte {
+	if m != nil {
+		return m.Bitmap
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetCommits() [][]byte {
+	if m != nil {
+		return m.Commits
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetRandBlockNumber() uint64 {
+	if m != nil {
+		return m.RandBlockNumber
+	}
+	return 0
+}
+
+func (m *GetRandomnessResponse) GetRand() []byte {
+	if m != nil {
+		return m.Rand
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetVdfOutput() []byte {
+	if m != nil {
+		return m.VdfOutput
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetVdfProof() []byte {
+	if m != nil {
+		return m.VdfProof
+	}
+	return nil
+}
+
+func (m *GetRandomnessResponse) GetVdfDifficulty() uint64 {
+	if m != nil {
+		return m.VdfDifficulty
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*FetchAccountStateRequest)(nil), "client.FetchAccountStateRequest")
 	proto.RegisterType((*FetchAccountStateResponse)(nil), "client.FetchAccountStateResponse")
@@ -302,31 +474,46 @@ func init() {
 	proto.RegisterType((*GetFreeTokenResponse)(nil), "client.GetFreeTokenResponse")
 	proto.RegisterType((*StakingContractInfoRequest)(nil), "client.StakingContractInfoRequest")
 	proto.RegisterType((*StakingContractInfoResponse)(nil), "client.StakingContractInfoResponse")
+	proto.RegisterType((*GetRandomnessRequest)(nil), "client.GetRandomnessRequest")
+	proto.RegisterType((*GetRandomnessResponse)(nil), "client.GetRandomnessResponse")
 }
 
 func init() { proto.RegisterFile("client.proto", fileDescriptor_014de31d7ac8c57c) }
 
 var fileDescriptor_014de31d7ac8c57c = []byte{
-	// 302 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x52, 0x41, 0x4f, 0xf2, 0x40,
-	0x14, 0xfc, 0xda, 0x0f, 0x31, 0xbe, 0xd4, 0xa8, 0x2b, 0x31, 0xb5, 0x78, 0xa8, 0xeb, 0x05, 0x3d,
-	0x60, 0xa2, 0xc6, 0x3b, 0x21, 0x81, 0x10, 0x6e, 0xad, 0x27, 0x2f, 0x66, 0xd9, 0x3e, 0xb5, 0x81,
-	0xec, 0x62, 0xfb, 0x30, 0xfc, 0x19, 0xff, 0xab, 0xa1, 0xdd, 0x35, 0x35, 0xb6, 0x70, 0xdb, 0x99,
-	0x7d, 0xf3, 0x26, 0x3b, 0xb3, 0xe0, 0xc9, 0x45, 0x8a, 0x8a, 0xfa, 0xcb, 0x4c, 0x93, 0x66, 0xed,
-	0x12, 0xf1, 0x07, 0xf0, 0x47, 0x48, 0xf2, 0x7d, 0x20, 0xa5, 0x5e, 0x29, 0x8a, 0x49, 0x10, 0x46,
-	0xf8, 0xb1, 0xc2, 0x9c, 0x98, 0x0f, 0xfb, 0x22, 0x49, 0x32, 0xcc, 0x73, 0xdf, 0x09, 0x9d, 0x9e,
-	0x17, 0x59, 0xc8, 0xa7, 0x70, 0x5e, 0xa3, 0xca, 0x97, 0x5a, 0xe5, 0xb8, 0x91, 0xcd, 0xc4, 0x42,
-	0x28, 0x89, 0x56, 0x66, 0x20, 0xeb, 0xc0, 0x9e, 0xd2, 0x1b, 0xde, 0x0d, 0x9d, 0x5e, 0x2b, 0x2a,
-	0x01, 0xbf, 0x85, 0xd3, 0x31, 0xd2, 0x28, 0x43, 0x7c, 0xd2, 0x73, 0x54, 0xbb, 0xdd, 0x6f, 0xa0,
-	0xf3, 0x5b, 0x60, 0x8c, 0x19, 0xb4, 0x68, 0x3d, 0x49, 0xcc, 0x78, 0x71, 0xe6, 0x8f, 0x10, 0xc4,
-	0x24, 0xe6, 0xa9, 0x7a, 0x1b, 0x6a, 0x45, 0x99, 0x90, 0x34, 0x51, 0xaf, 0x7a, 0xb7, 0xc7, 0x1a,
-	0xba, 0xb5, 0x3a, 0x63, 0x75, 0x0d, 0xc7, 0xd2, 0xf0, 0x2f, 0xd5, 0x0d, 0x07, 0xd1, 0x91, 0xe5,
-	0x07, 0x25, 0x5d, 0x8d, 0xc3, 0x6d, 0x88, 0xe3, 0x7f, 0x25, 0x8e, 0xbb, 0x2f, 0x17, 0x0e, 0x87,
-	0x45, 0x39, 0x31, 0x66, 0x9f, 0xa9, 0x44, 0xf6, 0x0c, 0x27, 0x7f, 0xd2, 0x66, 0x61, 0xdf, 0xf4,
-	0xd9, 0x54, 0x5f, 0x70, 0xb9, 0x65, 0xa2, 0x7c, 0x06, 0xff, 0xc7, 0xa6, 0xe0, 0x55, 0xb3, 0x64,
-	0x5d, 0x2b, 0xaa, 0xa9, 0x24, 0xb8, 0xa8, 0xbf, 0xfc, 0x59, 0x26, 0xe1, 0x6c, 0x8c, 0x54, 0x93,
-	0x1b, 0xe3, 0x56, 0xd9, 0x5c, 0x46, 0x70, 0xb5, 0x75, 0xc6, 0x9a, 0xcc, 0xda, 0xc5, 0x07, 0xbe,
-	0xff, 0x0e, 0x00, 0x00, 0xff, 0xff, 0xe9, 0x3e, 0x0b, 0x3e, 0xd0, 0x02, 0x00, 0x00,
+	// 499 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x85, 0x54, 0x5d, 0x4f, 0xdb, 0x30,
+	0x14, 0x1d, 0xa5, 0x14, 0x7a, 0xd7, 0xae, 0x60, 0x3e, 0xe4, 0xa5, 0x43, 0x6a, 0x33, 0x21, 0x01,
+	0x0f, 0x20, 0x31, 0xb4, 0x77, 0xc6, 0xc4, 0x84, 0x90, 0x60, 0x0a, 0x7b, 0xda, 0x4b, 0x94, 0x38,
+	0xce, 0x1a, 0xb5, 0xb1, 0x43, 0xe2, 0x20, 0xf8, 0x7b, 0xfb, 0x3f, 0xfc, 0x07, 0x6c, 0xc7, 0x29,
+	0xa1, 0xa4, 0xe5, 0xcd, 0xe7, 0x9c, 0x7b, 0xef, 0xb1, 0x6f, 0xee, 0x0d, 0x74, 0xc8, 0x24, 0xa2,
+	0x4c, 0x1c, 0x25, 0x29, 0x17, 0x1c, 0xb5, 0x0a, 0x64, 0x9f, 0x02, 0xbe, 0xa0, 0x82, 0x8c, 0xce,
+	0x08, 0xe1, 0x39, 0x13, 0xb7, 0xc2, 0x13, 0xd4, 0xa1, 0x77, 0x39, 0xcd, 0x04, 0xc2, 0xb0, 0xea,
+	0x05, 0x41, 0x4a, 0xb3, 0x0c, 0x2f, 0x0d, 0x96, 0xf6, 0x3b, 0x4e, 0x09, 0xed, 0x2b, 0xf8, 0x5c,
+	0x93, 0x95, 0x25, 0x9c, 0x65, 0x54, 0xa5, 0xf9, 0xde, 0xc4, 0x63, 0x84, 0x96, 0x69, 0x06, 0xa2,
+	0x2d, 0x58, 0x61, 0x5c, 0xf1, 0x0d, 0xc9, 0x37, 0x9d, 0x02, 0xd8, 0xc7, 0xb0, 0xf9, 0x8b, 0x8a,
+	0x8b, 0x94, 0xd2, 0x3f, 0x7c, 0x4c, 0xd9, 0xfb, 0xee, 0x87, 0xb0, 0xf5, 0x3a, 0xc1, 0x18, 0x23,
+	0x68, 0x8a, 0x87, 0xcb, 0xc0, 0x84, 0xeb, 0xb3, 0xfd, 0x1d, 0x2c, 0x79, 0xbb, 0x71, 0xc4, 0xfe,
+	0x9d, 0x73, 0x26, 0x52, 0x8f, 0x88, 0x4b, 0x16, 0xf2, 0xf7, 0x3d, 0x1e, 0xa0, 0x5f, 0x9b, 0x67,
+	0xac, 0x0e, 0x60, 0x9d, 0x18, 0xde, 0xad, 0x56, 0x68, 0x3b, 0xbd, 0x92, 0x3f, 0x2b, 0xe8, 0x6a,
+	0x3b, 0x1a, 0x73, 0xda, 0xb1, 0x5c, 0x6d, 0xc7, 0x8d, 0x7e, 0x9d, 0xe3, 0xb1, 0x80, 0xc7, 0x4c,
+	0x16, 0x28, 0xef, 0x2a, 0xa3, 0x69, 0xc2, 0xc9, 0x48, 0xfb, 0xc8, 0x68, 0x0d, 0xd0, 0x10, 0x3a,
+	0xfe, 0x84, 0x93, 0xb1, 0xcb, 0xf2, 0xd8, 0xa7, 0xa9, 0xe9, 0xec, 0x47, 0xcd, 0x5d, 0x6b, 0xca,
+	0xfe, 0xdf, 0x80, 0xed, 0x99, 0x8a, 0xe6, 0x15, 0xf5, 0x25, 0x0f, 0x61, 0x23, 0x49, 0x59, 0xe0,
+	0xd6, 0xd4, 0xed, 0x29, 0xe1, 0xc7, 0x4b, 0x6d, 0xd5, 0x72, 0x45, 0xe9, 0x17, 0xc8, 0x96, 0xab,
+	0x33, 0xda, 0x81, 0x96, 0x1f, 0x89, 0xd8, 0x4b, 0x70, 0x53, 0xb3, 0x06, 0xa9, 0x46, 0x10, 0x1e,
+	0xc7, 0x91, 0xc8, 0xf0, 0xca, 0x60, 0x59, 0x35, 0xc2, 0x40, 0xe5, 0x98, 0x7a, 0xb3, 0x8e, 0xad,
+	0xc2, 0x51, 0x09, 0x33, 0x8e, 0x8a, 0xc2, 0xab, 0x85, 0xa3, 0x3a, 0xa3, 0x5d, 0x80, 0xfb, 0x20,
+	0x74, 0x79, 0x2e, 0x92, 0x5c, 0xe0, 0x35, 0xad, 0xb4, 0x25, 0x73, 0xa3, 0x09, 0xd4, 0x07, 0x05,
+	0x5c, 0x39, 0xf8, 0x3c, 0xc4, 0x6d, 0xad, 0xae, 0x49, 0xe2, 0xb7, 0xc2, 0x68, 0x0f, 0x3e, 0x29,
+	0x31, 0x88, 0xc2, 0x30, 0x22, 0xf9, 0x44, 0x3c, 0x62, 0xd0, 0xc6, 0x5d, 0xc9, 0xfe, 0x9c, 0x92,
+	0x27, 0x4f, 0x0d, 0xe8, 0x9e, 0xeb, 0x95, 0xb9, 0xa5, 0xe9, 0x7d, 0x24, 0xbf, 0xde, 0x5f, 0xd8,
+	0x78, 0xb3, 0x03, 0x68, 0x70, 0x64, 0xb6, 0x6c, 0xde, 0x52, 0x59, 0xc3, 0x05, 0x11, 0xc5, 0x67,
+	0xb1, 0x3f, 0xa0, 0x2b, 0xe8, 0x54, 0x27, 0x1c, 0xf5, 0xcb, 0xa4, 0x9a, 0x45, 0xb1, 0xbe, 0xd4,
+	0x8b, 0xd3, 0x62, 0x04, 0x76, 0xa4, 0x52, 0x33, 0xcd, 0xc8, 0x2e, 0x33, 0xe7, 0xaf, 0x88, 0xf5,
+	0x75, 0x61, 0xcc, 0xd4, 0xe4, 0x1a, 0xba, 0xaf, 0x66, 0x0c, 0x55, 0x6f, 0xf5, 0x66, 0x98, 0xad,
+	0xdd, 0x39, 0x6a, 0x59, 0xcf, 0x6f, 0xe9, 0xdf, 0xd4, 0xb7, 0x67, 0xde, 0xee, 0x8e, 0xe7, 0xb6,
+	0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -344,6 +531,7 @@ type ClientServiceClient interface {
 	FetchAccountState(ctx context.Context, in *FetchAccountStateRequest, opts ...grpc.CallOption) (*FetchAccountStateResponse, error)
 	GetFreeToken(ctx context.Context, in *GetFreeTokenRequest, opts ...grpc.CallOption) (*GetFreeTokenResponse, error)
 	GetStakingContractInfo(ctx context.Context, in *StakingContractInfoRequest, opts ...grpc.CallOption) (*StakingContractInfoResponse, error)
+	GetRandomness(ctx context.Context, in *GetRandomnessRequest, opts ...grpc.CallOption) (*GetRandomnessResponse, error)
 }
 
 type clientServiceClient struct {
@@ -381,11 +569,21 @@ func (c *clientServiceClient) GetStakingContractInfo(ctx context.Context, in *St
 	return out, nil
 }
 
+func (c *clientServiceClient) GetRandomness(ctx context.Context, in *GetRandomnessRequest, opts ...grpc.CallOption) (*GetRandomnessResponse, error) {
+	out := new(GetRandomnessResponse)
+	err := c.cc.Invoke(ctx, "/client.ClientService/GetRandomness", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ClientServiceServer is the server API for ClientService service.
 type ClientServiceServer interface {
 	FetchAccountState(context.Context, *FetchAccountStateRequest) (*FetchAccountStateResponse, error)
 	GetFreeToken(context.Context, *GetFreeTokenRequest) (*GetFreeTokenResponse, error)
 	GetStakingContractInfo(context.Context, *StakingContractInfoRequest) (*StakingContractInfoResponse, error)
+	GetRandomness(context.Context, *GetRandomnessRequest) (*GetRandomnessResponse, error)
 }
 
 func RegisterClientServiceServer(s *grpc.Server, srv ClientServiceServer) {
@@ -446,6 +644,24 @@ func _ClientService_GetStakingContractInfo_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ClientService_GetRandomness_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRandomnessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientServiceServer).GetRandomness(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/client.ClientService/GetRandomness",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientServiceServer).GetRandomness(ctx, req.(*GetRandomnessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ClientService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "client.ClientService",
 	HandlerType: (*ClientServiceServer)(nil),
@@ -462,6 +678,10 @@ var _ClientService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetStakingContractInfo",
 			Handler:    _ClientService_GetStakingContractInfo_Handler,
 		},
+		{
+			MethodName: "GetRandomness",
+			Handler:    _ClientService_GetRandomness_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "client.proto",
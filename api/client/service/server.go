@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	proto "github.com/harmony-one/harmony/api/client/service/proto"
 	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/drand"
 	"google.golang.org/grpc"
 )
 
@@ -16,6 +17,8 @@ type Server struct {
 	stateReader                       func() (*state.DB, error)
 	callFaucetContract                func(common.Address) common.Hash
 	getDeployedStakingContractAddress func() common.Address
+	getEpochRandomness                func(epoch uint64) (*drand.EpochRandomness, error)
+	epochOf                           func(blockNumber uint64) uint64
 }
 
 // FetchAccountState implements the FetchAccountState interface to return account state.
@@ -53,6 +56,34 @@ func (s *Server) GetStakingContractInfo(ctx context.Context, request *proto.Stak
 	}, nil
 }
 
+// GetRandomness implements the GetRandomness interface to return the randomness of an epoch, with its proofs.
+func (s *Server) GetRandomness(ctx context.Context, request *proto.GetRandomnessRequest) (*proto.GetRandomnessResponse, error) {
+	epoch := request.Epoch
+	if request.BlockNumber != 0 {
+		epoch = s.epochOf(request.BlockNumber)
+	}
+	randomness, err := s.getEpochRandomness(epoch)
+	if err != nil {
+		return nil, err
+	}
+	response := &proto.GetRandomnessResponse{
+		Epoch:           randomness.Epoch,
+		PrndBlockNumber: randomness.PRndBlockNumber,
+		Prnd:            randomness.Preimage.PRnd[:],
+		Bitmap:          randomness.Preimage.Bitmap,
+		Commits:         randomness.Preimage.Commits,
+		RandBlockNumber: randomness.RandBlockNumber,
+		VdfDifficulty:   randomness.VdfDifficulty,
+	}
+	if randomness.Randomness != nil {
+		rand := randomness.Randomness.Rand()
+		response.Rand = rand[:]
+		response.VdfOutput = randomness.Randomness.Output
+		response.VdfProof = randomness.Randomness.Proof
+	}
+	return response, nil
+}
+
 // Start starts the Server on given ip and port.
 func (s *Server) Start(ip, port string) (*grpc.Server, error) {
 	// TODO(minhdoan): Currently not using ip. Fix it later.
@@ -72,11 +103,15 @@ func (s *Server) Start(ip, port string) (*grpc.Server, error) {
 func NewServer(
 	stateReader func() (*state.DB, error),
 	callFaucetContract func(common.Address) common.Hash,
-	getDeployedStakingContractAddress func() common.Address) *Server {
+	getDeployedStakingContractAddress func() common.Address,
+	getEpochRandomness func(epoch uint64) (*drand.EpochRandomness, error),
+	epochOf func(blockNumber uint64) uint64) *Server {
 	s := &Server{
 		stateReader:                       stateReader,
 		callFaucetContract:                callFaucetContract,
 		getDeployedStakingContractAddress: getDeployedStakingContractAddress,
+		getEpochRandomness:                getEpochRandomness,
+		epochOf:                           epochOf,
 	}
 	return s
 }
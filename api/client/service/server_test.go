@@ -15,6 +15,8 @@ import (
 	"github.com/harmony-one/harmony/consensus"
 	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/drand"
+	"github.com/harmony-one/harmony/internal/chainparams"
 )
 
 var (
@@ -33,7 +35,7 @@ func TestGetFreeToken(test *testing.T) {
 		return nil, nil
 	}, func(common.Address) common.Hash {
 		return hash
-	}, nil)
+	}, nil, nil, nil)
 
 	testBankKey, _ := crypto.GenerateKey()
 	testBankAddress := crypto.PubkeyToAddress(testBankKey.PublicKey)
@@ -67,7 +69,7 @@ func TestFetchAccountState(test *testing.T) {
 		return chain.State()
 	}, func(common.Address) common.Hash {
 		return hash
-	}, nil)
+	}, nil, nil, nil)
 
 	response, err := server.FetchAccountState(nil, &client.FetchAccountStateRequest{Address: testBankAddress.Bytes()})
 
@@ -83,3 +85,53 @@ func TestFetchAccountState(test *testing.T) {
 		test.Errorf("Wrong nonce is returned")
 	}
 }
+
+func TestGetRandomness(test *testing.T) {
+	randomness := &drand.EpochRandomness{
+		Epoch:           2,
+		PRndBlockNumber: 10,
+		Preimage:        &drand.Preimage{PRnd: [32]byte{1, 2, 3}, Bitmap: []byte{5}, Commits: [][]byte{{6}, {7}}},
+		RandBlockNumber: 14,
+		Randomness:      &drand.Randomness{PRnd: [32]byte{1, 2, 3}, Output: []byte{8}, Proof: []byte{9}},
+		VdfDifficulty:   100,
+	}
+	server := NewServer(nil, nil, nil, func(epoch uint64) (*drand.EpochRandomness, error) {
+		if epoch != randomness.Epoch {
+			return nil, drand.ErrRandomnessNotFound
+		}
+		return randomness, nil
+	}, chainparams.DefaultConfig.EpochOf)
+
+	// The epoch is taken from the block number if given
+	for _, request := range []*client.GetRandomnessRequest{{Epoch: 2}, {BlockNumber: 13}, {Epoch: 7, BlockNumber: 10}} {
+		response, err := server.GetRandomness(nil, request)
+		if err != nil {
+			test.Errorf("Failed to get randomness: %v", err)
+			continue
+		}
+		rand := randomness.Randomness.Rand()
+		if response.Epoch != 2 || response.PrndBlockNumber != 10 || response.RandBlockNumber != 14 {
+			test.Errorf("Wrong epoch or block numbers are returned")
+		}
+		if bytes.Compare(response.Prnd, randomness.Preimage.PRnd[:]) != 0 || bytes.Compare(response.Bitmap, []byte{5}) != 0 || len(response.Commits) != 2 {
+			test.Errorf("Wrong preimage is returned")
+		}
+		if bytes.Compare(response.Rand, rand[:]) != 0 || bytes.Compare(response.VdfOutput, []byte{8}) != 0 || bytes.Compare(response.VdfProof, []byte{9}) != 0 {
+			test.Errorf("Wrong randomness is returned")
+		}
+		if response.VdfDifficulty != randomness.VdfDifficulty {
+			test.Errorf("Wrong vdf difficulty is returned")
+		}
+	}
+
+	if _, err := server.GetRandomness(nil, &client.GetRandomnessRequest{Epoch: 3}); err != drand.ErrRandomnessNotFound {
+		test.Errorf("Expected the randomness not to be found")
+	}
+
+	// The final randomness isn't known before the last block of the epoch
+	randomness.Randomness = nil
+	response, err := server.GetRandomness(nil, &client.GetRandomnessRequest{Epoch: 2})
+	if err != nil || response.Rand != nil || response.VdfOutput != nil {
+		test.Errorf("Expected no final randomness")
+	}
+}
@@ -23,12 +23,15 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type MessageType int32
 
 const (
-	MessageType_UNKNOWN   MessageType = 0
-	MessageType_ANNOUNCE  MessageType = 1
-	MessageType_PREPARE   MessageType = 2
-	MessageType_PREPARED  MessageType = 3
-	MessageType_COMMIT    MessageType = 4
-	MessageType_COMMITTED MessageType = 5
+	MessageType_UNKNOWN    MessageType = 0
+	MessageType_ANNOUNCE   MessageType = 1
+	MessageType_PREPARE    MessageType = 2
+	MessageType_PREPARED   MessageType = 3
+	MessageType_COMMIT     MessageType = 4
+	MessageType_COMMITTED  MessageType = 5
+	MessageType_VIEWCHANGE MessageType = 6
+	MessageType_NEWVIEW    MessageType = 7
+	MessageType_EVIDENCE   MessageType = 8
 )
 
 var MessageType_name = map[int32]string{
@@ -38,15 +41,21 @@ var MessageType_name = map[int32]string{
 	3: "PREPARED",
 	4: "COMMIT",
 	5: "COMMITTED",
+	6: "VIEWCHANGE",
+	7: "NEWVIEW",
+	8: "EVIDENCE",
 }
 
 var MessageType_value = map[string]int32{
-	"UNKNOWN":   0,
-	"ANNOUNCE":  1,
-	"PREPARE":   2,
-	"PREPARED":  3,
-	"COMMIT":    4,
-	"COMMITTED": 5,
+	"UNKNOWN":    0,
+	"ANNOUNCE":   1,
+	"PREPARE":    2,
+	"PREPARED":   3,
+	"COMMIT":     4,
+	"COMMITTED":  5,
+	"VIEWCHANGE": 6,
+	"NEWVIEW":    7,
+	"EVIDENCE":   8,
 }
 
 func (x MessageType) String() string {
@@ -60,10 +69,11 @@ func (MessageType) EnumDescriptor() ([]byte, []int) {
 type Message struct {
 	Type                 MessageType `protobuf:"varint,1,opt,name=type,proto3,enum=consensus.MessageType" json:"type,omitempty"`
 	ConsensusId          uint32      `protobuf:"varint,2,opt,name=consensus_id,json=consensusId,proto3" json:"consensus_id,omitempty"`
-	SenderId             uint32      `protobuf:"varint,3,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	SenderPubkey         []byte      `protobuf:"bytes,3,opt,name=sender_pubkey,json=senderPubkey,proto3" json:"sender_pubkey,omitempty"`
 	BlockHash            []byte      `protobuf:"bytes,4,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
 	Payload              []byte      `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
 	Signature            []byte      `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	ViewId               uint32      `protobuf:"varint,7,opt,name=view_id,json=viewId,proto3" json:"view_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
 	XXX_sizecache        int32       `json:"-"`
@@ -108,11 +118,11 @@ func (m *Message) GetConsensusId() uint32 {
 	return 0
 }
 
-func (m *Message) GetSenderId() uint32 {
+func (m *Message) GetSenderPubkey() []byte {
 	if m != nil {
-		return m.SenderId
+		return m.SenderPubkey
 	}
-	return 0
+	return nil
 }
 
 func (m *Message) GetBlockHash() []byte {
@@ -136,6 +146,13 @@ func (m *Message) GetSignature() []byte {
 	return nil
 }
 
+func (m *Message) GetViewId() uint32 {
+	if m != nil {
+		return m.ViewId
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("consensus.MessageType", MessageType_name, MessageType_value)
 	proto.RegisterType((*Message)(nil), "consensus.Message")
@@ -144,22 +161,25 @@ func init() {
 func init() { proto.RegisterFile("consensus.proto", fileDescriptor_56f0f2c53b3de771) }
 
 var fileDescriptor_56f0f2c53b3de771 = []byte{
-	// 263 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xc1, 0x6a, 0xb3, 0x40,
-	0x14, 0x85, 0xff, 0x49, 0x8c, 0xc6, 0xab, 0xf9, 0x3b, 0xdc, 0x45, 0x19, 0x68, 0x0b, 0xb6, 0x2b,
-	0xc9, 0x22, 0x8b, 0xf6, 0x09, 0x42, 0x22, 0x54, 0x8a, 0x63, 0x10, 0x43, 0x77, 0x0d, 0x93, 0x38,
-	0xc4, 0xd0, 0xa0, 0xe2, 0x98, 0x85, 0xef, 0xd8, 0x87, 0x2a, 0x8e, 0xa9, 0xed, 0x6e, 0xce, 0xf7,
-	0x9d, 0x03, 0xc3, 0x85, 0x9b, 0x43, 0x59, 0x28, 0x59, 0xa8, 0x8b, 0x5a, 0x54, 0x75, 0xd9, 0x94,
-	0x68, 0x0f, 0xe0, 0xe9, 0x8b, 0x80, 0x15, 0x49, 0xa5, 0xc4, 0x51, 0xe2, 0x1c, 0x8c, 0xa6, 0xad,
-	0x24, 0x23, 0x1e, 0xf1, 0xff, 0x3f, 0xdf, 0x2e, 0x7e, 0x67, 0xd7, 0x46, 0xda, 0x56, 0x32, 0xd1,
-	0x1d, 0x7c, 0x04, 0x77, 0xd0, 0xbb, 0x53, 0xc6, 0x46, 0x1e, 0xf1, 0x67, 0x89, 0x33, 0xb0, 0x30,
-	0xc3, 0x3b, 0xb0, 0x95, 0x2c, 0x32, 0x59, 0x77, 0x7e, 0xac, 0xfd, 0xb4, 0x07, 0x61, 0x86, 0x0f,
-	0x00, 0xfb, 0x73, 0x79, 0xf8, 0xdc, 0xe5, 0x42, 0xe5, 0xcc, 0xf0, 0x88, 0xef, 0x26, 0xb6, 0x26,
-	0xaf, 0x42, 0xe5, 0xc8, 0xc0, 0xaa, 0x44, 0x7b, 0x2e, 0x45, 0xc6, 0x26, 0xda, 0xfd, 0x44, 0xbc,
-	0x07, 0x5b, 0x9d, 0x8e, 0x85, 0x68, 0x2e, 0xb5, 0x64, 0x66, 0xbf, 0x1b, 0xc0, 0xfc, 0x03, 0x9c,
-	0x3f, 0x7f, 0x45, 0x07, 0xac, 0x2d, 0x7f, 0xe3, 0xf1, 0x3b, 0xa7, 0xff, 0xd0, 0x85, 0xe9, 0x92,
-	0xf3, 0x78, 0xcb, 0x57, 0x01, 0x25, 0x9d, 0xda, 0x24, 0xc1, 0x66, 0x99, 0x04, 0x74, 0xd4, 0xa9,
-	0x6b, 0x58, 0xd3, 0x31, 0x02, 0x98, 0xab, 0x38, 0x8a, 0xc2, 0x94, 0x1a, 0x38, 0x03, 0xbb, 0x7f,
-	0xa7, 0xc1, 0x9a, 0x4e, 0xf6, 0xa6, 0x3e, 0xe0, 0xcb, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0xd5,
-	0x30, 0x8a, 0xaf, 0x53, 0x01, 0x00, 0x00,
+	// 307 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4d, 0x91, 0xcb, 0x6e, 0xc2, 0x30,
+	0x10, 0x45, 0x1b, 0x1e, 0x09, 0x99, 0x04, 0x6a, 0xcd, 0xa2, 0xf5, 0xa2, 0x95, 0xfa, 0xd8, 0x54,
+	0x2c, 0x58, 0x94, 0x2f, 0x40, 0x60, 0x95, 0xa8, 0x8a, 0x13, 0x45, 0x3c, 0x96, 0x28, 0x21, 0x16,
+	0x20, 0x10, 0x89, 0x30, 0xb4, 0xca, 0x27, 0xf4, 0x63, 0xfb, 0x0f, 0x8d, 0x0d, 0x4d, 0xbb, 0x9b,
+	0x39, 0xf7, 0xde, 0x99, 0xb1, 0x0c, 0xd7, 0xcb, 0x6c, 0x2f, 0xc5, 0x5e, 0x9e, 0x64, 0x2f, 0x3f,
+	0x64, 0xc7, 0x0c, 0xed, 0x0a, 0x3c, 0x7d, 0x1b, 0x60, 0xf9, 0x42, 0xca, 0x78, 0x25, 0xb0, 0x0b,
+	0x8d, 0x63, 0x91, 0x0b, 0x6a, 0x3c, 0x18, 0x2f, 0x9d, 0xd7, 0x9b, 0xde, 0x5f, 0xec, 0xe2, 0x98,
+	0x94, 0x6a, 0xa4, 0x3d, 0xf8, 0x08, 0x6e, 0x25, 0x2f, 0x36, 0x29, 0xad, 0x95, 0x99, 0x76, 0xe4,
+	0x54, 0xcc, 0x4b, 0xf1, 0x19, 0xda, 0x65, 0x9d, 0x8a, 0xc3, 0x22, 0x3f, 0x25, 0x5b, 0x51, 0xd0,
+	0x7a, 0xe9, 0x71, 0x23, 0xf7, 0x0c, 0x43, 0xcd, 0xf0, 0x1e, 0x20, 0xd9, 0x65, 0xcb, 0xed, 0x62,
+	0x1d, 0xcb, 0x35, 0x6d, 0x68, 0x87, 0xad, 0xc9, 0xb8, 0x04, 0x48, 0xc1, 0xca, 0xe3, 0x62, 0x97,
+	0xc5, 0x29, 0x6d, 0x6a, 0xed, 0xb7, 0xc5, 0x3b, 0xb0, 0xe5, 0x66, 0xb5, 0x8f, 0x8f, 0xa7, 0x83,
+	0xa0, 0xe6, 0x39, 0x57, 0x01, 0xbc, 0x05, 0xeb, 0x63, 0x23, 0x3e, 0xd5, 0x65, 0x96, 0xbe, 0xcc,
+	0x54, 0xad, 0x97, 0x76, 0xbf, 0x0c, 0x70, 0xfe, 0xbd, 0x06, 0x1d, 0xb0, 0xa6, 0xfc, 0x9d, 0x07,
+	0x73, 0x4e, 0xae, 0xd0, 0x85, 0xd6, 0x80, 0xf3, 0x60, 0xca, 0x87, 0x8c, 0x18, 0x4a, 0x0a, 0x23,
+	0x16, 0x0e, 0x22, 0x46, 0x6a, 0x4a, 0xba, 0x34, 0x23, 0x52, 0x47, 0x00, 0x73, 0x18, 0xf8, 0xbe,
+	0x37, 0x21, 0x0d, 0x6c, 0x83, 0x7d, 0xae, 0x27, 0xa5, 0xd4, 0xc4, 0x0e, 0xc0, 0xcc, 0x63, 0xf3,
+	0xe1, 0x78, 0xc0, 0xdf, 0x18, 0x31, 0xd5, 0x14, 0xce, 0xe6, 0x0a, 0x11, 0x4b, 0x4d, 0x61, 0x33,
+	0x6f, 0xc4, 0xd4, 0x82, 0x56, 0x62, 0xea, 0xdf, 0xe8, 0xff, 0x00, 0xf8, 0x44, 0x16, 0xd1, 0xa0,
+	0x01, 0x00, 0x00,
 }
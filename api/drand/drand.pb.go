@@ -50,7 +50,7 @@ func (MessageType) EnumDescriptor() ([]byte, []int) {
 
 type Message struct {
 	Type                 MessageType `protobuf:"varint,1,opt,name=type,proto3,enum=drand.MessageType" json:"type,omitempty"`
-	SenderId             uint32      `protobuf:"varint,3,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	SenderPubkey         []byte      `protobuf:"bytes,3,opt,name=sender_pubkey,json=senderPubkey,proto3" json:"sender_pubkey,omitempty"`
 	BlockHash            []byte      `protobuf:"bytes,4,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
 	Payload              []byte      `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
 	Signature            []byte      `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
@@ -91,11 +91,11 @@ func (m *Message) GetType() MessageType {
 	return MessageType_UNKNOWN
 }
 
-func (m *Message) GetSenderId() uint32 {
+func (m *Message) GetSenderPubkey() []byte {
 	if m != nil {
-		return m.SenderId
+		return m.SenderPubkey
 	}
-	return 0
+	return nil
 }
 
 func (m *Message) GetBlockHash() []byte {
@@ -127,19 +127,18 @@ func init() {
 func init() { proto.RegisterFile("drand.proto", fileDescriptor_1d855c36cf2c0c50) }
 
 var fileDescriptor_1d855c36cf2c0c50 = []byte{
-	// 214 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x8f, 0xcf, 0x4a, 0x87, 0x40,
-	0x10, 0x80, 0xdb, 0xf2, 0xe7, 0x9f, 0xb1, 0x42, 0xe6, 0xb4, 0x50, 0x81, 0x74, 0x08, 0xe9, 0x20,
-	0x51, 0x8f, 0xd0, 0xa5, 0x25, 0x5c, 0x41, 0x8c, 0x8e, 0xb2, 0xb6, 0x8b, 0x4a, 0xe2, 0x2e, 0xbb,
-	0x76, 0xf0, 0x81, 0x7a, 0xcf, 0x60, 0xad, 0xf8, 0x1d, 0xbf, 0xef, 0x9b, 0x81, 0x19, 0x48, 0xa5,
-	0x15, 0x8b, 0x2c, 0x8d, 0xd5, 0xab, 0xc6, 0x83, 0x87, 0xdb, 0x6f, 0x02, 0x51, 0xa5, 0x9c, 0x13,
-	0x83, 0xc2, 0x3b, 0x08, 0xd6, 0xcd, 0x28, 0x4a, 0x72, 0x52, 0x5c, 0x3e, 0x62, 0xb9, 0x8f, 0xff,
-	0xd6, 0x76, 0x33, 0xaa, 0xf1, 0x1d, 0xaf, 0x20, 0x71, 0x6a, 0x91, 0xca, 0x76, 0x93, 0xa4, 0x67,
-	0x39, 0x29, 0x2e, 0x9a, 0x78, 0x17, 0x4c, 0xe2, 0x0d, 0x40, 0x3f, 0xeb, 0x8f, 0xcf, 0x6e, 0x14,
-	0x6e, 0xa4, 0x41, 0x4e, 0x8a, 0xf3, 0x26, 0xf1, 0xe6, 0x45, 0xb8, 0x11, 0x29, 0x44, 0x46, 0x6c,
-	0xb3, 0x16, 0x92, 0x1e, 0x7c, 0xfb, 0x43, 0xbc, 0x86, 0xc4, 0x4d, 0xc3, 0x22, 0xd6, 0x2f, 0xab,
-	0x68, 0xb8, 0xef, 0xfd, 0x8b, 0xfb, 0x07, 0x48, 0x8f, 0x0e, 0xc1, 0x14, 0xa2, 0x37, 0xfe, 0xca,
-	0xeb, 0x77, 0x9e, 0x9d, 0x60, 0x0c, 0x01, 0xe3, 0xac, 0xcd, 0x08, 0x02, 0x84, 0xcf, 0x75, 0x55,
-	0xb1, 0x36, 0x3b, 0xed, 0x43, 0xff, 0xe7, 0xd3, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x79, 0xfa,
-	0xf8, 0x57, 0xf6, 0x00, 0x00, 0x00,
+	// 207 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4d, 0x8f, 0xcd, 0x0a, 0x82, 0x50,
+	0x10, 0x46, 0xb3, 0xfc, 0xc9, 0xd1, 0x42, 0x66, 0x75, 0x17, 0x05, 0x51, 0x10, 0xd1, 0x42, 0xa2,
+	0x1e, 0xa1, 0x4d, 0x12, 0x6a, 0x88, 0xd1, 0x52, 0xae, 0x79, 0xd1, 0x28, 0x54, 0xbc, 0xb6, 0xf0,
+	0xa1, 0x7a, 0xc7, 0xe4, 0x5a, 0xd1, 0x6e, 0xbe, 0x73, 0xbe, 0x81, 0x19, 0x30, 0x92, 0x8a, 0xe6,
+	0x89, 0x5d, 0x56, 0x45, 0x5d, 0xa0, 0x22, 0xc2, 0xfc, 0x25, 0x81, 0xe6, 0x32, 0xce, 0x69, 0xca,
+	0x70, 0x09, 0x72, 0xdd, 0x94, 0x8c, 0x48, 0x33, 0x69, 0x35, 0xde, 0xa2, 0xdd, 0xd5, 0x3f, 0x36,
+	0x6c, 0x4d, 0x20, 0x3c, 0x2e, 0x60, 0xc4, 0x59, 0x9e, 0xb0, 0x2a, 0x2a, 0x9f, 0xf1, 0x9d, 0x35,
+	0x64, 0xd0, 0x2e, 0x98, 0x81, 0xd9, 0xc1, 0x93, 0x60, 0x38, 0x05, 0x88, 0x1f, 0xc5, 0xf5, 0x1e,
+	0x65, 0x94, 0x67, 0x44, 0x16, 0x0d, 0x5d, 0x90, 0x43, 0x0b, 0x90, 0x80, 0x56, 0xd2, 0xe6, 0x51,
+	0xd0, 0x84, 0x28, 0xc2, 0x7d, 0x23, 0x4e, 0x40, 0xe7, 0xb7, 0x34, 0xa7, 0xf5, 0xb3, 0x62, 0x44,
+	0xed, 0xf6, 0x7e, 0x60, 0xbd, 0x01, 0xe3, 0xef, 0x20, 0x34, 0x40, 0x3b, 0x7b, 0x47, 0xcf, 0xbf,
+	0x78, 0x56, 0x0f, 0x87, 0x20, 0x3b, 0x9e, 0x13, 0x5a, 0x12, 0x02, 0xa8, 0x7b, 0xdf, 0x75, 0xdb,
+	0xb9, 0x1f, 0xab, 0xe2, 0xdf, 0xdd, 0x1b, 0x2b, 0x17, 0x6f, 0x83, 0xfe, 0x00, 0x00, 0x00,
 }
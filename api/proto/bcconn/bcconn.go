@@ -5,8 +5,10 @@ import (
 	"encoding/gob"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 
 	"github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core/types"
 )
 
 //ResponseRandomNumber struct for exchanging random information
@@ -63,3 +65,80 @@ func DeserializeRandomInfo(d []byte) ResponseRandomNumber {
 	}
 	return wn
 }
+
+// Crosslink is a committed block header of a shard chain, submitted to the beacon chain by
+// the leader of the shard
+type Crosslink struct {
+	Header     *types.Header
+	PublicKeys [][]byte         // the serialized BLS public keys of the committee, in the order of the header's bitmaps
+	ShardState types.ShardState // the shard state of the header, if it's an epoch block
+}
+
+// SerializeCrosslink serializes the crosslink
+func SerializeCrosslink(crosslink *Crosslink) []byte {
+	data, err := rlp.EncodeToBytes(crosslink)
+	if err != nil {
+		log.Error("Could not serialize crosslink", "error", err)
+	}
+	return data
+}
+
+// DeserializeCrosslink deserializes the crosslink
+func DeserializeCrosslink(d []byte) (*Crosslink, error) {
+	crosslink := &Crosslink{}
+	if err := rlp.DecodeBytes(d, crosslink); err != nil {
+		return nil, err
+	}
+	return crosslink, nil
+}
+
+// CrossShardReceipts is a message of the proven cross-shard receipts, relayed by the beacon
+// chain to the leader of their destination shard
+type CrossShardReceipts struct {
+	ToShardID uint32
+	Message   []byte // the node message of the receipts, as sent to the shard
+}
+
+// SerializeCrossShardReceipts serializes the cross-shard receipts to relay
+func SerializeCrossShardReceipts(receipts *CrossShardReceipts) []byte {
+	data, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		log.Error("Could not serialize cross-shard receipts", "error", err)
+	}
+	return data
+}
+
+// DeserializeCrossShardReceipts deserializes the cross-shard receipts to relay
+func DeserializeCrossShardReceipts(d []byte) (*CrossShardReceipts, error) {
+	receipts := &CrossShardReceipts{}
+	if err := rlp.DecodeBytes(d, receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// CommitteeRequest is sent by the beacon chain to the leader of a shard when it can't verify
+// a crosslink for not knowing the committee of its epoch, for the crosslink of the last block
+// before the epoch, which records the committee
+type CommitteeRequest struct {
+	ShardID uint32
+	Epoch   uint64
+}
+
+// SerializeCommitteeRequest serializes the committee request
+func SerializeCommitteeRequest(request *CommitteeRequest) []byte {
+	data, err := rlp.EncodeToBytes(request)
+	if err != nil {
+		log.Error("Could not serialize committee request", "error", err)
+	}
+	return data
+}
+
+// DeserializeCommitteeRequest deserializes the committee request
+func DeserializeCommitteeRequest(d []byte) (*CommitteeRequest, error) {
+	request := &CommitteeRequest{}
+	if err := rlp.DecodeBytes(d, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
@@ -2,10 +2,12 @@ package bcconn
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/internal/utils"
 )
 
@@ -52,3 +54,38 @@ func TestSerializeDeserializeRandomInfo(t *testing.T) {
 		t.Fatalf("serializin g and deserializing random response does not lead to original randominfo")
 	}
 }
+
+func TestSerializeDeserializeCrosslink(t *testing.T) {
+	_, pk := utils.GenKey("127.0.0.1", "8080")
+	header := &types.Header{Number: big.NewInt(7), ShardID: types.EncodeShardID(1), CommitBitmap: []byte{3}}
+	crosslink := &Crosslink{Header: header, PublicKeys: [][]byte{pk.Serialize()}}
+	deserialized, err := DeserializeCrosslink(SerializeCrosslink(crosslink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deserialized.Header.Hash() != header.Hash() || !reflect.DeepEqual(crosslink.PublicKeys, deserialized.PublicKeys) {
+		t.Fatalf("serializing and deserializing the crosslink does not lead to the original crosslink")
+	}
+}
+
+func TestSerializeDeserializeCrossShardReceipts(t *testing.T) {
+	receipts := &CrossShardReceipts{ToShardID: 2, Message: []byte{1, 2, 3}}
+	deserialized, err := DeserializeCrossShardReceipts(SerializeCrossShardReceipts(receipts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(receipts, deserialized) {
+		t.Fatalf("serializing and deserializing the cross-shard receipts does not lead to the original receipts")
+	}
+}
+
+func TestSerializeDeserializeCommitteeRequest(t *testing.T) {
+	request := &CommitteeRequest{ShardID: 1, Epoch: 3}
+	deserialized, err := DeserializeCommitteeRequest(SerializeCommitteeRequest(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(request, deserialized) {
+		t.Fatalf("serializing and deserializing the committee request does not lead to the original request")
+	}
+}
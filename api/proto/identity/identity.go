@@ -26,6 +26,9 @@ type MessageType int
 const (
 	Register MessageType = iota
 	Acknowledge
+	Crosslink
+	CrossShardReceipts
+	CommitteeRequest
 )
 
 // Returns string name for the MessageType enum
@@ -33,9 +36,12 @@ func (msgType MessageType) String() string {
 	names := [...]string{
 		"Register",
 		"Acknowledge",
+		"Crosslink",
+		"CrossShardReceipts",
+		"CommitteeRequest",
 	}
 
-	if msgType < Register || msgType > Acknowledge {
+	if msgType < Register || msgType > CommitteeRequest {
 		return "Unknown"
 	}
 	return names[msgType]
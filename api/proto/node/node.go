@@ -23,8 +23,9 @@ const (
 	Block
 	Client
 	Control
-	PING // node send ip/pki to register with leader
-	PONG // node broadcast pubK
+	PING       // node send ip/pki to register with leader
+	PONG       // node broadcast pubK
+	CrossShard // leader sends the proven receipts of the transfers to another shard
 	// TODO: add more types
 )
 
@@ -161,6 +162,17 @@ func ConstructStopMessage() []byte {
 	return byteBuffer.Bytes()
 }
 
+// ConstructCrossShardReceiptsMessage constructs the message sending the proven receipts
+// of the cross-shard transfers to their destination shard
+func ConstructCrossShardReceiptsMessage(proofs types.CrossShardReceiptProofs) []byte {
+	byteBuffer := bytes.NewBuffer([]byte{byte(proto.Node)})
+	byteBuffer.WriteByte(byte(CrossShard))
+
+	proofsData, _ := rlp.EncodeToBytes(proofs)
+	byteBuffer.Write(proofsData)
+	return byteBuffer.Bytes()
+}
+
 // ConstructBlocksSyncMessage constructs blocks sync message to send blocks to other nodes
 func ConstructBlocksSyncMessage(blocks []*types.Block) []byte {
 	byteBuffer := bytes.NewBuffer([]byte{byte(proto.Node)})
@@ -6,6 +6,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	clientService "github.com/harmony-one/harmony/api/client/service"
 	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/drand"
 	"google.golang.org/grpc"
 )
 
@@ -26,10 +27,12 @@ type Service struct {
 func New(stateReader func() (*state.DB, error),
 	callFaucetContract func(common.Address) common.Hash,
 	getDeployedStakingContract func() common.Address,
+	getEpochRandomness func(epoch uint64) (*drand.EpochRandomness, error),
+	epochOf func(blockNumber uint64) uint64,
 	ip, nodePort string) *Service {
 	port, _ := strconv.Atoi(nodePort)
 	return &Service{
-		server: clientService.NewServer(stateReader, callFaucetContract, getDeployedStakingContract),
+		server: clientService.NewServer(stateReader, callFaucetContract, getDeployedStakingContract, getEpochRandomness, epochOf),
 		ip:     ip,
 		port:   strconv.Itoa(port + ClientServicePortDiff)}
 }
@@ -55,7 +55,7 @@ func (s *Service) contactP2pPeers() {
 	tick := time.NewTicker(5 * time.Second)
 	ping := proto_discovery.NewPingMessage(s.host.GetSelfPeer())
 	buffer := ping.ConstructPingMessage()
-	content := host.ConstructP2pMessage(byte(0), buffer)
+	content := host.ConstructP2pMessage(host.MessageTypeDirect, buffer)
 	for {
 		select {
 		case peer, ok := <-s.peerChan:
@@ -93,7 +93,7 @@ func (s *Service) pingPeer() {
 	tick := time.NewTicker(5 * time.Second)
 	ping := proto_discovery.NewPingMessage(s.host.GetSelfPeer())
 	buffer := ping.ConstructPingMessage()
-	content := host.ConstructP2pMessage(byte(0), buffer)
+	content := host.ConstructP2pMessage(host.MessageTypeDirect, buffer)
 
 	for {
 		select {
@@ -12,6 +12,8 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/gorilla/mux"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/drand"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 )
@@ -23,18 +25,20 @@ const (
 
 // Service is the struct for explorer service.
 type Service struct {
-	router  *mux.Router
-	IP      string
-	Port    string
-	storage *Storage
-	server  *http.Server
+	router      *mux.Router
+	IP          string
+	Port        string
+	storage     *Storage
+	server      *http.Server
+	chainParams *chainparams.Config
 }
 
 // New returns explorer service.
-func New(selfPeer *p2p.Peer) *Service {
+func New(selfPeer *p2p.Peer, chainParams *chainparams.Config) *Service {
 	return &Service{
-		IP:   selfPeer.IP,
-		Port: selfPeer.Port,
+		IP:          selfPeer.IP,
+		Port:        selfPeer.Port,
+		chainParams: chainParams,
 	}
 }
 
@@ -87,6 +91,11 @@ func (s *Service) Run() *http.Server {
 	s.router.Path("/address").Queries("id", "{[0-9A-Fa-fx]*?}").HandlerFunc(s.GetExplorerAddress).Methods("GET")
 	s.router.Path("/address").HandlerFunc(s.GetExplorerAddress)
 
+	// Set up router for randomness.
+	s.router.Path("/randomness").Queries("epoch", "{[0-9]*?}").HandlerFunc(s.GetExplorerRandomness).Methods("GET")
+	s.router.Path("/randomness").Queries("block", "{[0-9]*?}").HandlerFunc(s.GetExplorerRandomness).Methods("GET")
+	s.router.Path("/randomness").HandlerFunc(s.GetExplorerRandomness)
+
 	// Do serving now.
 	utils.GetLogInstance().Info("Listening on ", "port: ", GetExplorerPort(s.Port))
 	server := &http.Server{Addr: addr, Handler: s.router}
@@ -244,3 +253,49 @@ func (s *Service) GetExplorerAddress(w http.ResponseWriter, r *http.Request) {
 	data.Address = address
 	json.NewEncoder(w).Encode(data.Address)
 }
+
+// GetHeader returns the header of the block of the given number in the explorer storage, or nil if not found.
+func (s *Service) GetHeader(number uint64) *types.Header {
+	data, err := s.storage.GetDB().Get([]byte(GetBlockKey(int(number))))
+	if err != nil {
+		return nil
+	}
+	block := new(types.Block)
+	if rlp.DecodeBytes(data, block) != nil {
+		return nil
+	}
+	return block.Header()
+}
+
+// GetExplorerRandomness serves /randomness end-point, returning the randomness of
+// the epoch given by number, or of the epoch of the given block.
+func (s *Service) GetExplorerRandomness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	epoch := r.FormValue("epoch")
+	block := r.FormValue("block")
+
+	var epochInt uint64
+	var err error
+	switch {
+	case block != "":
+		var blockInt uint64
+		blockInt, err = strconv.ParseUint(block, 10, 64)
+		epochInt = s.chainParams.EpochOf(blockInt)
+	case epoch != "":
+		epochInt, err = strconv.ParseUint(epoch, 10, 64)
+	default:
+		json.NewEncoder(w).Encode(nil)
+		return
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(nil)
+		return
+	}
+	randomness, err := drand.GetEpochRandomness(s.chainParams, epochInt, s.GetHeader)
+	if err != nil {
+		utils.GetLogInstance().Warn("Error on getting the randomness", "epoch", epochInt, "error", err)
+		json.NewEncoder(w).Encode(nil)
+		return
+	}
+	json.NewEncoder(w).Encode(GetRandomness(randomness))
+}
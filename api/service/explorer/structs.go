@@ -1,10 +1,12 @@
 package explorer
 
 import (
+	"encoding/hex"
 	"math/big"
 	"strconv"
 
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/drand"
 )
 
 /*
@@ -55,6 +57,43 @@ type RefBlock struct {
 	Height string `json:"height"`
 }
 
+// Randomness is the randomness of an epoch, with its vrf commits and VDF proof.
+type Randomness struct {
+	Epoch           string   `json:"epoch"`
+	PRndBlockNumber string   `json:"prndBlockNumber"`
+	PRnd            string   `json:"prnd"`
+	Bitmap          string   `json:"bitmap"`
+	Commits         []string `json:"commits"`
+	RandBlockNumber string   `json:"randBlockNumber"`
+	Rand            string   `json:"rand"`
+	VdfOutput       string   `json:"vdfOutput"`
+	VdfProof        string   `json:"vdfProof"`
+	VdfDifficulty   string   `json:"vdfDifficulty"`
+}
+
+// GetRandomness converts the randomness of an epoch into its explorer form, hex encoding the bytes.
+func GetRandomness(randomness *drand.EpochRandomness) *Randomness {
+	result := &Randomness{
+		Epoch:           strconv.FormatUint(randomness.Epoch, 10),
+		PRndBlockNumber: strconv.FormatUint(randomness.PRndBlockNumber, 10),
+		PRnd:            hex.EncodeToString(randomness.Preimage.PRnd[:]),
+		Bitmap:          hex.EncodeToString(randomness.Preimage.Bitmap),
+		Commits:         []string{},
+		RandBlockNumber: strconv.FormatUint(randomness.RandBlockNumber, 10),
+		VdfDifficulty:   strconv.FormatUint(randomness.VdfDifficulty, 10),
+	}
+	for _, commit := range randomness.Preimage.Commits {
+		result.Commits = append(result.Commits, hex.EncodeToString(commit))
+	}
+	if randomness.Randomness != nil {
+		rand := randomness.Randomness.Rand()
+		result.Rand = hex.EncodeToString(rand[:])
+		result.VdfOutput = hex.EncodeToString(randomness.Randomness.Output)
+		result.VdfProof = hex.EncodeToString(randomness.Randomness.Proof)
+	}
+	return result
+}
+
 // GetTransaction ...
 func GetTransaction(tx *types.Transaction, accountBlock *types.Block) *Transaction {
 	if tx.To() == nil {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/drand"
 )
 
 // Test for GetBlockInfoKey
@@ -25,3 +26,26 @@ func TestGetTransaction(t *testing.T) {
 	assert.Equal(t, tx.To, tx1.To().Hex(), "should be equal tx1.To()")
 	assert.Equal(t, tx.Bytes, strconv.Itoa(int(tx1.Size())), "should be equal tx1.Size()")
 }
+
+func TestGetRandomness(t *testing.T) {
+	randomness := &drand.EpochRandomness{
+		Epoch:           3,
+		PRndBlockNumber: 15,
+		Preimage:        &drand.Preimage{PRnd: [32]byte{0xab}, Bitmap: []byte{0x0f}, Commits: [][]byte{{0x01, 0x02}}},
+		RandBlockNumber: 19,
+	}
+	result := GetRandomness(randomness)
+	assert.Equal(t, "3", result.Epoch)
+	assert.Equal(t, "15", result.PRndBlockNumber)
+	assert.Equal(t, "19", result.RandBlockNumber)
+	assert.Equal(t, "ab", result.PRnd[:2])
+	assert.Equal(t, "0f", result.Bitmap)
+	assert.Equal(t, []string{"0102"}, result.Commits)
+	assert.Equal(t, "", result.Rand, "no final randomness before the last block of the epoch")
+
+	randomness.Randomness = &drand.Randomness{PRnd: [32]byte{0xab}, Output: []byte{0x03}, Proof: []byte{0x04}}
+	result = GetRandomness(randomness)
+	assert.Equal(t, 64, len(result.Rand))
+	assert.Equal(t, "03", result.VdfOutput)
+	assert.Equal(t, "04", result.VdfProof)
+}
@@ -30,6 +30,7 @@ const (
 	NetworkInfo
 	PeerDiscovery
 	Staking
+	ConsensusTracing
 	Test
 	Done
 )
@@ -54,6 +55,8 @@ func (t Type) String() string {
 		return "Staking"
 	case PeerDiscovery:
 		return "PeerDiscovery"
+	case ConsensusTracing:
+		return "ConsensusTracing"
 	case Test:
 		return "Test"
 	case Done:
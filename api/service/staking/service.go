@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/bls/ffi/go/bls"
 	client "github.com/harmony-one/harmony/api/client/service"
 	proto "github.com/harmony-one/harmony/api/client/service/proto"
 	"github.com/harmony-one/harmony/api/proto/message"
@@ -24,16 +25,22 @@ type Service struct {
 	stoppedChan   chan struct{}
 	peerChan      <-chan p2p.Peer
 	accountKey    *ecdsa.PrivateKey
+	blsPublicKey  *bls.PublicKey
 	stakingAmount int64
 }
 
+// depositFuncSignature is the function signature of deposit() of the staking contract.
+const depositFuncSignature = "0xd0e30db0"
+
 // New returns staking service.
-func New(accountKey *ecdsa.PrivateKey, stakingAmount int64, peerChan <-chan p2p.Peer) *Service {
+// The deposit registers the BLS public key the node runs consensus with once it joins a committee.
+func New(accountKey *ecdsa.PrivateKey, blsPublicKey *bls.PublicKey, stakingAmount int64, peerChan <-chan p2p.Peer) *Service {
 	return &Service{
 		stopChan:      make(chan struct{}),
 		stoppedChan:   make(chan struct{}),
 		peerChan:      peerChan,
 		accountKey:    accountKey,
+		blsPublicKey:  blsPublicKey,
 		stakingAmount: stakingAmount,
 	}
 }
@@ -84,6 +91,8 @@ func (s *Service) getStakingInfo(beaconPeer p2p.Peer) *proto.StakingContractInfo
 func (s *Service) createStakingMessage(beaconPeer p2p.Peer) *message.Message {
 	stakingInfo := s.getStakingInfo(beaconPeer)
 	toAddress := common.HexToAddress(stakingInfo.ContractAddress)
+	// Call deposit() with the BLS public key appended; the contract ignores the extra call data.
+	data := append(common.FromHex(depositFuncSignature), s.blsPublicKey.Serialize()...)
 	tx := types.NewTransaction(
 		stakingInfo.Nonce,
 		toAddress,
@@ -91,7 +100,7 @@ func (s *Service) createStakingMessage(beaconPeer p2p.Peer) *message.Message {
 		big.NewInt(s.stakingAmount),
 		params.CallValueTransferGas*2,           // hard-code
 		big.NewInt(int64(params.Sha256BaseGas)), // pick some predefined gas price.
-		nil)
+		data)
 
 	if signedTx, err := types.SignTx(tx, types.HomesteadSigner{}, s.accountKey); err == nil {
 		ts := types.Transactions{signedTx}
@@ -100,7 +109,7 @@ func (s *Service) createStakingMessage(beaconPeer p2p.Peer) *message.Message {
 			Request: &message.Message_Staking{
 				Staking: &message.StakingRequest{
 					Transaction: ts.GetRlp(0),
-					NodeId:      "",
+					NodeId:      s.blsPublicKey.SerializeToHexStr(),
 				},
 			},
 		}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// Constants for tracing service.
+const (
+	tracingPortDifference = 4500
+	defaultTimelineCount  = 10
+)
+
+// Service is the service exposing the timelines of the recent consensus rounds on localhost.
+type Service struct {
+	router *mux.Router
+	IP     string
+	Port   string
+	tracer *consensus.RoundTracer
+	server *http.Server
+}
+
+// New returns tracing service.
+func New(selfPeer *p2p.Peer, tracer *consensus.RoundTracer) *Service {
+	return &Service{
+		IP:     selfPeer.IP,
+		Port:   selfPeer.Port,
+		tracer: tracer,
+	}
+}
+
+// StartService starts tracing service.
+func (s *Service) StartService() {
+	utils.GetLogInstance().Info("Starting tracing service.")
+	s.server = s.Run()
+}
+
+// StopService shutdowns tracing service.
+func (s *Service) StopService() {
+	utils.GetLogInstance().Info("Shutting down tracing service.")
+	if err := s.server.Shutdown(context.Background()); err != nil {
+		utils.GetLogInstance().Error("Error when shutting down tracing server", "error", err)
+	}
+}
+
+// GetTracingPort returns the port serving the round timelines. This port is tracingPortDifference less than the node port.
+func GetTracingPort(nodePort string) string {
+	if port, err := strconv.Atoi(nodePort); err == nil {
+		return fmt.Sprintf("%d", port-tracingPortDifference)
+	}
+	utils.GetLogInstance().Error("error on parsing.")
+	return ""
+}
+
+// Run is to run serving round timelines.
+func (s *Service) Run() *http.Server {
+	// Only serve locally.
+	addr := net.JoinHostPort("127.0.0.1", GetTracingPort(s.Port))
+
+	s.router = mux.NewRouter()
+	// Set up router for the timelines, as a json array or in the export format.
+	s.router.Path("/timelines").HandlerFunc(s.GetTimelines).Methods("GET")
+	s.router.Path("/timelines/export").HandlerFunc(s.ExportTimelines).Methods("GET")
+
+	utils.GetLogInstance().Info("Listening on ", "port: ", GetTracingPort(s.Port))
+	server := &http.Server{Addr: addr, Handler: s.router}
+	go server.ListenAndServe()
+	return server
+}
+
+// GetTimelines serves the last timelines, latest first. The number of timelines is given by the count query.
+func (s *Service) GetTimelines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.tracer.Timelines(timelineCount(r))); err != nil {
+		utils.GetLogInstance().Warn("cannot JSON-encode timelines", "error", err)
+	}
+}
+
+// ExportTimelines serves the last timelines as a file with one JSON object per line.
+func (s *Service) ExportTimelines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"timelines.jsonl\"")
+	if err := consensus.ExportTimelines(w, s.tracer.Timelines(timelineCount(r))); err != nil {
+		utils.GetLogInstance().Warn("cannot export timelines", "error", err)
+	}
+}
+
+func timelineCount(r *http.Request) int {
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err != nil || count <= 0 {
+		return defaultTimelineCount
+	}
+	return count
+}
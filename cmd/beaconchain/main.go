@@ -44,6 +44,7 @@ func main() {
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Could not reset beaconchain from file: %+v\n", err)
 		}
+		beaconchain.SetSaveFile(*resetFlag)
 	} else {
 		fmt.Printf("Starting new beaconchain\n")
 		beaconchain.SetSaveFile(*resetFlag)
@@ -129,14 +129,16 @@ func main() {
 		panic("unable to new host in txgen")
 	}
 	for shardID := range shardIDLeaderMap {
-		node := node.New(host, &consensus.Consensus{ShardID: shardID}, nil)
+		consensusObj := consensus.NewFaker()
+		consensusObj.ShardID = shardID
+		node := node.New(host, consensusObj, nil, nil)
 		// Assign many fake addresses so we have enough address to play with at first
 		nodes = append(nodes, node)
 	}
 
 	// Client/txgenerator server node setup
 	consensusObj := consensus.New(host, "0", nil, p2p.Peer{})
-	clientNode := node.New(host, consensusObj, nil)
+	clientNode := node.New(host, consensusObj, nil, nil)
 	clientNode.Client = client.NewClient(clientNode.GetHost(), shardIDLeaderMap)
 
 	readySignal := make(chan uint32)
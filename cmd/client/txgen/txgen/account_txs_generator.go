@@ -19,8 +19,9 @@ type Settings struct {
 }
 
 // GenerateSimulatedTransactionsAccount generates simulated transaction for account model.
+// If cross shard transactions are enabled, CrossShardRatio percent of them transfer to
+// the accounts of other shards.
 func GenerateSimulatedTransactionsAccount(shardID int, dataNodes []*node.Node, setting Settings) (types.Transactions, types.Transactions) {
-	_ = setting // TODO: take use of settings
 	node := dataNodes[shardID]
 	txs := make([]*types.Transaction, 100)
 	for i := 0; i < 100; i++ {
@@ -28,7 +29,11 @@ func GenerateSimulatedTransactionsAccount(shardID int, dataNodes []*node.Node, s
 		for j := 0; j < 1; j++ {
 			randomUserAddress := crypto.PubkeyToAddress(node.TestBankKeys[rand.Intn(100)].PublicKey)
 			randAmount := rand.Float32()
-			tx, _ := types.SignTx(types.NewTransaction(baseNonce+uint64(j), randomUserAddress, uint32(shardID), big.NewInt(int64(params.Ether*randAmount)), params.TxGas, nil, nil), types.HomesteadSigner{}, node.TestBankKeys[i])
+			toShardID := shardID
+			if setting.CrossShard && len(dataNodes) > 1 && rand.Intn(100) < setting.CrossShardRatio {
+				toShardID = (shardID + 1 + rand.Intn(len(dataNodes)-1)) % len(dataNodes)
+			}
+			tx, _ := types.SignTx(types.NewCrossShardTransaction(baseNonce+uint64(j), randomUserAddress, uint32(shardID), uint32(toShardID), big.NewInt(int64(params.Ether*randAmount)), params.TxGas, nil, nil), types.HomesteadSigner{}, node.TestBankKeys[i])
 			txs[i*1+j] = tx
 		}
 	}
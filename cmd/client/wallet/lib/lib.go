@@ -43,7 +43,7 @@ func CreateWalletNode() *node.Node {
 		host.AddPeer(&leaderPeer)
 	}
 
-	walletNode := node.New(host, nil, nil)
+	walletNode := node.New(host, nil, nil, nil)
 	walletNode.Client = client.NewClient(walletNode.GetHost(), shardIDLeaderMap)
 	return walletNode
 }
@@ -31,7 +31,7 @@ func TestSubmitTransaction(test *testing.T) {
 	m.EXPECT().GetSelfPeer().AnyTimes()
 	m.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(1)
 
-	walletNode := node.New(m, nil, nil)
+	walletNode := node.New(m, nil, nil, nil)
 	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9990")
 	peerID, _ := peer.IDFromPrivateKey(priKey)
 	walletNode.Client = client.NewClient(walletNode.GetHost(), map[uint32]p2p.Peer{0: p2p.Peer{IP: "127.0.0.1", Port: "9990", PeerID: peerID}})
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"time"
 
@@ -17,7 +18,9 @@ import (
 	multiaddr "github.com/multiformats/go-multiaddr"
 
 	"github.com/harmony-one/harmony/consensus"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/attack"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	pkg_newnode "github.com/harmony-one/harmony/internal/newnode"
 	"github.com/harmony-one/harmony/internal/profiler"
 	"github.com/harmony-one/harmony/internal/utils"
@@ -62,6 +65,13 @@ func InitLDBDatabase(ip string, port string, freshDB bool) (*ethdb.LDBDatabase,
 	return ethdb.NewLDBDatabase(dbFileName, 0, 0)
 }
 
+// InitShardLDBDatabase initializes the LDBDatabase of the chain of the given shard, which the
+// node syncs once resharding moved it into the shard.
+func InitShardLDBDatabase(ip string, port string, shardID uint32) (*ethdb.LDBDatabase, error) {
+	dbFileName := fmt.Sprintf("./db/harmony_%s_%s_shard_%d", ip, port, shardID)
+	return ethdb.NewLDBDatabase(dbFileName, 0, 0)
+}
+
 func printVersion(me string) {
 	fmt.Fprintf(os.Stderr, "Harmony (C) 2018. %v, version %v-%v (%v %v)\n", path.Base(me), version, commit, builtBy, builtAt)
 	os.Exit(0)
@@ -117,6 +127,22 @@ func main() {
 	// logConn logs incoming/outgoing connections
 	logConn := flag.Bool("log_conn", false, "log incoming/outgoing connections")
 
+	// stakeQuorum makes the consensus quorum a fraction of the stake instead of a fraction of the nodes
+	stakeQuorum := flag.Bool("stake_quorum", false, "true means the consensus quorum is a fraction of the stake instead of a fraction of the nodes, per the chain params")
+
+	// traceFile is the file where the timelines of the consensus rounds are appended
+	traceFile := flag.String("trace_file", "", "the file to export the timelines of the consensus rounds to, one json object per line")
+
+	// chainParamsFile configures the protocol parameters of the chain in the genesis chain config
+	chainParamsFile := flag.String("chain_params", "", "the json file of the protocol parameters of the chain, e.g. the number of blocks per epoch; the defaults are used if not set")
+
+	// attackScript scripts the byzantine behaviors of this node, for testing the fault tolerance only
+	attackScript := flag.String("attack_script", "", "the json file, or the json itself, of the byzantine behaviors of this node (testing only)")
+
+	// pruneStates bounds the storage of the states, see also the prune command for the stopped nodes
+	pruneStates := flag.Uint64("prune_states", 0, "the number of recent states to keep in the database besides the states of the epoch blocks, at least 128; 0 disables the pruning")
+	pruneEpochStates := flag.Uint64("prune_epoch_states", 2, "the number of the last epochs whose epoch block states are kept by the pruning; 0 keeps the states of all the epoch blocks")
+
 	flag.Parse()
 
 	if *versionFlag {
@@ -212,10 +238,38 @@ func main() {
 	// Init logging.
 	loggingInit(*logFolder, role, *ip, *port, *onlyLogTps)
 
+	if *attackScript != "" {
+		script, err := attack.LoadScript(*attackScript)
+		if err != nil {
+			panic("unable to load the attack script: " + err.Error())
+		}
+		attack.GetInstance().SetScript(script)
+	}
+
+	chainParams := chainparams.DefaultConfig
+	if *chainParamsFile != "" {
+		chainParams, err = chainparams.LoadConfig(*chainParamsFile)
+		if err != nil {
+			panic("unable to load the chain params: " + err.Error())
+		}
+	}
+
 	// Initialize leveldb if dbSupported.
 	var ldb *ethdb.LDBDatabase
 	if *dbSupported {
 		ldb, _ = InitLDBDatabase(*ip, *port, *freshDB)
+		if *freshDB {
+			shardDBs, _ := filepath.Glob(fmt.Sprintf("./db/harmony_%s_%s_shard_*", *ip, *port))
+			for _, shardDB := range shardDBs {
+				os.RemoveAll(shardDB)
+			}
+		}
+
+		// Keep the consensus write-ahead log next to the chain database.
+		consensus.WALDir = "./db"
+		if *freshDB {
+			os.Remove(consensus.WALFileName(*ip, *port))
+		}
 	}
 
 	host, err := p2pimpl.NewHost(&selfPeer, nodePriKey)
@@ -235,6 +289,15 @@ func main() {
 	consensus := consensus.New(host, shardID, peers, leader)
 	consensus.MinPeers = *minPeers
 
+	// Export the round timelines if required
+	if *traceFile != "" {
+		file, err := os.OpenFile(*traceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			panic("unable to open the trace file " + *traceFile)
+		}
+		consensus.Tracer.SetExportWriter(file)
+	}
+
 	// Start Profiler for leader if profile argument is on
 	if role == "leader" && (*profile || *metricsReportURL != "") {
 		prof := profiler.GetProfiler()
@@ -245,9 +308,23 @@ func main() {
 	}
 
 	// Current node.
-	currentNode := node.New(host, consensus, ldb)
+	currentNode := node.New(host, consensus, ldb, chainParams)
 	currentNode.Consensus.OfflinePeers = currentNode.OfflinePeers
 	currentNode.Role = node.NewNode
+	if *pruneStates > 0 {
+		currentNode.Blockchain().SetStateRetention(*pruneStates, *pruneEpochStates)
+	}
+	if *dbSupported {
+		currentNode.OpenShardDatabase = func(shardID uint32) (ethdb.Database, error) {
+			return InitShardLDBDatabase(*ip, *port, shardID)
+		}
+	}
+
+	// The leader submits the committed blocks to the beacon chain as crosslinks
+	if BCPeer != nil {
+		host.AddPeer(BCPeer)
+		currentNode.BCPeers = []p2p.Peer{*BCPeer}
+	}
 
 	if *isBeacon {
 		if role == "leader" {
@@ -269,6 +346,9 @@ func main() {
 	// TODO: put this in a better place other than main.
 	dRand := drand.New(host, shardID, peers, leader, currentNode.ConfirmedBlockChannel)
 	currentNode.Consensus.RegisterPRndChannel(dRand.PRndChannel)
+	currentNode.Consensus.RegisterRndChannel(dRand.RndChannel)
+	currentNode.Consensus.OnInvalidRandomness = dRand.Rerun
+	dRand.ChainParams = currentNode.Blockchain().ChainParams()
 	currentNode.DRand = dRand
 
 	// If there is a client configured in the node list.
@@ -276,9 +356,30 @@ func main() {
 		currentNode.ClientPeer = clientPeer
 	}
 
+	// Weigh the votes by the stakes of the nodes if required
+	if *stakeQuorum {
+		consensus.NewQuorumPolicy = func(number, numerator, denominator uint64) bls_cosi.Policy {
+			return bls_cosi.NewStakeWeightedPolicy(currentNode.StakesAt(number), numerator, denominator)
+		}
+		consensus.SetChainParams(consensus.ChainParams)
+	}
+
 	// Assign closure functions to the consensus object
 	consensus.BlockVerifier = currentNode.VerifyNewBlock
 	consensus.OnConsensusDone = currentNode.PostConsensusProcessing
+	consensus.OnBlockPrepared = currentNode.OnBlockPrepared
+	consensus.OnViewChange = currentNode.OnViewChange
+	consensus.ShardCommittee = currentNode.ShardCommittee
+	consensus.OnHandOff = currentNode.OnHandOff
+	consensus.AddNewShardState = currentNode.AddNewShardState
+	// The bitmaps of the blocks follow the order of the committee recorded in the chain
+	head := currentNode.Blockchain().CurrentBlock().NumberU64()
+	if publicKeys := currentNode.ShardCommittee(consensus.ChainParams.EpochOf(head + 1)); len(publicKeys) > 0 {
+		consensus.UpdatePublicKeys(publicKeys)
+		consensus.ResetState()
+	}
+	// The rounds are numbered after the blocks, resume from the head of the chain
+	consensus.UpdateConsensusID(uint32(head))
 	currentNode.State = node.NodeWaitToJoin
 
 	if !*libp2pPD {
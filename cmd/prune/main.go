@@ -0,0 +1,88 @@
+// prune deletes the old states from the database of a stopped node, but the ones of the
+// recent blocks and of the epoch blocks, the same way the node does with -prune_states.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/internal/chainparams"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	version string
+	builtBy string
+	builtAt string
+	commit  string
+)
+
+func printVersion(me string) {
+	fmt.Fprintf(os.Stderr, "Harmony (C) 2019. %v, version %v-%v (%v %v)\n", path.Base(me), version, commit, builtBy, builtAt)
+	os.Exit(0)
+}
+
+func main() {
+	ip := flag.String("ip", "127.0.0.1", "IP of the node whose database is pruned")
+	port := flag.String("port", "9000", "port of the node whose database is pruned")
+	dbPath := flag.String("db", "", "the database to prune, the one of the node at -ip and -port if not set")
+	retention := flag.Uint64("states", 128, "the number of recent states to keep, besides the states of the epoch blocks")
+	epochRetention := flag.Uint64("epoch_states", 2, "the number of the last epochs whose epoch block states are kept; 0 keeps the states of all the epoch blocks")
+	versionFlag := flag.Bool("version", false, "Output version info")
+	flag.Parse()
+
+	if *versionFlag {
+		printVersion(os.Args[0])
+	}
+	if *retention == 0 {
+		fmt.Fprintln(os.Stderr, "At least the state of the head block must be kept")
+		os.Exit(1)
+	}
+	if *dbPath == "" {
+		*dbPath = fmt.Sprintf("./db/harmony_%s_%s", *ip, *port)
+	}
+	log.Root().SetHandler(log.StreamHandler(os.Stdout, log.TerminalFormat(false)))
+
+	// The database is locked while the node runs
+	db, err := ethdb.NewLDBDatabase(*dbPath, 0, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to open the database:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	headHash := rawdb.ReadHeadBlockHash(db)
+	head := rawdb.ReadHeaderNumber(db, headHash)
+	if head == nil {
+		fmt.Fprintln(os.Stderr, "No head block in the database")
+		os.Exit(1)
+	}
+	chainParams := rawdb.ReadChainParams(db, rawdb.ReadCanonicalHash(db, 0))
+	if chainParams == nil {
+		chainParams = chainparams.DefaultConfig
+	}
+
+	deleted, err := core.SweepState(db, state.NewDatabase(db), chainParams, *head, *retention, *epochRetention)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to prune the states:", err)
+		os.Exit(1)
+	}
+
+	// The disk space of the deleted entries is only reclaimed by the compaction
+	start := time.Now()
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to compact the database:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Pruned %d trie nodes and contract codes up to block %d (%x), compacted in %v\n", deleted, *head, headHash, common.PrettyDuration(time.Since(start)))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/harmony-one/harmony/cmd/shardsim/sim"
+)
+
+var (
+	version string
+	builtBy string
+	builtAt string
+	commit  string
+)
+
+func printVersion(me string) {
+	fmt.Fprintf(os.Stderr, "Harmony (C) 2018. %v, version %v-%v (%v %v)\n", path.Base(me), version, commit, builtBy, builtAt)
+	os.Exit(0)
+}
+
+// The main entrance of the sharding simulator, which runs the cuckoo rule resharding for many
+// epochs and outputs the statistics of the committees of each epoch.
+func main() {
+	numShards := flag.Int("num_shards", 10, "number of shards")
+	nodesPerShard := flag.Int("nodes_per_shard", 100, "initial number of nodes in each shard")
+	numEpochs := flag.Int("num_epochs", 1000, "number of epochs to simulate")
+	joinRate := flag.Float64("join_rate", 0.05, "number of nodes joining in each epoch, as a fraction of the current nodes")
+	leaveRate := flag.Float64("leave_rate", 0.05, "probability of each node to leave in each epoch")
+	stakeDistribution := flag.String("stake_distribution", sim.StakeEqual, "distribution of the stakes: equal, uniform or pareto")
+	meanStake := flag.Uint64("mean_stake", 100, "mean stake of the nodes")
+	adversaryFraction := flag.Float64("adversary_fraction", 0.1, "probability of each node to be adversarial")
+	seed := flag.Int64("seed", 42, "seed of the simulation")
+	format := flag.String("format", "csv", "output format: csv or json")
+	output := flag.String("output", "", "output file, the standard output if empty")
+	versionFlag := flag.Bool("version", false, "Output version info")
+	flag.Parse()
+
+	if *versionFlag {
+		printVersion(os.Args[0])
+	}
+
+	simulator, err := sim.New(sim.Config{
+		NumShards:         *numShards,
+		NodesPerShard:     *nodesPerShard,
+		JoinRate:          *joinRate,
+		LeaveRate:         *leaveRate,
+		StakeDistribution: *stakeDistribution,
+		MeanStake:         *meanStake,
+		AdversaryFraction: *adversaryFraction,
+		Seed:              *seed,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid simulation:", err)
+		os.Exit(1)
+	}
+	write := sim.WriteCSV
+	switch *format {
+	case "csv":
+	case "json":
+		write = sim.WriteJSON
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown output format:", *format)
+		os.Exit(1)
+	}
+
+	stats := []*sim.EpochStats{simulator.Stats()}
+	for i := 0; i < *numEpochs; i++ {
+		stats = append(stats, simulator.Step())
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to create the output file:", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	if err := write(out, stats); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write the statistics:", err)
+		os.Exit(1)
+	}
+}
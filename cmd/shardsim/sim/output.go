@@ -0,0 +1,55 @@
+package sim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader is the header of the CSV output, one column per field of EpochStats.
+var csvHeader = []string{
+	"epoch", "num_nodes", "joined", "left", "moved", "kickout_rate", "min_shard_size", "max_shard_size",
+	"max_adversary_fraction", "max_adversary_stake_fraction", "taken_over_shards", "shard_sizes",
+}
+
+// WriteCSV writes the statistics of the epochs as CSV, with the shard sizes separated by ';'.
+func WriteCSV(w io.Writer, stats []*EpochStats) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		shardSizes := make([]string, len(s.ShardSizes))
+		for i, size := range s.ShardSizes {
+			shardSizes[i] = strconv.Itoa(size)
+		}
+		record := []string{
+			strconv.Itoa(s.Epoch),
+			strconv.Itoa(s.NumNodes),
+			strconv.Itoa(s.Joined),
+			strconv.Itoa(s.Left),
+			strconv.Itoa(s.Moved),
+			strconv.FormatFloat(s.KickoutRate, 'f', 4, 64),
+			strconv.Itoa(s.MinShardSize),
+			strconv.Itoa(s.MaxShardSize),
+			strconv.FormatFloat(s.MaxAdversaryFraction, 'f', 4, 64),
+			strconv.FormatFloat(s.MaxAdversaryStakeFraction, 'f', 4, 64),
+			strconv.Itoa(s.TakenOverShards),
+			strings.Join(shardSizes, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the statistics of the epochs as a JSON array.
+func WriteJSON(w io.Writer, stats []*EpochStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
@@ -0,0 +1,212 @@
+// Package sim simulates the cuckoo rule resharding of core.ShardingState over many epochs,
+// with nodes joining and leaving, to study the balance of the committees and the chance of
+// adversarial nodes to take over a committee.
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// The stake distributions of the simulated nodes.
+const (
+	StakeEqual   = "equal"   // every node stakes the mean stake
+	StakeUniform = "uniform" // stakes are uniform between 1 and twice the mean stake
+	StakePareto  = "pareto"  // stakes follow a Pareto distribution of shape 2, a few nodes stake most
+)
+
+// takeoverThreshold is the fraction of a committee the adversary needs to break its BFT consensus.
+const takeoverThreshold = 1.0 / 3
+
+// Config is the configuration of a simulation.
+type Config struct {
+	NumShards         int     // the number of shards, at least 2
+	NodesPerShard     int     // the initial number of nodes in each shard
+	JoinRate          float64 // the number of nodes joining in each epoch, as a fraction of the current nodes
+	LeaveRate         float64 // the probability of each node to leave in each epoch
+	StakeDistribution string  // the distribution of the stakes of the nodes
+	MeanStake         uint64  // the mean stake of the nodes
+	AdversaryFraction float64 // the probability of each node to be adversarial
+	Seed              int64   // the seed of the simulation, which is deterministic given the seed
+}
+
+// EpochStats is the statistics of the committees after the resharding of an epoch.
+type EpochStats struct {
+	Epoch                     int     `json:"epoch"`
+	NumNodes                  int     `json:"numNodes"`
+	Joined                    int     `json:"joined"`
+	Left                      int     `json:"left"`
+	Moved                     int     `json:"moved"` // the nodes resharded into another shard
+	KickoutRate               float64 `json:"kickoutRate"`
+	ShardSizes                []int   `json:"shardSizes"` // in the order of the shard IDs
+	MinShardSize              int     `json:"minShardSize"`
+	MaxShardSize              int     `json:"maxShardSize"`
+	MaxAdversaryFraction      float64 `json:"maxAdversaryFraction"`      // the largest fraction of adversarial nodes in a shard
+	MaxAdversaryStakeFraction float64 `json:"maxAdversaryStakeFraction"` // the largest fraction of adversarial stake in a shard
+	TakenOverShards           int     `json:"takenOverShards"`           // the shards with at least 1/3 adversarial nodes
+}
+
+// Simulator runs the resharding epoch by epoch.
+type Simulator struct {
+	config      Config
+	rand        *rand.Rand
+	epoch       int
+	shardState  types.ShardState
+	adversaries map[string]bool
+	numNodes    int // the number of nodes ever created, used to name the new nodes
+}
+
+// New returns the simulator of the given configuration, with the initial committees.
+func New(config Config) (*Simulator, error) {
+	if config.NumShards < 2 {
+		return nil, errors.New("at least 2 shards are needed for the cuckoo rule")
+	}
+	if config.NodesPerShard < 1 {
+		return nil, errors.New("at least 1 node per shard is needed")
+	}
+	if config.JoinRate < 0 || config.LeaveRate < 0 || config.LeaveRate > 1 || config.AdversaryFraction < 0 || config.AdversaryFraction > 1 {
+		return nil, errors.New("rates and fractions must be between 0 and 1")
+	}
+	switch config.StakeDistribution {
+	case StakeEqual, StakeUniform, StakePareto:
+	default:
+		return nil, fmt.Errorf("unknown stake distribution %q", config.StakeDistribution)
+	}
+	if config.MeanStake < 1 {
+		return nil, errors.New("the mean stake must be at least 1")
+	}
+
+	sim := &Simulator{
+		config:      config,
+		rand:        rand.New(rand.NewSource(config.Seed)),
+		adversaries: map[string]bool{},
+	}
+	for i := 0; i < config.NumShards; i++ {
+		committee := types.Committee{ShardID: uint32(i)}
+		for j := 0; j < config.NodesPerShard; j++ {
+			committee.NodeList = append(committee.NodeList, sim.newNode())
+		}
+		sim.shardState = append(sim.shardState, committee)
+	}
+	return sim, nil
+}
+
+// Stats returns the statistics of the current committees.
+func (sim *Simulator) Stats() *EpochStats {
+	stats := &EpochStats{Epoch: sim.epoch, ShardSizes: make([]int, len(sim.shardState)), MinShardSize: math.MaxInt32}
+	for _, committee := range sim.shardState {
+		size := len(committee.NodeList)
+		stats.NumNodes += size
+		stats.ShardSizes[committee.ShardID] = size
+		if size < stats.MinShardSize {
+			stats.MinShardSize = size
+		}
+		if size > stats.MaxShardSize {
+			stats.MaxShardSize = size
+		}
+
+		adversaries, stake, adversaryStake := 0, uint64(0), uint64(0)
+		for _, nodeID := range committee.NodeList {
+			stake += nodeID.Stake
+			if sim.adversaries[nodeID.BlsPublicKey] {
+				adversaries++
+				adversaryStake += nodeID.Stake
+			}
+		}
+		if size == 0 {
+			continue
+		}
+		fraction := float64(adversaries) / float64(size)
+		if fraction > stats.MaxAdversaryFraction {
+			stats.MaxAdversaryFraction = fraction
+		}
+		if fraction >= takeoverThreshold {
+			stats.TakenOverShards++
+		}
+		if stake > 0 && float64(adversaryStake)/float64(stake) > stats.MaxAdversaryStakeFraction {
+			stats.MaxAdversaryStakeFraction = float64(adversaryStake) / float64(stake)
+		}
+	}
+	return stats
+}
+
+// Step runs one epoch: some nodes leave, new nodes join, then the committees are
+// resharded by the cuckoo rule. It returns the statistics after the resharding.
+func (sim *Simulator) Step() *EpochStats {
+	sim.epoch++
+
+	// Nodes leave, and the shard of each staying node is remembered to count the moves
+	left := 0
+	shardOf := map[string]uint32{}
+	for i := range sim.shardState {
+		staying := []types.NodeID{}
+		for _, nodeID := range sim.shardState[i].NodeList {
+			if sim.rand.Float64() < sim.config.LeaveRate {
+				delete(sim.adversaries, nodeID.BlsPublicKey)
+				left++
+				continue
+			}
+			staying = append(staying, nodeID)
+			shardOf[nodeID.BlsPublicKey] = sim.shardState[i].ShardID
+		}
+		sim.shardState[i].NodeList = staying
+	}
+
+	// New nodes join, the fractional part of the expected number joining with its probability
+	expected := sim.config.JoinRate * float64(len(shardOf))
+	numJoining := int(expected)
+	if sim.rand.Float64() < expected-float64(numJoining) {
+		numJoining++
+	}
+	newNodeList := []types.NodeID{}
+	for i := 0; i < numJoining; i++ {
+		newNodeList = append(newNodeList, sim.newNode())
+	}
+
+	ss := core.NewShardingState(uint64(sim.epoch), sim.rand.Int63(), sim.shardState)
+	kickoutRate := ss.Reshard(newNodeList)
+	sim.shardState = ss.ShardState()
+
+	stats := sim.Stats()
+	stats.Joined = numJoining
+	stats.Left = left
+	stats.KickoutRate = kickoutRate
+	for _, committee := range sim.shardState {
+		for _, nodeID := range committee.NodeList {
+			if shardID, ok := shardOf[nodeID.BlsPublicKey]; ok && shardID != committee.ShardID {
+				stats.Moved++
+			}
+		}
+	}
+	return stats
+}
+
+// newNode creates a node with a stake drawn from the stake distribution, adversarial
+// with the probability of the adversary fraction.
+func (sim *Simulator) newNode() types.NodeID {
+	sim.numNodes++
+	nodeID := types.NodeID{BlsPublicKey: fmt.Sprintf("node%d", sim.numNodes), Stake: sim.newStake()}
+	if sim.rand.Float64() < sim.config.AdversaryFraction {
+		sim.adversaries[nodeID.BlsPublicKey] = true
+	}
+	return nodeID
+}
+
+// newStake draws a stake from the stake distribution.
+func (sim *Simulator) newStake() uint64 {
+	mean := float64(sim.config.MeanStake)
+	switch sim.config.StakeDistribution {
+	case StakeUniform:
+		return 1 + uint64(sim.rand.Float64()*(2*mean-1))
+	case StakePareto:
+		// The scale of the Pareto distribution of shape 2 is half its mean
+		return 1 + uint64(mean/2/math.Sqrt(1-sim.rand.Float64()))
+	default:
+		return sim.config.MeanStake
+	}
+}
@@ -0,0 +1,98 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = Config{
+	NumShards:         4,
+	NodesPerShard:     20,
+	JoinRate:          0.1,
+	LeaveRate:         0.05,
+	StakeDistribution: StakePareto,
+	MeanStake:         100,
+	AdversaryFraction: 0.2,
+	Seed:              7,
+}
+
+func runTestSimulation(t *testing.T, config Config, numEpochs int) []*EpochStats {
+	simulator, err := New(config)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	stats := []*EpochStats{simulator.Stats()}
+	for i := 0; i < numEpochs; i++ {
+		stats = append(stats, simulator.Step())
+	}
+	return stats
+}
+
+func TestSimulation(t *testing.T) {
+	stats := runTestSimulation(t, testConfig, 50)
+	assert.Equal(t, 80, stats[0].NumNodes)
+	for i := 1; i < len(stats); i++ {
+		s := stats[i]
+		assert.Equal(t, i, s.Epoch)
+		// No node is lost by resharding
+		assert.Equal(t, stats[i-1].NumNodes+s.Joined-s.Left, s.NumNodes)
+		sum := 0
+		for _, size := range s.ShardSizes {
+			sum += size
+		}
+		assert.Equal(t, s.NumNodes, sum)
+		assert.True(t, s.MinShardSize <= s.MaxShardSize)
+		assert.True(t, s.Moved <= s.NumNodes-s.Joined)
+		assert.True(t, s.MaxAdversaryFraction <= 1)
+	}
+
+	// The simulation is determined by its seed
+	assert.Equal(t, stats, runTestSimulation(t, testConfig, 50))
+}
+
+func TestSimulationWithoutAdversary(t *testing.T) {
+	config := testConfig
+	config.AdversaryFraction = 0
+	for _, s := range runTestSimulation(t, config, 10) {
+		assert.Equal(t, 0.0, s.MaxAdversaryFraction)
+		assert.Equal(t, 0.0, s.MaxAdversaryStakeFraction)
+		assert.Equal(t, 0, s.TakenOverShards)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, update := range []func(*Config){
+		func(config *Config) { config.NumShards = 1 },
+		func(config *Config) { config.NodesPerShard = 0 },
+		func(config *Config) { config.LeaveRate = 1.5 },
+		func(config *Config) { config.AdversaryFraction = -0.1 },
+		func(config *Config) { config.StakeDistribution = "zipf" },
+		func(config *Config) { config.MeanStake = 0 },
+	} {
+		config := testConfig
+		update(&config)
+		_, err := New(config)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	stats := runTestSimulation(t, testConfig, 3)
+
+	csvOutput := &bytes.Buffer{}
+	assert.Nil(t, WriteCSV(csvOutput, stats))
+	lines := strings.Split(strings.TrimSpace(csvOutput.String()), "\n")
+	assert.Equal(t, len(stats)+1, len(lines))
+	assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+	assert.Equal(t, len(csvHeader), len(strings.Split(lines[1], ",")))
+
+	jsonOutput := &bytes.Buffer{}
+	assert.Nil(t, WriteJSON(jsonOutput, stats))
+	decoded := []*EpochStats{}
+	assert.Nil(t, json.Unmarshal(jsonOutput.Bytes(), &decoded))
+	assert.Equal(t, stats, decoded)
+}
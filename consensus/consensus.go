@@ -8,13 +8,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/params"
-	"github.com/ethereum/go-ethereum/rlp"
 	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	consensus_proto "github.com/harmony-one/harmony/api/consensus"
@@ -22,30 +21,25 @@ import (
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/drand"
+	"github.com/harmony-one/harmony/internal/attack"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	"github.com/harmony-one/harmony/p2p/host"
-	"golang.org/x/crypto/sha3"
 
 	proto_discovery "github.com/harmony-one/harmony/api/proto/discovery"
 )
 
 // Consensus is the main struct with all states and data related to consensus process.
 type Consensus struct {
-	// The current state of the consensus
-	state State
+	// The state of the round on consensusID
+	round *roundState
+	// Rounds in their commit phase while the leader moved on to the next one, keyed by consensus id
+	pendingRounds map[uint32]*roundState
 
-	// Commits collected from validators.
-	prepareSigs          map[uint32]*bls.Sign
-	commitSigs           map[uint32]*bls.Sign
-	aggregatedPrepareSig *bls.Sign
-	aggregatedCommitSig  *bls.Sign
-	prepareBitmap        *bls_cosi.Mask
-	commitBitmap         *bls_cosi.Mask
-
-	// map of nodeID to validator Peer object
-	// FIXME: should use PubKey of p2p.Peer as the hashkey
-	validators sync.Map // key is uint16, value is p2p.Peer
+	// map of the public keys of validators to validator Peer objects
+	validators sync.Map // key is the hex string of serialized public key, value is p2p.Peer
 
 	// Minimal number of peers in the shard
 	// If the number of validators is less than minPeers, the consensus won't start
@@ -62,16 +56,39 @@ type Consensus struct {
 	priKey *bls.SecretKey
 	pubKey *bls.PublicKey
 
+	// The protocol parameters of the chain, from the Harmony section of its genesis chain config
+	ChainParams *chainparams.Config
+
+	// Policy deciding whether the members who signed form a quorum, per the quorum in effect at the current block
+	QuorumPolicy bls_cosi.Policy
+	// Builder of the quorum policy for the quorum parameter in effect at the block of the given number,
+	// which is a fraction of the members by default
+	NewQuorumPolicy func(number, numerator, denominator uint64) bls_cosi.Policy
+
+	// The amount minted in every block, which is paid with the fees to the leader and the signers
+	BlockReward *big.Int
+	// The percentage of the block reward and the fees paid to the leader
+	LeaderRewardPercent uint64
+
 	// Whether I am leader. False means I am validator
 	IsLeader bool
-	// Leader or validator Id - 4 byte
-	nodeID uint32
-	// Consensus Id (View Id) - 4 byte
+	// Whether this node left the committee of the shard at the last handoff, after which it doesn't sign
+	leftCommittee bool
+	// The number of the last block of an epoch the consensus handed off at
+	handedOffBlock uint64
+	// Whether to accept all the block seals, only for fake consensus in tests
+	fakeSeal bool
+	// Consensus Id (block height) - 4 byte
 	consensusID uint32
-	// Blockhash - 32 byte
-	blockHash [32]byte
-	// Block to run consensus on
-	block []byte
+	// View Id - 4 byte, increased every time the committee moves to a new leader
+	viewID uint32
+	// The view this node is voting to move to; equal to viewID when no view change is going on
+	nextViewID uint32
+	// View change votes collected by the leader of nextViewID
+	viewChangeSigs   map[string]*bls.Sign
+	viewChangeBitmap *bls_cosi.Mask
+	// Hash of the block prepared in the previous view, reported by the view change votes
+	viewChangePreparedHash []byte
 	// Array of block hashes.
 	blockHashes [][32]byte
 	// Shard Id which this node belongs to
@@ -80,10 +97,38 @@ type Consensus struct {
 	// global consensus mutex
 	mutex sync.Mutex
 
+	// Source of time of the timers and traces
+	clock Clock
+	// Stops the timer of the current consensus phase, which starts a view change on expiry
+	stopPhaseTimer func() bool
+	// Generation of the phase timer, so that a stale timer firing late is ignored
+	timerGen  uint64
+	timerLock sync.Mutex
+
 	// Validator specific fields
 	// Blocks received but not done with consensus yet
 	blocksReceived map[uint32]*BlockConsensusStatus
 
+	// Messages of the upcoming rounds, replayed when consensusID reaches their round
+	futureMsgs    map[uint32]map[futureMsgKey]consensus_proto.Message
+	futureMsgLock sync.Mutex
+
+	// Votes of the recent rounds and the double sign evidences found from them
+	evidencePool *evidencePool
+
+	// Write-ahead log of the in-flight round, nil if disabled
+	wal *consensusWAL
+	// Hashes signed by the votes of this node in the current round
+	signedVotes map[voteKey][]byte
+	walLock     sync.Mutex
+
+	// Timelines of the recent rounds, for debugging slow rounds
+	Tracer *RoundTracer
+
+	// Byzantine behaviors scripted for this node, only for testing the fault tolerance
+	AttackScript *attack.Script
+	adversary    adversary
+
 	// Signal channel for starting a new consensus process
 	ReadySignal chan struct{}
 	// The verifier func passed from Node object
@@ -91,14 +136,36 @@ type Consensus struct {
 	// The post-consensus processing func passed from Node object
 	// Called when consensus on a new block is done
 	OnConsensusDone func(*types.Block)
+	// The pre-commit processing func passed from Node object
+	// Called on the leader when a block is prepared, so that the next block can be built on top of it
+	OnBlockPrepared func(*types.Block)
+	// The post-view-change processing func passed from Node object
+	// Called when the committee moved to a new leader, with whether this node is the new leader
+	OnViewChange func(bool)
+	// The blockchain of the shard passed from Node object, to look up the committees of the past blocks
+	ChainReader consensus_engine.ChainReader
+	// The committee reader func passed from Node object
+	// Returns the public keys of the committee of this shard recorded in the shard state of the epoch
+	ShardCommittee func(epoch uint64) []*bls.PublicKey
+	// The post-handoff processing func passed from Node object
+	// Called when the last block of an epoch is committed and the committee of the next epoch took over, with its public keys and leader
+	OnHandOff func(block *types.Block, publicKeys []*bls.PublicKey, leader p2p.Peer)
+	// The resharding func passed from Node object
+	// Called on the leader once the randomness is added into the last block of an epoch, to add the new shard state it reshards with
+	AddNewShardState func(*types.Block)
+	// The randomness rerun func passed from Node object
+	// Called on the leader when drand sent an invalid randomness for the block of the number, or none in time
+	OnInvalidRandomness func(number uint64)
 
 	// current consensus block to check if out of sync
 	ConsensusBlock chan *BFTBlockInfo
 	// verified block to state sync broadcast
 	VerifiedNewBlock chan *types.Block
 
-	// Channel for DRG protocol to send pRnd (preimage of randomness resulting from combined vrf randomnesses) to consensus. The first 32 bytes are randomness, the rest is for bitmap.
+	// Channel for DRG protocol to send pRnd (preimage of randomness resulting from combined vrf randomnesses) to consensus, along with the vrf commits it is combined from
 	PRndChannel chan []byte
+	// Channel for DRG protocol to send the final randomness (the VDF output over pRnd) to consensus
+	RndChannel chan []byte
 
 	uniqueIDInstance *utils.UniqueValidatorID
 
@@ -130,6 +197,13 @@ type BlockConsensusStatus struct {
 	state State  // the latest state of the consensus
 }
 
+// blockNumberOf returns the number of the block proposed in the round of the given
+// consensus id. The consensus ids count the blocks committed after the genesis block,
+// so every consensus message tells the height of the block it's about.
+func blockNumberOf(consensusID uint32) uint64 {
+	return uint64(consensusID) + 1
+}
+
 // UpdateConsensusID is used to update latest consensusID for nodes that out of sync
 func (consensus *Consensus) UpdateConsensusID(consensusID uint32) {
 	consensus.mutex.Lock()
@@ -144,6 +218,7 @@ func (consensus *Consensus) UpdateConsensusID(consensusID uint32) {
 func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer) *Consensus {
 	consensus := Consensus{}
 	consensus.host = host
+	consensus.clock = systemClock{}
 
 	selfPeer := host.GetSelfPeer()
 	if leader.Port == selfPeer.Port && leader.IP == selfPeer.IP {
@@ -154,12 +229,9 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer) *Cons
 
 	consensus.leader = leader
 	for _, peer := range peers {
-		consensus.validators.Store(utils.GetUniqueIDFromPeer(peer), peer)
+		consensus.validators.Store(getPeerKey(peer.PubKey), peer)
 	}
 
-	consensus.prepareSigs = map[uint32]*bls.Sign{}
-	consensus.commitSigs = map[uint32]*bls.Sign{}
-
 	// Initialize cosign bitmap
 	allPublicKeys := make([]*bls.PublicKey, 0)
 	for _, validatorPeer := range peers {
@@ -168,28 +240,22 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer) *Cons
 	allPublicKeys = append(allPublicKeys, leader.PubKey)
 
 	consensus.PublicKeys = allPublicKeys
+	consensus.NewQuorumPolicy = newFractionPolicy
+	consensus.SetChainParams(chainparams.DefaultConfig)
+	consensus.BlockReward = new(big.Int).Set(DefaultBlockReward)
+	consensus.LeaderRewardPercent = DefaultLeaderRewardPercent
 
-	prepareBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.PubKey)
-	commitBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.PubKey)
-	consensus.prepareBitmap = prepareBitmap
-	consensus.commitBitmap = commitBitmap
-
-	consensus.aggregatedPrepareSig = nil
-	consensus.aggregatedCommitSig = nil
-
-	// For now use socket address as ID
-	// TODO: populate Id derived from address
-	consensus.nodeID = utils.GetUniqueIDFromPeer(selfPeer)
+	consensus.round = newRoundState(consensus.PublicKeys, consensus.leader.PubKey)
+	consensus.pendingRounds = make(map[uint32]*roundState)
 
 	// Set private key for myself so that I can sign messages.
-	nodeIDBytes := make([]byte, 32)
-	binary.LittleEndian.PutUint32(nodeIDBytes, consensus.nodeID)
-	privateKey := bls.SecretKey{}
-	err := privateKey.SetLittleEndian(nodeIDBytes)
-	consensus.priKey = &privateKey
-	consensus.pubKey = privateKey.GetPublicKey()
+	// The other members identify this node by the public key.
+	consensus.priKey, consensus.pubKey = utils.GenKey(selfPeer.IP, selfPeer.Port)
 
-	consensus.consensusID = 0 // or view Id in the original pbft paper
+	consensus.consensusID = 0
+	consensus.viewID = 0 // or view Id in the original pbft paper
+	consensus.nextViewID = 0
+	consensus.viewChangeSigs = map[string]*bls.Sign{}
 
 	myShardID, err := strconv.Atoi(ShardID)
 	if err != nil {
@@ -200,20 +266,36 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer) *Cons
 	// For validators to keep track of all blocks received but not yet committed, so as to catch up to latest consensus if lagged behind.
 	consensus.blocksReceived = make(map[uint32]*BlockConsensusStatus)
 
+	// For messages that arrive before this node reaches their round.
+	consensus.futureMsgs = make(map[uint32]map[futureMsgKey]consensus_proto.Message)
+
+	consensus.evidencePool = newEvidencePool()
+	consensus.Tracer = NewRoundTracer(DefaultTraceCapacity)
+	consensus.AttackScript = attack.GetInstance().Script()
+
+	// Resume the round in flight before the restart, if any.
+	consensus.signedVotes = map[voteKey][]byte{}
+	if WALDir != "" {
+		consensus.initWAL(WALFileName(selfPeer.IP, selfPeer.Port))
+	}
+
 	if consensus.IsLeader {
 		consensus.ReadySignal = make(chan struct{})
 		// send a signal to indicate it's ready to run consensus
 		// this signal is consumed by node object to create a new block and in turn trigger a new consensus on it
 		// this is a goroutine because go channel without buffer will block
-		go func() {
-			consensus.ReadySignal <- struct{}{}
-		}()
+		// a round resumed from the WAL sends the signal once it's done instead
+		if consensus.round.state == Finished {
+			go func() {
+				consensus.ReadySignal <- struct{}{}
+			}()
+		}
 	}
 
 	consensus.uniqueIDInstance = utils.GetUniqueValidatorIDInstance()
 	consensus.OfflinePeerList = make([]p2p.Peer, 0)
 
-	//	consensus.Log.Info("New Consensus", "IP", ip, "Port", port, "NodeID", consensus.nodeID, "priKey", consensus.priKey, "pubKey", consensus.pubKey)
+	//	consensus.Log.Info("New Consensus", "IP", ip, "Port", port, "priKey", consensus.priKey, "pubKey", consensus.pubKey)
 	return &consensus
 }
 
@@ -222,6 +304,125 @@ func (consensus *Consensus) RegisterPRndChannel(pRndChannel chan []byte) {
 	consensus.PRndChannel = pRndChannel
 }
 
+// RegisterRndChannel registers the channel for receiving the final randomness from DRG protocol
+func (consensus *Consensus) RegisterRndChannel(rndChannel chan []byte) {
+	consensus.RndChannel = rndChannel
+}
+
+// addRandomness commits the randomness preimage from drand into the epoch block,
+// and its pRnd as the rand seed, if the preimage is valid.
+func (consensus *Consensus) addRandomness(block *types.Block, encodedPreimage []byte) error {
+	preimage, err := drand.DecodePreimage(encodedPreimage)
+	if err != nil {
+		return err
+	}
+	if err := preimage.Verify(block.ParentHash(), consensus.PublicKeys); err != nil {
+		return err
+	}
+	block.AddRandPreimage(encodedPreimage)
+	block.AddRandSeed(preimage.PRnd)
+	return nil
+}
+
+// addVdf commits the final randomness from drand into the last block of the
+// epoch, and the VDF output it's hashed from, if the VDF output verifies over
+// the pRnd of the epoch.
+func (consensus *Consensus) addVdf(block *types.Block, encodedRandomness []byte) error {
+	randomness, err := drand.DecodeRandomness(encodedRandomness)
+	if err != nil {
+		return err
+	}
+	if err := randomness.Verify(consensus.ChainParams.VdfDifficulty(consensus.ChainParams.EpochOf(block.NumberU64()))); err != nil {
+		return err
+	}
+	if consensus.ChainReader != nil {
+		// The VDF must be over the pRnd of the epoch, not a stale one
+		header := block.Header()
+		header.Vdf = encodedRandomness
+		if err := verifyRandomnessInput(consensus.ChainParams, consensus.ChainReader, header); err != nil {
+			return err
+		}
+	}
+	block.AddVdf(encodedRandomness)
+	block.AddRandSeed(randomness.Rand())
+	return nil
+}
+
+// isPRndBlock returns whether the block of the number commits pRnd, i.e. it's
+// the first block of an epoch other than the genesis.
+func isPRndBlock(config *chainparams.Config, number uint64) bool {
+	return number > 0 && config.IsEpochBlock(number)
+}
+
+// isRandBlock returns whether the block of the number commits the final
+// randomness, i.e. it's the last block of an epoch which has pRnd.
+func isRandBlock(config *chainparams.Config, number uint64) bool {
+	return config.EpochOf(number) > 0 && config.IsEpochLastBlock(number)
+}
+
+// verifyRandomness checks the randomness committed into a header. An epoch
+// block commits pRnd as its rand seed, along with the preimage it must be
+// recomputed from: enough valid vrf commits of the given committee on the last
+// block of the previous epoch. The last block of the epoch commits the final
+// randomness, along with the VDF output over pRnd it's hashed from. Other
+// blocks commit no randomness. Whether the VDF is over the pRnd of the epoch
+// is left to verifyRandomnessInput, which needs the chain.
+func verifyRandomness(config *chainparams.Config, header *types.Header, publicKeys []*bls.PublicKey) error {
+	number := header.Number.Uint64()
+	switch {
+	case isPRndBlock(config, number):
+		if len(header.Vdf) > 0 {
+			return consensus_engine.ErrUnexpectedRandomness
+		}
+		preimage, err := drand.DecodePreimage(header.RandPreimage)
+		if err != nil {
+			return err
+		}
+		if preimage.PRnd != header.RandSeed {
+			return consensus_engine.ErrInvalidRandomness
+		}
+		return preimage.Verify(header.ParentHash, publicKeys)
+	case isRandBlock(config, number):
+		if len(header.RandPreimage) > 0 {
+			return consensus_engine.ErrUnexpectedRandomness
+		}
+		randomness, err := drand.DecodeRandomness(header.Vdf)
+		if err != nil {
+			return err
+		}
+		if randomness.Rand() != header.RandSeed {
+			return consensus_engine.ErrInvalidRandomness
+		}
+		return randomness.Verify(config.VdfDifficulty(config.EpochOf(number)))
+	default:
+		if header.RandSeed != ([32]byte{}) || len(header.RandPreimage) > 0 || len(header.Vdf) > 0 {
+			return consensus_engine.ErrUnexpectedRandomness
+		}
+		return nil
+	}
+}
+
+// verifyRandomnessInput checks the VDF committed into the last block of an
+// epoch is over the pRnd committed into the first block of the epoch.
+func verifyRandomnessInput(config *chainparams.Config, chain consensus_engine.ChainReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if !isRandBlock(config, number) {
+		return nil
+	}
+	epochHeader := chain.GetHeaderByNumber(config.EpochBlockNumber(config.EpochOf(number)))
+	if epochHeader == nil {
+		return consensus_engine.ErrUnknownAncestor
+	}
+	randomness, err := drand.DecodeRandomness(header.Vdf)
+	if err != nil {
+		return err
+	}
+	if randomness.PRnd != epochHeader.RandSeed {
+		return consensus_engine.ErrInvalidRandomness
+	}
+	return nil
+}
+
 // Checks the basic meta of a consensus message, including the signature.
 func (consensus *Consensus) checkConsensusMessage(message consensus_proto.Message, publicKey *bls.PublicKey) error {
 	consensusID := message.ConsensusId
@@ -234,29 +435,49 @@ func (consensus *Consensus) checkConsensusMessage(message consensus_proto.Messag
 		return consensus_engine.ErrInvalidConsensusMessage
 	}
 
+	// Catch the signer voting for different blocks in the same round
+	consensus.recordVote(message, publicKey)
+
+	// check view Id
+	if message.ViewId != consensus.viewID {
+		utils.GetLogInstance().Warn("Wrong view Id", "myViewId", consensus.viewID, "theirViewId", message.ViewId, "consensus", consensus)
+		return consensus_engine.ErrViewIDNotMatch
+	}
+
 	// check consensus Id
-	if consensusID != consensus.consensusID {
+	round := consensus.roundOf(consensusID)
+	if round == nil {
 		utils.GetLogInstance().Warn("Wrong consensus Id", "myConsensusId", consensus.consensusID, "theirConsensusId", consensusID, "consensus", consensus)
 		return consensus_engine.ErrConsensusIDNotMatch
 	}
 
-	if !bytes.Equal(blockHash, consensus.blockHash[:]) {
+	if !bytes.Equal(blockHash, round.blockHash[:]) {
 		utils.GetLogInstance().Warn("Wrong blockHash", "consensus", consensus)
 		return consensus_engine.ErrInvalidConsensusMessage
 	}
 	return nil
 }
 
-// Gets the validator peer based on validator ID.
-func (consensus *Consensus) getValidatorPeerByID(validatorID uint32) *p2p.Peer {
-	v, ok := consensus.validators.Load(validatorID)
+// getPeerKey returns the key of a committee member in the maps of the consensus,
+// which is the hex string of its serialized public key.
+func getPeerKey(pubKey *bls.PublicKey) string {
+	if pubKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(pubKey.Serialize())
+}
+
+// Gets the validator peer based on the serialized public key of the validator.
+func (consensus *Consensus) getValidatorPeerByPubKey(senderPubKey []byte) *p2p.Peer {
+	validatorKey := hex.EncodeToString(senderPubKey)
+	v, ok := consensus.validators.Load(validatorKey)
 	if !ok {
-		utils.GetLogInstance().Warn("Unrecognized validator", "validatorID", validatorID, "consensus", consensus)
+		utils.GetLogInstance().Warn("Unrecognized validator", "validatorKey", validatorKey, "consensus", consensus)
 		return nil
 	}
 	value, ok := v.(p2p.Peer)
 	if !ok {
-		utils.GetLogInstance().Warn("Invalid validator", "validatorID", validatorID, "consensus", consensus)
+		utils.GetLogInstance().Warn("Invalid validator", "validatorKey", validatorKey, "consensus", consensus)
 		return nil
 	}
 	return &value
@@ -285,8 +506,8 @@ func verifyMessageSig(signerPubKey *bls.PublicKey, message consensus_proto.Messa
 
 // Author returns the author of the block header.
 func (consensus *Consensus) Author(header *types.Header) (common.Address, error) {
-	// TODO: implement this
-	return common.Address{}, nil
+	// The proposer of the block sets its account as the coinbase
+	return header.Coinbase, nil
 }
 
 // Sign on the hash of the message
@@ -327,35 +548,17 @@ func (consensus *Consensus) GetValidatorPeers() []p2p.Peer {
 
 // GetPrepareSigsArray returns the signatures for prepare as a array
 func (consensus *Consensus) GetPrepareSigsArray() []*bls.Sign {
-	sigs := []*bls.Sign{}
-	for _, sig := range consensus.prepareSigs {
-		sigs = append(sigs, sig)
-	}
-	return sigs
+	return consensus.round.getPrepareSigsArray()
 }
 
 // GetCommitSigsArray returns the signatures for commit as a array
 func (consensus *Consensus) GetCommitSigsArray() []*bls.Sign {
-	sigs := []*bls.Sign{}
-	for _, sig := range consensus.commitSigs {
-		sigs = append(sigs, sig)
-	}
-	return sigs
+	return consensus.round.getCommitSigsArray()
 }
 
 // ResetState resets the state of the consensus
 func (consensus *Consensus) ResetState() {
-	consensus.state = Finished
-	consensus.prepareSigs = map[uint32]*bls.Sign{}
-	consensus.commitSigs = map[uint32]*bls.Sign{}
-
-	prepareBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.PubKey)
-	commitBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.PubKey)
-	consensus.prepareBitmap = prepareBitmap
-	consensus.commitBitmap = commitBitmap
-
-	consensus.aggregatedPrepareSig = nil
-	consensus.aggregatedCommitSig = nil
+	consensus.round = newRoundState(consensus.PublicKeys, consensus.leader.PubKey)
 
 	// Clear the OfflinePeersList again
 	consensus.OfflinePeerList = make([]p2p.Peer, 0)
@@ -369,8 +572,8 @@ func (consensus *Consensus) String() string {
 	} else {
 		duty = "VLD" // validator
 	}
-	return fmt.Sprintf("[duty:%s, pubKey:%s, ShardID:%v, nodeID:%v, state:%s]",
-		duty, hex.EncodeToString(consensus.pubKey.Serialize()), consensus.ShardID, consensus.nodeID, consensus.state)
+	return fmt.Sprintf("[duty:%s, pubKey:%s, ShardID:%v, state:%s]",
+		duty, hex.EncodeToString(consensus.pubKey.Serialize()), consensus.ShardID, consensus.round.state)
 }
 
 // AddPeers adds new peers into the validator map of the consensus
@@ -379,15 +582,18 @@ func (consensus *Consensus) AddPeers(peers []*p2p.Peer) int {
 	count := 0
 
 	for _, peer := range peers {
-		_, ok := consensus.validators.Load(utils.GetUniqueIDFromPeer(*peer))
+		_, ok := consensus.validators.Load(getPeerKey(peer.PubKey))
 		if !ok {
 			if peer.ValidatorID == -1 {
 				peer.ValidatorID = int(consensus.uniqueIDInstance.GetUniqueID())
 			}
-			consensus.validators.Store(utils.GetUniqueIDFromPeer(*peer), *peer)
-			consensus.pubKeyLock.Lock()
-			consensus.PublicKeys = append(consensus.PublicKeys, peer.PubKey)
-			consensus.pubKeyLock.Unlock()
+			consensus.validators.Store(getPeerKey(peer.PubKey), *peer)
+			// The members of the committee recorded in the chain are in it already
+			if !consensus.isCommitteeMember(peer.PubKey) {
+				consensus.pubKeyLock.Lock()
+				consensus.PublicKeys = append(consensus.PublicKeys, peer.PubKey)
+				consensus.pubKeyLock.Unlock()
+			}
 			//			utils.GetLogInstance().Debug("[SYNC]", "new peer added", peer)
 		}
 		count++
@@ -408,18 +614,10 @@ func (consensus *Consensus) RemovePeers(peers []p2p.Peer) int {
 	newList := append(consensus.PublicKeys[:0:0], consensus.PublicKeys...)
 
 	for _, peer := range peers {
-		consensus.validators.Range(func(k, v interface{}) bool {
-			if p, ok := v.(p2p.Peer); ok {
-				// We are using peer.IP and peer.Port to identify the unique peer
-				// FIXME (lc): use a generic way to identify a peer
-				if p.IP == peer.IP && p.Port == peer.Port {
-					consensus.validators.Delete(k)
-					count++
-				}
-				return true
-			}
-			return false
-		})
+		if _, ok := consensus.validators.Load(getPeerKey(peer.PubKey)); ok {
+			consensus.validators.Delete(getPeerKey(peer.PubKey))
+			count++
+		}
 
 		for i, pp := range newList {
 			// Not Found the pubkey, if found pubkey, ignore it
@@ -442,7 +640,7 @@ func (consensus *Consensus) RemovePeers(peers []p2p.Peer) int {
 		buffer := pong.ConstructPongMessage()
 
 		if utils.UseLibP2P {
-			consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, buffer)
+			consensus.SendMessageToGroup(buffer)
 		} else {
 			host.BroadcastMessageFromLeader(consensus.host, validators, buffer, consensus.OfflinePeers)
 		}
@@ -485,16 +683,46 @@ func (consensus *Consensus) UpdatePublicKeys(pubKeys []*bls.PublicKey) int {
 	return len(consensus.PublicKeys)
 }
 
-// NewFaker returns a faker consensus.
+// SetChainParams sets the protocol parameters of the chain the consensus runs on, and
+// applies the quorum in effect at the block of the current round.
+func (consensus *Consensus) SetChainParams(config *chainparams.Config) {
+	consensus.ChainParams = config
+	consensus.updateQuorumPolicy(uint64(consensus.consensusID) + 1)
+}
+
+// updateQuorumPolicy applies the quorum in effect at the block of the given number.
+func (consensus *Consensus) updateQuorumPolicy(number uint64) {
+	consensus.QuorumPolicy = consensus.quorumPolicyAt(number)
+}
+
+// quorumPolicyAt returns the quorum policy of the quorum in effect at the block of the given number.
+func (consensus *Consensus) quorumPolicyAt(number uint64) bls_cosi.Policy {
+	params := consensus.ChainParams.At(number)
+	return consensus.NewQuorumPolicy(number, params.QuorumNumerator, params.QuorumDenominator)
+}
+
+// newFractionPolicy returns the policy requiring more than the fraction of the members, whatever the block.
+func newFractionPolicy(number, numerator, denominator uint64) bls_cosi.Policy {
+	return bls_cosi.NewFractionPolicy(numerator, denominator)
+}
+
+// NewFaker returns a faker consensus, which accepts all the block seals.
 func NewFaker() *Consensus {
-	return &Consensus{}
+	return &Consensus{fakeSeal: true, ChainParams: chainparams.DefaultConfig}
 }
 
 // VerifyHeader checks whether a header conforms to the consensus rules of the
 // stock bft engine.
 func (consensus *Consensus) VerifyHeader(chain consensus_engine.ChainReader, header *types.Header, seal bool) error {
-	// TODO: implement this
-	return nil
+	// Short circuit if the header is known
+	if chain.GetHeader(header.Hash(), header.Number.Uint64()) != nil {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus_engine.ErrUnknownAncestor
+	}
+	return consensus.verifyHeader(chain, header, parent, false, seal)
 }
 
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
@@ -502,9 +730,16 @@ func (consensus *Consensus) VerifyHeader(chain consensus_engine.ChainReader, hea
 // a results channel to retrieve the async verifications.
 func (consensus *Consensus) VerifyHeaders(chain consensus_engine.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
 	abort, results := make(chan struct{}), make(chan error, len(headers))
-	for i := 0; i < len(headers); i++ {
-		results <- nil
-	}
+	go func() {
+		for i := range headers {
+			err := consensus.verifyHeaderWorker(chain, headers, seals, i)
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
 	return abort, results
 }
 
@@ -527,45 +762,161 @@ func (consensus *Consensus) verifyHeaderWorker(chain consensus_engine.ChainReade
 // verifyHeader checks whether a header conforms to the consensus rules of the
 // stock bft engine.
 func (consensus *Consensus) verifyHeader(chain consensus_engine.ChainReader, header, parent *types.Header, uncle bool, seal bool) error {
+	if header.Number.Uint64() != parent.Number.Uint64()+1 {
+		return consensus_engine.ErrInvalidNumber
+	}
+	// The faker verifies chains which run no drand
+	if !consensus.fakeSeal {
+		// The randomness is committed by the committee of the epoch, which took over at the last block of the previous epoch
+		if err := verifyRandomness(consensus.ChainParams, header, consensus.CommitteeKeys(chain, header)); err != nil {
+			return err
+		}
+		if err := verifyRandomnessInput(consensus.ChainParams, chain, header); err != nil {
+			return err
+		}
+		if err := consensus.verifyLastCommit(chain, header, parent); err != nil {
+			return err
+		}
+	}
+	if seal {
+		return consensus.VerifySeal(chain, header)
+	}
 	return nil
 }
 
-// VerifySeal implements consensus.Engine, checking whether the given block satisfies
-// the PoW difficulty requirements.
+// VerifySeal implements consensus.Engine, checking whether the given block is signed
+// by a quorum of the committee in both prepare and commit phases.
 func (consensus *Consensus) VerifySeal(chain consensus_engine.ChainReader, header *types.Header) error {
+	if consensus.fakeSeal {
+		return nil
+	}
+	return VerifyHeaderSeal(header, consensus.CommitteeKeys(chain, header), consensus.quorumPolicyAt(header.Number.Uint64()))
+}
+
+// VerifyHeaderSeal checks whether the header is signed by a quorum of the committee of the
+// given public keys, in the order of the bitmaps, in both prepare and commit phases.
+func VerifyHeaderSeal(header *types.Header, publicKeys []*bls.PublicKey, quorumPolicy bls_cosi.Policy) error {
+	if len(publicKeys) == 0 {
+		return consensus_engine.ErrNotEnoughSigners
+	}
+
+	// The prepare signature is on the hash of the block before it is sealed.
+	prepareMask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil {
+		return err
+	}
+	if err := prepareMask.SetMask(header.PrepareBitmap); err != nil {
+		return consensus_engine.ErrNotEnoughSigners
+	}
+	if !quorumPolicy.Check(prepareMask) {
+		return consensus_engine.ErrNotEnoughSigners
+	}
+	prepareSig := bls.Sign{}
+	if err := prepareSig.Deserialize(header.PrepareSignature[:]); err != nil {
+		return consensus_engine.ErrInvalidPrepareSignature
+	}
+	blockHash := sealHash(header)
+	if !prepareSig.VerifyHash(prepareMask.AggregatePublic, blockHash[:]) {
+		return consensus_engine.ErrInvalidPrepareSignature
+	}
+
+	return verifyCommitSig(header, header.CommitSignature, header.CommitBitmap, publicKeys, quorumPolicy)
+}
+
+// verifyCommitSig checks whether the commit signature of the given bitmap is signed by a
+// quorum of the committee of the given public keys on the prepare multi-sig and bitmap of
+// the header.
+func verifyCommitSig(header *types.Header, sig [48]byte, bitmap []byte, publicKeys []*bls.PublicKey, quorumPolicy bls_cosi.Policy) error {
+	commitMask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil {
+		return err
+	}
+	if err := commitMask.SetMask(bitmap); err != nil {
+		return consensus_engine.ErrNotEnoughSigners
+	}
+	if !quorumPolicy.Check(commitMask) {
+		return consensus_engine.ErrNotEnoughSigners
+	}
+	commitSig := bls.Sign{}
+	if err := commitSig.Deserialize(sig[:]); err != nil {
+		return consensus_engine.ErrInvalidCommitSignature
+	}
+	prepareMultiSigAndBitmap := append(header.PrepareSignature[:0:0], header.PrepareSignature[:]...)
+	prepareMultiSigAndBitmap = append(prepareMultiSigAndBitmap, header.PrepareBitmap...)
+	if !commitSig.VerifyHash(commitMask.AggregatePublic, prepareMultiSigAndBitmap) {
+		return consensus_engine.ErrInvalidCommitSignature
+	}
 	return nil
 }
 
+// CommitteeKeys returns the public keys of the committee which signed the header,
+// in the order of the bitmaps.
+func (consensus *Consensus) CommitteeKeys(chain consensus_engine.ChainReader, header *types.Header) []*bls.PublicKey {
+	return consensus.committeeKeysAt(chain, binary.BigEndian.Uint32(header.ShardID[:]), header.Number.Uint64())
+}
+
+// committeeKeysAt returns the public keys of the committee which signs the block of the
+// given number of the given shard. The committee is taken from the shard state of the
+// epoch of the block, and no committee is known when it doesn't record the BLS keys
+// of the shard. Only a chain whose genesis records no committees, i.e. a test network,
+// trusts the committee this node runs consensus with to have signed the blocks of its shard.
+func (consensus *Consensus) committeeKeysAt(chain consensus_engine.ChainReader, shardID uint32, number uint64) []*bls.PublicKey {
+	if chain != nil {
+		for _, committee := range chain.ReadShardState(consensus.ChainParams.EpochOf(number)) {
+			if committee.ShardID == shardID {
+				return CommitteePublicKeys(committee)
+			}
+		}
+		if len(chain.ReadShardState(0)) > 0 {
+			return nil
+		}
+	}
+	if shardID != consensus.ShardID {
+		// The committee of another shard is only known from the shard state
+		return nil
+	}
+
+	consensus.pubKeyLock.Lock()
+	defer consensus.pubKeyLock.Unlock()
+	return append(consensus.PublicKeys[:0:0], consensus.PublicKeys...)
+}
+
+// isCommitteeMember returns whether the key belongs to the committee this node runs consensus with.
+func (consensus *Consensus) isCommitteeMember(pubKey *bls.PublicKey) bool {
+	consensus.pubKeyLock.Lock()
+	defer consensus.pubKeyLock.Unlock()
+	for _, publicKey := range consensus.PublicKeys {
+		if publicKey.IsEqual(pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state and assembling the block.
 func (consensus *Consensus) Finalize(chain consensus_engine.ChainReader, header *types.Header, state *state.DB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error) {
-	// Accumulate any block and uncle rewards and commit the final state root
+	// Accumulate the block reward and the fees and commit the final state root
 	// Header seems complete, assemble into a block and return
-	accumulateRewards(chain.Config(), state, header)
+	consensus.accumulateRewards(chain, state, header, txs, receipts)
 	header.Root = state.IntermediateRoot(false)
 	return types.NewBlock(header, txs, receipts), nil
 }
 
-// SealHash returns the hash of a block prior to it being sealed.
+// SealHash returns the hash of a block prior to it being sealed, which is
+// the block hash the committee signs on during consensus.
 func (consensus *Consensus) SealHash(header *types.Header) (hash common.Hash) {
-	hasher := sha3.NewLegacyKeccak256()
-
-	rlp.Encode(hasher, []interface{}{
-		header.ParentHash,
-		header.Coinbase,
-		header.Root,
-		header.TxHash,
-		header.ReceiptHash,
-		header.Bloom,
-		header.Difficulty,
-		header.Number,
-		header.GasLimit,
-		header.GasUsed,
-		header.Time,
-		header.Extra,
-	})
-	hasher.Sum(hash[:0])
-	return hash
+	return sealHash(header)
+}
+
+// sealHash returns the hash of the header without its signatures.
+func sealHash(header *types.Header) common.Hash {
+	unsealed := types.CopyHeader(header)
+	unsealed.PrepareSignature = [48]byte{}
+	unsealed.PrepareBitmap = nil
+	unsealed.CommitSignature = [48]byte{}
+	unsealed.CommitBitmap = nil
+	return unsealed.Hash()
 }
 
 // Seal is to seal final block.
@@ -574,53 +925,103 @@ func (consensus *Consensus) Seal(chain consensus_engine.ChainReader, block *type
 	return nil
 }
 
-// Prepare is to prepare ...
-// TODO(RJ): fix it.
+// Prepare implements consensus.Engine, recording the commit signature of the block
+// rewardDelay blocks earlier into the header, so that its signers are rewarded.
 func (consensus *Consensus) Prepare(chain consensus_engine.ChainReader, header *types.Header) error {
-	// TODO: implement prepare method
+	if header.Number.Uint64() < rewardDelay {
+		return nil
+	}
+	signed := chain.GetHeaderByNumber(header.Number.Uint64() - rewardDelay)
+	if signed == nil {
+		return consensus_engine.ErrUnknownAncestor
+	}
+	header.LastCommitSignature = signed.CommitSignature
+	header.LastCommitBitmap = append(signed.CommitBitmap[:0:0], signed.CommitBitmap...)
 	return nil
 }
 
-// AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, state *state.DB, header *types.Header) {
-	// TODO: implement mining rewards
+// GetPublicKey returns the public key identifying this node in the consensus
+func (consensus *Consensus) GetPublicKey() *bls.PublicKey {
+	return consensus.pubKey
 }
 
-// GetNodeID returns the nodeID
-func (consensus *Consensus) GetNodeID() uint32 {
-	return consensus.nodeID
+// GetLeader returns the leader of the current view. It doesn't take the consensus
+// mutex, so that the callbacks of the consensus, e.g. OnViewChange, can use it.
+func (consensus *Consensus) GetLeader() p2p.Peer {
+	return consensus.leader
 }
 
-// GetPeerFromID will get peer from peerID, bool value in return true means success and false means fail
+// GetPeerFromID will get peer from the peerID derived from its IP and port, as used by state syncing,
+// bool value in return true means success and false means fail
 func (consensus *Consensus) GetPeerFromID(peerID uint32) (p2p.Peer, bool) {
-	v, ok := consensus.validators.Load(peerID)
-	if !ok {
-		return p2p.Peer{}, false
-	}
-	value, ok := v.(p2p.Peer)
-	if !ok {
-		return p2p.Peer{}, false
-	}
-	return value, true
+	var result p2p.Peer
+	found := false
+	consensus.validators.Range(func(k, v interface{}) bool {
+		if peer, ok := v.(p2p.Peer); ok && utils.GetUniqueIDFromPeer(peer) == peerID {
+			result = peer
+			found = true
+			return false
+		}
+		return true
+	})
+	return result, found
 }
 
 // SendMessage sends message thru p2p host to peer.
 func (consensus *Consensus) SendMessage(peer p2p.Peer, message []byte) {
+	if consensus.silentTowards(peer) {
+		return
+	}
+	for _, stale := range consensus.staleMessages(message) {
+		host.SendMessage(consensus.host, peer, stale, nil)
+	}
 	host.SendMessage(consensus.host, peer, message, nil)
 }
 
+// SendMessageToGroup sends message thru p2p host to the consensus group of the shard.
+func (consensus *Consensus) SendMessageToGroup(message []byte) {
+	if consensus.silentTowardsGroup() {
+		return
+	}
+	groups := []p2p.GroupID{p2p.NewGroupIDByShardID(consensus.ShardID)}
+	for _, stale := range consensus.staleMessages(message) {
+		consensus.host.SendMessageToGroups(groups, host.ConstructP2pMessage(host.MessageTypeBroadcast, stale))
+	}
+	consensus.host.SendMessageToGroups(groups, host.ConstructP2pMessage(host.MessageTypeBroadcast, message))
+}
+
+// broadcastMessageFromLeader sends message thru p2p host to the validators.
+func (consensus *Consensus) broadcastMessageFromLeader(message []byte) {
+	peers := []p2p.Peer{}
+	for _, peer := range consensus.GetValidatorPeers() {
+		if !consensus.silentTowards(peer) {
+			peers = append(peers, peer)
+		}
+	}
+	for _, stale := range consensus.staleMessages(message) {
+		host.BroadcastMessageFromLeader(consensus.host, peers, stale, consensus.OfflinePeers)
+	}
+	host.BroadcastMessageFromLeader(consensus.host, peers, message, consensus.OfflinePeers)
+}
+
 // Populates the common basic fields for all consensus message.
 func (consensus *Consensus) populateMessageFields(message *consensus_proto.Message) {
+	consensus.populateRoundMessageFields(message, consensus.consensusID, consensus.round)
+}
+
+// Populates the common basic fields for the consensus message of the given round.
+func (consensus *Consensus) populateRoundMessageFields(message *consensus_proto.Message, consensusID uint32, round *roundState) {
 	// 4 byte consensus id
-	message.ConsensusId = consensus.consensusID
+	message.ConsensusId = consensusID
 
 	// 32 byte block hash
-	message.BlockHash = consensus.blockHash[:]
+	message.BlockHash = round.blockHash[:]
+
+	// 48 byte sender public key
+	message.SenderPubkey = consensus.pubKey.Serialize()
 
-	// 4 byte sender id
-	message.SenderId = uint32(consensus.nodeID)
+	// 4 byte view id
+	message.ViewId = consensus.viewID
 }
 
 // Signs the consensus message and returns the marshaled message.
@@ -0,0 +1,170 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/api/proto"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/attack"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// The number of sent messages kept by a node scripted to replay stale messages.
+const maxStaleMessages = 64
+
+// adversary is the state of the byzantine behaviors scripted for this node.
+// The behaviors only run for the rounds enabled by Consensus.AttackScript, so
+// that tests and testnets can check the honest nodes keep agreeing.
+type adversary struct {
+	mutex sync.Mutex
+	// Messages sent by this node, replayed in the later rounds
+	sent []staleMessage
+}
+
+type staleMessage struct {
+	consensusID uint32
+	message     []byte
+}
+
+// adversaryRule returns the rule enabling the byzantine behavior in the current round, or nil.
+func (consensus *Consensus) adversaryRule(behavior attack.Behavior) *attack.Rule {
+	return consensus.AttackScript.Rule(behavior, consensus.consensusID)
+}
+
+// silentTowards returns whether this node doesn't send anything to the peer.
+func (consensus *Consensus) silentTowards(peer p2p.Peer) bool {
+	rule := consensus.adversaryRule(attack.Silence)
+	return rule != nil && rule.Targets(peer.IP, peer.Port)
+}
+
+// silentTowardsGroup returns whether this node doesn't send anything to the
+// group. A group message can't skip some of the peers, so only the silence
+// towards all the peers applies to it.
+func (consensus *Consensus) silentTowardsGroup() bool {
+	rule := consensus.adversaryRule(attack.Silence)
+	return rule != nil && rule.TargetsAll()
+}
+
+// staleMessages records the message about to be sent, and returns the messages
+// of the earlier rounds to send again along with it.
+func (consensus *Consensus) staleMessages(message []byte) [][]byte {
+	if !consensus.AttackScript.Has(attack.StaleReplay) {
+		return nil
+	}
+	consensus.adversary.mutex.Lock()
+	defer consensus.adversary.mutex.Unlock()
+
+	stale := [][]byte{}
+	if consensus.adversaryRule(attack.StaleReplay) != nil {
+		for _, sent := range consensus.adversary.sent {
+			if sent.consensusID < consensus.consensusID {
+				stale = append(stale, sent.message)
+			}
+		}
+	}
+	consensus.adversary.sent = append(consensus.adversary.sent, staleMessage{consensusID: consensus.consensusID, message: message})
+	if len(consensus.adversary.sent) > maxStaleMessages {
+		consensus.adversary.sent = consensus.adversary.sent[len(consensus.adversary.sent)-maxStaleMessages:]
+	}
+	return stale
+}
+
+// voteShare returns the share of this node in the vote on the hash. It's the
+// given signature unless the node is scripted to send invalid shares.
+func (consensus *Consensus) voteShare(sign *bls.Sign, hash []byte) *bls.Sign {
+	if consensus.adversaryRule(attack.InvalidShare) == nil {
+		return sign
+	}
+	utils.GetLogInstance().Warn("Byzantine: sending an invalid vote share", "consensusID", consensus.consensusID)
+	return consensus.priKey.SignHash(conflictingHash(hash))
+}
+
+// equivocate sends a vote for a conflicting block after the vote of this node
+// on the hash, if the node is scripted to equivocate.
+func (consensus *Consensus) equivocate(msgType consensus_proto.MessageType, hash []byte) {
+	if consensus.adversaryRule(attack.Equivocation) == nil {
+		return
+	}
+	utils.GetLogInstance().Warn("Byzantine: voting for a conflicting block", "msgType", msgType, "consensusID", consensus.consensusID)
+
+	message := consensus_proto.Message{}
+	message.Type = msgType
+	consensus.populateMessageFields(&message)
+	message.BlockHash = conflictingHash(message.BlockHash)
+	message.Payload = consensus.priKey.SignHash(conflictingHash(hash)).Serialize()
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the conflicting vote", "error", err)
+		return
+	}
+	msgToSend := proto.ConstructConsensusMessage(marshaledMessage)
+	if utils.UseLibP2P {
+		consensus.SendMessageToGroup(msgToSend)
+	} else {
+		consensus.SendMessage(consensus.leader, msgToSend)
+	}
+}
+
+// splitAnnounce announces a conflicting block to the targeted validators, or to
+// every other validator if the rule has no peers, and the block of the round to
+// the rest, if the leader is scripted to do so. It returns whether it did.
+func (consensus *Consensus) splitAnnounce(newBlock *types.Block, msgToSend []byte) bool {
+	rule := consensus.adversaryRule(attack.SplitAnnounce)
+	if rule == nil {
+		return false
+	}
+	utils.GetLogInstance().Warn("Byzantine: announcing conflicting blocks", "consensusID", consensus.consensusID)
+
+	header := newBlock.Header()
+	header.Extra = append(header.Extra, []byte("conflicting")...)
+	conflictingBlock := types.NewBlockWithHeader(header).WithBody(newBlock.Transactions(), newBlock.Uncles(), newBlock.Evidences(), newBlock.CrossShardReceipts(), newBlock.IncomingReceipts())
+	blockHash := conflictingBlock.Hash()
+
+	message := consensus_proto.Message{}
+	message.Type = consensus_proto.MessageType_ANNOUNCE
+	consensus.populateMessageFields(&message)
+	message.BlockHash = blockHash[:]
+	encodedBlock, err := rlp.EncodeToBytes(conflictingBlock)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to encode the conflicting block", "error", err)
+		return false
+	}
+	message.Payload = encodedBlock
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the conflicting announce", "error", err)
+		return false
+	}
+	conflictingMsg := proto.ConstructConsensusMessage(marshaledMessage)
+
+	validators := consensus.GetValidatorPeers()
+	sort.Slice(validators, func(i, j int) bool {
+		return net.JoinHostPort(validators[i].IP, validators[i].Port) < net.JoinHostPort(validators[j].IP, validators[j].Port)
+	})
+	for i, peer := range validators {
+		targeted := rule.Targets(peer.IP, peer.Port)
+		if rule.TargetsAll() {
+			targeted = i%2 == 1
+		}
+		if targeted {
+			consensus.SendMessage(peer, conflictingMsg)
+		} else {
+			consensus.SendMessage(peer, msgToSend)
+		}
+	}
+	return true
+}
+
+// conflictingHash returns a hash different from the given one.
+func conflictingHash(hash []byte) []byte {
+	conflicting := sha256.Sum256(append([]byte("conflicting"), hash...))
+	return conflicting[:]
+}
@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"sort"
+
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Bounds of the future message buffer: messages are only kept for the next
+// maxFutureRounds rounds, and at most maxFutureMsgsPerRound of them per round.
+const (
+	maxFutureRounds       = 8
+	maxFutureMsgsPerRound = 256
+)
+
+// futureMsgKey identifies a buffered message, so that a resent message replaces
+// the buffered one instead of taking another slot of the round.
+type futureMsgKey struct {
+	msgType   consensus_proto.MessageType
+	senderKey string
+}
+
+// bufferFutureMessage keeps a message of an upcoming round until the consensus
+// reaches that round. It returns whether the message is buffered.
+// The caller must hold the consensus mutex, and is responsible for checking
+// the signature of the message.
+func (consensus *Consensus) bufferFutureMessage(message consensus_proto.Message) bool {
+	consensus.futureMsgLock.Lock()
+	defer consensus.futureMsgLock.Unlock()
+
+	consensusID := message.ConsensusId
+	if consensusID <= consensus.consensusID || consensusID > consensus.consensusID+maxFutureRounds {
+		return false
+	}
+	if consensus.futureMsgs == nil {
+		consensus.futureMsgs = make(map[uint32]map[futureMsgKey]consensus_proto.Message)
+	}
+	round, ok := consensus.futureMsgs[consensusID]
+	if !ok {
+		round = make(map[futureMsgKey]consensus_proto.Message)
+		consensus.futureMsgs[consensusID] = round
+	}
+	key := futureMsgKey{msgType: message.Type, senderKey: string(message.SenderPubkey)}
+	if _, ok := round[key]; !ok && len(round) >= maxFutureMsgsPerRound {
+		utils.GetLogInstance().Debug("Future message buffer is full", "consensusID", consensusID, "msgType", message.Type, "senderKey", hex.EncodeToString(message.SenderPubkey))
+		return false
+	}
+	round[key] = message
+	utils.GetLogInstance().Debug("Buffered future message", "myConsensusID", consensus.consensusID, "consensusID", consensusID, "msgType", message.Type, "senderKey", hex.EncodeToString(message.SenderPubkey))
+	return true
+}
+
+// replayFutureMessages dispatches the buffered messages of the current round
+// with the given handler, in the order of the consensus phases, and drops the
+// ones of the rounds already passed. It keeps replaying as long as handling the
+// messages moves the consensus to the next round.
+func (consensus *Consensus) replayFutureMessages(dispatch func(consensus_proto.Message)) {
+	for {
+		consensus.mutex.Lock()
+		consensusID := consensus.consensusID
+		consensus.mutex.Unlock()
+
+		consensus.futureMsgLock.Lock()
+		messages := []consensus_proto.Message{}
+		for id, round := range consensus.futureMsgs {
+			if id > consensusID {
+				continue
+			}
+			if id == consensusID {
+				for _, message := range round {
+					messages = append(messages, message)
+				}
+			}
+			delete(consensus.futureMsgs, id)
+		}
+		consensus.futureMsgLock.Unlock()
+
+		if len(messages) == 0 {
+			return
+		}
+		sort.SliceStable(messages, func(i, j int) bool {
+			return messages[i].Type < messages[j].Type
+		})
+		utils.GetLogInstance().Debug("Replaying future messages", "consensusID", consensusID, "numOfMessages", len(messages))
+		for _, message := range messages {
+			dispatch(message)
+		}
+	}
+}
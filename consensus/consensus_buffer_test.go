@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferFutureMessage(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7782"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7784", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(validator).AnyTimes()
+	consensus := New(m, "0", []p2p.Peer{validator}, leader)
+	consensus.consensusID = 10
+
+	message := consensus_proto.Message{Type: consensus_proto.MessageType_PREPARED, ConsensusId: 10}
+	assert.False(test, consensus.bufferFutureMessage(message), "message of the current round should not be buffered")
+	message.ConsensusId = 9
+	assert.False(test, consensus.bufferFutureMessage(message), "message of a past round should not be buffered")
+	message.ConsensusId = 10 + maxFutureRounds + 1
+	assert.False(test, consensus.bufferFutureMessage(message), "message too far ahead should not be buffered")
+
+	message.ConsensusId = 11
+	assert.True(test, consensus.bufferFutureMessage(message))
+	message.Payload = []byte{1}
+	assert.True(test, consensus.bufferFutureMessage(message))
+	assert.Equal(test, 1, len(consensus.futureMsgs[11]), "resent message should replace the buffered one")
+	assert.Equal(test, []byte{1}, consensus.futureMsgs[11][futureMsgKey{consensus_proto.MessageType_PREPARED, ""}].Payload)
+}
+
+func TestReplayFutureMessages(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7792"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7794", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(validator).AnyTimes()
+	consensus := New(m, "0", []p2p.Peer{validator}, leader)
+
+	for _, msgType := range []consensus_proto.MessageType{consensus_proto.MessageType_COMMITTED, consensus_proto.MessageType_ANNOUNCE, consensus_proto.MessageType_PREPARED} {
+		consensus.bufferFutureMessage(consensus_proto.Message{Type: msgType, ConsensusId: 1})
+		consensus.bufferFutureMessage(consensus_proto.Message{Type: msgType, ConsensusId: 2})
+	}
+
+	replayed := []consensus_proto.Message{}
+	dispatch := func(message consensus_proto.Message) {
+		replayed = append(replayed, message)
+	}
+
+	consensus.replayFutureMessages(dispatch)
+	assert.Empty(test, replayed, "messages should not be replayed before their round")
+
+	consensus.consensusID = 1
+	consensus.replayFutureMessages(dispatch)
+	if assert.Equal(test, 3, len(replayed)) {
+		assert.Equal(test, consensus_proto.MessageType_ANNOUNCE, replayed[0].Type)
+		assert.Equal(test, consensus_proto.MessageType_PREPARED, replayed[1].Type)
+		assert.Equal(test, consensus_proto.MessageType_COMMITTED, replayed[2].Type)
+	}
+
+	// Skipping round 2 drops its messages.
+	replayed = replayed[:0]
+	consensus.consensusID = 3
+	consensus.replayFutureMessages(dispatch)
+	assert.Empty(test, replayed)
+	assert.Empty(test, consensus.futureMsgs)
+}
@@ -0,0 +1,29 @@
+package consensus
+
+import "time"
+
+// Clock is the source of time of the consensus: the phase timers and the times of
+// the traced rounds. The simulated network drives the consensus with its virtual clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once d elapsed, unless stop is called before.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// systemClock is the clock of the running system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) (stop func() bool) {
+	return time.AfterFunc(d, f).Stop
+}
+
+// SetClock makes the consensus use the given clock for its timers and traces.
+// It must be called before the consensus starts.
+func (consensus *Consensus) SetClock(clock Clock) {
+	consensus.clock = clock
+	consensus.Tracer.now = clock.Now
+}
@@ -0,0 +1,316 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// The number of recent rounds whose votes are kept to detect double signing.
+const evidenceRounds = 16
+
+// Bounds of the evidences: an evidence may only be included in the maxEvidenceAge
+// blocks after the double signed one, and at most maxPendingEvidences of them wait
+// for a block.
+const (
+	maxEvidenceAge      = 256
+	maxPendingEvidences = 64
+)
+
+// voteKey identifies the vote of a validator in a phase of a consensus round.
+type voteKey struct {
+	consensusID uint32
+	viewID      uint32
+	msgType     consensus_proto.MessageType
+	pubKey      string
+}
+
+// evidencePool records the prepare and commit votes of the recent rounds, and
+// the double sign evidences found from them which are not in a block yet. Only
+// one evidence of each offense is kept.
+type evidencePool struct {
+	mutex   sync.Mutex
+	votes   map[voteKey]consensus_proto.Message
+	pending map[types.DoubleSignOffense]*types.DoubleSignEvidence
+	// Block numbers of the offenses already proven in a block, until they expire
+	included map[types.DoubleSignOffense]uint64
+}
+
+func newEvidencePool() *evidencePool {
+	return &evidencePool{
+		votes:    make(map[voteKey]consensus_proto.Message),
+		pending:  make(map[types.DoubleSignOffense]*types.DoubleSignEvidence),
+		included: make(map[types.DoubleSignOffense]uint64),
+	}
+}
+
+// addVote records a vote whose signature is already verified. If the signer
+// voted for another block in the same phase before, the evidence of the double
+// signing is returned.
+func (pool *evidencePool) addVote(message consensus_proto.Message, pubKey *bls.PublicKey) *types.DoubleSignEvidence {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	// Forget the votes of the rounds too old to matter.
+	for key := range pool.votes {
+		if key.consensusID+evidenceRounds < message.ConsensusId {
+			delete(pool.votes, key)
+		}
+	}
+
+	key := voteKey{
+		consensusID: message.ConsensusId,
+		viewID:      message.ViewId,
+		msgType:     message.Type,
+		pubKey:      pubKey.SerializeToHexStr(),
+	}
+	vote, ok := pool.votes[key]
+	if !ok {
+		pool.votes[key] = message
+		return nil
+	}
+	if bytes.Equal(vote.BlockHash, message.BlockHash) {
+		return nil
+	}
+
+	message1, err1 := protobuf.Marshal(&vote)
+	message2, err2 := protobuf.Marshal(&message)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	return &types.DoubleSignEvidence{
+		BlockNumber: blockNumberOf(message.ConsensusId),
+		PubKey:      pubKey.Serialize(),
+		Message1:    message1,
+		Message2:    message2,
+	}
+}
+
+// addEvidence adds a verified evidence to the pending ones. It returns false
+// if the offense is already known or the pool is full.
+func (pool *evidencePool) addEvidence(evidence *types.DoubleSignEvidence) bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	offense, err := evidence.Offense()
+	if err != nil {
+		return false
+	}
+	if _, ok := pool.pending[offense]; ok {
+		return false
+	}
+	if _, ok := pool.included[offense]; ok {
+		return false
+	}
+	if len(pool.pending) >= maxPendingEvidences {
+		utils.GetLogInstance().Debug("Evidence pool is full", "evidenceHash", evidence.Hash())
+		return false
+	}
+	pool.pending[offense] = evidence
+	return true
+}
+
+// pendingEvidences returns the evidences not included in a block yet which
+// may still be included in the block of the given number.
+func (pool *evidencePool) pendingEvidences(number uint64) []*types.DoubleSignEvidence {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	evidences := []*types.DoubleSignEvidence{}
+	for _, evidence := range pool.pending {
+		if evidence.BlockNumber <= number && number <= evidence.BlockNumber+maxEvidenceAge {
+			evidences = append(evidences, evidence)
+		}
+	}
+	return evidences
+}
+
+// markIncluded moves the evidences included in the block of the given number out of
+// the pending ones, and forgets the evidences which expired by that block.
+func (pool *evidencePool) markIncluded(evidences []*types.DoubleSignEvidence, number uint64) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for _, evidence := range evidences {
+		offense, err := evidence.Offense()
+		if err != nil {
+			continue
+		}
+		delete(pool.pending, offense)
+		pool.included[offense] = evidence.BlockNumber
+	}
+	// An expired evidence can't get into a block again, so it needn't be remembered.
+	for offense, blockNumber := range pool.included {
+		if blockNumber+maxEvidenceAge < number {
+			delete(pool.included, offense)
+		}
+	}
+	for offense, evidence := range pool.pending {
+		if evidence.BlockNumber+maxEvidenceAge < number {
+			delete(pool.pending, offense)
+		}
+	}
+}
+
+// VerifyDoubleSignEvidence checks that the evidence holds two prepare or two commit
+// messages of the same round, signed by the offender for different blocks, and that
+// its block number is the one of the round.
+func VerifyDoubleSignEvidence(evidence *types.DoubleSignEvidence) error {
+	pubKey := &bls.PublicKey{}
+	if err := pubKey.Deserialize(evidence.PubKey); err != nil {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	message1 := consensus_proto.Message{}
+	message2 := consensus_proto.Message{}
+	if protobuf.Unmarshal(evidence.Message1, &message1) != nil || protobuf.Unmarshal(evidence.Message2, &message2) != nil {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	if message1.Type != consensus_proto.MessageType_PREPARE && message1.Type != consensus_proto.MessageType_COMMIT {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	if message1.Type != message2.Type || message1.ConsensusId != message2.ConsensusId || message1.ViewId != message2.ViewId {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	// The age of the evidence and the committee of the offender follow from the block number
+	if evidence.BlockNumber != blockNumberOf(message1.ConsensusId) {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	if bytes.Equal(message1.BlockHash, message2.BlockHash) {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	if verifyMessageSig(pubKey, message1) != nil || verifyMessageSig(pubKey, message2) != nil {
+		return consensus_engine.ErrInvalidEvidence
+	}
+	return nil
+}
+
+// VerifyEvidence implements consensus.Engine, checking that the double sign evidence
+// proves the misbehavior of a member of the committee which signed the double signed
+// block, and that the block of the given header is not too late to include it.
+func (consensus *Consensus) VerifyEvidence(chain consensus_engine.ChainReader, header *types.Header, evidence *types.DoubleSignEvidence) error {
+	return consensus.verifyEvidence(chain, binary.BigEndian.Uint32(header.ShardID[:]), header.Number.Uint64(), evidence)
+}
+
+// verifyEvidence checks the double sign evidence may be included in the block of the
+// given number of the given shard.
+func (consensus *Consensus) verifyEvidence(chain consensus_engine.ChainReader, shardID uint32, number uint64, evidence *types.DoubleSignEvidence) error {
+	if evidence.BlockNumber > number || evidence.BlockNumber+maxEvidenceAge < number {
+		return consensus_engine.ErrExpiredEvidence
+	}
+	if err := VerifyDoubleSignEvidence(evidence); err != nil {
+		return err
+	}
+	// The faker knows no committee
+	if consensus.fakeSeal {
+		return nil
+	}
+	for _, publicKey := range consensus.committeeKeysAt(chain, shardID, evidence.BlockNumber) {
+		if bytes.Equal(publicKey.Serialize(), evidence.PubKey) {
+			return nil
+		}
+	}
+	return consensus_engine.ErrUnknownOffender
+}
+
+// PendingEvidences returns the double sign evidences to include in the block of the given
+// number. The offenses proven in the blocks still collecting commits are left out, as
+// the block may follow them.
+func (consensus *Consensus) PendingEvidences(number uint64) []*types.DoubleSignEvidence {
+	consensus.mutex.Lock()
+	proposed := make(map[types.DoubleSignOffense]bool)
+	for _, round := range consensus.pendingRounds {
+		blockObj, err := round.decodeBlock()
+		if err != nil {
+			continue
+		}
+		for _, evidence := range blockObj.Evidences() {
+			if offense, err := evidence.Offense(); err == nil {
+				proposed[offense] = true
+			}
+		}
+	}
+	consensus.mutex.Unlock()
+
+	evidences := []*types.DoubleSignEvidence{}
+	for _, evidence := range consensus.evidencePool.pendingEvidences(number) {
+		if offense, err := evidence.Offense(); err == nil && !proposed[offense] {
+			evidences = append(evidences, evidence)
+		}
+	}
+	return evidences
+}
+
+// nextBlockNumber returns the number of the block following the head of the chain,
+// or 0 if the consensus doesn't know the chain.
+func (consensus *Consensus) nextBlockNumber() uint64 {
+	if consensus.ChainReader == nil {
+		return 0
+	}
+	return consensus.ChainReader.CurrentHeader().Number.Uint64() + 1
+}
+
+// recordVote checks a prepare or commit vote with a verified signature against
+// the earlier votes of the signer, and spreads the evidence of double signing if any.
+func (consensus *Consensus) recordVote(message consensus_proto.Message, pubKey *bls.PublicKey) {
+	if message.Type != consensus_proto.MessageType_PREPARE && message.Type != consensus_proto.MessageType_COMMIT {
+		return
+	}
+	evidence := consensus.evidencePool.addVote(message, pubKey)
+	if evidence == nil {
+		return
+	}
+	if !consensus.evidencePool.addEvidence(evidence) {
+		return
+	}
+	utils.GetLogInstance().Warn("Double signing detected", "validatorKey", hex.EncodeToString(message.SenderPubkey), "msgType", message.Type, "consensusID", message.ConsensusId, "viewID", message.ViewId)
+	consensus.gossipEvidence(evidence)
+}
+
+// gossipEvidence sends the evidence to the other members of the committee.
+func (consensus *Consensus) gossipEvidence(evidence *types.DoubleSignEvidence) {
+	msgToSend := consensus.constructEvidenceMessage(evidence)
+	if utils.UseLibP2P {
+		consensus.SendMessageToGroup(msgToSend)
+	} else if consensus.IsLeader {
+		consensus.broadcastMessageFromLeader(msgToSend)
+	} else {
+		consensus.SendMessage(consensus.leader, msgToSend)
+	}
+}
+
+// processEvidenceMessage processes the double sign evidence gossiped by the other
+// members of the committee.
+func (consensus *Consensus) processEvidenceMessage(message consensus_proto.Message) {
+	senderKey := hex.EncodeToString(message.SenderPubkey)
+	senderPubKey := &bls.PublicKey{}
+	if err := senderPubKey.Deserialize(message.SenderPubkey); err != nil || !consensus.isCommitteeMember(senderPubKey) {
+		utils.GetLogInstance().Warn("Double sign evidence from outside the committee", "senderKey", senderKey)
+		return
+	}
+	if err := verifyMessageSig(senderPubKey, message); err != nil {
+		utils.GetLogInstance().Warn("Failed to verify the evidence message signature", "senderKey", senderKey, "error", err)
+		return
+	}
+
+	evidence := &types.DoubleSignEvidence{}
+	if err := rlp.DecodeBytes(message.Payload, evidence); err != nil {
+		utils.GetLogInstance().Warn("Unparseable double sign evidence", "error", err)
+		return
+	}
+	if err := consensus.verifyEvidence(consensus.ChainReader, consensus.ShardID, consensus.nextBlockNumber(), evidence); err != nil {
+		utils.GetLogInstance().Warn("Invalid double sign evidence", "senderKey", senderKey, "error", err)
+		return
+	}
+	if consensus.evidencePool.addEvidence(evidence) {
+		utils.GetLogInstance().Info("Received double sign evidence", "senderKey", senderKey, "evidenceHash", evidence.Hash())
+	}
+}
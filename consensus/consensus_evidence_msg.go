@@ -0,0 +1,32 @@
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/api/proto"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Construct the message gossiping a double sign evidence.
+func (consensus *Consensus) constructEvidenceMessage(evidence *types.DoubleSignEvidence) []byte {
+	message := consensus_proto.Message{}
+	message.Type = consensus_proto.MessageType_EVIDENCE
+
+	consensus.populateMessageFields(&message)
+	message.BlockHash = nil
+
+	//// Payload
+	payload, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to encode the double sign evidence", "error", err)
+	}
+	message.Payload = payload
+	//// END Payload
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the Evidence message", "error", err)
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage)
+}
@@ -0,0 +1,138 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	protobuf "github.com/golang/protobuf/proto"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoubleSignEvidence(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7882"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7884", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader).AnyTimes()
+	consensusLeader := New(m, "0", []p2p.Peer{validator}, leader)
+
+	m2 := mock_host.NewMockHost(ctrl)
+	m2.EXPECT().GetSelfPeer().Return(validator).AnyTimes()
+	consensusValidator := New(m2, "0", []p2p.Peer{validator}, leader)
+
+	vote := func(consensusID uint32, hash []byte) consensus_proto.Message {
+		message := consensus_proto.Message{
+			Type:         consensus_proto.MessageType_PREPARE,
+			ConsensusId:  consensusID,
+			SenderPubkey: consensusValidator.pubKey.Serialize(),
+			BlockHash:    hash,
+		}
+		consensusValidator.signConsensusMessage(&message)
+		return message
+	}
+
+	assert.Nil(test, consensusLeader.evidencePool.addVote(vote(1, []byte{1}), validator.PubKey))
+	assert.Nil(test, consensusLeader.evidencePool.addVote(vote(1, []byte{1}), validator.PubKey), "resent vote is not double signing")
+	assert.Nil(test, consensusLeader.evidencePool.addVote(vote(2, []byte{2}), validator.PubKey), "votes of different rounds are not double signing")
+
+	evidence := consensusLeader.evidencePool.addVote(vote(1, []byte{2}), validator.PubKey)
+	if evidence == nil {
+		test.Fatal("double signing is not detected")
+	}
+	assert.Equal(test, uint64(2), evidence.BlockNumber, "the round of consensus id 1 is for block 2")
+	assert.Nil(test, VerifyDoubleSignEvidence(evidence))
+	assert.Nil(test, consensusLeader.verifyEvidence(nil, 0, 3, evidence))
+	assert.True(test, consensusLeader.evidencePool.addEvidence(evidence))
+	assert.Equal(test, 1, len(consensusLeader.PendingEvidences(3)))
+	assert.Empty(test, consensusLeader.PendingEvidences(3+maxEvidenceAge), "expired evidence should not be included")
+
+	// Swapping the messages makes no other evidence of the same offense.
+	swapped := *evidence
+	swapped.Message1, swapped.Message2 = evidence.Message2, evidence.Message1
+	assert.Equal(test, evidence.Hash(), swapped.Hash())
+	assert.False(test, consensusLeader.evidencePool.addEvidence(&swapped))
+
+	// The evidence is kept until it gets into a block.
+	assert.False(test, consensusLeader.evidencePool.addEvidence(evidence))
+	consensusLeader.evidencePool.markIncluded(consensusLeader.PendingEvidences(3), 3)
+	assert.Empty(test, consensusLeader.PendingEvidences(3))
+	assert.False(test, consensusLeader.evidencePool.addEvidence(evidence), "evidence already in a block should not be pending again")
+
+	// The included evidences are forgotten once they expire.
+	consensusLeader.evidencePool.markIncluded(nil, 2+maxEvidenceAge)
+	assert.Equal(test, 1, len(consensusLeader.evidencePool.included))
+	consensusLeader.evidencePool.markIncluded(nil, 3+maxEvidenceAge)
+	assert.Empty(test, consensusLeader.evidencePool.included)
+	assert.Equal(test, consensus_engine.ErrExpiredEvidence, consensusLeader.verifyEvidence(nil, 0, 3+maxEvidenceAge, evidence))
+
+	// The block number can't be moved away from the round to dodge the expiry.
+	forged := *evidence
+	forged.BlockNumber = 3 + maxEvidenceAge
+	assert.Equal(test, consensus_engine.ErrInvalidEvidence, VerifyDoubleSignEvidence(&forged))
+
+	// Messages of different rounds don't prove anything.
+	message1 := vote(3, []byte{1})
+	message2 := vote(4, []byte{2})
+	forged = *evidence
+	forged.Message1, _ = protobuf.Marshal(&message1)
+	forged.Message2, _ = protobuf.Marshal(&message2)
+	assert.Equal(test, consensus_engine.ErrInvalidEvidence, VerifyDoubleSignEvidence(&forged))
+
+	// Nor messages signed by someone else.
+	forged = *evidence
+	forged.PubKey = leader.PubKey.Serialize()
+	assert.Equal(test, consensus_engine.ErrInvalidEvidence, VerifyDoubleSignEvidence(&forged))
+}
+
+func TestDoubleSignEvidenceOutOfCommittee(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7892"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7894", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+	outsider := p2p.Peer{IP: ip, Port: "7896"}
+	_, outsider.PubKey = utils.GenKey(outsider.IP, outsider.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader).AnyTimes()
+	consensusLeader := New(m, "0", []p2p.Peer{validator}, leader)
+
+	// The outsider signs two blocks of a round it has no say in.
+	m2 := mock_host.NewMockHost(ctrl)
+	m2.EXPECT().GetSelfPeer().Return(outsider).AnyTimes()
+	consensusOutsider := New(m2, "0", []p2p.Peer{validator}, leader)
+	vote := func(hash []byte) consensus_proto.Message {
+		message := consensus_proto.Message{
+			Type:         consensus_proto.MessageType_COMMIT,
+			ConsensusId:  1,
+			SenderPubkey: consensusOutsider.pubKey.Serialize(),
+			BlockHash:    hash,
+		}
+		consensusOutsider.signConsensusMessage(&message)
+		return message
+	}
+	consensusOutsider.evidencePool.addVote(vote([]byte{1}), outsider.PubKey)
+	evidence := consensusOutsider.evidencePool.addVote(vote([]byte{2}), outsider.PubKey)
+	if evidence == nil {
+		test.Fatal("double signing is not detected")
+	}
+	assert.Nil(test, VerifyDoubleSignEvidence(evidence))
+	assert.Equal(test, consensus_engine.ErrUnknownOffender, consensusLeader.verifyEvidence(nil, 0, 2, evidence))
+
+	// Nor is the evidence accepted when the outsider gossips it.
+	msg := consensusOutsider.constructEvidenceMessage(evidence)
+	consensusLeader.ProcessMessageLeader(msg[1:])
+	assert.Empty(test, consensusLeader.PendingEvidences(0))
+}
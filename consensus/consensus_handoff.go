@@ -0,0 +1,179 @@
+package consensus
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// CommitteePublicKeys returns the BLS public keys of the members of the committee,
+// in the order of its node list. It returns nil if any member has no valid BLS key.
+func CommitteePublicKeys(committee types.Committee) []*bls.PublicKey {
+	publicKeys := []*bls.PublicKey{}
+	for _, nodeID := range committee.NodeList {
+		publicKey := &bls.PublicKey{}
+		if err := publicKey.DeserializeHexStr(nodeID.BlsPublicKey); err != nil {
+			return nil
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys
+}
+
+// handOff moves the consensus to the committee of the next epoch once the last block of the
+// epoch, recording the new shard state, is committed, so that the new committee signs the
+// epoch block. The members leaving the shard stop signing and the joining ones are added
+// to the validators. The caller must hold the consensus mutex.
+func (consensus *Consensus) handOff(block *types.Block) {
+	// The quorum of the next block applies from now on, as it may be changed by an upgrade
+	consensus.updateQuorumPolicy(block.NumberU64() + 1)
+	if !consensus.ChainParams.IsEpochLastBlock(block.NumberU64()) || consensus.ShardCommittee == nil {
+		return
+	}
+	if block.NumberU64() <= consensus.handedOffBlock {
+		// The sync added the last block the consensus already handed off at.
+		return
+	}
+	consensus.handedOffBlock = block.NumberU64()
+	publicKeys := consensus.ShardCommittee(consensus.ChainParams.EpochOf(block.NumberU64()) + 1)
+	if len(publicKeys) == 0 {
+		// The chain records no committees, the committee of the shard stays the same.
+		consensus.pubKeyLock.Lock()
+		publicKeys = append(consensus.PublicKeys[:0:0], consensus.PublicKeys...)
+		consensus.pubKeyLock.Unlock()
+		if consensus.OnHandOff != nil {
+			consensus.OnHandOff(block, publicKeys, consensus.leader)
+		}
+		return
+	}
+	wasLeader := consensus.IsLeader
+	consensus.updateCommittee(publicKeys)
+	utils.GetLogInstance().Info("Handing off to the new committee", "blockNum", block.NumberU64(), "numMembers", len(publicKeys), "isLeader", consensus.IsLeader, "leftCommittee", consensus.leftCommittee)
+
+	// No round is in flight across the last block, as the leader doesn't pipeline it.
+	consensus.ResetState()
+	consensus.dropPendingRounds()
+	consensus.writeState(Finished)
+
+	if consensus.leftCommittee {
+		consensus.stopTimer()
+	}
+	if consensus.IsLeader && consensus.ReadySignal == nil {
+		consensus.ReadySignal = make(chan struct{})
+	}
+	if consensus.IsLeader != wasLeader && consensus.OnViewChange != nil {
+		consensus.OnViewChange(consensus.IsLeader)
+	}
+	if consensus.IsLeader && !wasLeader {
+		// Send signal to Node so the new leader proposes the epoch block
+		go func() {
+			consensus.ReadySignal <- struct{}{}
+		}()
+	}
+	if consensus.OnHandOff != nil {
+		consensus.OnHandOff(block, publicKeys, consensus.leader)
+	}
+}
+
+// HandOff moves the consensus to the committee of the next epoch once the last block of the
+// epoch is inserted into the chain, e.g. by the sync of a node which missed the consensus on
+// it. Only the epoch of the chain head is handed off to: the committees of the epochs the
+// sync went past are over. The handoff runs in the background, as the chain may insert the
+// block on behalf of the consensus holding its mutex, which hands off on its own then.
+func (consensus *Consensus) HandOff(block *types.Block) {
+	go consensus.handOffInserted(block)
+}
+
+// handOffInserted hands off at the last block of an epoch inserted into the chain, unless
+// the consensus already did or the chain moved past the next epoch.
+func (consensus *Consensus) handOffInserted(block *types.Block) {
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if consensus.ChainReader != nil {
+		head := consensus.ChainReader.CurrentHeader().Number.Uint64()
+		if consensus.ChainParams.EpochOf(head) > consensus.ChainParams.EpochOf(block.NumberU64()+1) {
+			return
+		}
+	}
+	if block.NumberU64() <= consensus.handedOffBlock {
+		return
+	}
+	consensus.handOff(block)
+}
+
+// JoinCommittee makes this node a member of the committee of the given shard, after it
+// was moved into the shard by resharding. The node still has to sync the chain of the
+// shard before it can take part in its consensus.
+func (consensus *Consensus) JoinCommittee(shardID uint32, publicKeys []*bls.PublicKey) {
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	utils.GetLogInstance().Info("Joining the committee of another shard", "shardID", shardID, "numMembers", len(publicKeys))
+	consensus.ShardID = shardID
+	consensus.validators.Range(func(key, value interface{}) bool {
+		consensus.validators.Delete(key)
+		return true
+	})
+	consensus.leader = p2p.Peer{}
+	consensus.updateCommittee(publicKeys)
+	consensus.blocksReceived = make(map[uint32]*BlockConsensusStatus)
+	consensus.handedOffBlock = 0
+	consensus.ResetState()
+	consensus.dropPendingRounds()
+	consensus.writeState(Finished)
+}
+
+// updateCommittee replaces the committee by the given members. The leader of the new
+// committee is the first member in the order of the keys' serialization, so that every
+// member picks the same one whatever leaders the view changes of the last epoch chose.
+// The joining members are known by their keys only; the messages reach them through
+// the group of the shard.
+func (consensus *Consensus) updateCommittee(publicKeys []*bls.PublicKey) {
+	keys := append(publicKeys[:0:0], publicKeys...)
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i].Serialize(), keys[j].Serialize()) < 0
+	})
+	leader, ok := consensus.getPeerByPubKey(keys[0])
+	if !ok {
+		leader = p2p.Peer{PubKey: keys[0]}
+	}
+
+	members := map[string]bool{}
+	for _, publicKey := range publicKeys {
+		members[getPeerKey(publicKey)] = true
+	}
+	oldLeader := consensus.leader
+	if oldLeader.PubKey != nil && members[getPeerKey(oldLeader.PubKey)] && !oldLeader.PubKey.IsEqual(consensus.pubKey) {
+		consensus.validators.Store(getPeerKey(oldLeader.PubKey), oldLeader)
+	}
+	consensus.validators.Range(func(key, value interface{}) bool {
+		if !members[key.(string)] {
+			consensus.validators.Delete(key)
+		}
+		return true
+	})
+	for _, publicKey := range publicKeys {
+		if publicKey.IsEqual(consensus.pubKey) {
+			continue
+		}
+		if _, ok := consensus.validators.Load(getPeerKey(publicKey)); !ok {
+			consensus.validators.Store(getPeerKey(publicKey), p2p.Peer{PubKey: publicKey, ValidatorID: int(consensus.uniqueIDInstance.GetUniqueID())})
+		}
+	}
+	consensus.validators.Delete(getPeerKey(leader.PubKey))
+	consensus.UpdatePublicKeys(publicKeys)
+
+	consensus.leader = leader
+	consensus.IsLeader = leader.PubKey.IsEqual(consensus.pubKey)
+	consensus.leftCommittee = !members[getPeerKey(consensus.pubKey)]
+	consensus.viewID = 0
+	consensus.nextViewID = 0
+	consensus.viewChangeSigs = map[string]*bls.Sign{}
+	consensus.viewChangeBitmap = nil
+	consensus.viewChangePreparedHash = nil
+}
@@ -0,0 +1,158 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitteePublicKeys(test *testing.T) {
+	_, pubKey1 := utils.GenKey(ip, "7001")
+	_, pubKey2 := utils.GenKey(ip, "7002")
+	committee := types.Committee{NodeList: []types.NodeID{
+		{BlsPublicKey: pubKey1.SerializeToHexStr()},
+		{BlsPublicKey: pubKey2.SerializeToHexStr()},
+	}}
+	publicKeys := CommitteePublicKeys(committee)
+	if assert.Equal(test, 2, len(publicKeys)) {
+		assert.True(test, publicKeys[0].IsEqual(pubKey1))
+		assert.True(test, publicKeys[1].IsEqual(pubKey2))
+	}
+
+	committee.NodeList = append(committee.NodeList, types.NodeID{BlsPublicKey: "not a key"})
+	assert.Nil(test, CommitteePublicKeys(committee))
+}
+
+func TestHandOff(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7066"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+
+	validators := make([]p2p.Peer, 3)
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 7077+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		_, validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+	_, joiningKey := utils.GenKey(ip, "7088")
+	// The old leader and the last validator leave, a new node joins.
+	newCommittee := []*bls.PublicKey{validators[0].PubKey, validators[1].PubKey, joiningKey}
+	newLeaderKey := newCommittee[0]
+	for _, key := range newCommittee[1:] {
+		if bytes.Compare(key.Serialize(), newLeaderKey.Serialize()) < 0 {
+			newLeaderKey = key
+		}
+	}
+
+	consensusValidators := make([]*Consensus, 3)
+	handedOff := make([]bool, 3)
+	for i := 0; i < 3; i++ {
+		m := mock_host.NewMockHost(ctrl)
+		m.EXPECT().GetSelfPeer().Return(validators[i]).AnyTimes()
+		consensus := New(m, "0", validators, leader)
+		consensus.ShardCommittee = func(epoch uint64) []*bls.PublicKey {
+			assert.Equal(test, uint64(1), epoch)
+			return newCommittee
+		}
+		index := i
+		consensus.OnHandOff = func(block *types.Block, publicKeys []*bls.PublicKey, newLeader p2p.Peer) {
+			handedOff[index] = true
+			assert.Equal(test, uint64(4), block.NumberU64())
+			assert.Equal(test, newCommittee, publicKeys)
+			assert.True(test, newLeader.PubKey.IsEqual(newLeaderKey))
+		}
+		consensusValidators[i] = consensus
+	}
+
+	// Only the last block of an epoch hands off the committee, which signs the epoch block
+	consensusValidators[0].handOff(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)}))
+	assert.False(test, handedOff[0])
+	assert.Equal(test, 4, len(consensusValidators[0].PublicKeys))
+
+	for i, consensus := range consensusValidators {
+		consensus.viewID = 2
+		consensus.handOff(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(4)}))
+		consensus.stopTimer()
+
+		assert.True(test, handedOff[i])
+		assert.Equal(test, newCommittee, consensus.PublicKeys)
+		assert.True(test, consensus.leader.PubKey.IsEqual(newLeaderKey), "every member should pick the same leader")
+		assert.Equal(test, consensus.pubKey.IsEqual(newLeaderKey), consensus.IsLeader)
+		assert.Equal(test, uint32(0), consensus.viewID)
+
+		_, ok := consensus.validators.Load(getPeerKey(leader.PubKey))
+		assert.False(test, ok, "the old leader left the committee")
+		_, ok = consensus.validators.Load(getPeerKey(validators[2].PubKey))
+		assert.False(test, ok, "the last validator left the committee")
+		_, ok = consensus.validators.Load(getPeerKey(joiningKey))
+		assert.Equal(test, !joiningKey.IsEqual(newLeaderKey), ok, "the joining node is a validator unless it leads")
+	}
+
+	assert.False(test, consensusValidators[0].leftCommittee)
+	assert.NotNil(test, consensusValidators[0].signVote(consensus_proto.MessageType_PREPARE, []byte("block hash")))
+	assert.True(test, consensusValidators[2].leftCommittee)
+	assert.Nil(test, consensusValidators[2].signVote(consensus_proto.MessageType_PREPARE, []byte("block hash")), "a node which left the committee must not sign")
+}
+
+func TestHandOffInserted(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7066"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7077", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(validator).AnyTimes()
+	consensus := New(m, "0", []p2p.Peer{validator}, leader)
+	epochs := []uint64{}
+	consensus.ShardCommittee = func(epoch uint64) []*bls.PublicKey {
+		epochs = append(epochs, epoch)
+		return []*bls.PublicKey{leader.PubKey, validator.PubKey}
+	}
+	handOffs := 0
+	consensus.OnHandOff = func(block *types.Block, publicKeys []*bls.PublicKey, newLeader p2p.Peer) {
+		handOffs++
+	}
+	lastBlock := func(number int64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+	}
+
+	// The sync inserted the last blocks 4 and 9 of the epochs, and went on to block 11
+	consensus.ChainReader = &headerChain{headers: map[uint64]*types.Header{
+		4:  lastBlock(4).Header(),
+		9:  lastBlock(9).Header(),
+		11: {Number: big.NewInt(11)},
+	}}
+	consensus.handOffInserted(lastBlock(4))
+	assert.Equal(test, 0, handOffs, "the committee of a past epoch is over")
+
+	consensus.handOffInserted(lastBlock(9))
+	consensus.stopTimer()
+	assert.Equal(test, 1, handOffs)
+	assert.Equal(test, []uint64{2}, epochs)
+
+	// The consensus already handed off at the last block it committed
+	consensus.handOffInserted(lastBlock(9))
+	assert.Equal(test, 1, handOffs)
+
+	// A chain recording no committees keeps the committee, and the handoff is still signaled
+	consensus.ShardCommittee = func(epoch uint64) []*bls.PublicKey { return nil }
+	consensus.ChainReader.(*headerChain).headers[14] = lastBlock(14).Header()
+	consensus.handOffInserted(lastBlock(14))
+	assert.Equal(test, 2, handOffs)
+	assert.Equal(test, 2, len(consensus.PublicKeys))
+}
@@ -2,30 +2,31 @@ package consensus
 
 import (
 	"encoding/hex"
-	"strconv"
+	"errors"
 	"time"
 
-	"github.com/harmony-one/harmony/core"
-
 	"github.com/ethereum/go-ethereum/rlp"
 	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	consensus_proto "github.com/harmony-one/harmony/api/consensus"
 	"github.com/harmony-one/harmony/api/service/explorer"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/profiler"
 	"github.com/harmony-one/harmony/internal/utils"
-	"github.com/harmony-one/harmony/p2p"
-	"github.com/harmony-one/harmony/p2p/host"
 )
 
 const (
 	waitForEnoughValidators = 1000
+	// Time the leader waits for drand before it asks for the randomness again
+	randomnessTimeout = 30 * time.Second
 )
 
+// Errors of waiting for the randomness of a block.
 var (
-	startTime time.Time
+	errRandomnessStopped = errors.New("stopped waiting for the randomness")
+	errNotLeader         = errors.New("not the leader anymore")
 )
 
 // WaitForNewBlock waits for the next new block to run consensus on
@@ -50,29 +51,31 @@ func (consensus *Consensus) WaitForNewBlock(blockChannel chan *types.Block, stop
 					time.Sleep(waitForEnoughValidators * time.Millisecond)
 				}
 
-				if core.IsEpochBlock(newBlock) {
+				var err error
+				if consensus.ChainParams.IsEpochBlock(newBlock.NumberU64()) {
 					// Receive pRnd from DRG protocol
+					// Validators don't sign an epoch block without a valid randomness
 					utils.GetLogInstance().Debug("[DRG] Waiting for pRnd")
-					pRndAndBitmap := <-consensus.PRndChannel
-					utils.GetLogInstance().Debug("[DRG] GOT pRnd", "pRnd", pRndAndBitmap)
-					pRnd := pRndAndBitmap[:32]
-					bitmap := pRndAndBitmap[32:]
-					vrfBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.PubKey)
-					vrfBitmap.SetMask(bitmap)
-
-					// TODO: check validity of pRnd
-					_ = pRnd
+					err = consensus.receiveRandomness(newBlock, consensus.PRndChannel, consensus.addRandomness, stopChan)
+				}
+				if isRandBlock(consensus.ChainParams, newBlock.NumberU64()) {
+					// Receive the final randomness of the epoch from the VDF over pRnd
+					// Validators don't sign the last block of an epoch without a valid randomness
+					utils.GetLogInstance().Debug("[DRG] Waiting for rand")
+					err = consensus.receiveRandomness(newBlock, consensus.RndChannel, consensus.addVdf, stopChan)
+				}
+				if err == errRandomnessStopped {
+					return
+				}
+				if err != nil {
+					utils.GetLogInstance().Warn("[DRG] Dropping the new block", "blockNum", newBlock.NumberU64(), "error", err)
+					continue
 				}
-				startTime = time.Now()
-				utils.GetLogInstance().Debug("STARTING CONSENSUS", "numTxs", len(newBlock.Transactions()), "consensus", consensus, "startTime", startTime, "publicKeys", len(consensus.PublicKeys))
-				for { // Wait until last consensus is finished
-					if consensus.state == Finished {
-						consensus.ResetState()
-						consensus.startConsensus(newBlock)
-						break
-					}
-					time.Sleep(500 * time.Millisecond)
+				if consensus.ChainParams.IsEpochLastBlock(newBlock.NumberU64()) && consensus.AddNewShardState != nil {
+					// The new shard state is resharded with the final randomness of the epoch
+					consensus.AddNewShardState(newBlock)
 				}
+				consensus.ProposeBlock(newBlock)
 			case <-stopChan:
 				return
 			}
@@ -80,6 +83,61 @@ func (consensus *Consensus) WaitForNewBlock(blockChannel chan *types.Block, stop
 	}()
 }
 
+// receiveRandomness receives the randomness from drand until a valid one is added
+// into the block. Drand is asked to run again for every invalid randomness, e.g. a
+// stale one or one from another committee, and whenever none comes in time, e.g.
+// because the leader moved during the protocol. It gives up once this node is no
+// longer the leader, or the stop channel is signaled.
+func (consensus *Consensus) receiveRandomness(block *types.Block, channel chan []byte, add func(*types.Block, []byte) error, stopChan chan struct{}) error {
+	for {
+		timeout := make(chan struct{})
+		stopTimer := consensus.clock.AfterFunc(randomnessTimeout, func() { close(timeout) })
+		select {
+		case randomness := <-channel:
+			stopTimer()
+			err := add(block, randomness)
+			if err == nil {
+				utils.GetLogInstance().Debug("[DRG] GOT randomness", "blockNum", block.NumberU64())
+				return nil
+			}
+			utils.GetLogInstance().Warn("[DRG] Invalid randomness, waiting for a new one", "blockNum", block.NumberU64(), "error", err)
+		case <-timeout:
+			consensus.mutex.Lock()
+			isLeader := consensus.IsLeader
+			consensus.mutex.Unlock()
+			if !isLeader {
+				return errNotLeader
+			}
+			utils.GetLogInstance().Warn("[DRG] No randomness in time, asking for it again", "blockNum", block.NumberU64())
+		case <-stopChan:
+			stopTimer()
+			return errRandomnessStopped
+		}
+		if consensus.OnInvalidRandomness != nil {
+			consensus.OnInvalidRandomness(block.NumberU64())
+		}
+	}
+}
+
+// ProposeBlock starts the consensus on the new block. The ready signal is only sent
+// once the last round is finished or collecting its commits, so the new round can
+// start right away, while the commits of the last block are still being collected.
+func (consensus *Consensus) ProposeBlock(newBlock *types.Block) {
+	utils.GetLogInstance().Debug("STARTING CONSENSUS", "numTxs", len(newBlock.Transactions()), "consensus", consensus, "publicKeys", len(consensus.PublicKeys))
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+	if !consensus.extendsChain(newBlock) {
+		utils.GetLogInstance().Warn("The new block doesn't extend the chain, dropping it", "blockNum", newBlock.NumberU64(), "consensus", consensus)
+		return
+	}
+	if newBlock.NumberU64() != blockNumberOf(consensus.consensusID) {
+		utils.GetLogInstance().Warn("The new block is not the one of the round, dropping it", "blockNum", newBlock.NumberU64(), "consensusID", consensus.consensusID)
+		return
+	}
+	consensus.ResetState()
+	consensus.startConsensus(newBlock)
+}
+
 // ProcessMessageLeader dispatches consensus message for the leader.
 func (consensus *Consensus) ProcessMessageLeader(payload []byte) {
 	message := consensus_proto.Message{}
@@ -89,11 +147,23 @@ func (consensus *Consensus) ProcessMessageLeader(payload []byte) {
 		utils.GetLogInstance().Error("Failed to unmarshal message payload.", "err", err, "consensus", consensus)
 	}
 
+	consensus.dispatchLeaderMessage(message)
+	consensus.replayFutureMessages(consensus.dispatchLeaderMessage)
+}
+
+// dispatchLeaderMessage calls the leader's handler of the message type.
+func (consensus *Consensus) dispatchLeaderMessage(message consensus_proto.Message) {
 	switch message.Type {
 	case consensus_proto.MessageType_PREPARE:
 		consensus.processPrepareMessage(message)
 	case consensus_proto.MessageType_COMMIT:
 		consensus.processCommitMessage(message)
+	case consensus_proto.MessageType_VIEWCHANGE:
+		consensus.processViewChangeMessage(message)
+	case consensus_proto.MessageType_NEWVIEW:
+		consensus.processNewViewMessage(message)
+	case consensus_proto.MessageType_EVIDENCE:
+		consensus.processEvidenceMessage(message)
 	default:
 		utils.GetLogInstance().Error("Unexpected message type", "msgType", message.Type, "consensus", consensus)
 	}
@@ -101,9 +171,14 @@ func (consensus *Consensus) ProcessMessageLeader(payload []byte) {
 
 // startConsensus starts a new consensus for a block by broadcast a announce message to the validators
 func (consensus *Consensus) startConsensus(newBlock *types.Block) {
+	if !consensus.IsLeader {
+		utils.GetLogInstance().Warn("Not the leader of the current view, dropping the new block", "consensus", consensus)
+		return
+	}
+
 	// Copy over block hash and block header data
 	blockHash := newBlock.Hash()
-	copy(consensus.blockHash[:], blockHash[:])
+	copy(consensus.round.blockHash[:], blockHash[:])
 
 	utils.GetLogInstance().Debug("Start encoding block")
 	// prepare message and broadcast to validators
@@ -112,52 +187,68 @@ func (consensus *Consensus) startConsensus(newBlock *types.Block) {
 		utils.GetLogInstance().Debug("Failed encoding block")
 		return
 	}
-	consensus.block = encodedBlock
+	consensus.round.block = encodedBlock
+	consensus.round.startTime = consensus.clock.Now()
 	utils.GetLogInstance().Debug("Stop encoding block")
 
+	// Leader sign the block hash itself
+	sign := consensus.signVote(consensus_proto.MessageType_PREPARE, consensus.round.blockHash[:])
+	if sign == nil {
+		return
+	}
+	consensus.round.prepareSigs[getPeerKey(consensus.pubKey)] = sign
+
 	msgToSend := consensus.constructAnnounceMessage()
 
 	// Set state to AnnounceDone
-	consensus.state = AnnounceDone
-
-	// Leader sign the block hash itself
-	consensus.prepareSigs[consensus.nodeID] = consensus.priKey.SignHash(consensus.blockHash[:])
+	consensus.enterState(AnnounceDone)
+	consensus.Tracer.start(consensus.consensusID, consensus.viewID, consensus.round.blockHash[:], true, EventAnnounceSent)
 
+	if consensus.splitAnnounce(newBlock, msgToSend) {
+		return
+	}
 	if utils.UseLibP2P {
 		// Construct broadcast p2p message
-		consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(17), msgToSend))
+		consensus.SendMessageToGroup(msgToSend)
 	} else {
-		host.BroadcastMessageFromLeader(consensus.host, consensus.GetValidatorPeers(), msgToSend, consensus.OfflinePeers)
+		consensus.broadcastMessageFromLeader(msgToSend)
 	}
 }
 
 // processPrepareMessage processes the prepare message sent from validators
 func (consensus *Consensus) processPrepareMessage(message consensus_proto.Message) {
-	validatorID := message.SenderId
+	validatorKey := hex.EncodeToString(message.SenderPubkey)
 	prepareSig := message.Payload
 
-	prepareSigs := consensus.prepareSigs
-	prepareBitmap := consensus.prepareBitmap
-
 	consensus.mutex.Lock()
 	defer consensus.mutex.Unlock()
 
-	validatorPeer := consensus.getValidatorPeerByID(validatorID)
+	validatorPeer := consensus.getValidatorPeerByPubKey(message.SenderPubkey)
+	if validatorPeer == nil {
+		return
+	}
 
 	if err := consensus.checkConsensusMessage(message, validatorPeer.PubKey); err != nil {
-		utils.GetLogInstance().Debug("Failed to check the validator message", "validatorID", validatorID)
+		if err == consensus_engine.ErrConsensusIDNotMatch && consensus.bufferFutureMessage(message) {
+			return
+		}
+		utils.GetLogInstance().Debug("Failed to check the validator message", "validatorKey", validatorKey)
 		return
 	}
 
+	round := consensus.roundOf(message.ConsensusId)
+	prepareSigs := round.prepareSigs
+	prepareBitmap := round.prepareBitmap
+
 	// proceed only when the message is not received before
-	_, ok := prepareSigs[validatorID]
+	_, ok := prepareSigs[validatorKey]
 	if ok {
-		utils.GetLogInstance().Debug("Already received prepare message from the validator", "validatorID", validatorID)
+		utils.GetLogInstance().Debug("Already received prepare message from the validator", "validatorKey", validatorKey)
 		return
 	}
 
-	if len(prepareSigs) >= ((len(consensus.PublicKeys)*2)/3 + 1) {
-		utils.GetLogInstance().Debug("Received additional prepare message", "validatorID", validatorID)
+	if consensus.QuorumPolicy.Check(prepareBitmap) {
+		utils.GetLogInstance().Debug("Received additional prepare message", "validatorKey", validatorKey)
 		return
 	}
 
@@ -165,69 +256,84 @@ func (consensus *Consensus) processPrepareMessage(message consensus_proto.Messag
 	var sign bls.Sign
 	err := sign.Deserialize(prepareSig)
 	if err != nil {
-		utils.GetLogInstance().Error("Failed to deserialize bls signature", "validatorID", validatorID)
+		utils.GetLogInstance().Error("Failed to deserialize bls signature", "validatorKey", validatorKey)
 		return
 	}
 
-	if !sign.VerifyHash(validatorPeer.PubKey, consensus.blockHash[:]) {
-		utils.GetLogInstance().Error("Received invalid BLS signature", "validatorID", validatorID)
+	if !sign.VerifyHash(validatorPeer.PubKey, round.blockHash[:]) {
+		utils.GetLogInstance().Error("Received invalid BLS signature", "validatorKey", validatorKey)
 		return
 	}
 
-	utils.GetLogInstance().Debug("Received new prepare signature", "numReceivedSoFar", len(prepareSigs), "validatorID", validatorID, "PublicKeys", len(consensus.PublicKeys))
-	prepareSigs[validatorID] = &sign
+	utils.GetLogInstance().Debug("Received new prepare signature", "numReceivedSoFar", len(prepareSigs), "validatorKey", validatorKey, "PublicKeys", len(consensus.PublicKeys))
+	consensus.writeVote(message.ConsensusId, consensus_proto.MessageType_PREPARE, validatorPeer.PubKey, round.blockHash[:], &sign)
+	prepareSigs[validatorKey] = &sign
 	prepareBitmap.SetKey(validatorPeer.PubKey, true) // Set the bitmap indicating that this validator signed.
+	consensus.Tracer.record(message.ConsensusId, EventPrepareReceived, validatorKey)
 
 	targetState := PreparedDone
-	if len(prepareSigs) >= ((len(consensus.PublicKeys)*2)/3+1) && consensus.state < targetState {
-		utils.GetLogInstance().Debug("Enough prepares received with signatures", "num", len(prepareSigs), "state", consensus.state)
+	if consensus.QuorumPolicy.Check(prepareBitmap) && round.state < targetState {
+		utils.GetLogInstance().Debug("Enough prepares received with signatures", "num", len(prepareSigs), "state", round.state)
+		consensus.Tracer.record(message.ConsensusId, EventPrepareQuorum, "")
 
 		// Construct and broadcast prepared message
-		msgToSend, aggSig := consensus.constructPreparedMessage()
-		consensus.aggregatedPrepareSig = aggSig
+		msgToSend, aggSig := consensus.constructPreparedMessage(message.ConsensusId)
+		round.aggregatedPrepareSig = aggSig
+
+		// Set state to targetState
+		consensus.enterState(targetState)
 
 		if utils.UseLibP2P {
-			consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(17), msgToSend))
+			consensus.SendMessageToGroup(msgToSend)
 		} else {
-			host.BroadcastMessageFromLeader(consensus.host, consensus.GetValidatorPeers(), msgToSend, consensus.OfflinePeers)
+			consensus.broadcastMessageFromLeader(msgToSend)
 		}
 
-		// Set state to targetState
-		consensus.state = targetState
-
 		// Leader sign the multi-sig and bitmap (for commit phase)
 		multiSigAndBitmap := append(aggSig.Serialize(), prepareBitmap.Bitmap...)
-		consensus.commitSigs[consensus.nodeID] = consensus.priKey.SignHash(multiSigAndBitmap)
+		if sign := consensus.signVote(consensus_proto.MessageType_COMMIT, multiSigAndBitmap); sign != nil {
+			round.commitSigs[getPeerKey(consensus.pubKey)] = sign
+		}
+
+		// Announce the next block while the commits of this one are collected
+		consensus.startNextRound()
 	}
 }
 
 // Processes the commit message sent from validators
 func (consensus *Consensus) processCommitMessage(message consensus_proto.Message) {
-	validatorID := message.SenderId
+	validatorKey := hex.EncodeToString(message.SenderPubkey)
 	commitSig := message.Payload
 
 	consensus.mutex.Lock()
 	defer consensus.mutex.Unlock()
 
-	validatorPeer := consensus.getValidatorPeerByID(validatorID)
+	validatorPeer := consensus.getValidatorPeerByPubKey(message.SenderPubkey)
+	if validatorPeer == nil {
+		return
+	}
 
 	if err := consensus.checkConsensusMessage(message, validatorPeer.PubKey); err != nil {
-		utils.GetLogInstance().Debug("Failed to check the validator message", "validatorID", validatorID)
+		if err == consensus_engine.ErrConsensusIDNotMatch && consensus.bufferFutureMessage(message) {
+			return
+		}
+		utils.GetLogInstance().Debug("Failed to check the validator message", "validatorKey", validatorKey)
 		return
 	}
 
-	commitSigs := consensus.commitSigs
-	commitBitmap := consensus.commitBitmap
+	round := consensus.roundOf(message.ConsensusId)
+	commitSigs := round.commitSigs
+	commitBitmap := round.commitBitmap
 
 	// proceed only when the message is not received before
-	_, ok := commitSigs[validatorID]
+	_, ok := commitSigs[validatorKey]
 	if ok {
-		utils.GetLogInstance().Debug("Already received commit message from the validator", "validatorID", validatorID)
+		utils.GetLogInstance().Debug("Already received commit message from the validator", "validatorKey", validatorKey)
 		return
 	}
 
-	if len((commitSigs)) >= ((len(consensus.PublicKeys)*2)/3 + 1) {
-		utils.GetLogInstance().Debug("Received additional new commit message", "validatorID", strconv.Itoa(int(validatorID)))
+	if consensus.QuorumPolicy.Check(commitBitmap) {
+		utils.GetLogInstance().Debug("Received additional new commit message", "validatorKey", validatorKey)
 		return
 	}
 
@@ -235,47 +341,50 @@ func (consensus *Consensus) processCommitMessage(message consensus_proto.Message
 	var sign bls.Sign
 	err := sign.Deserialize(commitSig)
 	if err != nil {
-		utils.GetLogInstance().Debug("Failed to deserialize bls signature", "validatorID", validatorID)
+		utils.GetLogInstance().Debug("Failed to deserialize bls signature", "validatorKey", validatorKey)
 		return
 	}
-	aggSig := bls_cosi.AggregateSig(consensus.GetPrepareSigsArray())
-	if !sign.VerifyHash(validatorPeer.PubKey, append(aggSig.Serialize(), consensus.prepareBitmap.Bitmap...)) {
-		utils.GetLogInstance().Error("Received invalid BLS signature", "validatorID", validatorID)
+	aggSig := bls_cosi.AggregateSig(round.getPrepareSigsArray())
+	prepareMultiSigAndBitmap := append(aggSig.Serialize(), round.prepareBitmap.Bitmap...)
+	if !sign.VerifyHash(validatorPeer.PubKey, prepareMultiSigAndBitmap) {
+		utils.GetLogInstance().Error("Received invalid BLS signature", "validatorKey", validatorKey)
 		return
 	}
 
-	utils.GetLogInstance().Debug("Received new commit message", "numReceivedSoFar", len(commitSigs), "validatorID", strconv.Itoa(int(validatorID)))
-	commitSigs[validatorID] = &sign
+	utils.GetLogInstance().Debug("Received new commit message", "numReceivedSoFar", len(commitSigs), "validatorKey", validatorKey)
+	consensus.writeVote(message.ConsensusId, consensus_proto.MessageType_COMMIT, validatorPeer.PubKey, prepareMultiSigAndBitmap, &sign)
+	commitSigs[validatorKey] = &sign
 	// Set the bitmap indicating that this validator signed.
 	commitBitmap.SetKey(validatorPeer.PubKey, true)
+	consensus.Tracer.record(message.ConsensusId, EventCommitReceived, validatorKey)
 
 	targetState := CommittedDone
-	if len(commitSigs) >= ((len(consensus.PublicKeys)*2)/3+1) && consensus.state != targetState {
-		utils.GetLogInstance().Info("Enough commits received!", "num", len(commitSigs), "state", consensus.state)
+	if consensus.QuorumPolicy.Check(commitBitmap) && round.state != targetState {
+		utils.GetLogInstance().Info("Enough commits received!", "num", len(commitSigs), "state", round.state)
+		consensus.Tracer.record(message.ConsensusId, EventCommitQuorum, "")
 
 		// Construct and broadcast committed message
-		msgToSend, aggSig := consensus.constructCommittedMessage()
-		consensus.aggregatedCommitSig = aggSig
+		msgToSend, aggSig := consensus.constructCommittedMessage(message.ConsensusId)
+		round.aggregatedCommitSig = aggSig
 
 		if utils.UseLibP2P {
-			consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(17), msgToSend))
+			consensus.SendMessageToGroup(msgToSend)
 		} else {
-			host.BroadcastMessageFromLeader(consensus.host, consensus.GetValidatorPeers(), msgToSend, consensus.OfflinePeers)
+			consensus.broadcastMessageFromLeader(msgToSend)
 		}
 
 		var blockObj types.Block
-		err := rlp.DecodeBytes(consensus.block, &blockObj)
+		err := rlp.DecodeBytes(round.block, &blockObj)
 		if err != nil {
 			utils.GetLogInstance().Debug("failed to construct the new block after consensus")
 		}
 
 		// Sign the block
-		copy(blockObj.Header().PrepareSignature[:], consensus.aggregatedPrepareSig.Serialize()[:])
-		copy(blockObj.Header().PrepareBitmap[:], consensus.prepareBitmap.Bitmap)
-		copy(blockObj.Header().CommitSignature[:], consensus.aggregatedCommitSig.Serialize()[:])
-		copy(blockObj.Header().CommitBitmap[:], consensus.commitBitmap.Bitmap)
+		blockObj.SetPrepareSig(round.aggregatedPrepareSig.Serialize(), round.prepareBitmap.Bitmap)
+		blockObj.SetCommitSig(round.aggregatedCommitSig.Serialize(), round.commitBitmap.Bitmap)
 
-		consensus.state = targetState
+		round.state = targetState
+		consensus.Tracer.finish(message.ConsensusId, EventBlockCommitted)
 
 		select {
 		case consensus.VerifiedNewBlock <- &blockObj:
@@ -283,27 +392,36 @@ func (consensus *Consensus) processCommitMessage(message consensus_proto.Message
 			utils.GetLogInstance().Info("[SYNC] consensus verified block send to chan failed", "blockHash", blockObj.Hash())
 		}
 
-		consensus.reportMetrics(blockObj)
+		consensus.reportMetrics(blockObj, round.startTime)
 
 		// Dump new block into level db.
-		explorer.GetStorageInstance(consensus.leader.IP, consensus.leader.Port, true).Dump(&blockObj, consensus.consensusID)
+		explorer.GetStorageInstance(consensus.leader.IP, consensus.leader.Port, true).Dump(&blockObj, message.ConsensusId)
 
-		// Reset state to Finished, and clear other data.
-		consensus.ResetState()
-		consensus.consensusID++
+		// The next round already started if the block was pipelined.
+		pipelined := round != consensus.round
+		if pipelined {
+			delete(consensus.pendingRounds, message.ConsensusId)
+		} else {
+			// Reset state to Finished, and clear other data.
+			consensus.ResetState()
+			consensus.consensusID++
+		}
+		consensus.pruneWAL()
+		consensus.evidencePool.markIncluded(blockObj.Evidences(), blockObj.NumberU64())
 
 		consensus.OnConsensusDone(&blockObj)
-		utils.GetLogInstance().Debug("HOORAY!!! CONSENSUS REACHED!!!", "consensusID", consensus.consensusID, "numOfSignatures", len(commitSigs))
+		utils.GetLogInstance().Debug("HOORAY!!! CONSENSUS REACHED!!!", "consensusID", message.ConsensusId, "numOfSignatures", len(commitSigs))
+		consensus.handOff(&blockObj)
 
-		// TODO: remove this temporary delay
-		time.Sleep(500 * time.Millisecond)
-		// Send signal to Node so the new block can be added and new round of consensus can be triggered
-		consensus.ReadySignal <- struct{}{}
+		if !pipelined && consensus.IsLeader {
+			// Send signal to Node so the new block can be added and new round of consensus can be triggered
+			consensus.ReadySignal <- struct{}{}
+		}
 	}
 }
 
-func (consensus *Consensus) reportMetrics(block types.Block) {
-	endTime := time.Now()
+func (consensus *Consensus) reportMetrics(block types.Block, startTime time.Time) {
+	endTime := consensus.clock.Now()
 	timeElapsed := endTime.Sub(startTime)
 	numOfTxs := len(block.Transactions())
 	tps := float64(numOfTxs) / timeElapsed.Seconds()
@@ -326,12 +444,13 @@ func (consensus *Consensus) reportMetrics(block types.Block) {
 		txHash := block.Transactions()[end-1-i].Hash()
 		txHashes = append(txHashes, hex.EncodeToString(txHash[:]))
 	}
+	blockHash := block.Hash()
 	metrics := map[string]interface{}{
 		"key":             hex.EncodeToString(consensus.pubKey.Serialize()),
 		"tps":             tps,
 		"txCount":         numOfTxs,
 		"nodeCount":       len(consensus.PublicKeys) + 1,
-		"latestBlockHash": hex.EncodeToString(consensus.blockHash[:]),
+		"latestBlockHash": hex.EncodeToString(blockHash[:]),
 		"latestTxHashes":  txHashes,
 		"blockLatency":    int(timeElapsed / time.Millisecond),
 	}
@@ -18,7 +18,7 @@ func (consensus *Consensus) constructAnnounceMessage() []byte {
 	consensus.populateMessageFields(&message)
 
 	// n byte of block header
-	message.Payload = consensus.block // TODO: send only block header in the announce phase.
+	message.Payload = consensus.round.block // TODO: send only block header in the announce phase.
 
 	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
 	if err != nil {
@@ -27,22 +27,23 @@ func (consensus *Consensus) constructAnnounceMessage() []byte {
 	return proto.ConstructConsensusMessage(marshaledMessage)
 }
 
-// Construct the prepared message, returning prepared message in bytes.
-func (consensus *Consensus) constructPreparedMessage() ([]byte, *bls.Sign) {
+// Construct the prepared message of the given round, returning prepared message in bytes.
+func (consensus *Consensus) constructPreparedMessage(consensusID uint32) ([]byte, *bls.Sign) {
 	message := consensus_proto.Message{}
 	message.Type = consensus_proto.MessageType_PREPARED
 
-	consensus.populateMessageFields(&message)
+	round := consensus.roundOf(consensusID)
+	consensus.populateRoundMessageFields(&message, consensusID, round)
 
 	//// Payload
 	buffer := bytes.NewBuffer([]byte{})
 
 	// 48 bytes aggregated signature
-	aggSig := bls_cosi.AggregateSig(consensus.GetPrepareSigsArray())
+	aggSig := bls_cosi.AggregateSig(round.getPrepareSigsArray())
 	buffer.Write(aggSig.Serialize())
 
 	// Bitmap
-	buffer.Write(consensus.prepareBitmap.Bitmap)
+	buffer.Write(round.prepareBitmap.Bitmap)
 
 	message.Payload = buffer.Bytes()
 	//// END Payload
@@ -54,22 +55,23 @@ func (consensus *Consensus) constructPreparedMessage() ([]byte, *bls.Sign) {
 	return proto.ConstructConsensusMessage(marshaledMessage), aggSig
 }
 
-// Construct the committed message, returning committed message in bytes.
-func (consensus *Consensus) constructCommittedMessage() ([]byte, *bls.Sign) {
+// Construct the committed message of the given round, returning committed message in bytes.
+func (consensus *Consensus) constructCommittedMessage(consensusID uint32) ([]byte, *bls.Sign) {
 	message := consensus_proto.Message{}
 	message.Type = consensus_proto.MessageType_COMMITTED
 
-	consensus.populateMessageFields(&message)
+	round := consensus.roundOf(consensusID)
+	consensus.populateRoundMessageFields(&message, consensusID, round)
 
 	//// Payload
 	buffer := bytes.NewBuffer([]byte{})
 
 	// 48 bytes aggregated signature
-	aggSig := bls_cosi.AggregateSig(consensus.GetCommitSigsArray())
+	aggSig := bls_cosi.AggregateSig(round.getCommitSigsArray())
 	buffer.Write(aggSig.Serialize())
 
 	// Bitmap
-	buffer.Write(consensus.commitBitmap.Bitmap)
+	buffer.Write(round.commitBitmap.Bitmap)
 
 	message.Payload = buffer.Bytes()
 	//// END Payload
@@ -19,7 +19,7 @@ func TestConstructAnnounceMessage(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
-	consensus.blockHash = [32]byte{}
+	consensus.round.blockHash = [32]byte{}
 	msg := consensus.constructAnnounceMessage()
 
 	if len(msg) != 93 {
@@ -40,15 +40,15 @@ func TestConstructPreparedMessage(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
-	consensus.blockHash = [32]byte{}
+	consensus.round.blockHash = [32]byte{}
 
 	message := "test string"
-	consensus.prepareSigs[0] = leaderPriKey.Sign(message)
-	consensus.prepareSigs[1] = validatorPriKey.Sign(message)
-	consensus.prepareBitmap.SetKey(leaderPubKey, true)
-	consensus.prepareBitmap.SetKey(validatorPubKey, true)
+	consensus.round.prepareSigs[getPeerKey(leaderPubKey)] = leaderPriKey.Sign(message)
+	consensus.round.prepareSigs[getPeerKey(validatorPubKey)] = validatorPriKey.Sign(message)
+	consensus.round.prepareBitmap.SetKey(leaderPubKey, true)
+	consensus.round.prepareBitmap.SetKey(validatorPubKey, true)
 
-	msg, _ := consensus.constructPreparedMessage()
+	msg, _ := consensus.constructPreparedMessage(consensus.consensusID)
 
 	if len(msg) != 144 {
 		test.Errorf("Challenge message is not constructed in the correct size: %d", len(msg))
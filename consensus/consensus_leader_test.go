@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"fmt"
+	"math/big"
 
 	"testing"
 	"time"
@@ -51,7 +52,7 @@ func TestProcessMessageLeaderPrepare(test *testing.T) {
 	m.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(3)
 
 	consensusLeader := New(m, "0", validators, leader)
-	consensusLeader.blockHash = blockHash
+	consensusLeader.round.blockHash = blockHash
 
 	consensusValidators := make([]*Consensus, 3)
 	for i := 0; i < 3; i++ {
@@ -63,12 +64,12 @@ func TestProcessMessageLeaderPrepare(test *testing.T) {
 		hosts[i] = host
 
 		consensusValidators[i] = New(hosts[i], "0", validators, leader)
-		consensusValidators[i].blockHash = blockHash
+		consensusValidators[i].round.blockHash = blockHash
 		msg := consensusValidators[i].constructPrepareMessage()
 		consensusLeader.ProcessMessageLeader(msg[1:])
 	}
 
-	assert.Equal(test, PreparedDone, consensusLeader.state)
+	assert.Equal(test, PreparedDone, consensusLeader.round.state)
 
 	time.Sleep(1 * time.Second)
 }
@@ -96,7 +97,7 @@ func TestProcessMessageLeaderPrepareInvalidSignature(test *testing.T) {
 	m.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(0)
 
 	consensusLeader := New(m, "0", validators, leader)
-	consensusLeader.blockHash = blockHash
+	consensusLeader.round.blockHash = blockHash
 
 	consensusValidators := make([]*Consensus, 3)
 	for i := 0; i < 3; i++ {
@@ -108,7 +109,7 @@ func TestProcessMessageLeaderPrepareInvalidSignature(test *testing.T) {
 		hosts[i] = host
 
 		consensusValidators[i] = New(hosts[i], "0", validators, leader)
-		consensusValidators[i].blockHash = blockHash
+		consensusValidators[i].round.blockHash = blockHash
 		msg := consensusValidators[i].constructPrepareMessage()
 
 		message := consensus_proto.Message{}
@@ -119,7 +120,7 @@ func TestProcessMessageLeaderPrepareInvalidSignature(test *testing.T) {
 		consensusLeader.ProcessMessageLeader(msg[1:])
 	}
 
-	assert.Equal(test, Finished, consensusLeader.state)
+	assert.Equal(test, Finished, consensusLeader.round.state)
 
 	time.Sleep(1 * time.Second)
 }
@@ -156,15 +157,15 @@ func TestProcessMessageLeaderCommit(test *testing.T) {
 	}
 
 	consensusLeader := New(m, "0", validators, leader)
-	consensusLeader.state = PreparedDone
-	consensusLeader.blockHash = blockHash
+	consensusLeader.round.state = PreparedDone
+	consensusLeader.round.blockHash = blockHash
 	consensusLeader.OnConsensusDone = func(newBlock *types.Block) {}
-	consensusLeader.block, _ = rlp.EncodeToBytes(types.NewBlock(&types.Header{}, nil, nil))
-	consensusLeader.prepareSigs[consensusLeader.nodeID] = consensusLeader.priKey.SignHash(consensusLeader.blockHash[:])
+	consensusLeader.round.block, _ = rlp.EncodeToBytes(types.NewBlock(&types.Header{}, nil, nil))
+	consensusLeader.round.prepareSigs[getPeerKey(consensusLeader.pubKey)] = consensusLeader.priKey.SignHash(consensusLeader.round.blockHash[:])
 
 	aggSig := bls_cosi.AggregateSig(consensusLeader.GetPrepareSigsArray())
-	multiSigAndBitmap := append(aggSig.Serialize(), consensusLeader.prepareBitmap.Bitmap...)
-	consensusLeader.aggregatedPrepareSig = aggSig
+	multiSigAndBitmap := append(aggSig.Serialize(), consensusLeader.round.prepareBitmap.Bitmap...)
+	consensusLeader.round.aggregatedPrepareSig = aggSig
 
 	consensusValidators := make([]*Consensus, 3)
 
@@ -174,12 +175,107 @@ func TestProcessMessageLeaderCommit(test *testing.T) {
 	}()
 	for i := 0; i < 3; i++ {
 		consensusValidators[i] = New(hosts[i], "0", validators, leader)
-		consensusValidators[i].blockHash = blockHash
+		consensusValidators[i].round.blockHash = blockHash
 		msg := consensusValidators[i].constructCommitMessage(multiSigAndBitmap)
 		consensusLeader.ProcessMessageLeader(msg[1:])
 	}
 
-	assert.Equal(test, Finished, consensusLeader.state)
+	assert.Equal(test, Finished, consensusLeader.round.state)
 
 	time.Sleep(1 * time.Second)
 }
+
+func TestProcessMessageLeaderPipelinesNextRound(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "8989"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+
+	validators := make([]p2p.Peer, 3)
+	hosts := make([]p2p.Host, 3)
+
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 8998+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		_, validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader)
+	// The prepared message of the first round, the announce of the second one, and then
+	// the committed message of the first round.
+	m.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Times(9)
+
+	for i := 0; i < 3; i++ {
+		priKey, _, _ := utils.GenKeyP2P(validators[i].IP, validators[i].Port)
+		host, err := p2pimpl.NewHost(&validators[i], priKey)
+		if err != nil {
+			test.Fatalf("newhost error: %v", err)
+		}
+		hosts[i] = host
+	}
+
+	consensusLeader := New(m, "0", validators, leader)
+	<-consensusLeader.ReadySignal
+	consensusLeader.round.state = AnnounceDone
+	consensusLeader.round.blockHash = blockHash
+	consensusLeader.round.block, _ = rlp.EncodeToBytes(types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil))
+	consensusLeader.round.prepareSigs[getPeerKey(consensusLeader.pubKey)] = consensusLeader.priKey.SignHash(consensusLeader.round.blockHash[:])
+
+	var preparedBlock *types.Block
+	consensusLeader.OnBlockPrepared = func(newBlock *types.Block) { preparedBlock = newBlock }
+	var committedBlock *types.Block
+	consensusLeader.OnConsensusDone = func(newBlock *types.Block) { committedBlock = newBlock }
+
+	consensusValidators := make([]*Consensus, 3)
+	for i := 0; i < 3; i++ {
+		consensusValidators[i] = New(hosts[i], "0", validators, leader)
+		consensusValidators[i].round.blockHash = blockHash
+		msg := consensusValidators[i].constructPrepareMessage()
+		consensusLeader.ProcessMessageLeader(msg[1:])
+	}
+
+	// The next round starts as soon as the block is prepared.
+	select {
+	case <-consensusLeader.ReadySignal:
+	case <-time.After(3 * time.Second):
+		test.Fatal("next round is not started")
+	}
+	assert.Equal(test, uint32(1), consensusLeader.consensusID)
+	assert.Equal(test, Finished, consensusLeader.round.state)
+	if assert.NotNil(test, preparedBlock) {
+		assert.Equal(test, uint64(1), preparedBlock.NumberU64())
+	}
+	prepared, ok := consensusLeader.pendingRounds[0]
+	if !assert.True(test, ok, "prepared round should keep collecting commits") {
+		return
+	}
+
+	// The next block, built on the prepared block, is announced before the prepared one commits.
+	consensusLeader.ChainReader = &headerChain{headers: map[uint64]*types.Header{0: {Number: big.NewInt(0)}}}
+	otherBlock := types.NewBlock(&types.Header{Number: big.NewInt(2), ParentHash: blockHash}, nil, nil)
+	consensusLeader.ProposeBlock(otherBlock)
+	assert.Equal(test, Finished, consensusLeader.round.state, "a block on another parent should be dropped")
+	nextBlock := types.NewBlock(&types.Header{Number: big.NewInt(2), ParentHash: preparedBlock.Hash()}, nil, nil)
+	consensusLeader.ProposeBlock(nextBlock)
+	assert.Equal(test, AnnounceDone, consensusLeader.round.state)
+	assert.Equal(test, [32]byte(nextBlock.Hash()), consensusLeader.round.blockHash)
+	assert.Equal(test, 1, len(consensusLeader.pendingRounds))
+	assert.Nil(test, committedBlock)
+
+	// The commits of the prepared round are still accepted.
+	multiSigAndBitmap := append(prepared.aggregatedPrepareSig.Serialize(), prepared.prepareBitmap.Bitmap...)
+	for i := 0; i < 3; i++ {
+		msg := consensusValidators[i].constructCommitMessage(multiSigAndBitmap)
+		consensusLeader.ProcessMessageLeader(msg[1:])
+	}
+
+	assert.Equal(test, 0, len(consensusLeader.pendingRounds))
+	assert.Equal(test, uint32(1), consensusLeader.consensusID)
+	assert.Equal(test, AnnounceDone, consensusLeader.round.state, "the announced round should go on")
+	if assert.NotNil(test, committedBlock) {
+		assert.Equal(test, preparedBlock.Hash(), committedBlock.Hash(), "commit signature should not change the block hash")
+		assert.Equal(test, committedBlock.Hash(), nextBlock.ParentHash())
+	}
+}
@@ -0,0 +1,107 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/crypto/pki"
+)
+
+// Default parameters of the block rewards.
+var (
+	DefaultBlockReward         = big.NewInt(params.Ether)
+	DefaultLeaderRewardPercent = uint64(10)
+)
+
+// The signers of a block are only known once the block is committed, which may
+// be after the next block is built in pipelined consensus. So the commit signature
+// is recorded into the block rewardDelay blocks later, which pays the signers.
+const rewardDelay = 2
+
+// accumulateRewards credits the block reward and the fees collected in the block
+// to the leader proposing the block, and to the signers of the last commit recorded
+// in the header, which is the one of the block rewardDelay blocks earlier. The
+// leader gets LeaderRewardPercent of the total, and the rest is split evenly among
+// the signers, so that each member is paid for every block it signs. The leader
+// gets the whole total if no signers are known, e.g. for the first blocks.
+func (consensus *Consensus) accumulateRewards(chain consensus_engine.ChainReader, state *state.DB, header *types.Header, txs []*types.Transaction, receipts []*types.Receipt) {
+	total := new(big.Int)
+	if consensus.BlockReward != nil {
+		total.Set(consensus.BlockReward)
+	}
+
+	// The fees were credited to the leader while applying the transactions,
+	// and are redistributed with the block reward.
+	fees := new(big.Int)
+	for i, receipt := range receipts {
+		if i >= len(txs) {
+			break
+		}
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), txs[i].GasPrice()))
+	}
+	leader, _ := consensus.Author(header)
+	state.SubBalance(leader, fees)
+	total.Add(total, fees)
+	if total.Sign() == 0 {
+		return
+	}
+
+	signers := consensus.rewardedSigners(chain, header)
+	leaderReward := new(big.Int).Set(total)
+	if len(signers) > 0 {
+		signersReward := new(big.Int).Mul(total, new(big.Int).SetUint64(100-consensus.LeaderRewardPercent))
+		signersReward.Div(signersReward, big.NewInt(100))
+		share := new(big.Int).Div(signersReward, big.NewInt(int64(len(signers))))
+		for _, signer := range signers {
+			state.AddBalance(signer, share)
+			leaderReward.Sub(leaderReward, share)
+		}
+	}
+	// The leader also gets the remainder of the division.
+	state.AddBalance(leader, leaderReward)
+}
+
+// rewardedSigners returns the accounts of the members who signed the last commit
+// recorded in the header. The last commit is part of the block hash, and so signed by
+// the committee with the block.
+func (consensus *Consensus) rewardedSigners(chain consensus_engine.ChainReader, header *types.Header) []common.Address {
+	if consensus.LeaderRewardPercent >= 100 || header.Number.Uint64() < rewardDelay || len(header.LastCommitBitmap) == 0 {
+		return nil
+	}
+	publicKeys := consensus.committeeKeysAt(chain, binary.BigEndian.Uint32(header.ShardID[:]), header.Number.Uint64()-rewardDelay)
+	mask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil || mask.SetMask(header.LastCommitBitmap) != nil {
+		return nil
+	}
+	signers := []common.Address{}
+	for _, key := range mask.GetPubKeyFromMask(true) {
+		signers = append(signers, common.Address(pki.GetAddressFromPublicKey(key)))
+	}
+	return signers
+}
+
+// verifyLastCommit checks whether the last commit recorded in the header is signed by a
+// quorum of the committee on the block rewardDelay blocks earlier, in the ancestors of
+// the given parent.
+func (consensus *Consensus) verifyLastCommit(chain consensus_engine.ChainReader, header, parent *types.Header) error {
+	if header.Number.Uint64() <= rewardDelay {
+		// No commit is known before the first blocks, as the genesis block is not signed
+		if header.LastCommitSignature != [48]byte{} || len(header.LastCommitBitmap) != 0 {
+			return consensus_engine.ErrInvalidCommitSignature
+		}
+		return nil
+	}
+	signed := parent
+	for i := 1; i < rewardDelay; i++ {
+		if signed = chain.GetHeader(signed.ParentHash, signed.Number.Uint64()-1); signed == nil {
+			return consensus_engine.ErrUnknownAncestor
+		}
+	}
+	return verifyCommitSig(signed, header.LastCommitSignature, header.LastCommitBitmap, consensus.CommitteeKeys(chain, signed), consensus.quorumPolicyAt(signed.Number.Uint64()))
+}
@@ -0,0 +1,158 @@
+package consensus
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/golang/mock/gomock"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/crypto/pki"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// headerChain is a chain reader serving the given headers by number.
+type headerChain struct {
+	consensus_engine.ChainReader
+	headers map[uint64]*types.Header
+}
+
+func (chain *headerChain) GetHeaderByNumber(number uint64) *types.Header {
+	return chain.headers[number]
+}
+
+func (chain *headerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := chain.headers[number]; header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (chain *headerChain) CurrentHeader() *types.Header {
+	var current *types.Header
+	for _, header := range chain.headers {
+		if current == nil || header.Number.Uint64() > current.Number.Uint64() {
+			current = header
+		}
+	}
+	return current
+}
+
+func (chain *headerChain) ReadShardState(epoch uint64) types.ShardState {
+	return nil
+}
+
+func TestAccumulateRewards(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "9902"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validators := make([]p2p.Peer, 3)
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 9903+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		_, validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader)
+	consensus := New(m, "0", validators, leader)
+	consensus.BlockReward = big.NewInt(1000000)
+	consensus.LeaderRewardPercent = 10
+
+	// Two of the validators signed the commit of block 3, recorded in block 5.
+	commitBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	commitBitmap.SetKey(validators[0].PubKey, true)
+	commitBitmap.SetKey(validators[2].PubKey, true)
+	chain := &headerChain{}
+
+	leaderAddress := common.Address(pki.GetAddressFromPublicKey(leader.PubKey))
+	header := &types.Header{Number: big.NewInt(5), Coinbase: leaderAddress, LastCommitBitmap: commitBitmap.Bitmap}
+	author, err := consensus.Author(header)
+	assert.Nil(test, err)
+	assert.Equal(test, leaderAddress, author)
+
+	// The fees are credited to the leader while applying the transaction.
+	tx := types.NewTransaction(0, common.Address{}, 0, big.NewInt(0), 21000, big.NewInt(2), nil)
+	receipt := &types.Receipt{GasUsed: 21000}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.AddBalance(leaderAddress, big.NewInt(42000))
+
+	consensus.accumulateRewards(chain, statedb, header, []*types.Transaction{tx}, []*types.Receipt{receipt})
+
+	// 1042000 in total, 90% of which is split between the two signers.
+	assert.Equal(test, big.NewInt(104200), statedb.GetBalance(leaderAddress))
+	for _, i := range []int{0, 2} {
+		signer := common.Address(pki.GetAddressFromPublicKey(validators[i].PubKey))
+		assert.Equal(test, big.NewInt(468900), statedb.GetBalance(signer))
+	}
+	absent := common.Address(pki.GetAddressFromPublicKey(validators[1].PubKey))
+	assert.Equal(test, 0, statedb.GetBalance(absent).Sign())
+
+	// No signers are known for the first blocks.
+	first := &types.Header{Number: big.NewInt(1), Coinbase: leaderAddress}
+	statedb, _ = state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	consensus.accumulateRewards(chain, statedb, first, nil, nil)
+	assert.Equal(test, big.NewInt(1000000), statedb.GetBalance(leaderAddress))
+}
+
+func TestVerifyLastCommit(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "9902"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validators := make([]p2p.Peer, 3)
+	priKeys := make([]*bls.SecretKey, 3)
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 9903+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		priKeys[i], validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader)
+	consensus := New(m, "0", validators, leader)
+
+	// Block 3 is committed by the three validators.
+	prepareBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	signed := &types.Header{Number: big.NewInt(3), PrepareBitmap: prepareBitmap.Bitmap}
+	copy(signed.PrepareSignature[:], consensus.priKey.SignHash([]byte{3}).Serialize())
+	prepareMultiSigAndBitmap := append(signed.PrepareSignature[:], signed.PrepareBitmap...)
+	commitBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	sigs := []*bls.Sign{}
+	for i := 0; i < 3; i++ {
+		commitBitmap.SetKey(validators[i].PubKey, true)
+		sigs = append(sigs, priKeys[i].SignHash(prepareMultiSigAndBitmap))
+	}
+	parent := &types.Header{Number: big.NewInt(4), ParentHash: signed.Hash()}
+	chain := &headerChain{headers: map[uint64]*types.Header{3: signed, 4: parent}}
+
+	header := &types.Header{Number: big.NewInt(5), ParentHash: parent.Hash(), LastCommitBitmap: commitBitmap.Bitmap}
+	copy(header.LastCommitSignature[:], bls_cosi.AggregateSig(sigs).Serialize())
+	assert.Nil(test, consensus.verifyLastCommit(chain, header, parent))
+
+	// Recording other signers changes the rewards, and is rejected.
+	commitBitmap.SetKey(validators[0].PubKey, false)
+	commitBitmap.SetKey(leader.PubKey, true)
+	header.LastCommitBitmap = commitBitmap.Bitmap
+	assert.Equal(test, consensus_engine.ErrInvalidCommitSignature, consensus.verifyLastCommit(chain, header, parent))
+
+	// The commit of another block is rejected.
+	chain.headers[3] = &types.Header{Number: big.NewInt(3)}
+	assert.Equal(test, consensus_engine.ErrUnknownAncestor, consensus.verifyLastCommit(chain, header, parent))
+
+	// No commit is recorded in the first blocks.
+	first := &types.Header{Number: big.NewInt(2), LastCommitBitmap: commitBitmap.Bitmap}
+	assert.NotNil(test, consensus.verifyLastCommit(chain, first, signed))
+}
@@ -0,0 +1,147 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// roundState is the state of the consensus on a single block.
+// The leader keeps the round of a prepared block while it's collecting the commits,
+// so that the next round can start before the previous one is committed.
+type roundState struct {
+	// The current state of the round
+	state State
+
+	// Signatures collected from validators.
+	prepareSigs          map[string]*bls.Sign
+	commitSigs           map[string]*bls.Sign
+	aggregatedPrepareSig *bls.Sign
+	aggregatedCommitSig  *bls.Sign
+	prepareBitmap        *bls_cosi.Mask
+	commitBitmap         *bls_cosi.Mask
+
+	// Blockhash - 32 byte
+	blockHash [32]byte
+	// Block to run consensus on
+	block []byte
+	// Time when the leader announced the block
+	startTime time.Time
+}
+
+// newRoundState creates the state of a round which hasn't started yet.
+func newRoundState(publicKeys []*bls.PublicKey, leaderKey *bls.PublicKey) *roundState {
+	prepareBitmap, _ := bls_cosi.NewMask(publicKeys, leaderKey)
+	commitBitmap, _ := bls_cosi.NewMask(publicKeys, leaderKey)
+	return &roundState{
+		state:         Finished,
+		prepareSigs:   map[string]*bls.Sign{},
+		commitSigs:    map[string]*bls.Sign{},
+		prepareBitmap: prepareBitmap,
+		commitBitmap:  commitBitmap,
+	}
+}
+
+// getPrepareSigsArray returns the prepare signatures collected in the round.
+func (round *roundState) getPrepareSigsArray() []*bls.Sign {
+	sigs := []*bls.Sign{}
+	for _, sig := range round.prepareSigs {
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+// getCommitSigsArray returns the commit signatures collected in the round.
+func (round *roundState) getCommitSigsArray() []*bls.Sign {
+	sigs := []*bls.Sign{}
+	for _, sig := range round.commitSigs {
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+// roundOf returns the round of the given consensus id, either the current one or
+// a prepared one still collecting commits. It returns nil for any other id.
+func (consensus *Consensus) roundOf(consensusID uint32) *roundState {
+	if consensusID == consensus.consensusID {
+		return consensus.round
+	}
+	return consensus.pendingRounds[consensusID]
+}
+
+// decodeBlock returns the block of the round.
+func (round *roundState) decodeBlock() (*types.Block, error) {
+	var blockObj types.Block
+	if err := rlp.DecodeBytes(round.block, &blockObj); err != nil {
+		return nil, err
+	}
+	return &blockObj, nil
+}
+
+// startNextRound moves the leader to the next round once the current block is
+// prepared, so that the next block is announced while the commits of the
+// current one are still being collected.
+func (consensus *Consensus) startNextRound() {
+	round := consensus.round
+	blockObj, err := round.decodeBlock()
+	if err != nil {
+		utils.GetLogInstance().Debug("Failed to decode the prepared block", "consensusID", consensus.consensusID, "error", err)
+		return
+	}
+	// The new shard state recorded in the last block of an epoch depends on the
+	// chain up to its parent, so the last block waits for the previous one to commit.
+	// The block after it waits as well, as it's run by the committee handed off to.
+	if consensus.ChainParams.IsEpochLastBlock(blockObj.NumberU64()) || consensus.ChainParams.IsEpochLastBlock(blockObj.NumberU64()+1) {
+		return
+	}
+
+	// The hash of the prepared block doesn't depend on the commit signature,
+	// so the next block can be built on top of it already.
+	blockObj.SetPrepareSig(round.aggregatedPrepareSig.Serialize(), round.prepareBitmap.Bitmap)
+	if consensus.OnBlockPrepared != nil {
+		consensus.OnBlockPrepared(blockObj)
+	}
+
+	consensus.pendingRounds[consensus.consensusID] = round
+	consensus.consensusID++
+	consensus.ResetState()
+	consensus.writeState(Finished)
+	utils.GetLogInstance().Debug("Starting the next round while collecting commits", "consensusID", consensus.consensusID, "pendingRounds", len(consensus.pendingRounds))
+
+	// Send signal to Node so the next block can be proposed.
+	go func() {
+		consensus.ReadySignal <- struct{}{}
+	}()
+}
+
+// dropPendingRounds abandons the rounds still collecting commits, e.g. on a view change.
+// The caller must hold the consensus mutex.
+func (consensus *Consensus) dropPendingRounds() {
+	consensus.pendingRounds = make(map[uint32]*roundState)
+}
+
+// extendsChain returns whether the new block extends the chain: its parent is either the
+// head of the chain, or the prepared block of a round still collecting its commits. It
+// returns false if the new block was built on a block the chain moved past, e.g. one
+// abandoned by a view change. The caller must hold the consensus mutex.
+func (consensus *Consensus) extendsChain(newBlock *types.Block) bool {
+	if consensus.ChainReader == nil || newBlock.NumberU64() == 0 {
+		return true
+	}
+	for _, round := range consensus.pendingRounds {
+		prepared, err := round.decodeBlock()
+		if err != nil || prepared.NumberU64()+1 != newBlock.NumberU64() {
+			continue
+		}
+		prepared.SetPrepareSig(round.aggregatedPrepareSig.Serialize(), round.prepareBitmap.Bitmap)
+		if prepared.Hash() == newBlock.ParentHash() {
+			return true
+		}
+	}
+	parent := consensus.ChainReader.GetHeaderByNumber(newBlock.NumberU64() - 1)
+	return parent != nil && parent.Hash() == newBlock.ParentHash()
+}
@@ -0,0 +1,202 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harmony-one/harmony/api/proto"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/attack"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+// simulatedShard is a shard of consensus nodes in a simulated network.
+type simulatedShard struct {
+	network *simulator.Network
+	nodes   []*Consensus // the leader first
+
+	mutex     sync.Mutex
+	committed [][]*types.Block // by node
+}
+
+func newSimulatedShard(test *testing.T, config simulator.Config, size int) *simulatedShard {
+	shard := &simulatedShard{
+		network:   simulator.NewNetwork(config),
+		committed: make([][]*types.Block, size),
+	}
+
+	peers := make([]p2p.Peer, size)
+	hosts := make([]*simulator.Host, size)
+	for i := range peers {
+		peers[i] = p2p.Peer{IP: ip, Port: fmt.Sprintf("%d", 9100+i), ValidatorID: i}
+		_, peers[i].PubKey = utils.GenKey(peers[i].IP, peers[i].Port)
+		hosts[i] = shard.network.NewHost(&peers[i])
+	}
+	leader, validators := peers[0], peers[1:]
+
+	for i := range peers {
+		i := i
+		node := New(hosts[i], "0", validators, leader)
+		node.SetClock(shard.network.Clock())
+		node.BlockVerifier = func(*types.Block) bool { return true }
+		node.OnConsensusDone = func(block *types.Block) {
+			shard.mutex.Lock()
+			defer shard.mutex.Unlock()
+			shard.committed[i] = append(shard.committed[i], block)
+		}
+		hosts[i].BindHandlerAndServe(func(s p2p.Stream) {
+			content, err := p2p.ReadMessageContent(s)
+			if err != nil {
+				test.Errorf("unreadable message: %v", err)
+				return
+			}
+			payload, _ := proto.GetConsensusMessagePayload(content)
+			if node.IsLeader {
+				node.ProcessMessageLeader(payload)
+			} else {
+				node.ProcessMessageValidator(payload)
+			}
+		})
+		shard.nodes = append(shard.nodes, node)
+	}
+	return shard
+}
+
+// propose makes the leader propose the given number of blocks, each one once
+// the last one is committed by the leader. The blocks are proposed in the
+// simulation loop, so the runs only depend on the seed of the network.
+func (shard *simulatedShard) propose(numBlocks int, stop chan struct{}) {
+	leader := shard.nodes[0]
+	// The leader doesn't pipeline the rounds, its ready signals are ignored.
+	go func() {
+		for {
+			select {
+			case <-leader.ReadySignal:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	proposed := 0
+	proposeNext := func() {
+		proposed++
+		leader.ProposeBlock(types.NewBlock(&types.Header{Number: big.NewInt(int64(proposed))}, nil, nil))
+	}
+	onConsensusDone := leader.OnConsensusDone
+	leader.OnConsensusDone = func(block *types.Block) {
+		onConsensusDone(block)
+		if proposed < numBlocks {
+			shard.network.AfterFunc(0, proposeNext)
+		}
+	}
+	shard.network.AfterFunc(0, proposeNext)
+}
+
+// committedBy returns whether every node committed the given number of blocks.
+func (shard *simulatedShard) committedBy(numBlocks int) bool {
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	for _, blocks := range shard.committed {
+		if len(blocks) < numBlocks {
+			return false
+		}
+	}
+	return true
+}
+
+// run runs the network until every node committed the given number of blocks,
+// for up to limit of virtual time.
+func (shard *simulatedShard) run(numBlocks int, limit time.Duration) bool {
+	return shard.network.RunUntil(func() bool { return shard.committedBy(numBlocks) }, limit)
+}
+
+func TestSimulatedConsensus(test *testing.T) {
+	const numBlocks = 3
+
+	shard := newSimulatedShard(test, simulator.DefaultConfig(1), 7)
+	stop := make(chan struct{})
+	defer close(stop)
+	shard.propose(numBlocks, stop)
+
+	// Liveness: every node commits the blocks.
+	if !assert.True(test, shard.run(numBlocks, 30*time.Second), "blocks are not committed by every node") {
+		return
+	}
+
+	shard.assertAgreement(test)
+	assert.Equal(test, 0, shard.network.Stats().Dropped)
+}
+
+// assertAgreement checks the safety: every node commits the same blocks in the same order.
+func (shard *simulatedShard) assertAgreement(test *testing.T) {
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	for i, blocks := range shard.committed {
+		for n, block := range blocks {
+			assert.Equal(test, uint64(n+1), block.NumberU64(), "node %d", i)
+			for _, other := range shard.committed {
+				if n < len(other) {
+					assert.Equal(test, other[n].Hash(), block.Hash(), "node %d", i)
+				}
+			}
+		}
+	}
+}
+
+func TestSimulatedConsensusWithByzantineValidators(test *testing.T) {
+	const numBlocks = 3
+
+	shard := newSimulatedShard(test, simulator.DefaultConfig(2), 7)
+	// Two byzantine validators out of seven nodes, the most the quorum tolerates
+	shard.nodes[5].AttackScript = &attack.Script{Rules: []attack.Rule{
+		{Behavior: attack.Equivocation},
+		{Behavior: attack.StaleReplay, From: 1},
+	}}
+	shard.nodes[6].AttackScript = &attack.Script{Rules: []attack.Rule{
+		{Behavior: attack.InvalidShare, To: 1},
+		{Behavior: attack.Silence, From: 2},
+	}}
+	stop := make(chan struct{})
+	defer close(stop)
+	shard.propose(numBlocks, stop)
+
+	if !assert.True(test, shard.run(numBlocks, 30*time.Second), "blocks are not committed by every node") {
+		return
+	}
+	shard.assertAgreement(test)
+
+	// The leader caught the equivocating validator.
+	caught := false
+	for _, evidence := range shard.nodes[0].PendingEvidences(uint64(numBlocks)) {
+		if bytes.Equal(evidence.PubKey, shard.nodes[5].pubKey.Serialize()) {
+			caught = true
+		}
+	}
+	assert.True(test, caught, "no double sign evidence of the equivocating validator")
+}
+
+func TestSimulatedConsensusWithSplitAnnounce(test *testing.T) {
+	shard := newSimulatedShard(test, simulator.DefaultConfig(3), 7)
+	// The leader announces a conflicting block to half of the validators.
+	shard.nodes[0].AttackScript = &attack.Script{Rules: []attack.Rule{{Behavior: attack.SplitAnnounce}}}
+	stop := make(chan struct{})
+	defer close(stop)
+	shard.propose(1, stop)
+
+	// Neither block gets a quorum, so nothing is committed, even after the
+	// timeouts of the validators start the view changes.
+	assert.False(test, shard.run(1, announceTimeout+2*viewChangeTimeout))
+	shard.mutex.Lock()
+	for i, blocks := range shard.committed {
+		assert.Empty(test, blocks, "node %d", i)
+	}
+	shard.mutex.Unlock()
+}
@@ -11,6 +11,7 @@ const (
 	PreparedDone
 	CommitDone
 	CommittedDone
+	ViewChanging
 )
 
 // Returns string name for the State enum
@@ -21,9 +22,10 @@ func (state State) String() string {
 		"PrepareDone",
 		"PreparedDone",
 		"CommitDone",
-		"CommittedDone"}
+		"CommittedDone",
+		"ViewChanging"}
 
-	if state < Finished || state > CommittedDone {
+	if state < Finished || state > ViewChanging {
 		return "Unknown"
 	}
 	return names[state]
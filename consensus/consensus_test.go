@@ -2,11 +2,20 @@ package consensus
 
 import (
 	"bytes"
+	"math/big"
+	"runtime"
 	"testing"
 
+	"github.com/harmony-one/bls/ffi/go/bls"
 	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/drand"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host/simulator"
 	"github.com/harmony-one/harmony/p2p/p2pimpl"
 )
 
@@ -76,6 +85,8 @@ func TestGetPeerFromID(t *testing.T) {
 	if err != nil {
 		t.Fatalf("newhost failure: %v", err)
 	}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
 	leaderID := utils.GetUniqueIDFromIPPort(leader.IP, leader.Port)
 	validatorID := utils.GetUniqueIDFromIPPort(validator.IP, validator.Port)
@@ -99,8 +110,7 @@ func TestPopulateMessageFields(t *testing.T) {
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
 	consensus.consensusID = 2
-	consensus.blockHash = blockHash
-	consensus.nodeID = 3
+	consensus.round.blockHash = blockHash
 
 	msg := consensus_proto.Message{}
 	consensus.populateMessageFields(&msg)
@@ -111,8 +121,8 @@ func TestPopulateMessageFields(t *testing.T) {
 	if !bytes.Equal(msg.BlockHash[:], blockHash[:]) {
 		t.Errorf("Block hash is not populated correctly")
 	}
-	if msg.SenderId != 3 {
-		t.Errorf("Sender ID is not populated correctly")
+	if !bytes.Equal(msg.SenderPubkey, consensus.pubKey.Serialize()) {
+		t.Errorf("Sender public key is not populated correctly")
 	}
 }
 
@@ -126,8 +136,7 @@ func TestSignAndMarshalConsensusMessage(t *testing.T) {
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
 	consensus.consensusID = 2
-	consensus.blockHash = blockHash
-	consensus.nodeID = 3
+	consensus.round.blockHash = blockHash
 
 	msg := consensus_proto.Message{}
 	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&msg)
@@ -139,3 +148,262 @@ func TestSignAndMarshalConsensusMessage(t *testing.T) {
 		t.Error("No signature is signed on the consensus message.")
 	}
 }
+
+// fakeChainReader is a chain reader which has no shard state recorded.
+type fakeChainReader struct {
+	consensus_engine.ChainReader
+}
+
+func (cr fakeChainReader) ReadShardState(epoch uint64) types.ShardState { return nil }
+
+func TestVerifySeal(t *testing.T) {
+	leaderPriKey, leaderPubKey := utils.GenKey("127.0.0.1", "9902")
+	validatorPriKey, validatorPubKey := utils.GenKey("127.0.0.1", "9905")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902", PubKey: leaderPubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "9905", PubKey: validatorPubKey}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := New(host, "0", []p2p.Peer{validator}, leader)
+
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(0), Difficulty: big.NewInt(0)}
+	if err := consensus.VerifySeal(fakeChainReader{}, header); err != consensus_engine.ErrNotEnoughSigners {
+		t.Errorf("unsealed header should be rejected, got: %v", err)
+	}
+
+	mask, _ := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	mask.SetKey(leaderPubKey, true)
+	mask.SetKey(validatorPubKey, true)
+	sealHash := consensus.SealHash(header)
+	prepareSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(sealHash[:]), validatorPriKey.SignHash(sealHash[:])})
+	block := types.NewBlockWithHeader(header)
+	block.SetPrepareSig(prepareSig.Serialize(), mask.Bitmap)
+	prepareMultiSigAndBitmap := append(prepareSig.Serialize(), mask.Bitmap...)
+	commitSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(prepareMultiSigAndBitmap), validatorPriKey.SignHash(prepareMultiSigAndBitmap)})
+	block.SetCommitSig(commitSig.Serialize(), mask.Bitmap)
+
+	if err := consensus.VerifySeal(fakeChainReader{}, block.Header()); err != nil {
+		t.Errorf("sealed header should be verified, got: %v", err)
+	}
+	if consensus.SealHash(block.Header()) != sealHash {
+		t.Error("seal hash should not depend on the seal")
+	}
+
+	// Only the leader signs the commit, which doesn't reach the quorum.
+	commitMask, _ := bls_cosi.NewMask(consensus.PublicKeys, leaderPubKey)
+	block.SetCommitSig(leaderPriKey.SignHash(prepareMultiSigAndBitmap).Serialize(), commitMask.Bitmap)
+	if err := consensus.VerifySeal(fakeChainReader{}, block.Header()); err != consensus_engine.ErrNotEnoughSigners {
+		t.Errorf("header committed by the leader only should be rejected, got: %v", err)
+	}
+
+	// The commit signature is not on the prepare multi-sig.
+	block.SetCommitSig(prepareSig.Serialize(), mask.Bitmap)
+	if err := consensus.VerifySeal(fakeChainReader{}, block.Header()); err != consensus_engine.ErrInvalidCommitSignature {
+		t.Errorf("header with wrong commit signature should be rejected, got: %v", err)
+	}
+
+	if err := NewFaker().VerifySeal(fakeChainReader{}, header); err != nil {
+		t.Errorf("faker should accept any seal, got: %v", err)
+	}
+}
+
+// shardStateChainReader is a chain reader which has the shard states of some epochs recorded.
+type shardStateChainReader struct {
+	consensus_engine.ChainReader
+	shardStates map[uint64]types.ShardState
+}
+
+func (cr shardStateChainReader) ReadShardState(epoch uint64) types.ShardState {
+	return cr.shardStates[epoch]
+}
+
+func TestCommitteeKeysAt(t *testing.T) {
+	_, leaderPubKey := utils.GenKey("127.0.0.1", "9906")
+	_, validatorPubKey := utils.GenKey("127.0.0.1", "9907")
+	_, genesisPubKey := utils.GenKey("127.0.0.1", "9908")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9906", PubKey: leaderPubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "9907", PubKey: validatorPubKey}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9906")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := New(host, "0", []p2p.Peer{validator}, leader)
+
+	// A chain without genesis committees trusts the committee of the node
+	if publicKeys := consensus.committeeKeysAt(fakeChainReader{}, 0, 12); len(publicKeys) != 2 {
+		t.Errorf("expected the committee of the node, got %d keys", len(publicKeys))
+	}
+
+	chain := shardStateChainReader{shardStates: map[uint64]types.ShardState{
+		0: {{ShardID: 0, NodeList: []types.NodeID{{BlsPublicKey: genesisPubKey.SerializeToHexStr()}}}},
+		1: {{ShardID: 0, NodeList: []types.NodeID{{BlsPublicKey: "not a key"}}}},
+	}}
+	publicKeys := consensus.committeeKeysAt(chain, 0, 1)
+	if len(publicKeys) != 1 || !publicKeys[0].IsEqual(genesisPubKey) {
+		t.Error("expected the genesis committee to sign the blocks of epoch 0")
+	}
+	// The committees the chain doesn't record with valid keys are unknown
+	if publicKeys := consensus.committeeKeysAt(chain, 0, 7); publicKeys != nil {
+		t.Errorf("expected no committee of invalid keys, got %d keys", len(publicKeys))
+	}
+	if publicKeys := consensus.committeeKeysAt(chain, 0, 12); publicKeys != nil {
+		t.Errorf("expected no committee of an epoch without shard state, got %d keys", len(publicKeys))
+	}
+}
+
+func TestVerifyRandomness(t *testing.T) {
+	_, pubKey := utils.GenKey("127.0.0.1", "9902")
+	publicKeys := []*bls.PublicKey{pubKey}
+
+	// Only epoch blocks commit randomness
+	header := &types.Header{Number: big.NewInt(3)}
+	if err := verifyRandomness(chainparams.DefaultConfig, header, publicKeys); err != nil {
+		t.Errorf("block out of epoch boundary should need no randomness, got: %v", err)
+	}
+	header.RandSeed = [32]byte{1}
+	if err := verifyRandomness(chainparams.DefaultConfig, header, publicKeys); err != consensus_engine.ErrUnexpectedRandomness {
+		t.Errorf("block out of epoch boundary should commit no randomness, got: %v", err)
+	}
+	genesis := &types.Header{Number: big.NewInt(0)}
+	if err := verifyRandomness(chainparams.DefaultConfig, genesis, publicKeys); err != nil {
+		t.Errorf("genesis block should need no randomness, got: %v", err)
+	}
+
+	epochHeader := &types.Header{Number: big.NewInt(5)}
+	if err := verifyRandomness(chainparams.DefaultConfig, epochHeader, publicKeys); err == nil {
+		t.Error("epoch block without randomness should be rejected")
+	}
+	epochHeader.RandPreimage = []byte("garbage")
+	if err := verifyRandomness(chainparams.DefaultConfig, epochHeader, publicKeys); err == nil {
+		t.Error("epoch block with garbage preimage should be rejected")
+	}
+
+	// The last block of the epoch commits the final randomness
+	config := fastVdfConfig()
+	randomness := drand.EvaluateRandomness([32]byte{1, 2, 3}, config.VdfDifficulty(1))
+	encoded, _ := randomness.Encode()
+	lastHeader := &types.Header{Number: big.NewInt(9)}
+	if err := verifyRandomness(config, lastHeader, publicKeys); err == nil {
+		t.Error("last block of epoch without randomness should be rejected")
+	}
+	lastHeader.Vdf = encoded
+	if err := verifyRandomness(config, lastHeader, publicKeys); err != consensus_engine.ErrInvalidRandomness {
+		t.Errorf("last block of epoch with a rand seed other than the vdf output should be rejected, got: %v", err)
+	}
+	lastHeader.RandSeed = randomness.Rand()
+	if err := verifyRandomness(config, lastHeader, publicKeys); err != nil {
+		t.Errorf("last block of epoch with valid randomness should be verified, got: %v", err)
+	}
+	// The last block of the first epoch has no pRnd to run the VDF over
+	firstLastHeader := &types.Header{Number: big.NewInt(4), RandSeed: randomness.Rand(), Vdf: encoded}
+	if err := verifyRandomness(config, firstLastHeader, publicKeys); err != consensus_engine.ErrUnexpectedRandomness {
+		t.Errorf("last block of the first epoch should commit no randomness, got: %v", err)
+	}
+}
+
+func TestAddInvalidRandomness(t *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := New(host, "0", []p2p.Peer{}, leader)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)})
+	hash := block.Hash()
+	if err := consensus.addRandomness(block, []byte("garbage")); err == nil {
+		t.Error("invalid preimage should be rejected")
+	}
+	if block.Hash() != hash {
+		t.Error("block should be left as it is")
+	}
+}
+
+func TestReceiveRandomnessSkipsInvalid(t *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := New(host, "0", []p2p.Peer{}, leader)
+	consensus.ChainParams = fastVdfConfig()
+	reruns := []uint64{}
+	consensus.OnInvalidRandomness = func(number uint64) {
+		reruns = append(reruns, number)
+	}
+
+	encoded, _ := drand.EvaluateRandomness([32]byte{1, 2, 3}, consensus.ChainParams.VdfDifficulty(1)).Encode()
+	channel := make(chan []byte, 2)
+	channel <- []byte("garbage")
+	channel <- encoded
+
+	// The leader doesn't propose the block with the invalid randomness, drand runs again.
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(9)})
+	consensus.receiveRandomness(block, channel, consensus.addVdf)
+	if !bytes.Equal(block.Header().Vdf, encoded) {
+		t.Error("the valid randomness should be added into the block")
+	}
+	if len(reruns) != 1 || reruns[0] != 9 {
+		t.Errorf("drand should run again once for block 9, got: %v", reruns)
+	}
+}
+
+func TestReceiveRandomnessGivesUp(t *testing.T) {
+	network := simulator.NewNetwork(simulator.DefaultConfig(1))
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9903"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	consensus := New(network.NewHost(&leader), "0", []p2p.Peer{}, leader)
+	consensus.SetClock(network.Clock())
+	reruns := make(chan uint64, 10)
+	consensus.OnInvalidRandomness = func(number uint64) {
+		reruns <- number
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(9)})
+
+	stop := make(chan struct{})
+	close(stop)
+	if err := consensus.receiveRandomness(block, make(chan []byte), consensus.addVdf, stop); err != errRandomnessStopped {
+		t.Errorf("should stop waiting for the randomness, got: %v", err)
+	}
+
+	// No randomness comes in time: drand is asked again, until the leader moved.
+	result := make(chan error)
+	go func() {
+		result <- consensus.receiveRandomness(block, make(chan []byte), consensus.addVdf, make(chan struct{}))
+	}()
+	for len(reruns) < 2 {
+		if !network.Step() {
+			runtime.Gosched()
+		}
+	}
+	consensus.mutex.Lock()
+	consensus.IsLeader = false
+	consensus.mutex.Unlock()
+	for {
+		if !network.Step() {
+			runtime.Gosched()
+		}
+		select {
+		case err := <-result:
+			if err != errNotLeader {
+				t.Errorf("should give up once not the leader, got: %v", err)
+			}
+			return
+		default:
+		}
+	}
+}
+
+// fastVdfConfig returns the default chain params with a VDF cheap enough for the tests.
+func fastVdfConfig() *chainparams.Config {
+	config := *chainparams.DefaultConfig
+	config.VdfSquaringsPerSecond = 10
+	return &config
+}
@@ -0,0 +1,171 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// DefaultTraceCapacity is the number of finished round timelines kept in memory.
+const DefaultTraceCapacity = 100
+
+// Types of the events in a round timeline.
+const (
+	EventAnnounceSent     = "announce_sent"
+	EventAnnounceReceived = "announce_received"
+	EventPrepareReceived  = "prepare_received"
+	EventPrepareQuorum    = "prepare_quorum"
+	EventPreparedReceived = "prepared_received"
+	EventCommitReceived   = "commit_received"
+	EventCommitQuorum     = "commit_quorum"
+	EventBlockCommitted   = "block_committed"
+	EventViewChanged      = "view_changed"
+)
+
+// RoundEvent is an event in the timeline of a consensus round.
+type RoundEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Time since the start of the round, in nanoseconds
+	Latency time.Duration `json:"latency"`
+	// The validator who sent the vote, for the prepare and commit events
+	ValidatorKey string `json:"validatorKey,omitempty"`
+}
+
+// RoundTimeline is the timeline of a consensus round, from the announce of the
+// block to its commit.
+type RoundTimeline struct {
+	ConsensusID uint32       `json:"consensusId"`
+	ViewID      uint32       `json:"viewId"`
+	BlockHash   string       `json:"blockHash"`
+	IsLeader    bool         `json:"isLeader"`
+	Start       time.Time    `json:"start"`
+	Events      []RoundEvent `json:"events"`
+}
+
+// RoundTracer records the timelines of the consensus rounds in flight and keeps
+// the last finished ones.
+type RoundTracer struct {
+	mutex    sync.Mutex
+	capacity int
+	active   map[uint32]*RoundTimeline
+	finished []*RoundTimeline // oldest first
+	export   io.Writer
+	now      func() time.Time
+}
+
+// NewRoundTracer creates a tracer which keeps the last capacity finished timelines.
+func NewRoundTracer(capacity int) *RoundTracer {
+	return &RoundTracer{
+		capacity: capacity,
+		active:   make(map[uint32]*RoundTimeline),
+		now:      time.Now,
+	}
+}
+
+// SetExportWriter makes the tracer write every finished timeline into w as a line of JSON.
+func (tracer *RoundTracer) SetExportWriter(w io.Writer) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	tracer.export = w
+}
+
+// Timelines returns up to count of the last finished timelines, latest first.
+func (tracer *RoundTracer) Timelines(count int) []*RoundTimeline {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	timelines := []*RoundTimeline{}
+	for i := len(tracer.finished) - 1; i >= 0 && len(timelines) < count; i-- {
+		timelines = append(timelines, tracer.finished[i])
+	}
+	return timelines
+}
+
+// ExportTimelines writes the timelines into w, one JSON object per line.
+func ExportTimelines(w io.Writer, timelines []*RoundTimeline) error {
+	encoder := json.NewEncoder(w)
+	for _, timeline := range timelines {
+		if err := encoder.Encode(timeline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start begins the timeline of a round with its first event.
+func (tracer *RoundTracer) start(consensusID uint32, viewID uint32, blockHash []byte, isLeader bool, eventType string) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	now := tracer.now()
+	tracer.active[consensusID] = &RoundTimeline{
+		ConsensusID: consensusID,
+		ViewID:      viewID,
+		BlockHash:   hex.EncodeToString(blockHash),
+		IsLeader:    isLeader,
+		Start:       now,
+		Events:      []RoundEvent{{Type: eventType, Time: now}},
+	}
+}
+
+// record adds an event to the timeline of a round in flight.
+func (tracer *RoundTracer) record(consensusID uint32, eventType string, validatorKey string) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	if timeline, ok := tracer.active[consensusID]; ok {
+		timeline.addEvent(tracer.now(), eventType, validatorKey)
+	}
+}
+
+// finish adds the last event to the timeline of a round and keeps it as finished.
+func (tracer *RoundTracer) finish(consensusID uint32, eventType string) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	tracer.finishTimeline(consensusID, eventType)
+}
+
+// finishAll finishes the timelines of all the rounds in flight, e.g. when they
+// are abandoned by a view change.
+func (tracer *RoundTracer) finishAll(eventType string) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	for consensusID := range tracer.active {
+		tracer.finishTimeline(consensusID, eventType)
+	}
+}
+
+func (tracer *RoundTracer) finishTimeline(consensusID uint32, eventType string) {
+	timeline, ok := tracer.active[consensusID]
+	if !ok {
+		return
+	}
+	delete(tracer.active, consensusID)
+	timeline.addEvent(tracer.now(), eventType, "")
+
+	tracer.finished = append(tracer.finished, timeline)
+	if len(tracer.finished) > tracer.capacity {
+		tracer.finished = tracer.finished[len(tracer.finished)-tracer.capacity:]
+	}
+	if tracer.export != nil {
+		if err := ExportTimelines(tracer.export, []*RoundTimeline{timeline}); err != nil {
+			utils.GetLogInstance().Warn("Failed to export the round timeline", "consensusID", consensusID, "error", err)
+		}
+	}
+}
+
+func (timeline *RoundTimeline) addEvent(now time.Time, eventType string, validatorKey string) {
+	timeline.Events = append(timeline.Events, RoundEvent{
+		Type:         eventType,
+		Time:         now,
+		Latency:      now.Sub(timeline.Start),
+		ValidatorKey: validatorKey,
+	})
+}
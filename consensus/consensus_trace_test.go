@@ -0,0 +1,62 @@
+package consensus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTracer(test *testing.T) {
+	tracer := NewRoundTracer(2)
+	for consensusID := uint32(0); consensusID < 3; consensusID++ {
+		tracer.start(consensusID, 0, blockHash[:], true, EventAnnounceSent)
+		tracer.record(consensusID, EventPrepareReceived, "validator")
+		tracer.record(consensusID, EventPrepareQuorum, "")
+		tracer.finish(consensusID, EventBlockCommitted)
+	}
+	// Events of rounds not in flight are dropped.
+	tracer.record(5, EventCommitReceived, "validator")
+
+	timelines := tracer.Timelines(10)
+	if assert.Equal(test, 2, len(timelines), "only the last rounds should be kept") {
+		assert.Equal(test, uint32(2), timelines[0].ConsensusID)
+		assert.Equal(test, uint32(1), timelines[1].ConsensusID)
+	}
+	events := timelines[0].Events
+	if assert.Equal(test, 4, len(events)) {
+		assert.Equal(test, EventAnnounceSent, events[0].Type)
+		assert.Equal(test, "validator", events[1].ValidatorKey)
+		assert.Equal(test, EventBlockCommitted, events[3].Type)
+		assert.True(test, events[3].Latency >= events[1].Latency)
+	}
+	assert.Equal(test, 1, len(tracer.Timelines(1)))
+}
+
+func TestRoundTracerExport(test *testing.T) {
+	var buffer bytes.Buffer
+	tracer := NewRoundTracer(DefaultTraceCapacity)
+	tracer.SetExportWriter(&buffer)
+
+	tracer.start(1, 0, blockHash[:], false, EventAnnounceReceived)
+	tracer.start(2, 0, blockHash[:], false, EventAnnounceReceived)
+	tracer.finish(1, EventBlockCommitted)
+	tracer.finishAll(EventViewChanged)
+
+	scanner := bufio.NewScanner(&buffer)
+	timelines := []RoundTimeline{}
+	for scanner.Scan() {
+		timeline := RoundTimeline{}
+		if err := json.Unmarshal(scanner.Bytes(), &timeline); err != nil {
+			test.Fatal(err)
+		}
+		timelines = append(timelines, timeline)
+	}
+	if assert.Equal(test, 2, len(timelines)) {
+		assert.Equal(test, uint32(1), timelines[0].ConsensusID)
+		assert.Equal(test, uint32(2), timelines[1].ConsensusID)
+		assert.Equal(test, EventViewChanged, timelines[1].Events[1].Type)
+	}
+}
@@ -1,10 +1,11 @@
 package consensus
 
 import (
+	"encoding/hex"
+	"time"
+
 	"github.com/harmony-one/bls/ffi/go/bls"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
-	"github.com/harmony-one/harmony/p2p"
-	"github.com/harmony-one/harmony/p2p/host"
 
 	"github.com/ethereum/go-ethereum/rlp"
 	protobuf "github.com/golang/protobuf/proto"
@@ -15,6 +16,59 @@ import (
 	"github.com/harmony-one/harmony/internal/utils"
 )
 
+// Timeouts of the consensus phases on the validator side. If the leader doesn't
+// move the consensus forward in time, the validator starts a view change.
+const (
+	announceTimeout   = 60 * time.Second
+	preparedTimeout   = 20 * time.Second
+	committedTimeout  = 20 * time.Second
+	viewChangeTimeout = 30 * time.Second
+)
+
+// resetTimer restarts the phase timer with the given timeout. If it expires
+// before the timer is reset or stopped, a view change to the next view starts.
+func (consensus *Consensus) resetTimer(timeout time.Duration) {
+	consensus.timerLock.Lock()
+	defer consensus.timerLock.Unlock()
+
+	if consensus.stopPhaseTimer != nil {
+		consensus.stopPhaseTimer()
+	}
+	consensus.timerGen++
+	gen := consensus.timerGen
+	consensus.stopPhaseTimer = consensus.clock.AfterFunc(timeout, func() {
+		consensus.onPhaseTimeout(gen)
+	})
+}
+
+// stopTimer stops the phase timer.
+func (consensus *Consensus) stopTimer() {
+	consensus.timerLock.Lock()
+	defer consensus.timerLock.Unlock()
+
+	if consensus.stopPhaseTimer != nil {
+		consensus.stopPhaseTimer()
+		consensus.stopPhaseTimer = nil
+	}
+	consensus.timerGen++
+}
+
+// onPhaseTimeout is called when the phase timer of the given generation expires.
+func (consensus *Consensus) onPhaseTimeout(gen uint64) {
+	consensus.timerLock.Lock()
+	stale := gen != consensus.timerGen
+	consensus.timerLock.Unlock()
+	if stale {
+		return
+	}
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	utils.GetLogInstance().Warn("Consensus phase timeout", "state", consensus.round.state, "consensusID", consensus.consensusID, "viewID", consensus.viewID)
+	consensus.startViewChange(consensus.nextViewID + 1)
+}
+
 // sendBFTBlockToStateSyncing will send the latest BFT consensus block to state syncing checkingjjkkkkkkkkkkkkkkkjnjk
 func (consensus *Consensus) sendBFTBlockToStateSyncing(consensusID uint32) {
 	// validator send consensus block to state syncing
@@ -47,6 +101,13 @@ func (consensus *Consensus) ProcessMessageValidator(payload []byte) {
 	if err != nil {
 		utils.GetLogInstance().Error("Failed to unmarshal message payload.", "err", err, "consensus", consensus)
 	}
+
+	consensus.dispatchValidatorMessage(message)
+	consensus.replayFutureMessages(consensus.dispatchValidatorMessage)
+}
+
+// dispatchValidatorMessage calls the validator's handler of the message type.
+func (consensus *Consensus) dispatchValidatorMessage(message consensus_proto.Message) {
 	switch message.Type {
 	case consensus_proto.MessageType_ANNOUNCE:
 		consensus.processAnnounceMessage(message)
@@ -54,6 +115,12 @@ func (consensus *Consensus) ProcessMessageValidator(payload []byte) {
 		consensus.processPreparedMessage(message)
 	case consensus_proto.MessageType_COMMITTED:
 		consensus.processCommittedMessage(message)
+	case consensus_proto.MessageType_VIEWCHANGE:
+		consensus.processViewChangeMessage(message)
+	case consensus_proto.MessageType_NEWVIEW:
+		consensus.processNewViewMessage(message)
+	case consensus_proto.MessageType_EVIDENCE:
+		consensus.processEvidenceMessage(message)
 	default:
 		utils.GetLogInstance().Error("Unexpected message type", "msgType", message.Type, "consensus", consensus)
 	}
@@ -61,19 +128,25 @@ func (consensus *Consensus) ProcessMessageValidator(payload []byte) {
 
 // Processes the announce message sent from the leader
 func (consensus *Consensus) processAnnounceMessage(message consensus_proto.Message) {
-	utils.GetLogInstance().Info("Received Announce Message", "nodeID", consensus.nodeID)
+	utils.GetLogInstance().Info("Received Announce Message", "leaderIP", consensus.leader.IP, "leaderPort", consensus.leader.Port)
 
 	consensusID := message.ConsensusId
 	blockHash := message.BlockHash
 	block := message.Payload
 
-	// Add block to received block cache
 	consensus.mutex.Lock()
-	consensus.blocksReceived[consensusID] = &BlockConsensusStatus{block, consensus.state}
+	// Keep the announce of an upcoming round until this node is done with the current one
+	if consensusID > consensus.consensusID && verifyMessageSig(consensus.leader.PubKey, message) == nil && consensus.bufferFutureMessage(message) {
+		consensus.mutex.Unlock()
+		return
+	}
+
+	// Add block to received block cache
+	consensus.blocksReceived[consensusID] = &BlockConsensusStatus{block, consensus.round.state}
 	consensus.mutex.Unlock()
 
-	copy(consensus.blockHash[:], blockHash[:])
-	consensus.block = block
+	copy(consensus.round.blockHash[:], blockHash[:])
+	consensus.round.block = block
 
 	if err := consensus.checkConsensusMessage(message, consensus.leader.PubKey); err != nil {
 		utils.GetLogInstance().Debug("Failed to check the leader message")
@@ -84,6 +157,8 @@ func (consensus *Consensus) processAnnounceMessage(message consensus_proto.Messa
 		return
 	}
 
+	consensus.Tracer.start(consensusID, message.ViewId, blockHash, false, EventAnnounceReceived)
+
 	// check block header is valid
 	var blockObj types.Block
 	err := rlp.DecodeBytes(block, &blockObj)
@@ -91,6 +166,10 @@ func (consensus *Consensus) processAnnounceMessage(message consensus_proto.Messa
 		utils.GetLogInstance().Warn("Unparseable block header data", "error", err)
 		return
 	}
+	if blockObj.NumberU64() != blockNumberOf(consensusID) {
+		utils.GetLogInstance().Warn("Block number not matching the consensus id", "blockNum", blockObj.NumberU64(), "consensusID", consensusID)
+		return
+	}
 
 	// Add attack model of IncorrectResponse
 	if attack.GetInstance().IncorrectResponse() {
@@ -104,24 +183,35 @@ func (consensus *Consensus) processAnnounceMessage(message consensus_proto.Messa
 		return
 	}
 
+	// check the randomness committed into the block
+	if err := verifyRandomness(consensus.ChainParams, blockObj.Header(), consensus.PublicKeys); err != nil {
+		utils.GetLogInstance().Warn("Invalid randomness", "error", err, "consensus", consensus)
+		return
+	}
+
 	// Construct and send prepare message
 	msgToSend := consensus.constructPrepareMessage()
+	if msgToSend == nil {
+		return
+	}
+	consensus.enterState(PrepareDone)
 	if utils.UseLibP2P {
-		consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(17), msgToSend))
+		consensus.SendMessageToGroup(msgToSend)
 	} else {
 		consensus.SendMessage(consensus.leader, msgToSend)
 	}
+	consensus.equivocate(consensus_proto.MessageType_PREPARE, consensus.round.blockHash[:])
 
-	consensus.state = PrepareDone
+	consensus.resetTimer(preparedTimeout)
 }
 
 // Processes the prepared message sent from the leader
 func (consensus *Consensus) processPreparedMessage(message consensus_proto.Message) {
-	utils.GetLogInstance().Info("Received Prepared Message", "nodeID", consensus.nodeID)
+	utils.GetLogInstance().Info("Received Prepared Message", "leaderIP", consensus.leader.IP, "leaderPort", consensus.leader.Port)
 
 	consensusID := message.ConsensusId
 	blockHash := message.BlockHash
-	leaderID := message.SenderId
+	leaderKey := hex.EncodeToString(message.SenderPubkey)
 	messagePayload := message.Payload
 
 	//#### Read payload data
@@ -137,7 +227,13 @@ func (consensus *Consensus) processPreparedMessage(message consensus_proto.Messa
 	// Update readyByConsensus for attack.
 	attack.GetInstance().UpdateConsensusReady(consensusID)
 
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
 	if err := consensus.checkConsensusMessage(message, consensus.leader.PubKey); err != nil {
+		if err == consensus_engine.ErrConsensusIDNotMatch && consensus.bufferFutureMessage(message) {
+			return
+		}
 		utils.GetLogInstance().Debug("processPreparedMessage error", "error", err)
 		return
 	}
@@ -148,43 +244,46 @@ func (consensus *Consensus) processPreparedMessage(message consensus_proto.Messa
 		return
 	}
 
-	consensus.mutex.Lock()
-	defer consensus.mutex.Unlock()
-
 	// Verify the multi-sig for prepare phase
 	deserializedMultiSig := bls.Sign{}
 	err := deserializedMultiSig.Deserialize(multiSig)
 	if err != nil {
-		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for prepare phase", "Error", err, "leader ID", leaderID)
+		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for prepare phase", "Error", err, "leader key", leaderKey)
 		return
 	}
 	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
 	mask.SetMask(bitmap)
 	if !deserializedMultiSig.VerifyHash(mask.AggregatePublic, blockHash) || err != nil {
-		utils.GetLogInstance().Warn("Failed to verify the multi signature for prepare phase", "Error", err, "leader ID", leaderID)
+		utils.GetLogInstance().Warn("Failed to verify the multi signature for prepare phase", "Error", err, "leader key", leaderKey)
 		return
 	}
-	consensus.aggregatedPrepareSig = &deserializedMultiSig
-	consensus.prepareBitmap = mask
+	consensus.round.aggregatedPrepareSig = &deserializedMultiSig
+	consensus.round.prepareBitmap = mask
+	consensus.Tracer.record(consensusID, EventPreparedReceived, "")
 
 	// Construct and send the commit message
 	multiSigAndBitmap := append(multiSig, bitmap...)
 	msgToSend := consensus.constructCommitMessage(multiSigAndBitmap)
+	if msgToSend == nil {
+		return
+	}
+	consensus.enterState(CommitDone)
 	if utils.UseLibP2P {
-		consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(17), msgToSend))
+		consensus.SendMessageToGroup(msgToSend)
 	} else {
 		consensus.SendMessage(consensus.leader, msgToSend)
 	}
+	consensus.equivocate(consensus_proto.MessageType_COMMIT, multiSigAndBitmap)
 
-	consensus.state = CommitDone
+	consensus.resetTimer(committedTimeout)
 }
 
 // Processes the committed message sent from the leader
 func (consensus *Consensus) processCommittedMessage(message consensus_proto.Message) {
-	utils.GetLogInstance().Warn("Received Committed Message", "nodeID", consensus.nodeID)
+	utils.GetLogInstance().Warn("Received Committed Message", "leaderIP", consensus.leader.IP, "leaderPort", consensus.leader.Port)
 
 	consensusID := message.ConsensusId
-	leaderID := message.SenderId
+	leaderKey := hex.EncodeToString(message.SenderPubkey)
 	messagePayload := message.Payload
 
 	//#### Read payload data
@@ -200,7 +299,13 @@ func (consensus *Consensus) processCommittedMessage(message consensus_proto.Mess
 	// Update readyByConsensus for attack.
 	attack.GetInstance().UpdateConsensusReady(consensusID)
 
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
 	if err := consensus.checkConsensusMessage(message, consensus.leader.PubKey); err != nil {
+		if err == consensus_engine.ErrConsensusIDNotMatch && consensus.bufferFutureMessage(message) {
+			return
+		}
 		utils.GetLogInstance().Debug("processCommittedMessage error", "error", err)
 		return
 	}
@@ -211,67 +316,63 @@ func (consensus *Consensus) processCommittedMessage(message consensus_proto.Mess
 		return
 	}
 
-	consensus.mutex.Lock()
-	defer consensus.mutex.Unlock()
-
 	// Verify the multi-sig for commit phase
 	deserializedMultiSig := bls.Sign{}
 	err := deserializedMultiSig.Deserialize(multiSig)
 	if err != nil {
-		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for commit phase", "Error", err, "leader ID", leaderID)
+		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for commit phase", "Error", err, "leader key", leaderKey)
 		return
 	}
 	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
 	mask.SetMask(bitmap)
-	prepareMultiSigAndBitmap := append(consensus.aggregatedPrepareSig.Serialize(), consensus.prepareBitmap.Bitmap...)
+	prepareMultiSigAndBitmap := append(consensus.round.aggregatedPrepareSig.Serialize(), consensus.round.prepareBitmap.Bitmap...)
 	if !deserializedMultiSig.VerifyHash(mask.AggregatePublic, prepareMultiSigAndBitmap) || err != nil {
-		utils.GetLogInstance().Warn("Failed to verify the multi signature for commit phase", "Error", err, "leader ID", leaderID)
+		utils.GetLogInstance().Warn("Failed to verify the multi signature for commit phase", "Error", err, "leader key", leaderKey)
 		return
 	}
-	consensus.aggregatedCommitSig = &deserializedMultiSig
-	consensus.commitBitmap = mask
-
-	consensus.state = CommittedDone
-	// TODO: the block catch up logic is a temporary workaround for full failure node catchup. Implement the full node catchup logic
-	// The logic is to roll up to the latest blocks one by one to try catching up with the leader.
-	// but because of checkConsensusMessage, the catchup logic will never be used here
-	for {
-		val, ok := consensus.blocksReceived[consensus.consensusID]
-		if ok {
-			delete(consensus.blocksReceived, consensus.consensusID)
-
-			consensus.blockHash = [32]byte{}
-			consensus.consensusID = consensusID + 1 // roll up one by one, until the next block is not received yet.
-
-			var blockObj types.Block
-			err := rlp.DecodeBytes(val.block, &blockObj)
-			if err != nil {
-				utils.GetLogInstance().Debug("failed to construct the new block after consensus")
-			}
-			// check block data (transactions
-			if !consensus.BlockVerifier(&blockObj) {
-				utils.GetLogInstance().Debug("[WARNING] Block content is not verified successfully", "consensusID", consensus.consensusID)
-				return
-			}
-
-			// Put the signatures into the block
-			copy(blockObj.Header().PrepareSignature[:], consensus.aggregatedPrepareSig.Serialize()[:])
-			copy(blockObj.Header().PrepareBitmap[:], consensus.prepareBitmap.Bitmap)
-			copy(blockObj.Header().CommitSignature[:], consensus.aggregatedCommitSig.Serialize()[:])
-			copy(blockObj.Header().CommitBitmap[:], consensus.commitBitmap.Bitmap)
-			utils.GetLogInstance().Info("Adding block to chain", "numTx", len(blockObj.Transactions()))
-			consensus.OnConsensusDone(&blockObj)
-			consensus.ResetState()
-
-			select {
-			case consensus.VerifiedNewBlock <- &blockObj:
-			default:
-				utils.GetLogInstance().Info("[SYNC] consensus verified block send to chan failed", "blockHash", blockObj.Hash())
-				continue
-			}
-		} else {
-			break
+	consensus.round.aggregatedCommitSig = &deserializedMultiSig
+	consensus.round.commitBitmap = mask
+
+	consensus.round.state = CommittedDone
+	if val, ok := consensus.blocksReceived[consensusID]; ok {
+		delete(consensus.blocksReceived, consensusID)
+
+		consensus.round.blockHash = [32]byte{}
+		// Move on to the next round; its buffered messages are replayed once this one returns.
+		consensus.consensusID = consensusID + 1
+		consensus.pruneWAL()
+
+		var blockObj types.Block
+		err := rlp.DecodeBytes(val.block, &blockObj)
+		if err != nil {
+			utils.GetLogInstance().Debug("failed to construct the new block after consensus")
+			return
 		}
+		// check block data (transactions
+		if !consensus.BlockVerifier(&blockObj) {
+			utils.GetLogInstance().Debug("[WARNING] Block content is not verified successfully", "consensusID", consensusID)
+			return
+		}
+
+		// Put the signatures into the block
+		blockObj.SetPrepareSig(consensus.round.aggregatedPrepareSig.Serialize(), consensus.round.prepareBitmap.Bitmap)
+		blockObj.SetCommitSig(consensus.round.aggregatedCommitSig.Serialize(), consensus.round.commitBitmap.Bitmap)
+		utils.GetLogInstance().Info("Adding block to chain", "numTx", len(blockObj.Transactions()))
+		consensus.OnConsensusDone(&blockObj)
+		consensus.Tracer.finish(consensusID, EventBlockCommitted)
+		consensus.ResetState()
+		consensus.handOff(&blockObj)
+		consensus.evidencePool.markIncluded(blockObj.Evidences(), blockObj.NumberU64())
+
+		select {
+		case consensus.VerifiedNewBlock <- &blockObj:
+		default:
+			utils.GetLogInstance().Info("[SYNC] consensus verified block send to chan failed", "blockHash", blockObj.Hash())
+		}
+	}
 
+	// Wait for the leader to announce the next block, unless this node took over or left
+	if !consensus.IsLeader && !consensus.leftCommittee {
+		consensus.resetTimer(announceTimeout)
 	}
 }
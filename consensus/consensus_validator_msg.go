@@ -7,6 +7,7 @@ import (
 )
 
 // Construct the prepare message to send to leader (assumption the consensus data is already verified)
+// Returns nil if this node can't vote for the block, i.e. it already voted for another one in the round.
 func (consensus *Consensus) constructPrepareMessage() []byte {
 	message := consensus_proto.Message{}
 	message.Type = consensus_proto.MessageType_PREPARE
@@ -14,10 +15,11 @@ func (consensus *Consensus) constructPrepareMessage() []byte {
 	consensus.populateMessageFields(&message)
 
 	// 48 byte of bls signature
-	sign := consensus.priKey.SignHash(message.BlockHash)
-	if sign != nil {
-		message.Payload = sign.Serialize()
+	sign := consensus.signVote(consensus_proto.MessageType_PREPARE, message.BlockHash)
+	if sign == nil {
+		return nil
 	}
+	message.Payload = consensus.voteShare(sign, message.BlockHash).Serialize()
 
 	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
 	if err != nil {
@@ -27,6 +29,7 @@ func (consensus *Consensus) constructPrepareMessage() []byte {
 }
 
 // Construct the commit message which contains the signature on the multi-sig of prepare phase.
+// Returns nil if this node already voted for another one in the round.
 func (consensus *Consensus) constructCommitMessage(multiSigAndBitmap []byte) []byte {
 	message := consensus_proto.Message{}
 	message.Type = consensus_proto.MessageType_COMMIT
@@ -34,10 +37,11 @@ func (consensus *Consensus) constructCommitMessage(multiSigAndBitmap []byte) []b
 	consensus.populateMessageFields(&message)
 
 	// 48 byte of bls signature
-	sign := consensus.priKey.SignHash(multiSigAndBitmap)
-	if sign != nil {
-		message.Payload = sign.Serialize()
+	sign := consensus.signVote(consensus_proto.MessageType_COMMIT, multiSigAndBitmap)
+	if sign == nil {
+		return nil
 	}
+	message.Payload = consensus.voteShare(sign, multiSigAndBitmap).Serialize()
 
 	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
 	if err != nil {
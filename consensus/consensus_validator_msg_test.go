@@ -18,7 +18,7 @@ func TestConstructPrepareMessage(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
-	consensus.blockHash = [32]byte{}
+	consensus.round.blockHash = [32]byte{}
 	msg := consensus.constructPrepareMessage()
 
 	if len(msg) != 93 {
@@ -35,7 +35,7 @@ func TestConstructCommitMessage(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensus := New(host, "0", []p2p.Peer{leader, validator}, leader)
-	consensus.blockHash = [32]byte{}
+	consensus.round.blockHash = [32]byte{}
 	msg := consensus.constructCommitMessage([]byte("random string"))
 
 	if len(msg) != 143 {
@@ -2,9 +2,11 @@ package consensus
 
 import (
 	"encoding/hex"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/golang/mock/gomock"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
@@ -44,11 +46,12 @@ func TestProcessMessageValidatorAnnounce(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensusLeader := New(host, "0", []p2p.Peer{validator1, validator2, validator3}, leader)
-	blockBytes, err := hex.DecodeString("f90264f9025fa00000000000000000000000000000000000000000000000000000000000000000940000000000000000000000000000000000000000a02b418211410ee3e75b32abd925bbeba215172afa509d65c1953d4b4e505a4a2aa056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421b901000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000083020000808502540be400808080a000000000000000000000000000000000000000000000000000000000000000008800000000000000008400000001b000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000080b00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008080a00000000000000000000000000000000000000000000000000000000000000000c0c0")
-	consensusLeader.block = blockBytes
+	// The round of consensus id 0 is for block 1
+	blockBytes, err := rlp.EncodeToBytes(types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil))
+	consensusLeader.round.block = blockBytes
 	hashBytes, err := hex.DecodeString("26d7cdbbaf6cedcaf946ad1e8c0bc2567e17418ce63026db4160a7cc32d9e488")
 
-	copy(consensusLeader.blockHash[:], hashBytes[:])
+	copy(consensusLeader.round.blockHash[:], hashBytes[:])
 
 	msg := consensusLeader.constructAnnounceMessage()
 
@@ -64,10 +67,10 @@ func TestProcessMessageValidatorAnnounce(test *testing.T) {
 		return true
 	}
 
-	copy(consensusValidator1.blockHash[:], hashBytes[:])
+	copy(consensusValidator1.round.blockHash[:], hashBytes[:])
 	consensusValidator1.processAnnounceMessage(message)
 
-	assert.Equal(test, PrepareDone, consensusValidator1.state)
+	assert.Equal(test, PrepareDone, consensusValidator1.round.state)
 
 	time.Sleep(1 * time.Second)
 }
@@ -98,16 +101,17 @@ func TestProcessMessageValidatorPrepared(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensusLeader := New(host, "0", []p2p.Peer{validator1, validator2, validator3}, leader)
-	blockBytes, err := hex.DecodeString("f90264f9025fa00000000000000000000000000000000000000000000000000000000000000000940000000000000000000000000000000000000000a02b418211410ee3e75b32abd925bbeba215172afa509d65c1953d4b4e505a4a2aa056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421b901000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000083020000808502540be400808080a000000000000000000000000000000000000000000000000000000000000000008800000000000000008400000001b000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000080b00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008080a00000000000000000000000000000000000000000000000000000000000000000c0c0")
-	consensusLeader.block = blockBytes
+	// The round of consensus id 0 is for block 1
+	blockBytes, err := rlp.EncodeToBytes(types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil))
+	consensusLeader.round.block = blockBytes
 	hashBytes, err := hex.DecodeString("26d7cdbbaf6cedcaf946ad1e8c0bc2567e17418ce63026db4160a7cc32d9e488")
 
-	copy(consensusLeader.blockHash[:], hashBytes[:])
+	copy(consensusLeader.round.blockHash[:], hashBytes[:])
 
 	announceMsg := consensusLeader.constructAnnounceMessage()
-	consensusLeader.prepareSigs[consensusLeader.nodeID] = consensusLeader.priKey.SignHash(consensusLeader.blockHash[:])
+	consensusLeader.round.prepareSigs[getPeerKey(consensusLeader.pubKey)] = consensusLeader.priKey.SignHash(consensusLeader.round.blockHash[:])
 
-	preparedMsg, _ := consensusLeader.constructPreparedMessage()
+	preparedMsg, _ := consensusLeader.constructPreparedMessage(consensusLeader.consensusID)
 
 	if err != nil {
 		test.Errorf("Failed to unmarshal message payload")
@@ -120,14 +124,14 @@ func TestProcessMessageValidatorPrepared(test *testing.T) {
 
 	message := consensus_proto.Message{}
 	err = message.XXX_Unmarshal(announceMsg[1:])
-	copy(consensusValidator1.blockHash[:], hashBytes[:])
+	copy(consensusValidator1.round.blockHash[:], hashBytes[:])
 	consensusValidator1.processAnnounceMessage(message)
 
 	message = consensus_proto.Message{}
 	err = message.XXX_Unmarshal(preparedMsg[1:])
 	consensusValidator1.processPreparedMessage(message)
 
-	assert.Equal(test, CommitDone, consensusValidator1.state)
+	assert.Equal(test, CommitDone, consensusValidator1.round.state)
 
 	time.Sleep(1 * time.Second)
 }
@@ -158,21 +162,22 @@ func TestProcessMessageValidatorCommitted(test *testing.T) {
 		test.Fatalf("newhost failure: %v", err)
 	}
 	consensusLeader := New(host, "0", []p2p.Peer{validator1, validator2, validator3}, leader)
-	blockBytes, err := hex.DecodeString("f90264f9025fa00000000000000000000000000000000000000000000000000000000000000000940000000000000000000000000000000000000000a02b418211410ee3e75b32abd925bbeba215172afa509d65c1953d4b4e505a4a2aa056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421b901000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000083020000808502540be400808080a000000000000000000000000000000000000000000000000000000000000000008800000000000000008400000001b000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000080b00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008080a00000000000000000000000000000000000000000000000000000000000000000c0c0")
-	consensusLeader.block = blockBytes
+	// The round of consensus id 0 is for block 1
+	blockBytes, err := rlp.EncodeToBytes(types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil))
+	consensusLeader.round.block = blockBytes
 	hashBytes, err := hex.DecodeString("26d7cdbbaf6cedcaf946ad1e8c0bc2567e17418ce63026db4160a7cc32d9e488")
 
-	copy(consensusLeader.blockHash[:], hashBytes[:])
+	copy(consensusLeader.round.blockHash[:], hashBytes[:])
 
 	announceMsg := consensusLeader.constructAnnounceMessage()
-	consensusLeader.prepareSigs[consensusLeader.nodeID] = consensusLeader.priKey.SignHash(consensusLeader.blockHash[:])
+	consensusLeader.round.prepareSigs[getPeerKey(consensusLeader.pubKey)] = consensusLeader.priKey.SignHash(consensusLeader.round.blockHash[:])
 
-	preparedMsg, _ := consensusLeader.constructPreparedMessage()
+	preparedMsg, _ := consensusLeader.constructPreparedMessage(consensusLeader.consensusID)
 	aggSig := bls_cosi.AggregateSig(consensusLeader.GetPrepareSigsArray())
-	multiSigAndBitmap := append(aggSig.Serialize(), consensusLeader.prepareBitmap.Bitmap...)
+	multiSigAndBitmap := append(aggSig.Serialize(), consensusLeader.round.prepareBitmap.Bitmap...)
 
-	consensusLeader.commitSigs[consensusLeader.nodeID] = consensusLeader.priKey.SignHash(multiSigAndBitmap)
-	committedMsg, _ := consensusLeader.constructCommittedMessage()
+	consensusLeader.round.commitSigs[getPeerKey(consensusLeader.pubKey)] = consensusLeader.priKey.SignHash(multiSigAndBitmap)
+	committedMsg, _ := consensusLeader.constructCommittedMessage(consensusLeader.consensusID)
 
 	if err != nil {
 		test.Errorf("Failed to unmarshal message payload")
@@ -186,7 +191,7 @@ func TestProcessMessageValidatorCommitted(test *testing.T) {
 
 	message := consensus_proto.Message{}
 	err = message.XXX_Unmarshal(announceMsg[1:])
-	copy(consensusValidator1.blockHash[:], hashBytes[:])
+	copy(consensusValidator1.round.blockHash[:], hashBytes[:])
 	consensusValidator1.processAnnounceMessage(message)
 
 	message = consensus_proto.Message{}
@@ -197,7 +202,7 @@ func TestProcessMessageValidatorCommitted(test *testing.T) {
 	err = message.XXX_Unmarshal(committedMsg[1:])
 	consensusValidator1.processCommittedMessage(message)
 
-	assert.Equal(test, Finished, consensusValidator1.state)
+	assert.Equal(test, Finished, consensusValidator1.round.state)
 
 	time.Sleep(1 * time.Second)
 }
@@ -0,0 +1,378 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// viewChangeDigest returns the hash the committee members sign on to vote for
+// moving the consensus of consensusID to the given view.
+func viewChangeDigest(consensusID uint32, viewID uint32) []byte {
+	buffer := bytes.NewBuffer([]byte("viewchange"))
+	binary.Write(buffer, binary.BigEndian, consensusID)
+	binary.Write(buffer, binary.BigEndian, viewID)
+	hash := sha256.Sum256(buffer.Bytes())
+	return hash[:]
+}
+
+// leaderKeyForView returns the public key of the leader of the given view.
+// The committee keys are ordered by their serialization so every node picks
+// the same leader, no matter in which order the peers joined. The leader
+// rotates to the next key for every view after the current one.
+func (consensus *Consensus) leaderKeyForView(viewID uint32) *bls.PublicKey {
+	consensus.pubKeyLock.Lock()
+	keys := append(consensus.PublicKeys[:0:0], consensus.PublicKeys...)
+	consensus.pubKeyLock.Unlock()
+
+	if len(keys) == 0 || viewID < consensus.viewID {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i].Serialize(), keys[j].Serialize()) < 0
+	})
+
+	// Position of the current leader. If the leader was removed from the
+	// committee, start from the key right before where it used to be.
+	pos := -1
+	if consensus.leader.PubKey != nil {
+		leaderKey := consensus.leader.PubKey.Serialize()
+		pos = sort.Search(len(keys), func(i int) bool {
+			return bytes.Compare(keys[i].Serialize(), leaderKey) >= 0
+		})
+		if pos == len(keys) || !bytes.Equal(keys[pos].Serialize(), leaderKey) {
+			pos--
+		}
+	}
+	pos = (pos + int((viewID-consensus.viewID)%uint32(len(keys)))) % len(keys)
+	if pos < 0 {
+		pos += len(keys)
+	}
+	return keys[pos]
+}
+
+// getPeerByPubKey returns the peer in the committee owning the given public key.
+func (consensus *Consensus) getPeerByPubKey(pubKey *bls.PublicKey) (p2p.Peer, bool) {
+	if consensus.leader.PubKey != nil && consensus.leader.PubKey.IsEqual(pubKey) {
+		return consensus.leader, true
+	}
+	if consensus.pubKey.IsEqual(pubKey) {
+		selfPeer := consensus.host.GetSelfPeer()
+		selfPeer.PubKey = consensus.pubKey
+		return selfPeer, true
+	}
+
+	if v, ok := consensus.validators.Load(getPeerKey(pubKey)); ok {
+		peer, ok := v.(p2p.Peer)
+		return peer, ok
+	}
+	return p2p.Peer{}, false
+}
+
+// startViewChange votes for moving to the given view, after the leader failed to
+// make progress in time. The caller must hold the consensus mutex.
+func (consensus *Consensus) startViewChange(viewID uint32) {
+	if consensus.leftCommittee {
+		return
+	}
+	utils.GetLogInstance().Warn("Starting view change", "consensusID", consensus.consensusID, "viewID", viewID, "consensus", consensus)
+
+	consensus.prepareViewChange(viewID)
+	consensus.enterState(ViewChanging)
+
+	// Move on to the view after if the next leader doesn't show up either.
+	consensus.resetTimer(viewChangeTimeout)
+
+	nextLeaderKey := consensus.leaderKeyForView(viewID)
+	if nextLeaderKey == nil {
+		utils.GetLogInstance().Warn("No leader available for the view", "viewID", viewID)
+		return
+	}
+
+	if nextLeaderKey.IsEqual(consensus.pubKey) {
+		// I am the next leader, vote for myself.
+		consensus.addViewChangeSig(consensus.pubKey, consensus.priKey.SignHash(viewChangeDigest(consensus.consensusID, viewID)))
+		return
+	}
+
+	msgToSend := consensus.constructViewChangeMessage()
+	if utils.UseLibP2P {
+		consensus.SendMessageToGroup(msgToSend)
+	} else {
+		nextLeader, ok := consensus.getPeerByPubKey(nextLeaderKey)
+		if !ok {
+			utils.GetLogInstance().Warn("Unknown peer of the next leader", "viewID", viewID)
+			return
+		}
+		consensus.SendMessage(nextLeader, msgToSend)
+	}
+}
+
+// prepareViewChange clears the view change votes collected so far if they are
+// for a view older than the given one.
+func (consensus *Consensus) prepareViewChange(viewID uint32) {
+	if viewID <= consensus.nextViewID && consensus.viewChangeBitmap != nil {
+		return
+	}
+	if viewID > consensus.nextViewID {
+		consensus.nextViewID = viewID
+	}
+	consensus.viewChangeSigs = map[string]*bls.Sign{}
+	consensus.viewChangeBitmap, _ = bls_cosi.NewMask(consensus.PublicKeys, nil)
+	consensus.viewChangePreparedHash = nil
+}
+
+// processViewChangeMessage processes the view change vote sent to the next leader.
+func (consensus *Consensus) processViewChangeMessage(message consensus_proto.Message) {
+	validatorKey := hex.EncodeToString(message.SenderPubkey)
+	viewID := message.ViewId
+	payload := message.Payload
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if message.ConsensusId != consensus.consensusID || viewID <= consensus.viewID || viewID < consensus.nextViewID {
+		utils.GetLogInstance().Debug("Ignoring stale view change message", "validatorKey", validatorKey, "consensusID", message.ConsensusId, "viewID", viewID)
+		return
+	}
+
+	nextLeaderKey := consensus.leaderKeyForView(viewID)
+	if nextLeaderKey == nil || !nextLeaderKey.IsEqual(consensus.pubKey) {
+		utils.GetLogInstance().Debug("Not the leader of the proposed view", "validatorKey", validatorKey, "viewID", viewID)
+		return
+	}
+
+	validatorPeer := consensus.getValidatorPeerByPubKey(message.SenderPubkey)
+	if validatorPeer == nil {
+		return
+	}
+	if err := verifyMessageSig(validatorPeer.PubKey, message); err != nil {
+		utils.GetLogInstance().Warn("Failed to verify the view change message signature", "validatorKey", validatorKey, "error", err)
+		return
+	}
+
+	//#### Read payload data
+	if len(payload) < 48 {
+		utils.GetLogInstance().Warn("Malformed view change message", "validatorKey", validatorKey)
+		return
+	}
+	// 48 byte of bls signature on the view change digest
+	var sign bls.Sign
+	if err := sign.Deserialize(payload[:48]); err != nil {
+		utils.GetLogInstance().Warn("Failed to deserialize bls signature", "validatorKey", validatorKey)
+		return
+	}
+	if !sign.VerifyHash(validatorPeer.PubKey, viewChangeDigest(consensus.consensusID, viewID)) {
+		utils.GetLogInstance().Warn("Received invalid BLS signature", "validatorKey", validatorKey)
+		return
+	}
+	// Optional prepared certificate: 48 byte of aggregated prepare signature and the prepare bitmap
+	preparedCert := payload[48:]
+	//#### END Read payload data
+
+	consensus.prepareViewChange(viewID)
+	if _, ok := consensus.viewChangeSigs[validatorKey]; ok {
+		utils.GetLogInstance().Debug("Already received view change message from the validator", "validatorKey", validatorKey)
+		return
+	}
+	if len(preparedCert) > 48 && consensus.verifyPreparedCert(message.BlockHash, preparedCert) {
+		consensus.viewChangePreparedHash = message.BlockHash
+	}
+
+	utils.GetLogInstance().Debug("Received new view change message", "numReceivedSoFar", len(consensus.viewChangeSigs), "validatorKey", validatorKey, "viewID", viewID)
+	consensus.addViewChangeSig(validatorPeer.PubKey, &sign)
+
+	// Join the view change once f+1 members confirm the leader failure.
+	if _, ok := consensus.viewChangeSigs[getPeerKey(consensus.pubKey)]; !ok && !consensus.leftCommittee && consensus.viewID < viewID && len(consensus.viewChangeSigs) >= len(consensus.PublicKeys)/3+1 {
+		consensus.enterState(ViewChanging)
+		consensus.addViewChangeSig(consensus.pubKey, consensus.priKey.SignHash(viewChangeDigest(consensus.consensusID, viewID)))
+	}
+}
+
+// verifyPreparedCert checks the aggregated prepare signature and bitmap
+// reported in a view change message are a valid quorum on the block hash.
+func (consensus *Consensus) verifyPreparedCert(blockHash []byte, preparedCert []byte) bool {
+	var multiSig bls.Sign
+	if err := multiSig.Deserialize(preparedCert[:48]); err != nil {
+		return false
+	}
+	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	if err != nil || mask.SetMask(preparedCert[48:]) != nil {
+		return false
+	}
+	if !consensus.QuorumPolicy.Check(mask) {
+		return false
+	}
+	return multiSig.VerifyHash(mask.AggregatePublic, blockHash)
+}
+
+// addViewChangeSig records a view change vote and takes over the leadership
+// once enough votes are collected.
+func (consensus *Consensus) addViewChangeSig(pubKey *bls.PublicKey, sign *bls.Sign) {
+	consensus.viewChangeSigs[getPeerKey(pubKey)] = sign
+	consensus.viewChangeBitmap.SetKey(pubKey, true)
+
+	if !consensus.QuorumPolicy.Check(consensus.viewChangeBitmap) {
+		return
+	}
+	utils.GetLogInstance().Info("Enough view change messages received!", "num", len(consensus.viewChangeSigs), "viewID", consensus.nextViewID)
+
+	aggSig := bls_cosi.AggregateSig(consensus.getViewChangeSigsArray())
+	bitmap := consensus.viewChangeBitmap.Bitmap
+	preparedHash := consensus.viewChangePreparedHash
+
+	selfPeer := consensus.host.GetSelfPeer()
+	selfPeer.PubKey = consensus.pubKey
+	consensus.switchView(consensus.nextViewID, selfPeer)
+
+	// Construct and broadcast new view message
+	msgToSend := consensus.constructNewViewMessage(aggSig, bitmap)
+	if utils.UseLibP2P {
+		consensus.SendMessageToGroup(msgToSend)
+	} else {
+		consensus.broadcastMessageFromLeader(msgToSend)
+	}
+
+	// Re-propose the block prepared in the previous view so that a block
+	// possibly committed by some members is not replaced by another one.
+	if val, ok := consensus.blocksReceived[consensus.consensusID]; ok && preparedHash != nil {
+		var blockObj types.Block
+		if err := rlp.DecodeBytes(val.block, &blockObj); err == nil && bytes.Equal(blockObj.Hash().Bytes(), preparedHash) {
+			utils.GetLogInstance().Info("Re-proposing prepared block in the new view", "blockHash", blockObj.Hash().Hex())
+			consensus.startConsensus(&blockObj)
+			return
+		}
+	}
+
+	// Send signal to Node so a new block can be proposed in the new view
+	go func() {
+		consensus.ReadySignal <- struct{}{}
+	}()
+}
+
+// getViewChangeSigsArray returns the signatures for view change as a array
+func (consensus *Consensus) getViewChangeSigsArray() []*bls.Sign {
+	sigs := []*bls.Sign{}
+	for _, sig := range consensus.viewChangeSigs {
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+// processNewViewMessage processes the new view message sent from the new leader.
+func (consensus *Consensus) processNewViewMessage(message consensus_proto.Message) {
+	viewID := message.ViewId
+	messagePayload := message.Payload
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if message.ConsensusId != consensus.consensusID || viewID <= consensus.viewID {
+		utils.GetLogInstance().Debug("Ignoring stale new view message", "consensusID", message.ConsensusId, "myConsensusID", consensus.consensusID, "viewID", viewID, "myViewID", consensus.viewID)
+		return
+	}
+
+	leaderKey := consensus.leaderKeyForView(viewID)
+	if leaderKey == nil {
+		return
+	}
+	if err := verifyMessageSig(leaderKey, message); err != nil {
+		utils.GetLogInstance().Warn("Failed to verify the new view message signature", "viewID", viewID, "error", err)
+		return
+	}
+
+	//#### Read payload data
+	if len(messagePayload) < 48 {
+		utils.GetLogInstance().Warn("Malformed new view message", "viewID", viewID)
+		return
+	}
+	offset := 0
+	// 48 byte of multi-sig
+	multiSig := messagePayload[offset : offset+48]
+	offset += 48
+
+	// bitmap
+	bitmap := messagePayload[offset:]
+	//#### END Read payload data
+
+	// Verify the multi-sig of the view change votes
+	deserializedMultiSig := bls.Sign{}
+	if err := deserializedMultiSig.Deserialize(multiSig); err != nil {
+		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for view change", "Error", err, "viewID", viewID)
+		return
+	}
+	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	if err != nil || mask.SetMask(bitmap) != nil {
+		utils.GetLogInstance().Warn("Invalid bitmap for view change", "viewID", viewID)
+		return
+	}
+	if !consensus.QuorumPolicy.Check(mask) {
+		utils.GetLogInstance().Warn("Not enough view change votes", "num", mask.CountEnabled(), "viewID", viewID)
+		return
+	}
+	if !deserializedMultiSig.VerifyHash(mask.AggregatePublic, viewChangeDigest(consensus.consensusID, viewID)) {
+		utils.GetLogInstance().Warn("Failed to verify the multi signature for view change", "viewID", viewID)
+		return
+	}
+
+	newLeader, ok := consensus.getPeerByPubKey(leaderKey)
+	if !ok {
+		newLeader = p2p.Peer{PubKey: leaderKey}
+	}
+	consensus.switchView(viewID, newLeader)
+}
+
+// switchView moves the consensus to the given view led by the given leader.
+// The caller must hold the consensus mutex.
+func (consensus *Consensus) switchView(viewID uint32, leader p2p.Peer) {
+	utils.GetLogInstance().Info("Switching to new view", "viewID", viewID, "leaderIP", leader.IP, "leaderPort", leader.Port)
+	consensus.stopTimer()
+	consensus.applyView(viewID, leader)
+
+	// The rounds still collecting commits are left to the new view.
+	consensus.Tracer.finishAll(EventViewChanged)
+	consensus.writeState(Finished)
+
+	if consensus.IsLeader && consensus.ReadySignal == nil {
+		consensus.ReadySignal = make(chan struct{})
+	}
+	if consensus.OnViewChange != nil {
+		consensus.OnViewChange(consensus.IsLeader)
+	}
+	if !consensus.IsLeader {
+		consensus.resetTimer(announceTimeout)
+	}
+}
+
+// applyView sets the given view and leader, and drops the rounds of the old view.
+// It has no side effect beyond the consensus state, so that it can be used to
+// replay the write-ahead log. The caller must hold the consensus mutex.
+func (consensus *Consensus) applyView(viewID uint32, leader p2p.Peer) {
+	// The old leader becomes an ordinary member of the committee.
+	oldLeader := consensus.leader
+	if oldLeader.PubKey != nil && !oldLeader.PubKey.IsEqual(leader.PubKey) && !oldLeader.PubKey.IsEqual(consensus.pubKey) {
+		consensus.validators.Store(getPeerKey(oldLeader.PubKey), oldLeader)
+	}
+	consensus.validators.Delete(getPeerKey(leader.PubKey))
+
+	consensus.leader = leader
+	consensus.IsLeader = leader.PubKey.IsEqual(consensus.pubKey)
+	consensus.viewID = viewID
+	consensus.nextViewID = viewID
+	consensus.viewChangeSigs = map[string]*bls.Sign{}
+	consensus.viewChangeBitmap = nil
+	consensus.viewChangePreparedHash = nil
+
+	consensus.ResetState()
+	consensus.dropPendingRounds()
+}
@@ -0,0 +1,72 @@
+package consensus
+
+import (
+	"bytes"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/api/proto"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Construct the view change message to send to the leader of the next view.
+func (consensus *Consensus) constructViewChangeMessage() []byte {
+	message := consensus_proto.Message{}
+	message.Type = consensus_proto.MessageType_VIEWCHANGE
+
+	consensus.populateMessageFields(&message)
+	message.ViewId = consensus.nextViewID
+
+	//// Payload
+	buffer := bytes.NewBuffer([]byte{})
+
+	// 48 byte of bls signature on the view change
+	sign := consensus.priKey.SignHash(viewChangeDigest(consensus.consensusID, consensus.nextViewID))
+	buffer.Write(sign.Serialize())
+
+	// Prepared certificate, if the block already got enough prepares
+	if consensus.round.aggregatedPrepareSig != nil && consensus.round.prepareBitmap != nil {
+		// 48 bytes aggregated signature
+		buffer.Write(consensus.round.aggregatedPrepareSig.Serialize())
+		// Bitmap
+		buffer.Write(consensus.round.prepareBitmap.Bitmap)
+	} else {
+		message.BlockHash = nil
+	}
+
+	message.Payload = buffer.Bytes()
+	//// END Payload
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the ViewChange message", "error", err)
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage)
+}
+
+// Construct the new view message, carrying the aggregated view change votes.
+func (consensus *Consensus) constructNewViewMessage(aggSig *bls.Sign, bitmap []byte) []byte {
+	message := consensus_proto.Message{}
+	message.Type = consensus_proto.MessageType_NEWVIEW
+
+	consensus.populateMessageFields(&message)
+	message.BlockHash = nil
+
+	//// Payload
+	buffer := bytes.NewBuffer([]byte{})
+
+	// 48 bytes aggregated signature
+	buffer.Write(aggSig.Serialize())
+
+	// Bitmap
+	buffer.Write(bitmap)
+
+	message.Payload = buffer.Bytes()
+	//// END Payload
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the NewView message", "error", err)
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage)
+}
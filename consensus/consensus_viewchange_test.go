@@ -0,0 +1,120 @@
+package consensus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderKeyForView(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "6666"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+
+	validators := make([]p2p.Peer, 3)
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 6677+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		_, validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+	reversed := []p2p.Peer{validators[2], validators[1], validators[0]}
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader).AnyTimes()
+
+	consensus1 := New(m, "0", validators, leader)
+	consensus2 := New(m, "0", reversed, leader)
+
+	assert.True(test, consensus1.leaderKeyForView(0).IsEqual(leader.PubKey), "view 0 should be led by the current leader")
+	for viewID := uint32(1); viewID < 4; viewID++ {
+		key1 := consensus1.leaderKeyForView(viewID)
+		key2 := consensus2.leaderKeyForView(viewID)
+		assert.True(test, key1.IsEqual(key2), "leader of view %d should not depend on the order of peers", viewID)
+		assert.False(test, key1.IsEqual(leader.PubKey), "view %d should be led by another member", viewID)
+	}
+	assert.True(test, consensus1.leaderKeyForView(4).IsEqual(leader.PubKey), "leadership should rotate back after a full round")
+}
+
+func TestViewChange(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "6766"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+
+	validators := make([]p2p.Peer, 3)
+	for i := 0; i < 3; i++ {
+		port := fmt.Sprintf("%d", 6777+i)
+		validators[i] = p2p.Peer{IP: ip, Port: port, ValidatorID: i + 1}
+		_, validators[i].PubKey = utils.GenKey(validators[i].IP, validators[i].Port)
+	}
+
+	sent := make(chan []byte, 10)
+	consensusValidators := make([]*Consensus, 3)
+	for i := 0; i < 3; i++ {
+		m := mock_host.NewMockHost(ctrl)
+		m.EXPECT().GetSelfPeer().Return(validators[i]).AnyTimes()
+		m.EXPECT().SendMessage(gomock.Any(), gomock.Any()).Do(func(peer p2p.Peer, message []byte) {
+			sent <- message
+		}).AnyTimes()
+		consensusValidators[i] = New(m, "0", validators, leader)
+	}
+
+	nextLeaderKey := consensusValidators[0].leaderKeyForView(1)
+	var nextLeader *Consensus
+	for _, consensus := range consensusValidators {
+		if consensus.pubKey.IsEqual(nextLeaderKey) {
+			nextLeader = consensus
+		}
+	}
+	if nextLeader == nil {
+		test.Fatal("next leader is not one of the validators")
+	}
+
+	// The other validators vote for the view change.
+	for _, consensus := range consensusValidators {
+		if consensus == nextLeader {
+			continue
+		}
+		consensus.nextViewID = 1
+		msg := consensus.constructViewChangeMessage()
+		nextLeader.ProcessMessageValidator(msg[1:])
+	}
+
+	assert.True(test, nextLeader.IsLeader, "next leader should take over after enough view change votes")
+	assert.Equal(test, uint32(1), nextLeader.viewID)
+	assert.Equal(test, Finished, nextLeader.round.state)
+
+	// The new view message convinces the other validators.
+	var newView []byte
+	select {
+	case newView = <-sent:
+	case <-time.After(3 * time.Second):
+		test.Fatal("new view message is not sent")
+	}
+	for _, consensus := range consensusValidators {
+		if consensus == nextLeader {
+			continue
+		}
+		// A new view of another round is ignored.
+		consensus.consensusID++
+		consensus.ProcessMessageValidator(newView[5:][1:])
+		assert.Equal(test, uint32(0), consensus.viewID, "new view of another round should be rejected")
+		consensus.consensusID--
+
+		consensus.ProcessMessageValidator(newView[5:][1:])
+		consensus.stopTimer()
+
+		assert.False(test, consensus.IsLeader)
+		assert.Equal(test, uint32(1), consensus.viewID)
+		assert.True(test, consensus.leader.PubKey.IsEqual(nextLeaderKey), "validator should follow the new leader")
+	}
+}
@@ -0,0 +1,500 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// WALDir is the directory of the consensus write-ahead logs, which keep the
+// votes and phase transitions of the in-flight round across restarts.
+// The write-ahead log is disabled if WALDir is empty.
+var WALDir string
+
+// Types of the write-ahead log records.
+const (
+	walStateRecord byte = iota // phase transition
+	walVoteRecord              // vote signed by this node or collected by the leader
+)
+
+// Size limit of a single record, so that a corrupted length doesn't make the
+// whole file be read into memory.
+const maxWALRecordSize = 64 * 1024 * 1024
+
+// Each record is stored as 4 byte of length, 4 byte of crc32 checksum and the rlp encoded record.
+const walRecordHeaderSize = 8
+
+// walRecord is an entry of the consensus write-ahead log.
+type walRecord struct {
+	Type        byte
+	ConsensusID uint32
+	ViewID      uint32
+
+	// Phase transition: the view the node is voting to move to, the new state,
+	// the block of the round and the aggregated prepare signature if known.
+	NextViewID     uint32
+	State          uint32
+	BlockHash      []byte
+	Block          []byte
+	PreparedSig    []byte
+	PreparedBitmap []byte
+
+	// Vote: the message type, the signer, the signed hash and the signature.
+	MsgType uint32
+	PubKey  []byte
+	Hash    []byte
+	Sig     []byte
+}
+
+// walSegment is a file of the write-ahead log.
+type walSegment struct {
+	path       string
+	seq        uint64
+	numRecords int
+	// The latest round recorded in the segment
+	lastConsensusID uint32
+}
+
+// consensusWAL is the append-only write-ahead log. It is split into segment files,
+// so that the records of the committed rounds are dropped by deleting or truncating
+// whole segments instead of rewriting the log.
+type consensusWAL struct {
+	path     string
+	segments []*walSegment
+	// The last segment, which the records are appended to
+	file *os.File
+	// The records of the rounds not pruned yet
+	records []*walRecord
+}
+
+// WALFileName returns the path of the write-ahead log of the node with the
+// given ip and port, which is named after the chain database of the node.
+// The segments of the log are stored at the path followed by their sequence number.
+func WALFileName(ip, port string) string {
+	return filepath.Join(WALDir, fmt.Sprintf("harmony_%s_%s.wal", ip, port))
+}
+
+// segmentPath returns the path of the segment of the given sequence number.
+func (wal *consensusWAL) segmentPath(seq uint64) string {
+	return fmt.Sprintf("%s.%d", wal.path, seq)
+}
+
+// openWAL opens the write-ahead log at path and reads the records in it.
+// A partially written record at the end of a segment, left by a crash, is discarded.
+func openWAL(path string) (*consensusWAL, []*walRecord, error) {
+	wal := &consensusWAL{path: path}
+	paths, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, segmentPath := range paths {
+		seq, err := strconv.ParseUint(strings.TrimPrefix(segmentPath, path+"."), 10, 64)
+		if err != nil {
+			continue
+		}
+		wal.segments = append(wal.segments, &walSegment{path: segmentPath, seq: seq})
+	}
+	sort.Slice(wal.segments, func(i, j int) bool {
+		return wal.segments[i].seq < wal.segments[j].seq
+	})
+	if len(wal.segments) == 0 {
+		wal.segments = []*walSegment{{path: wal.segmentPath(0)}}
+	}
+
+	records := []*walRecord{}
+	for i, segment := range wal.segments {
+		file, err := os.OpenFile(segment.path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		segmentRecords, size := readWALRecords(file)
+		for _, record := range segmentRecords {
+			if record.ConsensusID > segment.lastConsensusID {
+				segment.lastConsensusID = record.ConsensusID
+			}
+		}
+		segment.numRecords = len(segmentRecords)
+		records = append(records, segmentRecords...)
+
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		if i < len(wal.segments)-1 {
+			file.Close()
+			continue
+		}
+		if _, err := file.Seek(size, io.SeekStart); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		wal.file = file
+	}
+	wal.records = records
+	return wal, records, nil
+}
+
+// readWALRecords reads the records until the first incomplete or corrupted one,
+// and returns them with the total size of the valid records.
+func readWALRecords(r io.Reader) ([]*walRecord, int64) {
+	records := []*walRecord{}
+	size := int64(0)
+	header := make([]byte, walRecordHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		checksum := binary.BigEndian.Uint32(header[4:])
+		if length > maxWALRecordSize {
+			break
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(data) != checksum {
+			break
+		}
+		record := &walRecord{}
+		if err := rlp.DecodeBytes(data, record); err != nil {
+			break
+		}
+		records = append(records, record)
+		size += walRecordHeaderSize + int64(length)
+	}
+	return records, size
+}
+
+// write appends the record to the log. If sync is set, it returns after the record
+// and the ones written before it are on the disk; otherwise the record is only
+// guaranteed to be on the disk once a later record is synced.
+func (wal *consensusWAL) write(record *walRecord, sync bool) error {
+	data, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxWALRecordSize {
+		return errors.New("consensus WAL record is too large")
+	}
+	buf := make([]byte, walRecordHeaderSize, walRecordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(data))
+	buf = append(buf, data...)
+	if _, err := wal.file.Write(buf); err != nil {
+		return err
+	}
+	if sync {
+		if err := wal.file.Sync(); err != nil {
+			return err
+		}
+	}
+	segment := wal.segments[len(wal.segments)-1]
+	segment.numRecords++
+	if record.ConsensusID > segment.lastConsensusID {
+		segment.lastConsensusID = record.ConsensusID
+	}
+	wal.records = append(wal.records, record)
+	return nil
+}
+
+// prune removes the records of the rounds before consensusID from the log. The
+// older segments holding no later record are deleted. The last segment is truncated
+// if it holds no later record either, and otherwise the records from now on are
+// written into a new segment, which lets the last one be deleted at a later prune.
+func (wal *consensusWAL) prune(consensusID uint32) error {
+	records := wal.records[:0]
+	for _, record := range wal.records {
+		if record.ConsensusID >= consensusID {
+			records = append(records, record)
+		}
+	}
+	wal.records = records
+
+	last := wal.segments[len(wal.segments)-1]
+	segments := []*walSegment{}
+	for _, segment := range wal.segments[:len(wal.segments)-1] {
+		if segment.lastConsensusID < consensusID {
+			if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	wal.segments = append(segments, last)
+
+	if last.numRecords == 0 {
+		return nil
+	}
+	if last.lastConsensusID < consensusID {
+		if err := wal.file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := wal.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		last.numRecords = 0
+		last.lastConsensusID = 0
+		return nil
+	}
+	return wal.rotate()
+}
+
+// rotate starts a new segment which the records are appended to from now on.
+func (wal *consensusWAL) rotate() error {
+	seq := wal.segments[len(wal.segments)-1].seq + 1
+	path := wal.segmentPath(seq)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	wal.file.Close()
+	wal.file = file
+	wal.segments = append(wal.segments, &walSegment{path: path, seq: seq})
+	return nil
+}
+
+// initWAL opens the write-ahead log at path and restores the round recorded in it.
+func (consensus *Consensus) initWAL(path string) {
+	wal, records, err := openWAL(path)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to open the consensus WAL", "path", path, "error", err)
+		return
+	}
+	consensus.wal = wal
+	consensus.replayWAL(records)
+}
+
+// writeWAL appends the record to the write-ahead log, if it is enabled, and waits
+// for it to be on the disk if sync is set.
+func (consensus *Consensus) writeWAL(record *walRecord, sync bool) error {
+	consensus.walLock.Lock()
+	defer consensus.walLock.Unlock()
+
+	if consensus.wal == nil {
+		return nil
+	}
+	err := consensus.wal.write(record, sync)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to write the consensus WAL", "error", err)
+	}
+	return err
+}
+
+// enterState moves the consensus to the given phase after recording the phase
+// transition in the write-ahead log.
+func (consensus *Consensus) enterState(state State) {
+	consensus.writeState(state)
+	consensus.round.state = state
+}
+
+// writeState records the phase transition of the current round in the write-ahead log.
+func (consensus *Consensus) writeState(state State) {
+	record := &walRecord{
+		Type:        walStateRecord,
+		ConsensusID: consensus.consensusID,
+		ViewID:      consensus.viewID,
+		NextViewID:  consensus.nextViewID,
+		State:       uint32(state),
+		BlockHash:   consensus.round.blockHash[:],
+	}
+	// The block is only needed to finish the round after a restart
+	if state == AnnounceDone || state == PrepareDone {
+		record.Block = consensus.round.block
+	}
+	if consensus.round.aggregatedPrepareSig != nil && consensus.round.prepareBitmap != nil {
+		record.PreparedSig = consensus.round.aggregatedPrepareSig.Serialize()
+		record.PreparedBitmap = consensus.round.prepareBitmap.Bitmap
+	}
+	consensus.writeWAL(record, true)
+}
+
+// writeVote records a vote of the round of consensusID in the write-ahead log.
+// The votes of this node are synced before they are sent. The ones collected from
+// the other members are batched and synced along with the next phase transition,
+// as the leader can collect them again.
+func (consensus *Consensus) writeVote(consensusID uint32, msgType consensus_proto.MessageType, pubKey *bls.PublicKey, hash []byte, sign *bls.Sign) error {
+	return consensus.writeWAL(&walRecord{
+		Type:        walVoteRecord,
+		ConsensusID: consensusID,
+		ViewID:      consensus.viewID,
+		MsgType:     uint32(msgType),
+		PubKey:      pubKey.Serialize(),
+		Hash:        hash,
+		Sig:         sign.Serialize(),
+	}, pubKey.IsEqual(consensus.pubKey))
+}
+
+// signVote signs the hash as the vote of this node of the given type in the
+// current round. The vote is written into the write-ahead log before it can be
+// sent, and nil is returned if this node already voted for another hash in the
+// round, so that it never double signs even across restarts. Nil is also returned
+// once this node left the committee of the shard.
+func (consensus *Consensus) signVote(msgType consensus_proto.MessageType, hash []byte) *bls.Sign {
+	if consensus.leftCommittee {
+		return nil
+	}
+	key := voteKey{consensusID: consensus.consensusID, viewID: consensus.viewID, msgType: msgType, pubKey: getPeerKey(consensus.pubKey)}
+
+	consensus.walLock.Lock()
+	signed, ok := consensus.signedVotes[key]
+	consensus.walLock.Unlock()
+	if ok && !bytes.Equal(signed, hash) {
+		utils.GetLogInstance().Warn("Refusing to sign a conflicting vote", "msgType", msgType, "consensusID", consensus.consensusID, "viewID", consensus.viewID)
+		return nil
+	}
+
+	sign := consensus.priKey.SignHash(hash)
+	if sign == nil || consensus.writeVote(consensus.consensusID, msgType, consensus.pubKey, hash, sign) != nil {
+		return nil
+	}
+
+	consensus.walLock.Lock()
+	consensus.signedVotes[key] = append([]byte{}, hash...)
+	consensus.walLock.Unlock()
+	return sign
+}
+
+// pruneWAL drops the records of the committed rounds from the write-ahead log
+// and records the start of the current round if it has no records yet.
+func (consensus *Consensus) pruneWAL() {
+	consensusID := consensus.consensusID
+	for id := range consensus.pendingRounds {
+		if id < consensusID {
+			consensusID = id
+		}
+	}
+
+	started := false
+	consensus.walLock.Lock()
+	for key := range consensus.signedVotes {
+		if key.consensusID < consensusID {
+			delete(consensus.signedVotes, key)
+		}
+	}
+	if consensus.wal != nil {
+		if err := consensus.wal.prune(consensusID); err != nil {
+			utils.GetLogInstance().Error("Failed to prune the consensus WAL", "error", err)
+		}
+		for _, record := range consensus.wal.records {
+			if record.ConsensusID == consensus.consensusID {
+				started = true
+			}
+		}
+	}
+	consensus.walLock.Unlock()
+
+	if !started {
+		consensus.writeState(Finished)
+	}
+}
+
+// replayWAL restores the rounds recorded in the write-ahead log: the view,
+// the phase, the block and the votes signed or collected so far.
+func (consensus *Consensus) replayWAL(records []*walRecord) {
+	if len(records) == 0 {
+		return
+	}
+	for _, record := range records {
+		if record.ConsensusID > consensus.consensusID {
+			consensus.restoreRound(record.ConsensusID)
+		}
+		switch record.Type {
+		case walStateRecord:
+			consensus.restoreState(record)
+		case walVoteRecord:
+			consensus.restoreVote(record)
+		}
+	}
+	utils.GetLogInstance().Info("Restored consensus from WAL", "consensusID", consensus.consensusID, "viewID", consensus.viewID, "state", consensus.round.state)
+}
+
+// restoreRound moves the replay on to the round of consensusID. The leader keeps
+// the prepared round collecting commits while the next one was started.
+func (consensus *Consensus) restoreRound(consensusID uint32) {
+	if consensus.IsLeader && consensus.round.state == PreparedDone {
+		consensus.pendingRounds[consensus.consensusID] = consensus.round
+	}
+	consensus.consensusID = consensusID
+	consensus.ResetState()
+}
+
+// restoreState applies a phase transition record.
+func (consensus *Consensus) restoreState(record *walRecord) {
+	if record.ViewID != consensus.viewID {
+		leaderKey := consensus.leaderKeyForView(record.ViewID)
+		if leaderKey == nil {
+			utils.GetLogInstance().Warn("No leader available for the view in WAL", "viewID", record.ViewID)
+			return
+		}
+		leader, ok := consensus.getPeerByPubKey(leaderKey)
+		if !ok {
+			utils.GetLogInstance().Warn("Unknown peer of the leader in WAL", "viewID", record.ViewID)
+			return
+		}
+		consensus.applyView(record.ViewID, leader)
+	}
+
+	consensus.consensusID = record.ConsensusID
+	consensus.nextViewID = record.NextViewID
+	consensus.round.state = State(record.State)
+	copy(consensus.round.blockHash[:], record.BlockHash)
+	if len(record.Block) > 0 {
+		consensus.round.block = record.Block
+		consensus.blocksReceived[record.ConsensusID] = &BlockConsensusStatus{record.Block, consensus.round.state}
+	}
+	if len(record.PreparedSig) > 0 {
+		var multiSig bls.Sign
+		mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
+		if err == nil && multiSig.Deserialize(record.PreparedSig) == nil && mask.SetMask(record.PreparedBitmap) == nil {
+			consensus.round.aggregatedPrepareSig = &multiSig
+			consensus.round.prepareBitmap = mask
+		}
+	}
+}
+
+// restoreVote applies a vote record.
+func (consensus *Consensus) restoreVote(record *walRecord) {
+	pubKey := &bls.PublicKey{}
+	if err := pubKey.Deserialize(record.PubKey); err != nil {
+		return
+	}
+	sign := &bls.Sign{}
+	if err := sign.Deserialize(record.Sig); err != nil {
+		return
+	}
+	msgType := consensus_proto.MessageType(record.MsgType)
+	key := getPeerKey(pubKey)
+
+	if pubKey.IsEqual(consensus.pubKey) {
+		consensus.signedVotes[voteKey{consensusID: record.ConsensusID, viewID: record.ViewID, msgType: msgType, pubKey: key}] = record.Hash
+	}
+	// The leader keeps collecting the votes from where it stopped.
+	round := consensus.roundOf(record.ConsensusID)
+	if !consensus.IsLeader || record.ViewID != consensus.viewID || round == nil {
+		return
+	}
+	switch msgType {
+	case consensus_proto.MessageType_PREPARE:
+		round.prepareSigs[key] = sign
+		round.prepareBitmap.SetKey(pubKey, true)
+	case consensus_proto.MessageType_COMMIT:
+		round.commitSigs[key] = sign
+		round.commitBitmap.SetKey(pubKey, true)
+	}
+}
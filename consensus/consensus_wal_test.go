@@ -0,0 +1,157 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	mock_host "github.com/harmony-one/harmony/p2p/host/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWALRestoresRound(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	dir, err := ioutil.TempDir("", "consensus_wal")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	WALDir = dir
+	defer func() { WALDir = "" }()
+
+	leader := p2p.Peer{IP: ip, Port: "7982"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: ip, Port: "7984", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(validator).AnyTimes()
+
+	consensus := New(m, "0", []p2p.Peer{validator}, leader)
+	consensus.consensusID = 5
+	consensus.round.blockHash = blockHash
+	consensus.round.block = []byte{1, 2, 3}
+	assert.NotNil(test, consensus.constructPrepareMessage())
+	consensus.enterState(PrepareDone)
+
+	// Restart in the middle of the round, after a view change
+	consensus.mutex.Lock()
+	consensus.switchView(1, leader)
+	consensus.stopTimer()
+	consensus.mutex.Unlock()
+	consensus.round.blockHash = blockHash
+	consensus.round.block = []byte{1, 2, 3}
+	assert.NotNil(test, consensus.constructPrepareMessage())
+	consensus.enterState(PrepareDone)
+	numRecords := len(consensus.wal.records)
+
+	restarted := New(m, "0", []p2p.Peer{validator}, leader)
+	assert.Equal(test, numRecords, len(restarted.wal.records), "replay should not write into the log")
+	assert.Equal(test, uint32(1), restarted.viewID)
+	assert.Equal(test, uint32(5), restarted.consensusID)
+	assert.Equal(test, PrepareDone, restarted.round.state)
+	assert.Equal(test, blockHash, restarted.round.blockHash)
+	if assert.Contains(test, restarted.blocksReceived, uint32(5)) {
+		assert.Equal(test, []byte{1, 2, 3}, restarted.blocksReceived[5].block)
+	}
+
+	restarted.round.blockHash = [32]byte{9}
+	assert.Nil(test, restarted.constructPrepareMessage(), "should not vote for another block in the same round")
+	restarted.round.blockHash = blockHash
+	assert.NotNil(test, restarted.constructPrepareMessage(), "should vote for the same block again")
+
+	// The finished round is dropped from the log
+	restarted.consensusID++
+	restarted.ResetState()
+	restarted.pruneWAL()
+	next := New(m, "0", []p2p.Peer{validator}, leader)
+	assert.Equal(test, uint32(6), next.consensusID)
+	assert.Equal(test, Finished, next.round.state)
+	next.round.blockHash = [32]byte{9}
+	assert.NotNil(test, next.constructPrepareMessage())
+}
+
+func TestWALDropsPartialRecord(test *testing.T) {
+	dir, err := ioutil.TempDir("", "consensus_wal")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.wal")
+
+	wal, records, err := openWAL(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	assert.Equal(test, 0, len(records))
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 1}, true))
+	assert.Nil(test, wal.write(&walRecord{Type: walVoteRecord, ConsensusID: 1, Hash: []byte{1}}, false))
+	// A crash in the middle of writing a record
+	wal.file.Write([]byte{0, 0, 0, 100, 1, 2})
+	wal.file.Close()
+
+	wal, records, err = openWAL(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	assert.Equal(test, 2, len(records))
+	assert.Equal(test, []byte{1}, records[1].Hash)
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 2}, true))
+	wal.file.Close()
+
+	wal, records, err = openWAL(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer wal.file.Close()
+	assert.Equal(test, 3, len(records))
+	assert.Equal(test, uint32(2), records[2].ConsensusID)
+}
+
+func TestWALPruneDropsSegments(test *testing.T) {
+	dir, err := ioutil.TempDir("", "consensus_wal")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.wal")
+
+	wal, _, err := openWAL(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	// The leader collects the commits of round 1 while round 2 is started.
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 1}, true))
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 2}, true))
+
+	// Round 1 is committed while round 2 still runs: a new segment is started.
+	assert.Nil(test, wal.prune(2))
+	assert.Equal(test, 2, len(wal.segments))
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 3}, true))
+
+	// Round 2 is committed: the first segment goes away.
+	assert.Nil(test, wal.prune(3))
+	_, err = os.Stat(wal.segmentPath(0))
+	assert.True(test, os.IsNotExist(err), "segment of the committed rounds should be deleted")
+	assert.Nil(test, wal.prune(4))
+	assert.Equal(test, 1, len(wal.segments))
+
+	// Every round recorded is committed: the last segment is truncated instead.
+	assert.Nil(test, wal.write(&walRecord{Type: walStateRecord, ConsensusID: 4}, true))
+	assert.Nil(test, wal.prune(5))
+	assert.Equal(test, 1, len(wal.segments))
+	wal.file.Close()
+
+	wal, records, err := openWAL(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer wal.file.Close()
+	assert.Empty(test, records)
+}
@@ -28,6 +28,9 @@ type ChainReader interface {
 
 	// GetBlock retrieves a block from the database by hash and number.
 	GetBlock(hash common.Hash, number uint64) *types.Block
+
+	// ReadShardState retrieves sharding state given the epoch number.
+	ReadShardState(epoch uint64) types.ShardState
 }
 
 // Engine is an algorithm agnostic consensus engine.
@@ -70,6 +73,10 @@ type Engine interface {
 	// than one result may also be returned depending on the consensus algorithm.
 	Seal(chain ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error
 
+	// VerifyEvidence checks whether a double sign evidence proves the misbehavior of
+	// a member of the committee, and may be included in the block of the given header.
+	VerifyEvidence(chain ChainReader, header *types.Header, evidence *types.DoubleSignEvidence) error
+
 	// SealHash returns the hash of a block prior to it being sealed.
 	SealHash(header *types.Header) common.Hash
 }
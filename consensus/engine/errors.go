@@ -38,6 +38,33 @@ var (
 	// ErrConsensusIDNotMatch is returned if the current consensusID is not equal message's consensusID
 	ErrConsensusIDNotMatch = errors.New("consensusID not match")
 
+	// ErrViewIDNotMatch is returned if the current viewID is not equal message's viewID
+	ErrViewIDNotMatch = errors.New("viewID not match")
+
 	// ErrInvalidConsensusMessage is returned is the consensus message received is invalid
 	ErrInvalidConsensusMessage = errors.New("invalid consensus message")
+
+	// ErrNotEnoughSigners is returned if the bitmap of a block seal doesn't reach the quorum of the committee
+	ErrNotEnoughSigners = errors.New("not enough signers")
+
+	// ErrInvalidPrepareSignature is returned if the aggregated prepare signature of a block is invalid
+	ErrInvalidPrepareSignature = errors.New("invalid prepare signature")
+
+	// ErrInvalidCommitSignature is returned if the aggregated commit signature of a block is invalid
+	ErrInvalidCommitSignature = errors.New("invalid commit signature")
+
+	// ErrInvalidRandomness is returned if the randomness of an epoch block doesn't match its preimage
+	ErrInvalidRandomness = errors.New("invalid randomness")
+
+	// ErrUnexpectedRandomness is returned if a block out of the epoch boundary commits randomness
+	ErrUnexpectedRandomness = errors.New("unexpected randomness")
+
+	// ErrInvalidEvidence is returned if a double sign evidence doesn't prove the double signing
+	ErrInvalidEvidence = errors.New("invalid double sign evidence")
+
+	// ErrExpiredEvidence is returned if a double sign evidence is too old or too new for the block including it
+	ErrExpiredEvidence = errors.New("expired double sign evidence")
+
+	// ErrUnknownOffender is returned if the offender of a double sign evidence wasn't in the committee signing the block
+	ErrUnknownOffender = errors.New("double sign evidence of a key out of the committee")
 )
@@ -67,6 +67,23 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	if hash := types.DeriveSha(block.Evidences()); hash != header.EvidenceHash {
+		return fmt.Errorf("evidence root hash mismatch: have %x, want %x", hash, header.EvidenceHash)
+	}
+	for _, evidence := range block.Evidences() {
+		if err := v.engine.VerifyEvidence(v.bc, header, evidence); err != nil {
+			return fmt.Errorf("invalid double sign evidence %x: %v", evidence.Hash(), err)
+		}
+	}
+	if err := v.bc.ValidateEvidences(block); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(block.CrossShardReceipts()); hash != header.CrossShardReceiptHash {
+		return fmt.Errorf("cross-shard receipt root hash mismatch: have %x, want %x", hash, header.CrossShardReceiptHash)
+	}
+	if hash := types.DeriveSha(block.IncomingReceipts()); hash != header.IncomingReceiptHash {
+		return fmt.Errorf("incoming receipt root hash mismatch: have %x, want %x", hash, header.IncomingReceiptHash)
+	}
 	return nil
 }
 
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -43,6 +44,7 @@ import (
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	lru "github.com/hashicorp/golang-lru"
 )
@@ -65,11 +67,6 @@ const (
 	triesInMemory       = 128
 	shardCacheLimit     = 2
 
-	// BlocksPerEpoch is the number of blocks in one epoch
-	// currently set to small number for testing
-	// in future, this need to be adjusted dynamically instead of constant
-	BlocksPerEpoch = 5
-
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -80,6 +77,14 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	// StateRetention is the number of recent states kept in the database besides the ones
+	// of the epoch blocks, which are flushed to disk. The other states are pruned every
+	// that many blocks. 0 disables the pruning.
+	StateRetention uint64
+	// EpochStateRetention is the number of the last epochs whose epoch block states are
+	// kept by the pruning. 0 keeps the states of all the epoch blocks.
+	EpochStateRetention uint64
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -98,12 +103,15 @@ type CacheConfig struct {
 // canonical chain.
 type BlockChain struct {
 	chainConfig *params.ChainConfig // Chain & network configuration
+	chainParams *chainparams.Config // Harmony protocol parameters, from the Harmony section of the chain config
 	cacheConfig *CacheConfig        // Cache configuration for pruning
 
 	db     ethdb.Database // Low level persistent database to store final content in
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
+	lastPrune uint64 // Number of the block whose insertion last triggered the state pruning
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -142,6 +150,9 @@ type BlockChain struct {
 
 	badBlocks      *lru.Cache              // Bad block cache
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
+
+	newNodeList      func(epoch uint64) []types.NodeID // Function used to get the nodes joining in the given epoch from the staking transactions.
+	onEpochLastBlock func(block *types.Block)          // Function called once the last block of an epoch is inserted into the canonical chain.
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -192,6 +203,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	// The chains committed before the Harmony section was persisted run the default parameters
+	if bc.chainParams = rawdb.ReadChainParams(db, bc.genesisBlock.Hash()); bc.chainParams == nil {
+		bc.chainParams = chainparams.DefaultConfig
+	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
@@ -223,16 +238,6 @@ func (bc *BlockChain) ValidateNewBlock(block *types.Block, address common.Addres
 	return nil
 }
 
-// IsEpochBlock returns whether this block is the first block of an epoch.
-func IsEpochBlock(block *types.Block) bool {
-	return block.NumberU64()%BlocksPerEpoch == 0
-}
-
-// IsEpochLastBlock returns whether this block is the last block of an epoch.
-func IsEpochLastBlock(block *types.Block) bool {
-	return block.NumberU64()%BlocksPerEpoch == BlocksPerEpoch-1
-}
-
 func (bc *BlockChain) getProcInterrupt() bool {
 	return atomic.LoadInt32(&bc.procInterrupt) == 1
 }
@@ -696,6 +701,44 @@ func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
 	return bc.stateCache.TrieDB().Node(hash)
 }
 
+// SetStateRetention sets the number of recent states kept in the database besides the
+// ones of the epoch blocks of the last epochRetention epochs, or of all of them if it's 0,
+// and enables the pruning of the other states if retention is not 0.
+func (bc *BlockChain) SetStateRetention(retention uint64, epochRetention uint64) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+	bc.cacheConfig.StateRetention = retention
+	bc.cacheConfig.EpochStateRetention = epochRetention
+	bc.lastPrune = bc.CurrentBlock().NumberU64()
+}
+
+// stateRetention returns the number of recent states kept by the pruning, which is at
+// least the number of tries kept in memory, as they reference the nodes on disk.
+func (bc *BlockChain) stateRetention() uint64 {
+	retention := bc.cacheConfig.StateRetention
+	if retention > 0 && retention < triesInMemory {
+		retention = triesInMemory
+	}
+	return retention
+}
+
+// PruneState deletes the states of the chain but the ones of the recent blocks and of
+// the recent epoch blocks, from where the last pruning stopped, and returns the number of
+// trie nodes and contract codes deleted. The insertion of blocks waits for the pruning
+// to finish.
+func (bc *BlockChain) PruneState() (int, error) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+	if bc.cacheConfig.Disabled {
+		return 0, errors.New("state pruning disabled on archive nodes")
+	}
+	retention := bc.stateRetention()
+	if retention == 0 {
+		return 0, errors.New("state retention not set")
+	}
+	return PruneState(bc.db, bc.stateCache, bc.chainParams, bc.CurrentBlock().NumberU64(), retention, bc.cacheConfig.EpochStateRetention)
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -1010,6 +1053,26 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 				triedb.Dereference(root.(common.Hash))
 			}
 		}
+
+		if retention := bc.stateRetention(); retention > 0 {
+			// The states of the epoch blocks are never pruned, so they are flushed right away
+			if bc.chainParams.IsEpochBlock(block.NumberU64()) {
+				if err := triedb.Commit(root, false); err != nil {
+					return NonStatTy, err
+				}
+			}
+			// The pruning waits for the insertion of the blocks to finish
+			if block.NumberU64() >= bc.lastPrune+retention {
+				bc.lastPrune = block.NumberU64()
+				bc.wg.Add(1)
+				go func() {
+					defer bc.wg.Done()
+					if _, err := bc.PruneState(); err != nil {
+						log.Error("Failed to prune states", "err", err)
+					}
+				}()
+			}
+		}
 	}
 
 	// Write other block data using a batch.
@@ -1255,8 +1318,11 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		cache, _ := bc.stateCache.TrieDB().Size()
 		stats.report(chain, i, cache)
 
-		// only insert new shardstate when block is epoch block
+		// only insert new shardstate when block is the last block of an epoch
 		bc.InsertNewShardState(block)
+		if status == CanonStatTy && bc.onEpochLastBlock != nil && bc.chainParams.IsEpochLastBlock(block.NumberU64()) {
+			bc.onEpochLastBlock(block)
+		}
 	}
 	// Append a single chain head event if we've progressed the chain
 	if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
@@ -1624,6 +1690,9 @@ func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
 // Config retrieves the blockchain's chain configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
+// ChainParams retrieves the Harmony protocol parameters of the blockchain.
+func (bc *BlockChain) ChainParams() *chainparams.Config { return bc.chainParams }
+
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus_engine.Engine { return bc.engine }
 
@@ -1675,6 +1744,12 @@ func (bc *BlockChain) GetShardStateByNumber(number uint64) types.ShardState {
 	return bc.GetShardState(hash, number)
 }
 
+// ReadShardState retrieves sharding state given the epoch number, return nil if not exist.
+// The shard state of an epoch is recorded in the last block of the previous epoch.
+func (bc *BlockChain) ReadShardState(epoch uint64) types.ShardState {
+	return bc.GetShardStateByNumber(bc.chainParams.ShardStateBlockNumber(epoch))
+}
+
 // GetShardStateByHash retrieves the shard state given the blockhash, return nil if not exist
 func (bc *BlockChain) GetShardStateByHash(hash common.Hash) types.ShardState {
 	number := bc.hc.GetBlockNumber(hash)
@@ -1684,33 +1759,53 @@ func (bc *BlockChain) GetShardStateByHash(hash common.Hash) types.ShardState {
 	return bc.GetShardState(hash, *number)
 }
 
-// GetRandSeedByNumber retrieves the rand seed given the block number, return 0 if not exist
+// GetRandSeedByNumber retrieves the rand seed given the block number, return 0 if not exist.
+// The seed is taken from the randomness committed into the header.
 func (bc *BlockChain) GetRandSeedByNumber(number uint64) int64 {
 	header := bc.GetHeaderByNumber(number)
 	if header == nil {
 		return 0
 	}
-	return int64(header.RandSeed)
+	return int64(binary.BigEndian.Uint64(header.RandSeed[:8]))
 }
 
-// GetNewShardState will calculate (if not exist) and get the new shard state for epoch block or nil if block is not epoch block
-// epoch block is where the new shard state stored
+// GetNewShardState will calculate (if not exist) and get the new shard state for the last block of an epoch or nil if block is not the last one
+// the last block of an epoch is where the shard state of the next epoch is stored, it's resharded with the randomness the block commits
 func (bc *BlockChain) GetNewShardState(block *types.Block) types.ShardState {
 	hash := block.Hash()
-	// just ignore non-epoch block
-	if !IsEpochBlock(block) {
+	// just ignore the blocks but the last one of an epoch
+	if !bc.chainParams.IsEpochLastBlock(block.NumberU64()) {
 		return nil
 	}
 	number := block.NumberU64()
 	shardState := bc.GetShardState(hash, number)
 	if shardState == nil {
-		epoch := GetEpochFromBlockNumber(number)
-		shardState = CalculateNewShardState(bc, epoch)
+		epoch := bc.chainParams.EpochOf(number) + 1
+		var newNodeList []types.NodeID
+		if bc.newNodeList != nil {
+			newNodeList = bc.newNodeList(epoch)
+		}
+		rnd := int64(binary.BigEndian.Uint64(block.Header().RandSeed[:8]))
+		shardState = CalculateNewShardState(bc, epoch, newNodeList, rnd)
 		bc.shardStateCache.Add(hash, shardState)
 	}
 	return shardState
 }
 
+// SetNewNodeListReader sets the function returning the nodes which join in the given
+// epoch, i.e. the nodes which staked during the previous epoch before its last block, for
+// resharding.
+func (bc *BlockChain) SetNewNodeListReader(newNodeList func(epoch uint64) []types.NodeID) {
+	bc.newNodeList = newNodeList
+}
+
+// SetEpochLastBlockHandler sets the function called once the last block of an epoch is
+// inserted into the canonical chain, e.g. to hand off the consensus to the committee of the
+// next epoch. It's called with the chain lock held, so it mustn't insert into the chain.
+func (bc *BlockChain) SetEpochLastBlockHandler(onEpochLastBlock func(block *types.Block)) {
+	bc.onEpochLastBlock = onEpochLastBlock
+}
+
 // ValidateNewShardState validate whether the new shard state root matches
 func (bc *BlockChain) ValidateNewShardState(block *types.Block) error {
 	shardState := bc.GetNewShardState(block)
@@ -1724,7 +1819,45 @@ func (bc *BlockChain) ValidateNewShardState(block *types.Block) error {
 	return nil
 }
 
-// InsertNewShardState insert new shard state into epoch block
+// ValidateEvidences checks that every double signing is proven once: by no two
+// evidences of the block, and by no evidence of the chain the block extends.
+func (bc *BlockChain) ValidateEvidences(block *types.Block) error {
+	offenses := make(map[types.DoubleSignOffense]bool)
+	for _, evidence := range block.Evidences() {
+		offense, err := evidence.Offense()
+		if err != nil {
+			return fmt.Errorf("invalid double sign evidence %x: %v", evidence.Hash(), err)
+		}
+		if offenses[offense] {
+			return fmt.Errorf("double sign evidence %x repeated in the block", evidence.Hash())
+		}
+		offenses[offense] = true
+		if bc.HasEvidence(evidence, block.ParentHash(), block.NumberU64()-1) {
+			return fmt.Errorf("double sign evidence %x already in the chain", evidence.Hash())
+		}
+	}
+	return nil
+}
+
+// HasEvidence returns whether the double signing proven by the evidence is proven
+// in the block of the given hash and number or one of its ancestors. The search
+// stops at the double signed block, as no evidence of it can be older.
+func (bc *BlockChain) HasEvidence(evidence *types.DoubleSignEvidence, hash common.Hash, number uint64) bool {
+	offense, err := evidence.Offense()
+	if err != nil {
+		return false
+	}
+	for block := bc.GetBlock(hash, number); block != nil && block.NumberU64() >= evidence.BlockNumber && block.NumberU64() > 0; block = bc.GetBlock(block.ParentHash(), block.NumberU64()-1) {
+		for _, included := range block.Evidences() {
+			if includedOffense, err := included.Offense(); err == nil && includedOffense == offense {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InsertNewShardState insert new shard state into the last block of an epoch
 func (bc *BlockChain) InsertNewShardState(block *types.Block) {
 	shardState := bc.GetNewShardState(block)
 	if shardState == nil {
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	protobuf "github.com/golang/protobuf/proto"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+// testEvidence returns an evidence of the given validator double signing the prepare
+// of the block of the given number, for the given pair of blocks.
+func testEvidence(pubKey string, number uint64, hash1, hash2 byte) *types.DoubleSignEvidence {
+	message := func(hash byte) []byte {
+		encoded, _ := protobuf.Marshal(&consensus_proto.Message{Type: consensus_proto.MessageType_PREPARE, ConsensusId: uint32(number - 1), BlockHash: []byte{hash}})
+		return encoded
+	}
+	return &types.DoubleSignEvidence{BlockNumber: number, PubKey: []byte(pubKey), Message1: message(hash1), Message2: message(hash2)}
+}
+
+func TestValidateEvidences(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The offense of the first validator is proven in block 1
+	first := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()})
+	first.AddEvidences([]*types.DoubleSignEvidence{testEvidence("validator1", 1, 1, 2)})
+	rawdb.WriteBlock(db, first)
+
+	newBlock := func(evidences ...*types.DoubleSignEvidence) *types.Block {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), ParentHash: first.Hash()})
+		block.AddEvidences(evidences)
+		return block
+	}
+	if err := chain.ValidateEvidences(newBlock(testEvidence("validator2", 1, 1, 2), testEvidence("validator1", 2, 1, 2))); err != nil {
+		t.Errorf("evidences of new offenses should be valid, got %v", err)
+	}
+	if err := chain.ValidateEvidences(newBlock(testEvidence("validator1", 1, 2, 1))); err == nil {
+		t.Error("an offense already proven in the chain should be rejected")
+	}
+	if err := chain.ValidateEvidences(newBlock(testEvidence("validator2", 1, 1, 2), testEvidence("validator2", 1, 1, 3))); err == nil {
+		t.Error("an offense proven twice in the block should be rejected")
+	}
+	if !chain.HasEvidence(testEvidence("validator1", 1, 3, 1), first.Hash(), 1) {
+		t.Error("the offense of block 1 should be found from block 1")
+	}
+	if chain.HasEvidence(testEvidence("validator1", 1, 1, 2), genesis.Hash(), 0) {
+		t.Error("the offense of block 1 can't be proven before block 1")
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// CrossShardReceiptsAddress is the account whose storage records the cross-shard receipts
+// credited in the shard, keyed by the receipt hashes, so that none is credited twice.
+var CrossShardReceiptsAddress = common.BytesToAddress([]byte("harmony-cross-shard-receipts"))
+
+// receiptSpent is the storage value of the credited receipts.
+var receiptSpent = common.BigToHash(common.Big1)
+
+// NewCrossShardReceipt returns the receipt of the cross-shard transaction sent from the
+// given address.
+func NewCrossShardReceipt(tx *types.Transaction, from common.Address) *types.CrossShardReceipt {
+	return &types.CrossShardReceipt{
+		TxHash:    tx.Hash(),
+		From:      from,
+		To:        *tx.To(),
+		ShardID:   tx.ShardID(),
+		ToShardID: tx.ToShardID(),
+		Amount:    tx.Value(),
+	}
+}
+
+// IsReceiptSpent returns whether the receipt was already credited in the state.
+func IsReceiptSpent(statedb *state.DB, receipt *types.CrossShardReceipt) bool {
+	return statedb.GetState(CrossShardReceiptsAddress, receipt.Hash()) != (common.Hash{})
+}
+
+// isCommitteeKnown returns whether the shard state of the chain records the BLS keys of
+// the committee signing the block of the given number of the shard.
+func isCommitteeKnown(bc *BlockChain, shardID uint32, number uint64) bool {
+	for _, committee := range bc.ReadShardState(bc.ChainParams().EpochOf(number)) {
+		if committee.ShardID != shardID {
+			continue
+		}
+		for _, nodeID := range committee.NodeList {
+			if err := new(bls.PublicKey).DeserializeHexStr(nodeID.BlsPublicKey); err != nil {
+				return false
+			}
+		}
+		return len(committee.NodeList) > 0
+	}
+	return false
+}
+
+// CanDeliverCrossShardTx returns whether the receipt of a transfer to the given shard in
+// the block of the header can be credited there: the destination shard must be in the
+// shard state, which must record the keys of the committee signing the header. The shard
+// chains share the shard states, so the destination verifies the header the same way.
+func CanDeliverCrossShardTx(bc *BlockChain, header *types.Header, toShardID uint32) bool {
+	number := header.Number.Uint64()
+	if !isCommitteeKnown(bc, binary.BigEndian.Uint32(header.ShardID[:]), number) {
+		return false
+	}
+	for _, committee := range bc.ReadShardState(bc.ChainParams().EpochOf(number)) {
+		if committee.ShardID == toShardID {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyIncomingReceipt checks that the proven receipt is sent to the given shard, is in
+// the cross-shard receipts of the proving header, and that the header is signed by the
+// committee of the source shard recorded in the shard state. ErrCrossShardCommitteeUnknown
+// is returned if the shard state doesn't record the committee, so the receipt can be
+// verified again once it does.
+func VerifyIncomingReceipt(chain consensus_engine.ChainReader, engine consensus_engine.Engine, shardID uint32, proof *types.CrossShardReceiptProof) error {
+	if proof.Receipt == nil || proof.Receipt.ToShardID != shardID {
+		return ErrCrossShardReceiptWrongShard
+	}
+	if err := proof.Verify(); err != nil {
+		return err
+	}
+	if err := engine.VerifySeal(chain, proof.Header); err != nil {
+		sourceShardID := binary.BigEndian.Uint32(proof.Header.ShardID[:])
+		if bc, ok := chain.(*BlockChain); ok && bc != nil && !isCommitteeKnown(bc, sourceShardID, proof.Header.Number.Uint64()) {
+			return ErrCrossShardCommitteeUnknown
+		}
+		return err
+	}
+	return nil
+}
+
+// ApplyIncomingReceipt credits the recipient of the proven receipt in the shard of the
+// header, and records the receipt as spent. It returns an error if the proof is invalid
+// or the receipt was already credited, leaving the state unchanged.
+func ApplyIncomingReceipt(chain consensus_engine.ChainReader, engine consensus_engine.Engine, statedb *state.DB, header *types.Header, proof *types.CrossShardReceiptProof) error {
+	if err := VerifyIncomingReceipt(chain, engine, binary.BigEndian.Uint32(header.ShardID[:]), proof); err != nil {
+		return err
+	}
+	if IsReceiptSpent(statedb, proof.Receipt) {
+		return ErrCrossShardReceiptSpent
+	}
+	statedb.AddBalance(proof.Receipt.To, proof.Receipt.Amount)
+	// A nonce keeps the account from being deleted as empty
+	if statedb.GetNonce(CrossShardReceiptsAddress) == 0 {
+		statedb.SetNonce(CrossShardReceiptsAddress, 1)
+	}
+	statedb.SetState(CrossShardReceiptsAddress, proof.Receipt.Hash(), receiptSpent)
+	return nil
+}
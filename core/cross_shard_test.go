@@ -0,0 +1,155 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/consensus"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/p2pimpl"
+)
+
+func TestApplyIncomingReceipt(t *testing.T) {
+	to := common.BytesToAddress([]byte{2})
+	source := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3), ShardID: types.EncodeShardID(1)})
+	source.AddCrossShardReceipts([]*types.CrossShardReceipt{
+		{TxHash: common.BytesToHash([]byte{1}), From: common.BytesToAddress([]byte{1}), To: to, ShardID: 1, ToShardID: 0, Amount: big.NewInt(100)},
+	})
+	proofs, err := source.CrossShardReceiptProofs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := proofs[0][0]
+
+	engine := consensus.NewFaker()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	header := &types.Header{Number: big.NewInt(7), ShardID: types.EncodeShardID(0)}
+
+	if err := ApplyIncomingReceipt(nil, engine, statedb, &types.Header{Number: big.NewInt(7), ShardID: types.EncodeShardID(2)}, proof); err != ErrCrossShardReceiptWrongShard {
+		t.Errorf("a receipt credited in another shard should fail, got %v", err)
+	}
+	if err := ApplyIncomingReceipt(nil, engine, statedb, header, proof); err != nil {
+		t.Fatal(err)
+	}
+	if balance := statedb.GetBalance(to); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("expected the recipient to be credited 100, got %v", balance)
+	}
+	if !IsReceiptSpent(statedb, proof.Receipt) {
+		t.Error("the credited receipt should be spent")
+	}
+	if err := ApplyIncomingReceipt(nil, engine, statedb, header, proof); err != ErrCrossShardReceiptSpent {
+		t.Errorf("a receipt should only be credited once, got %v", err)
+	}
+	if balance := statedb.GetBalance(to); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("expected the recipient to keep a balance of 100, got %v", balance)
+	}
+}
+
+// newShardChain returns a chain of the shard whose genesis records the shard state, if any.
+func newShardChain(t *testing.T, shardID uint32, alloc GenesisAlloc, shardState types.ShardState, engine consensus_engine.Engine) *BlockChain {
+	db := ethdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig, Alloc: alloc, ShardID: shardID}).MustCommit(db)
+	if shardState != nil {
+		rawdb.WriteShardState(db, genesis.Hash(), 0, shardState)
+	}
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain
+}
+
+func TestCrossShardTransferAcrossChains(t *testing.T) {
+	leaderPriKey, leaderPubKey := utils.GenKey("127.0.0.1", "9920")
+	validatorPriKey, validatorPubKey := utils.GenKey("127.0.0.1", "9921")
+	_, otherPubKey := utils.GenKey("127.0.0.1", "9922")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9920", PubKey: leaderPubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "9921", PubKey: validatorPubKey}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9920")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	engine := consensus.New(host, "0", []p2p.Peer{validator}, leader)
+
+	// The shard state records the committee of the source shard 1 and the destination shard 0
+	shardState := types.ShardState{
+		{ShardID: 0, NodeList: []types.NodeID{{BlsPublicKey: otherPubKey.SerializeToHexStr()}}},
+		{ShardID: 1, NodeList: []types.NodeID{{BlsPublicKey: leaderPubKey.SerializeToHexStr()}, {BlsPublicKey: validatorPubKey.SerializeToHexStr()}}},
+	}
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.BytesToAddress([]byte{2})
+	source := newShardChain(t, 1, GenesisAlloc{from: {Balance: big.NewInt(1000)}}, shardState, engine)
+	destination := newShardChain(t, 0, nil, shardState, engine)
+
+	// The transfer is debited in the source shard
+	parent := source.CurrentBlock()
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), ShardID: types.EncodeShardID(1), GasLimit: parent.GasLimit(), Time: big.NewInt(1), Difficulty: big.NewInt(0)}
+	statedb, _ := source.State()
+	signer := types.MakeSigner(params.TestChainConfig, header.Number)
+	undeliverable, _ := types.SignTx(types.NewCrossShardTransaction(0, to, 1, 5, big.NewInt(100), params.TxGas, big.NewInt(0), nil), signer, key)
+	if _, _, _, err := ApplyTransaction(params.TestChainConfig, source, &common.Address{}, new(GasPool).AddGas(header.GasLimit), statedb, header, undeliverable, new(uint64), vm.Config{}); err != ErrUndeliverableCrossShardTx {
+		t.Errorf("a transfer to a shard not in the shard state should be refused, got %v", err)
+	}
+	tx, _ := types.SignTx(types.NewCrossShardTransaction(0, to, 1, 0, big.NewInt(100), params.TxGas, big.NewInt(0), nil), signer, key)
+	_, receipt, _, err := ApplyTransaction(params.TestChainConfig, source, &common.Address{}, new(GasPool).AddGas(header.GasLimit), statedb, header, tx, new(uint64), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := statedb.GetBalance(from); balance.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("expected the sender to be debited to 900, got %v", balance)
+	}
+
+	// The committee of the source shard seals the block of the transfer
+	block := types.NewBlockWithHeader(header)
+	block.AddCrossShardReceipts([]*types.CrossShardReceipt{receipt})
+	mask, _ := bls_cosi.NewMask([]*bls.PublicKey{leaderPubKey, validatorPubKey}, nil)
+	mask.SetKey(leaderPubKey, true)
+	mask.SetKey(validatorPubKey, true)
+	sealHash := engine.SealHash(block.Header())
+	prepareSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(sealHash[:]), validatorPriKey.SignHash(sealHash[:])})
+	block.SetPrepareSig(prepareSig.Serialize(), mask.Bitmap)
+	prepareMultiSigAndBitmap := append(prepareSig.Serialize(), mask.Bitmap...)
+	commitSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(prepareMultiSigAndBitmap), validatorPriKey.SignHash(prepareMultiSigAndBitmap)})
+	block.SetCommitSig(commitSig.Serialize(), mask.Bitmap)
+	proofs, err := block.CrossShardReceiptProofs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := proofs[0][0]
+
+	// The destination shard verifies the seal against the shard state and credits the transfer
+	destinationState, _ := destination.State()
+	destinationHeader := &types.Header{Number: big.NewInt(1), ShardID: types.EncodeShardID(0)}
+	if err := ApplyIncomingReceipt(destination, engine, destinationState, destinationHeader, proof); err != nil {
+		t.Fatal(err)
+	}
+	if balance := destinationState.GetBalance(to); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("expected the recipient to be credited 100, got %v", balance)
+	}
+
+	// A shard not knowing the committee of the source shard keeps the receipt for later,
+	// and refuses the transfers it can't deliver
+	unknown := newShardChain(t, 0, GenesisAlloc{from: {Balance: big.NewInt(1000)}}, nil, engine)
+	unknownState, _ := unknown.State()
+	if err := ApplyIncomingReceipt(unknown, engine, unknownState, destinationHeader, proof); err != ErrCrossShardCommitteeUnknown {
+		t.Errorf("a receipt of an unknown committee should wait, got %v", err)
+	}
+	tx, _ = types.SignTx(types.NewCrossShardTransaction(0, to, 0, 1, big.NewInt(100), params.TxGas, big.NewInt(0), nil), signer, key)
+	if _, _, _, err := ApplyTransaction(params.TestChainConfig, unknown, &common.Address{}, new(GasPool).AddGas(header.GasLimit), unknownState, destinationHeader, tx, new(uint64), vm.Config{}); err != ErrUndeliverableCrossShardTx {
+		t.Errorf("a transfer unable to be verified in its shard should be refused, got %v", err)
+	}
+}
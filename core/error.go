@@ -35,4 +35,28 @@ var (
 
 	// ErrShardStateNotMatch is returned if the calculated shardState hash not equal that in the block header
 	ErrShardStateNotMatch = errors.New("shard state root hash not match")
+
+	// ErrInvalidCrossShardTx is returned if a cross-shard transaction is not a plain
+	// transfer from the shard of the block.
+	ErrInvalidCrossShardTx = errors.New("invalid cross-shard transaction")
+
+	// ErrUndeliverableCrossShardTx is returned if the receipt of a cross-shard transfer
+	// can't be verified in its destination shard, which would never credit it.
+	ErrUndeliverableCrossShardTx = errors.New("cross-shard transaction to a shard unable to verify it")
+
+	// ErrCrossShardCommitteeUnknown is returned if the receipt of a cross-shard transfer
+	// is proven by a header of a shard whose committee isn't recorded in the shard state.
+	ErrCrossShardCommitteeUnknown = errors.New("committee of the source shard of the cross-shard receipt unknown")
+
+	// ErrCrossShardReceiptSpent is returned if the receipt of a cross-shard transfer
+	// was already credited in the shard.
+	ErrCrossShardReceiptSpent = errors.New("cross-shard receipt already credited")
+
+	// ErrCrossShardReceiptWrongShard is returned if the receipt of a cross-shard transfer
+	// is credited in a shard other than its destination.
+	ErrCrossShardReceiptWrongShard = errors.New("cross-shard receipt to another shard")
+
+	// ErrMissingHeadState is returned if the states are pruned while the state of the head
+	// block is missing.
+	ErrMissingHeadState = errors.New("state of the head block missing")
 )
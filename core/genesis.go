@@ -34,6 +34,7 @@ import (
 	"github.com/harmony-one/harmony/core/rawdb"
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 )
 
@@ -45,16 +46,17 @@ var errGenesisNoConfig = errors.New("genesis has no chain configuration")
 // Genesis specifies the header fields, state of a genesis block. It also defines hard
 // fork switch-over blocks through the chain configuration.
 type Genesis struct {
-	Config     *params.ChainConfig `json:"config"`
-	Nonce      uint64              `json:"nonce"`
-	ShardID    uint32              `json:"shardID"`
-	Timestamp  uint64              `json:"timestamp"`
-	ExtraData  []byte              `json:"extraData"`
-	GasLimit   uint64              `json:"gasLimit"   gencodec:"required"`
-	Difficulty *big.Int            `json:"difficulty" gencodec:"required"`
-	Mixhash    common.Hash         `json:"mixHash"`
-	Coinbase   common.Address      `json:"coinbase"`
-	Alloc      GenesisAlloc        `json:"alloc"      gencodec:"required"`
+	Config      *params.ChainConfig `json:"config"`
+	ChainParams *chainparams.Config `json:"harmony"` // the Harmony section of the chain config
+	Nonce       uint64              `json:"nonce"`
+	ShardID     uint32              `json:"shardID"`
+	Timestamp   uint64              `json:"timestamp"`
+	ExtraData   []byte              `json:"extraData"`
+	GasLimit    uint64              `json:"gasLimit"   gencodec:"required"`
+	Difficulty  *big.Int            `json:"difficulty" gencodec:"required"`
+	Mixhash     common.Hash         `json:"mixHash"`
+	Coinbase    common.Address      `json:"coinbase"`
+	Alloc       GenesisAlloc        `json:"alloc"      gencodec:"required"`
 
 	// These fields are used for consensus tests. Please don't use them
 	// in actual genesis blocks.
@@ -171,11 +173,26 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 
 	// Check whether the genesis block is already written.
 	if genesis != nil {
-		hash := genesis.ToBlock(nil).Hash()
+		hash := genesis.ToBlock(ethdb.NewMemDatabase()).Hash()
 		if hash != stored {
 			return genesis.Config, hash, &GenesisMismatchError{stored, hash}
 		}
 	}
+	// The genesis block commits to the chain params it starts with, only upgrades after
+	// the head block can be added to them.
+	if genesis != nil {
+		newParams := genesis.chainParamsOrDefault()
+		if storedParams := rawdb.ReadChainParams(db, stored); storedParams != nil {
+			height := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+			if height == nil {
+				return genesis.Config, stored, fmt.Errorf("missing block number for head header hash")
+			}
+			if err := storedParams.CheckCompatible(newParams, *height); err != nil {
+				return genesis.Config, stored, fmt.Errorf("incompatible chain params: %v", err)
+			}
+		}
+		rawdb.WriteChainParams(db, stored, newParams)
+	}
 
 	// Get the existing chain configuration.
 	newcfg := genesis.configOrDefault(stored)
@@ -206,6 +223,13 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	return newcfg, stored, nil
 }
 
+func (g *Genesis) chainParamsOrDefault() *chainparams.Config {
+	if g.ChainParams == nil {
+		return chainparams.DefaultConfig
+	}
+	return g.ChainParams
+}
+
 func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	switch {
 	case g != nil:
@@ -242,7 +266,8 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 		ShardID:    types.EncodeShardID(g.ShardID),
 		Time:       new(big.Int).SetUint64(g.Timestamp),
 		ParentHash: g.ParentHash,
-		Extra:      g.ExtraData,
+		// The hash of the chain params makes the chains of other params tell apart
+		Extra:      append(append([]byte{}, g.ExtraData...), g.chainParamsOrDefault().GenesisHash().Bytes()...),
 		GasLimit:   g.GasLimit,
 		GasUsed:    g.GasUsed,
 		Difficulty: g.Difficulty,
@@ -256,6 +281,10 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	if g.Difficulty == nil {
 		head.Difficulty = params.GenesisDifficulty
 	}
+	// The committees of epoch 0 sign the blocks up to the first resharding
+	if shardState := GenesisShardState(g.chainParamsOrDefault()); len(shardState) > 0 {
+		head.ShardStateHash = shardState.Hash()
+	}
 	statedb.Commit(false)
 	statedb.Database().TrieDB().Commit(root, true)
 
@@ -265,6 +294,11 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
+	if g.ChainParams != nil {
+		if err := g.ChainParams.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid chain params: %v", err)
+		}
+	}
 	block := g.ToBlock(db)
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
@@ -275,12 +309,16 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
 	rawdb.WriteHeadBlockHash(db, block.Hash())
 	rawdb.WriteHeadHeaderHash(db, block.Hash())
+	if shardState := GenesisShardState(g.chainParamsOrDefault()); len(shardState) > 0 {
+		rawdb.WriteShardState(db, block.Hash(), block.NumberU64(), shardState)
+	}
 
 	config := g.Config
 	if config == nil {
 		config = params.AllEthashProtocolChanges
 	}
 	rawdb.WriteChainConfig(db, block.Hash(), config)
+	rawdb.WriteChainParams(db, block.Hash(), g.chainParamsOrDefault())
 	return block, nil
 }
 
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
+)
+
+func TestSetupGenesisChainParams(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	config := *chainparams.DefaultConfig
+	genesis := &Genesis{Config: params.TestChainConfig, ChainParams: &config}
+	_, hash, err := SetupGenesisBlock(db, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The chain restarts on the same params
+	if _, _, err := SetupGenesisBlock(db, genesis); err != nil {
+		t.Errorf("the same params should be accepted, got: %v", err)
+	}
+	// The params of another chain are refused, as its genesis block differs
+	otherConfig := config
+	otherConfig.BlocksPerEpoch = 10
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: params.TestChainConfig, ChainParams: &otherConfig}); err == nil {
+		t.Error("other params should be refused")
+	} else if _, ok := err.(*GenesisMismatchError); !ok {
+		t.Errorf("expected a genesis mismatch, got: %v", err)
+	}
+
+	// An upgrade can be added ahead of the head block
+	header := &types.Header{Number: big.NewInt(12)}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteHeadHeaderHash(db, header.Hash())
+	upgraded := config
+	upgraded.Upgrades = []chainparams.Upgrade{{Block: 15, Params: chainparams.Params{MaxTxsPerBlock: 100}}}
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: params.TestChainConfig, ChainParams: &upgraded}); err != nil {
+		t.Errorf("an upgrade ahead of the head should be accepted, got: %v", err)
+	}
+	if stored := rawdb.ReadChainParams(db, hash); stored == nil || len(stored.Upgrades) != 1 {
+		t.Error("the upgrade should be stored")
+	}
+	// Changing an upgrade or adding one the chain passed is refused
+	changed := config
+	changed.Upgrades = []chainparams.Upgrade{{Block: 15, Params: chainparams.Params{MaxTxsPerBlock: 200}}}
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: params.TestChainConfig, ChainParams: &changed}); err == nil {
+		t.Error("a changed upgrade should be refused")
+	}
+	header = &types.Header{Number: big.NewInt(22)}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteHeadHeaderHash(db, header.Hash())
+	passed := upgraded
+	passed.Upgrades = append(upgraded.Upgrades[:1:1], chainparams.Upgrade{Block: 20})
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: params.TestChainConfig, ChainParams: &passed}); err == nil {
+		t.Error("an upgrade the chain passed should be refused")
+	}
+}
+
+func TestGenesisCommittees(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	config := *chainparams.DefaultConfig
+	config.GenesisCommittees = map[uint32][]string{0: {"key1", "key2"}}
+	genesis := &Genesis{Config: params.TestChainConfig, ChainParams: &config}
+	block := genesis.MustCommit(db)
+
+	shardState := rawdb.ReadShardState(db, block.Hash(), 0)
+	if len(shardState) != 1 || len(shardState[0].NodeList) != 2 {
+		t.Fatalf("expected the genesis committee in the shard state, got %v", shardState)
+	}
+	if block.Header().ShardStateHash != shardState.Hash() {
+		t.Error("the genesis block should commit to its shard state")
+	}
+
+	// Other genesis committees make another chain
+	otherConfig := config
+	otherConfig.GenesisCommittees = map[uint32][]string{0: {"key1"}}
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: params.TestChainConfig, ChainParams: &otherConfig}); err == nil {
+		t.Error("other genesis committees should be refused")
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/internal/chainparams"
+)
+
+// keptStates returns the numbers of the blocks whose states are kept at the head block:
+// the last retention blocks, and the epoch blocks of the last epochRetention epochs, or
+// of all the epochs if it's 0. It also returns the lowest block whose state may be pruned
+// later, as it isn't kept for good.
+func keptStates(chainParams *chainparams.Config, head uint64, retention uint64, epochRetention uint64) (map[uint64]bool, uint64) {
+	kept := make(map[uint64]bool)
+	recent := uint64(0)
+	if head >= retention {
+		recent = head - retention + 1
+	}
+	for number := recent; number <= head; number++ {
+		kept[number] = true
+	}
+	pruneFrom := recent
+	for epoch, count := chainParams.EpochOf(head), uint64(1); ; epoch, count = epoch-1, count+1 {
+		number := chainParams.EpochBlockNumber(epoch)
+		kept[number] = true
+		if epochRetention > 0 && number < pruneFrom {
+			pruneFrom = number
+		}
+		if epoch == 0 || count == epochRetention {
+			break
+		}
+	}
+	return kept, pruneFrom
+}
+
+// markStates marks the trie nodes and contract codes of the kept states of the canonical
+// chain up to the head block. The states kept are the ones found in the state database,
+// either flushed to disk or still cached in memory; the head state must be found. It
+// returns the marked nodes and the number of states marked.
+func markStates(db ethdb.Database, stateCache state.Database, kept map[uint64]bool, head uint64) (map[common.Hash]struct{}, int, error) {
+	marked := make(map[common.Hash]struct{})
+	numMarked := 0
+	for number := range kept {
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		if header == nil {
+			return nil, 0, fmt.Errorf("missing header of block %d", number)
+		}
+		if _, err := stateCache.OpenTrie(header.Root); err != nil {
+			if number == head {
+				return nil, 0, ErrMissingHeadState
+			}
+			// The state was never flushed to disk
+			continue
+		}
+		if err := state.MarkState(stateCache, header.Root, marked); err != nil {
+			return nil, 0, fmt.Errorf("incomplete state of block %d: %v", number, err)
+		}
+		numMarked++
+	}
+	return marked, numMarked, nil
+}
+
+// PruneState deletes from the database the states of the canonical chain up to the head
+// block, but the ones of the last retention blocks and of the epoch blocks of the last
+// epochRetention epochs, or of all the epochs if it's 0. It starts from the height the
+// last pruning stopped at, so only the states which got old since are walked through. It
+// returns the number of trie nodes and contract codes deleted from the database.
+//
+// Nothing must be written to the state database while the states are pruned.
+func PruneState(db ethdb.Database, stateCache state.Database, chainParams *chainparams.Config, head uint64, retention uint64, epochRetention uint64) (int, error) {
+	start := time.Now()
+	kept, pruneFrom := keptStates(chainParams, head, retention, epochRetention)
+	marked, numMarked, err := markStates(db, stateCache, kept, head)
+	if err != nil {
+		return 0, err
+	}
+
+	// The states below the recent ones and not kept are collected
+	from := rawdb.ReadPrunedStateHeight(db)
+	dead := make(map[common.Hash]struct{})
+	for number := from; number+retention <= head; number++ {
+		if kept[number] {
+			continue
+		}
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		if header == nil {
+			return 0, fmt.Errorf("missing header of block %d", number)
+		}
+		if _, err := stateCache.OpenTrie(header.Root); err != nil {
+			// The state was never flushed to disk, or pruned already
+			continue
+		}
+		if err := state.CollectUnmarked(stateCache, header.Root, marked, dead); err != nil {
+			log.Debug("Collected the state pruned in part", "number", number, "err", err)
+		}
+	}
+	deleted, err := state.DeleteNodes(db, dead)
+	if err != nil {
+		return deleted, err
+	}
+	rawdb.WritePrunedStateHeight(db, pruneFrom)
+	log.Info("Pruned states", "head", head, "from", from, "keptStates", numMarked, "keptNodes", len(marked), "deletedNodes", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return deleted, nil
+}
+
+// SweepState deletes from the database every trie node and contract code but the ones of
+// the states PruneState keeps, including the ones of no block of the canonical chain. It
+// iterates over the whole database, which is meant for the nodes stopped.
+func SweepState(db ethdb.Database, stateCache state.Database, chainParams *chainparams.Config, head uint64, retention uint64, epochRetention uint64) (int, error) {
+	start := time.Now()
+	kept, pruneFrom := keptStates(chainParams, head, retention, epochRetention)
+	marked, numMarked, err := markStates(db, stateCache, kept, head)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := state.SweepNodes(db, marked)
+	if err != nil {
+		return deleted, err
+	}
+	rawdb.WritePrunedStateHeight(db, pruneFrom)
+	log.Info("Swept states", "head", head, "keptStates", numMarked, "keptNodes", len(marked), "deletedNodes", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return deleted, nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
+)
+
+// pruneTestContract is the contract whose storage every block of the prune tests changes.
+var (
+	pruneTestContract = common.BytesToAddress([]byte{100})
+	pruneTestCode     = []byte{1, 2, 3}
+)
+
+// writeTestStates appends to the canonical chain the blocks up to the given number, each
+// one changing a balance and a storage slot of the contract, and returns the state roots
+// of all the blocks.
+func writeTestStates(t *testing.T, db ethdb.Database, stateCache state.Database, roots []common.Hash, to int64) []common.Hash {
+	root := common.Hash{}
+	if len(roots) > 0 {
+		root = roots[len(roots)-1]
+	}
+	for number := int64(len(roots)); number <= to; number++ {
+		statedb, err := state.New(root, stateCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if number == 0 {
+			statedb.SetCode(pruneTestContract, pruneTestCode)
+		}
+		statedb.AddBalance(common.BytesToAddress([]byte{byte(number % 3)}), big.NewInt(1))
+		statedb.SetState(pruneTestContract, common.BigToHash(big.NewInt(number)), common.BigToHash(big.NewInt(number+1)))
+		if root, err = statedb.Commit(false); err != nil {
+			t.Fatal(err)
+		}
+		if err := stateCache.TrieDB().Commit(root, false); err != nil {
+			t.Fatal(err)
+		}
+		header := &types.Header{Number: big.NewInt(number), Root: root}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(number))
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// checkPrunedStates checks that the states of the kept blocks are whole and the other
+// ones are pruned.
+func checkPrunedStates(t *testing.T, db ethdb.Database, roots []common.Hash, kept map[int]bool) {
+	prunedCache := state.NewDatabase(db)
+	for number, root := range roots {
+		statedb, err := state.New(root, prunedCache)
+		if !kept[number] {
+			if err == nil {
+				t.Errorf("state of block %d should be pruned", number)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("state of block %d should be kept, got %v", number, err)
+			continue
+		}
+		if err := state.MarkState(prunedCache, root, map[common.Hash]struct{}{}); err != nil {
+			t.Errorf("state of block %d is incomplete: %v", number, err)
+		}
+		if !bytes.Equal(statedb.GetCode(pruneTestContract), pruneTestCode) {
+			t.Errorf("code of the contract missing in the state of block %d", number)
+		}
+		if value := statedb.GetState(pruneTestContract, common.BigToHash(big.NewInt(int64(number)))); value != common.BigToHash(big.NewInt(int64(number+1))) {
+			t.Errorf("storage of the contract wrong in the state of block %d: %x", number, value)
+		}
+	}
+}
+
+func TestPruneState(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	stateCache := state.NewDatabase(db)
+	config := &chainparams.Config{Params: chainparams.Params{BlocksPerEpoch: 5}}
+	roots := writeTestStates(t, db, stateCache, nil, 12)
+
+	deleted, err := PruneState(db, stateCache, config, 12, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted == 0 {
+		t.Error("no state pruned")
+	}
+
+	// The states of the last 3 blocks and of the epoch blocks are kept whole
+	checkPrunedStates(t, db, roots, map[int]bool{0: true, 5: true, 10: true, 11: true, 12: true})
+	prunedCache := state.NewDatabase(db)
+
+	// A head block without its state can't be pruned up to
+	header := &types.Header{Number: big.NewInt(13), Root: common.BytesToHash([]byte{13})}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), 13)
+	if _, err := PruneState(db, prunedCache, config, 13, 3, 0); err != ErrMissingHeadState {
+		t.Errorf("pruning with the head state missing should fail, got %v", err)
+	}
+}
+
+func TestPruneStateIncrementally(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	stateCache := state.NewDatabase(db)
+	config := &chainparams.Config{Params: chainparams.Params{BlocksPerEpoch: 5}}
+	roots := writeTestStates(t, db, stateCache, nil, 12)
+
+	// Only the state of the last epoch block is kept
+	if _, err := PruneState(db, stateCache, config, 12, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkPrunedStates(t, db, roots, map[int]bool{10: true, 11: true, 12: true})
+	if height := rawdb.ReadPrunedStateHeight(db); height != 10 {
+		t.Errorf("expected the pruning to stop at the last epoch block 10, got %d", height)
+	}
+
+	// The next pruning goes on from there, up to the new epoch block
+	roots = writeTestStates(t, db, stateCache, roots, 17)
+	if _, err := PruneState(db, stateCache, config, 17, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkPrunedStates(t, db, roots, map[int]bool{15: true, 16: true, 17: true})
+	if height := rawdb.ReadPrunedStateHeight(db); height != 15 {
+		t.Errorf("expected the pruning to stop at the last epoch block 15, got %d", height)
+	}
+
+	// The states pruned by the sweep are the same, and the nodes of no state go too
+	orphan := common.BytesToHash([]byte("orphan"))
+	db.Put(orphan[:], []byte{1})
+	if _, err := SweepState(db, stateCache, config, 17, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkPrunedStates(t, db, roots, map[int]bool{15: true, 16: true, 17: true})
+	if has, _ := db.Has(orphan[:]); has {
+		t.Error("the sweep should delete the nodes of no state")
+	}
+}
@@ -126,6 +126,24 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// ReadPrunedStateHeight retrieves the number of the block below which the states not
+// kept by the last pruning are deleted, 0 if the states were never pruned.
+func ReadPrunedStateHeight(db DatabaseReader) uint64 {
+	data, _ := db.Get(prunedStateHeightKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WritePrunedStateHeight stores the number of the block below which the states not kept
+// by the pruning are deleted, so that the next pruning starts from there.
+func WritePrunedStateHeight(db DatabaseWriter, number uint64) {
+	if err := db.Put(prunedStateHeightKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store the pruned state height", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
@@ -330,7 +348,7 @@ func ReadBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block
 	if body == nil {
 		return nil
 	}
-	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles, body.Evidences, body.CrossShardReceipts, body.IncomingReceipts)
 }
 
 // WriteBlock serializes a block into the database, header and body separately.
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/internal/chainparams"
 )
 
 // ReadDatabaseVersion retrieves the version number of the database.
@@ -71,6 +72,34 @@ func WriteChainConfig(db DatabaseWriter, hash common.Hash, cfg *params.ChainConf
 	}
 }
 
+// ReadChainParams retrieves the Harmony section of the chain config based on the given genesis hash.
+func ReadChainParams(db DatabaseReader, hash common.Hash) *chainparams.Config {
+	data, _ := db.Get(chainParamsKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var config chainparams.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Error("Invalid chain params JSON", "hash", hash, "err", err)
+		return nil
+	}
+	return &config
+}
+
+// WriteChainParams writes the Harmony section of the chain config to the database.
+func WriteChainParams(db DatabaseWriter, hash common.Hash, config *chainparams.Config) {
+	if config == nil {
+		return
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		log.Crit("Failed to JSON encode chain params", "err", err)
+	}
+	if err := db.Put(chainParamsKey(hash), data); err != nil {
+		log.Crit("Failed to store chain params", "err", err)
+	}
+}
+
 // ReadPreimage retrieves a single preimage of the provided hash.
 func ReadPreimage(db DatabaseReader, hash common.Hash) []byte {
 	data, _ := db.Get(preimageKey(hash))
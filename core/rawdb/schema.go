@@ -41,6 +41,9 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// prunedStateHeightKey tracks the block number below which the states are pruned.
+	prunedStateHeightKey = []byte("PrunedStateHeight")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -55,8 +58,9 @@ var (
 
 	shardStatePrefix = []byte("ss") // shardStatePrefix + num (uint64 big endian) + hash -> shardState
 
-	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
-	configPrefix   = []byte("ethereum-config-") // config prefix for the db
+	preimagePrefix    = []byte("secure-key-")      // preimagePrefix + hash -> preimage
+	configPrefix      = []byte("ethereum-config-") // config prefix for the db
+	chainParamsPrefix = []byte("harmony-config-")  // chain params prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
@@ -135,6 +139,11 @@ func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
 }
 
+// chainParamsKey = chainParamsPrefix + hash
+func chainParamsKey(hash common.Hash) []byte {
+	return append(chainParamsPrefix, hash.Bytes()...)
+}
+
 func shardStateKey(number uint64, hash common.Hash) []byte {
 	return append(append(shardStatePrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
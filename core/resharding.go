@@ -3,14 +3,9 @@ package core
 import (
 	"math/rand"
 	"sort"
-	"strconv"
 
 	"github.com/harmony-one/harmony/core/types"
-)
-
-const (
-	// InitialSeed is the initial random seed, a magic number to answer everything, remove later
-	InitialSeed int64 = 42
+	"github.com/harmony-one/harmony/internal/chainparams"
 )
 
 // ShardingState is data structure hold the sharding state
@@ -21,6 +16,16 @@ type ShardingState struct {
 	shardState types.ShardState
 }
 
+// NewShardingState returns the sharding state of the given committees, to be resharded with the random seed.
+func NewShardingState(epoch uint64, rnd int64, shardState types.ShardState) *ShardingState {
+	return &ShardingState{epoch: epoch, rnd: rnd, shardState: shardState, numShards: len(shardState)}
+}
+
+// ShardState returns the committees of the sharding state.
+func (ss *ShardingState) ShardState() types.ShardState {
+	return ss.shardState
+}
+
 // sortedCommitteeBySize will sort shards by size
 // Suppose there are N shards, the first N/2 larger shards are called active committees
 // the rest N/2 smaller committees are called inactive committees
@@ -32,10 +37,19 @@ func (ss *ShardingState) sortCommitteeBySize() {
 	})
 }
 
+// numActiveShards returns the number of the active committees: the larger half of the
+// shards, or the only shard.
+func (ss *ShardingState) numActiveShards() int {
+	if ss.numShards == 1 {
+		return 1
+	}
+	return ss.numShards / 2
+}
+
 // assignNewNodes add new nodes into the N/2 active committees evenly
 func (ss *ShardingState) assignNewNodes(newNodeList []types.NodeID) {
 	ss.sortCommitteeBySize()
-	numActiveShards := ss.numShards / 2
+	numActiveShards := ss.numActiveShards()
 	Shuffle(newNodeList)
 	for i, nid := range newNodeList {
 		id := i % numActiveShards
@@ -46,7 +60,11 @@ func (ss *ShardingState) assignNewNodes(newNodeList []types.NodeID) {
 // cuckooResharding uses cuckoo rule to reshard X% of active committee(shards) into inactive committee(shards)
 func (ss *ShardingState) cuckooResharding(percent float64) {
 	ss.sortCommitteeBySize()
-	numActiveShards := ss.numShards / 2
+	numActiveShards := ss.numActiveShards()
+	if numActiveShards == ss.numShards {
+		// No inactive committee to kick out nodes into
+		return
+	}
 	kickedNodes := []types.NodeID{}
 	for i := range ss.shardState {
 		if i >= numActiveShards {
@@ -73,6 +91,14 @@ func (ss *ShardingState) UpdateShardState(newNodeList []types.NodeID, percent fl
 	ss.cuckooResharding(percent)
 }
 
+// Reshard adds the new nodes into the active committees, then uses cuckoo rule at the kick out rate
+// balancing the committees to reshard them. It returns the kick out rate.
+func (ss *ShardingState) Reshard(newNodeList []types.NodeID) float64 {
+	percent := ss.calculateKickoutRate(newNodeList)
+	ss.UpdateShardState(newNodeList, percent)
+	return percent
+}
+
 // Shuffle will shuffle the list with result uniquely determined by seed, assuming there is no repeat items in the list
 func Shuffle(list []types.NodeID) {
 	sort.Slice(list, func(i, j int) bool {
@@ -83,87 +109,59 @@ func Shuffle(list []types.NodeID) {
 	})
 }
 
-// GetBlockNumberFromEpoch calculates the block number where epoch sharding information is stored
-func GetBlockNumberFromEpoch(epoch uint64) uint64 {
-	number := epoch * uint64(BlocksPerEpoch) // currently we use the first block in each epoch
-	return number
-}
-
-// GetEpochFromBlockNumber calculates the epoch number the block belongs to
-func GetEpochFromBlockNumber(blockNumber uint64) uint64 {
-	return blockNumber / uint64(BlocksPerEpoch)
+// GetShardingStateFromBlockChain will retrieve the shard map of the given epoch from the chain, to be resharded with the random seed.
+// The random seed is the final randomness of the epoch, committed into its last block along with the new shard state.
+func GetShardingStateFromBlockChain(bc *BlockChain, epoch uint64, rnd int64) *ShardingState {
+	shardState := bc.ReadShardState(epoch)
+	return &ShardingState{epoch: epoch, rnd: rnd, shardState: shardState, numShards: len(shardState)}
 }
 
-// GetPreviousEpochBlockNumber gets the epoch block number of previous epoch
-func GetPreviousEpochBlockNumber(blockNumber uint64) uint64 {
-	epoch := GetEpochFromBlockNumber(blockNumber)
-	if epoch == 1 {
-		// no previous epoch
-		return epoch
+// CalculateNewShardState get sharding state from previous epoch and calcualte sharding state for new epoch.
+// The new nodes are the nodes which staked during the previous epoch; they are assigned into the
+// active committees before the cuckoo rule reshards the committees with the random seed. The committees
+// of epoch 0 are the ones recorded in the genesis block; it returns nil if the chain records no committees.
+func CalculateNewShardState(bc *BlockChain, epoch uint64, newNodeList []types.NodeID, rnd int64) types.ShardState {
+	ss := GetShardingStateFromBlockChain(bc, epoch-1, rnd)
+	if ss.numShards == 0 {
+		return nil
 	}
-	return GetBlockNumberFromEpoch(epoch - 1)
-}
-
-// GetShardingStateFromBlockChain will retrieve random seed and shard map from beacon chain for given a epoch
-func GetShardingStateFromBlockChain(bc *BlockChain, epoch uint64) *ShardingState {
-	number := GetBlockNumberFromEpoch(epoch)
-	shardState := bc.GetShardStateByNumber(number)
-	rnd := bc.GetRandSeedByNumber(number)
-
-	return &ShardingState{epoch: epoch, rnd: rnd, shardState: shardState, numShards: len(shardState)}
+	ss.Reshard(newNodeList)
+	return ss.shardState
 }
 
-// CalculateNewShardState get sharding state from previous epoch and calcualte sharding state for new epoch
-// TODO: currently, we just mock everything
-func CalculateNewShardState(bc *BlockChain, epoch uint64) types.ShardState {
-	if epoch == 1 {
-		return fakeGetInitShardState()
+// GenesisShardState returns the shard state the genesis block records: the committees of epoch 0
+// configured in the chain params, in the order of their shard ids. It returns nil if the chain
+// params configure no committees.
+func GenesisShardState(chainParams *chainparams.Config) types.ShardState {
+	var shardState types.ShardState
+	for shardID, publicKeys := range chainParams.GenesisCommittees {
+		committee := types.Committee{ShardID: shardID}
+		for _, publicKey := range publicKeys {
+			committee.NodeList = append(committee.NodeList, types.NodeID{BlsPublicKey: publicKey})
+		}
+		shardState = append(shardState, committee)
 	}
-	ss := GetShardingStateFromBlockChain(bc, epoch-1)
-	newNodeList := fakeNewNodeList(ss.rnd)
-	percent := ss.calculateKickoutRate(newNodeList)
-	ss.UpdateShardState(newNodeList, percent)
-	return ss.shardState
+	sort.Slice(shardState, func(i, j int) bool {
+		return shardState[i].ShardID < shardState[j].ShardID
+	})
+	return shardState
 }
 
 // calculateKickoutRate calculates the cuckoo rule kick out rate in order to make committee balanced
+// The rate is at most 1, i.e. all the nodes of the active committees are kicked out.
 func (ss *ShardingState) calculateKickoutRate(newNodeList []types.NodeID) float64 {
-	numActiveCommittees := ss.numShards / 2
+	numActiveCommittees := ss.numActiveShards()
+	if numActiveCommittees == ss.numShards {
+		return 0
+	}
 	newNodesPerShard := len(newNodeList) / numActiveCommittees
 	ss.sortCommitteeBySize()
-	return float64(newNodesPerShard) / float64(len(ss.shardState[numActiveCommittees].NodeList))
-}
-
-// FakeGenRandSeed generate random seed based on previous rnd seed; remove later after VRF implemented
-func FakeGenRandSeed(seed int64) int64 {
-	rand.Seed(seed)
-	return rand.Int63()
-}
-
-// remove later after bootstrap codes ready
-func fakeGetInitShardState() types.ShardState {
-	rand.Seed(InitialSeed)
-	shardState := types.ShardState{}
-	for i := 0; i < 6; i++ {
-		sid := uint32(i)
-		com := types.Committee{ShardID: sid}
-		for j := 0; j < 10; j++ {
-			nid := strconv.Itoa(int(rand.Int63()))
-			com.NodeList = append(com.NodeList, types.NodeID(nid))
-		}
-		shardState = append(shardState, com)
+	inactiveSize := len(ss.shardState[numActiveCommittees].NodeList)
+	if newNodesPerShard == 0 {
+		return 0
 	}
-	return shardState
-}
-
-// remove later after new nodes list generation ready
-func fakeNewNodeList(seed int64) []types.NodeID {
-	rand.Seed(seed)
-	numNewNodes := rand.Intn(10)
-	nodeList := []types.NodeID{}
-	for i := 0; i < numNewNodes; i++ {
-		nid := strconv.Itoa(int(rand.Int63()))
-		nodeList = append(nodeList, types.NodeID(nid))
+	if newNodesPerShard >= inactiveSize {
+		return 1
 	}
-	return nodeList
+	return float64(newNodesPerShard) / float64(inactiveSize)
 }
@@ -3,16 +3,112 @@ package core
 import (
 	"fmt"
 	"testing"
+
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
 )
 
-func TestFakeGetInitShardState(t *testing.T) {
-	ss := fakeGetInitShardState()
-	for i := range ss {
-		fmt.Printf("ShardID: %v, NodeList: %v\n", ss[i].ShardID, ss[i].NodeList)
+// testShardState returns 6 committees of 10 nodes each.
+func testShardState() types.ShardState {
+	shardState := types.ShardState{}
+	for i := 0; i < 6; i++ {
+		committee := types.Committee{ShardID: uint32(i)}
+		for j := 0; j < 10; j++ {
+			committee.NodeList = append(committee.NodeList, types.NodeID{BlsPublicKey: fmt.Sprintf("node%d-%d", i, j)})
+		}
+		shardState = append(shardState, committee)
+	}
+	return shardState
+}
+
+func TestGenesisShardState(t *testing.T) {
+	chainParams := *chainparams.DefaultConfig
+	if shardState := GenesisShardState(&chainParams); shardState != nil {
+		t.Errorf("expected no shard state without genesis committees, got %v", shardState)
+	}
+	chainParams.GenesisCommittees = map[uint32][]string{1: {"key3"}, 0: {"key1", "key2"}}
+	shardState := GenesisShardState(&chainParams)
+	if len(shardState) != 2 || shardState[0].ShardID != 0 || shardState[1].ShardID != 1 {
+		t.Fatalf("expected the committees of shards 0 and 1, got %v", shardState)
+	}
+	if len(shardState[0].NodeList) != 2 || shardState[0].NodeList[1].BlsPublicKey != "key2" {
+		t.Errorf("expected the keys of shard 0 in their order, got %v", shardState[0].NodeList)
+	}
+}
+
+func TestReshardSingleShard(t *testing.T) {
+	shardState := types.ShardState{{ShardID: 0, NodeList: []types.NodeID{{BlsPublicKey: "node1"}}}}
+	ss := NewShardingState(1, 42, shardState)
+	if rate := ss.Reshard([]types.NodeID{{BlsPublicKey: "new1"}, {BlsPublicKey: "new2"}}); rate != 0 {
+		t.Errorf("expected no kick out from the only shard, got %v", rate)
+	}
+	if len(ss.ShardState()[0].NodeList) != 3 {
+		t.Errorf("expected the new nodes to join the only shard, got %v", ss.ShardState()[0].NodeList)
 	}
 }
 
-func TestFakeNewNodeList(t *testing.T) {
-	nodeList := fakeNewNodeList(42)
-	fmt.Println("newNodeList: ", nodeList)
+func TestUpdateShardState(t *testing.T) {
+	newShardingState := func() *ShardingState {
+		shardState := testShardState()
+		return &ShardingState{epoch: 1, rnd: 42, shardState: shardState, numShards: len(shardState)}
+	}
+	newNodeList := []types.NodeID{}
+	for i := 0; i < 9; i++ {
+		newNodeList = append(newNodeList, types.NodeID{BlsPublicKey: fmt.Sprintf("newnode%d", i), Stake: uint64(100 + i)})
+	}
+
+	ss := newShardingState()
+	percent := ss.calculateKickoutRate(newNodeList)
+	ss.UpdateShardState(append([]types.NodeID{}, newNodeList...), percent)
+
+	// All the new nodes joined a committee with their stake, and no node was lost
+	numNodes := 0
+	stakes := map[string]uint64{}
+	for _, committee := range ss.shardState {
+		numNodes += len(committee.NodeList)
+		for _, nodeID := range committee.NodeList {
+			stakes[nodeID.BlsPublicKey] = nodeID.Stake
+		}
+	}
+	if numNodes != 6*10+len(newNodeList) {
+		t.Errorf("expected %d nodes after resharding, got %d", 6*10+len(newNodeList), numNodes)
+	}
+	for _, nodeID := range newNodeList {
+		if stake, ok := stakes[nodeID.BlsPublicKey]; !ok || stake != nodeID.Stake {
+			t.Errorf("new node %s is missing or has a wrong stake", nodeID.BlsPublicKey)
+		}
+	}
+
+	// The resharding is determined by the random seed
+	another := newShardingState()
+	another.UpdateShardState(append([]types.NodeID{}, newNodeList...), percent)
+	if ss.shardState.Hash() != another.shardState.Hash() {
+		t.Error("resharding with the same seed should give the same shard state")
+	}
+}
+
+func TestCalculateKickoutRate(t *testing.T) {
+	shardState := types.ShardState{
+		{ShardID: 0, NodeList: []types.NodeID{{BlsPublicKey: "node1"}, {BlsPublicKey: "node2"}, {BlsPublicKey: "node3"}}},
+		{ShardID: 1, NodeList: []types.NodeID{{BlsPublicKey: "node4"}, {BlsPublicKey: "node5"}}},
+	}
+	ss := NewShardingState(1, 42, shardState)
+	if rate := ss.calculateKickoutRate(nil); rate != 0 {
+		t.Errorf("expected no kick out without new nodes, got %v", rate)
+	}
+	if rate := ss.calculateKickoutRate([]types.NodeID{{BlsPublicKey: "new1"}}); rate != 0.5 {
+		t.Errorf("expected kick out rate 0.5, got %v", rate)
+	}
+	// The rate is capped so that resharding never kicks out more nodes than a committee has
+	newNodeList := []types.NodeID{{BlsPublicKey: "new1"}, {BlsPublicKey: "new2"}, {BlsPublicKey: "new3"}}
+	if rate := ss.Reshard(newNodeList); rate != 1 {
+		t.Errorf("expected kick out rate 1, got %v", rate)
+	}
+	numNodes := 0
+	for _, committee := range ss.ShardState() {
+		numNodes += len(committee.NodeList)
+	}
+	if numNodes != 8 {
+		t.Errorf("expected 8 nodes after resharding, got %d", numNodes)
+	}
 }
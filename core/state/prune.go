@@ -0,0 +1,163 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// ErrUnsupportedDatabase is returned if the entries of the database can't be iterated over.
+var ErrUnsupportedDatabase = errors.New("database not supported for pruning")
+
+// MarkState adds to the set the hashes of the trie nodes and of the contract codes which
+// make up the state of the given root, including the storage tries of the accounts. The
+// subtries already in the set are skipped, as they are shared with a state marked before.
+func MarkState(db Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	return walkState(db, root, func(hash common.Hash) bool {
+		return markNode(hash, marked)
+	}, func(codeHash common.Hash) {
+		marked[codeHash] = struct{}{}
+	})
+}
+
+// CollectUnmarked adds to the dead set the hashes of the trie nodes and of the contract
+// codes of the state of the given root which are not in the marked set. The marked
+// subtries are skipped, as they belong to a state kept, as are the ones already collected.
+// It returns an error if the state is incomplete, e.g. pruned in part already, leaving
+// the nodes collected up to the missing one in the set.
+func CollectUnmarked(db Database, root common.Hash, marked, dead map[common.Hash]struct{}) error {
+	return walkState(db, root, func(hash common.Hash) bool {
+		if _, ok := marked[hash]; ok {
+			return false
+		}
+		return markNode(hash, dead)
+	}, func(codeHash common.Hash) {
+		if _, ok := marked[codeHash]; !ok {
+			dead[codeHash] = struct{}{}
+		}
+	})
+}
+
+// walkState iterates over the trie nodes of the state of the given root, including the
+// storage tries of the accounts, and over the contract codes. The visit function returns
+// whether to descend into the children of the node.
+func walkState(db Database, root common.Hash, visit func(hash common.Hash) bool, visitCode func(codeHash common.Hash)) error {
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = visit(it.Hash())
+		if !it.Leaf() {
+			continue
+		}
+		var account Account
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return err
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCode {
+			visitCode(codeHash)
+		}
+		if account.Root == types.EmptyRootHash {
+			continue
+		}
+		storage, err := db.OpenStorageTrie(common.BytesToHash(it.LeafKey()), account.Root)
+		if err != nil {
+			return err
+		}
+		storageIt := storage.NodeIterator(nil)
+		for descend := true; storageIt.Next(descend); {
+			descend = visit(storageIt.Hash())
+		}
+		if err := storageIt.Error(); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// markNode adds the hash of the trie node to the set, and returns whether the iteration
+// should descend into its children. The nodes embedded in their parents have no hash.
+func markNode(hash common.Hash, marked map[common.Hash]struct{}) bool {
+	if hash == (common.Hash{}) {
+		return true
+	}
+	if _, ok := marked[hash]; ok {
+		return false
+	}
+	marked[hash] = struct{}{}
+	return true
+}
+
+// SweepNodes deletes from the database the trie nodes and contract codes, which are the
+// entries keyed by their hashes, that are not in the set to keep. It returns the number
+// of entries deleted.
+func SweepNodes(db ethdb.Database, keep map[common.Hash]struct{}) (int, error) {
+	batch := db.NewBatch()
+	deleted := 0
+	sweep := func(key []byte) error {
+		if len(key) != common.HashLength {
+			return nil
+		}
+		if _, ok := keep[common.BytesToHash(key)]; ok {
+			return nil
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+		deleted++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}
+
+	switch db := db.(type) {
+	case *ethdb.LDBDatabase:
+		it := db.NewIterator()
+		for it.Next() {
+			if err := sweep(it.Key()); err != nil {
+				it.Release()
+				return deleted, err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return deleted, err
+		}
+	case *ethdb.MemDatabase:
+		for _, key := range db.Keys() {
+			if err := sweep(key); err != nil {
+				return deleted, err
+			}
+		}
+	default:
+		return 0, ErrUnsupportedDatabase
+	}
+	return deleted, batch.Write()
+}
+
+// DeleteNodes deletes from the database the trie nodes and contract codes of the set, and
+// returns the number of entries deleted.
+func DeleteNodes(db ethdb.Database, dead map[common.Hash]struct{}) (int, error) {
+	batch := db.NewBatch()
+	for hash := range dead {
+		if err := batch.Delete(hash[:]); err != nil {
+			return 0, err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	return len(dead), batch.Write()
+}
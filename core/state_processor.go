@@ -17,6 +17,8 @@
 package core
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
@@ -52,13 +54,18 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
+//
+// The cross-shard transfers debit their senders and must be committed as the
+// cross-shard receipts of the block, and the proven receipts of the transfers
+// from other shards credit their recipients once.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.DB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
 	var (
-		receipts types.Receipts
-		usedGas  = new(uint64)
-		header   = block.Header()
-		allLogs  []*types.Log
-		gp       = new(GasPool).AddGas(block.GasLimit())
+		receipts           types.Receipts
+		crossShardReceipts types.CrossShardReceipts
+		usedGas            = new(uint64)
+		header             = block.Header()
+		allLogs            []*types.Log
+		gp                 = new(GasPool).AddGas(block.GasLimit())
 	)
 	// Mutate the block and state according to any hard-fork specs
 	//if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
@@ -67,12 +74,23 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.DB, cfg vm.C
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+		receipt, crossShardReceipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
 		if err != nil {
 			return nil, nil, 0, err
 		}
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
+		if crossShardReceipt != nil {
+			crossShardReceipts = append(crossShardReceipts, crossShardReceipt)
+		}
+	}
+	if hash := types.DeriveSha(crossShardReceipts); hash != header.CrossShardReceiptHash {
+		return nil, nil, 0, fmt.Errorf("invalid cross-shard receipt root hash (remote: %x local: %x)", header.CrossShardReceiptHash, hash)
+	}
+	for _, proof := range block.IncomingReceipts() {
+		if err := ApplyIncomingReceipt(p.bc, p.engine, statedb, header, proof); err != nil {
+			return nil, nil, 0, err
+		}
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts)
@@ -82,12 +100,20 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.DB, cfg vm.C
 
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
-// for the transaction, gas used and an error if the transaction failed,
-// indicating the block was invalid.
-func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.DB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
+// for the transaction, the cross-shard receipt if it transfers to another shard,
+// gas used and an error if the transaction failed, indicating the block was invalid.
+func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.DB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, *types.CrossShardReceipt, uint64, error) {
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
+	}
+	// The sender of a cross-shard transfer can only be debited in its own shard
+	if tx.IsCrossShard() && types.EncodeShardID(tx.ShardID()) != header.ShardID {
+		return nil, nil, 0, ErrInvalidCrossShardTx
+	}
+	// The transfer would debit the sender without ever crediting the recipient
+	if chain, ok := bc.(*BlockChain); ok && tx.IsCrossShard() && !CanDeliverCrossShardTx(chain, header, tx.ToShardID()) {
+		return nil, nil, 0, ErrUndeliverableCrossShardTx
 	}
 	// Create a new context to be used in the EVM environment
 	context := NewEVMContext(msg, header, bc, author)
@@ -97,7 +123,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Apply the transaction to the current state (included in the env)
 	_, gas, failed, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	// Update the state with pending changes
 	var root []byte
@@ -121,5 +147,10 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	//receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 
-	return receipt, gas, err
+	var crossShardReceipt *types.CrossShardReceipt
+	if tx.IsCrossShard() {
+		crossShardReceipt = NewCrossShardReceipt(tx, msg.From())
+	}
+
+	return receipt, crossShardReceipt, gas, err
 }
@@ -73,6 +73,8 @@ type Message interface {
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	// CrossShard returns whether the message transfers the value to another shard
+	CrossShard() bool
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
@@ -205,7 +207,18 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		// error.
 		vmerr error
 	)
-	if contractCreation {
+	if msg.CrossShard() {
+		// Only the sender is debited here; the recipient is credited in its own shard
+		// once the receipt of the transfer is proven there.
+		if contractCreation || len(st.data) != 0 {
+			return nil, 0, false, ErrInvalidCrossShardTx
+		}
+		if st.state.GetBalance(msg.From()).Cmp(st.value) < 0 {
+			return nil, 0, false, vm.ErrInsufficientBalance
+		}
+		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
+		st.state.SubBalance(msg.From(), st.value)
+	} else if contractCreation {
 		ret, _, st.gas, vmerr = evm.Create(sender, st.data, st.gas, st.value)
 	} else {
 		// Increment the nonce for the next transaction
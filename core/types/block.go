@@ -98,8 +98,19 @@ type Header struct {
 	CommitSignature  [48]byte `json:"signature"        gencodec:"required"`
 	CommitBitmap     []byte   `json:"bitmap"           gencodec:"required"` // Contains which validator signed
 
-	RandSeed       uint64      `json:"randomSeed"`
+	RandSeed       [32]byte    `json:"randomSeed"` // pRnd in epoch blocks, and the final randomness in the last blocks of epochs
 	ShardStateHash common.Hash `json:"shardStateRoot"`
+	EvidenceHash   common.Hash `json:"evidenceRoot"`
+	RandPreimage   []byte      `json:"randPreimage"` // The encoded drand preimage of the epoch randomness, only in epoch blocks
+	Vdf            []byte      `json:"vdf"`          // The encoded VDF output over pRnd and its proof, only in the last blocks of epochs
+
+	CrossShardReceiptHash common.Hash `json:"crossShardReceiptsRoot"` // The root of the receipts of the transfers to other shards
+	IncomingReceiptHash   common.Hash `json:"incomingReceiptsRoot"`   // The root of the proofs of the receipts of the transfers from other shards
+
+	// The commit signature and bitmap of the last block committed when the block is built,
+	// two blocks earlier in pipelined consensus. Its signers are rewarded in the block.
+	LastCommitSignature [48]byte `json:"lastCommitSignature"`
+	LastCommitBitmap    []byte   `json:"lastCommitBitmap"`
 }
 
 // field type overrides for gencodec
@@ -113,10 +124,16 @@ type headerMarshaling struct {
 	Hash       common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
 }
 
-// Hash returns the block hash of the header, which is simply the keccak256 hash of its
-// RLP encoding.
+// Hash returns the block hash of the header, which is the keccak256 hash of its
+// RLP encoding without the commit signature. The commit signature is only known
+// once the block is committed, while the next block is announced on top of the
+// prepared block in pipelined consensus. It's checked along with the seal, and
+// covered by the hash of the block recording it as its last commit.
 func (h *Header) Hash() common.Hash {
-	return rlpHash(h)
+	cpy := *h
+	cpy.CommitSignature = [48]byte{}
+	cpy.CommitBitmap = nil
+	return rlpHash(&cpy)
 }
 
 // Size returns the approximate memory used by all internal contents. It is used
@@ -133,10 +150,13 @@ func rlpHash(x interface{}) (h common.Hash) {
 }
 
 // Body is a simple (mutable, non-safe) data container for storing and moving
-// a block's data contents (transactions and uncles) together.
+// a block's data contents (transactions, uncles, evidences and cross-shard receipts) together.
 type Body struct {
-	Transactions []*Transaction
-	Uncles       []*Header
+	Transactions       []*Transaction
+	Uncles             []*Header
+	Evidences          []*DoubleSignEvidence
+	CrossShardReceipts []*CrossShardReceipt
+	IncomingReceipts   []*CrossShardReceiptProof
 }
 
 // Block represents an entire block in the Ethereum blockchain.
@@ -144,6 +164,11 @@ type Block struct {
 	header       *Header
 	uncles       []*Header
 	transactions Transactions
+	evidences    DoubleSignEvidences
+	// The receipts of the transfers to other shards, and the proven receipts of the
+	// transfers from other shards
+	crossShardReceipts CrossShardReceipts
+	incomingReceipts   CrossShardReceiptProofs
 
 	// caches
 	hash atomic.Value
@@ -174,9 +199,12 @@ type StorageBlock Block
 
 // "external" block encoding. used for eth protocol, etc.
 type extblock struct {
-	Header *Header
-	Txs    []*Transaction
-	Uncles []*Header
+	Header             *Header
+	Txs                []*Transaction
+	Uncles             []*Header
+	Evidences          []*DoubleSignEvidence
+	CrossShardReceipts []*CrossShardReceipt
+	IncomingReceipts   []*CrossShardReceiptProof
 }
 
 // [deprecated by eth/63]
@@ -214,6 +242,10 @@ func NewBlock(header *Header, txs []*Transaction, receipts []*Receipt) *Block {
 		b.header.Bloom = CreateBloom(receipts)
 	}
 
+	b.header.EvidenceHash = EmptyRootHash
+	b.header.CrossShardReceiptHash = EmptyRootHash
+	b.header.IncomingReceiptHash = EmptyRootHash
+
 	return b
 }
 
@@ -241,6 +273,12 @@ func CopyHeader(h *Header) *Header {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
 	}
+	if len(h.RandPreimage) > 0 {
+		cpy.RandPreimage = common.CopyBytes(h.RandPreimage)
+	}
+	if len(h.Vdf) > 0 {
+		cpy.Vdf = common.CopyBytes(h.Vdf)
+	}
 	return &cpy
 }
 
@@ -251,7 +289,8 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.uncles, b.transactions = eb.Header, eb.Uncles, eb.Txs
+	b.header, b.uncles, b.transactions, b.evidences = eb.Header, eb.Uncles, eb.Txs, eb.Evidences
+	b.crossShardReceipts, b.incomingReceipts = eb.CrossShardReceipts, eb.IncomingReceipts
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
@@ -259,9 +298,12 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 // EncodeRLP serializes b into the Ethereum RLP block format.
 func (b *Block) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, extblock{
-		Header: b.header,
-		Txs:    b.transactions,
-		Uncles: b.uncles,
+		Header:             b.header,
+		Txs:                b.transactions,
+		Uncles:             b.uncles,
+		Evidences:          b.evidences,
+		CrossShardReceipts: b.crossShardReceipts,
+		IncomingReceipts:   b.incomingReceipts,
 	})
 }
 
@@ -286,6 +328,22 @@ func (b *Block) Transactions() Transactions {
 	return b.transactions
 }
 
+// Evidences returns the double sign evidences included in the block.
+func (b *Block) Evidences() DoubleSignEvidences {
+	return b.evidences
+}
+
+// CrossShardReceipts returns the receipts of the transfers to other shards in the block.
+func (b *Block) CrossShardReceipts() CrossShardReceipts {
+	return b.crossShardReceipts
+}
+
+// IncomingReceipts returns the proven receipts of the transfers from other shards
+// credited in the block.
+func (b *Block) IncomingReceipts() CrossShardReceiptProofs {
+	return b.incomingReceipts
+}
+
 // Transaction returns Transaction.
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
@@ -344,11 +402,16 @@ func (b *Block) ReceiptHash() common.Hash { return b.header.ReceiptHash }
 // Extra returns header extra.
 func (b *Block) Extra() []byte { return common.CopyBytes(b.header.Extra) }
 
+// RandPreimage returns the encoded drand preimage of the epoch randomness.
+func (b *Block) RandPreimage() []byte { return common.CopyBytes(b.header.RandPreimage) }
+
 // Header returns a copy of Header.
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
-func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles} }
+func (b *Block) Body() *Body {
+	return &Body{b.transactions, b.uncles, b.evidences, b.crossShardReceipts, b.incomingReceipts}
+}
 
 // Size returns the true RLP encoded storage size of the block, either by encoding
 // and returning it, or returning a previsouly cached value.
@@ -383,17 +446,29 @@ func (b *Block) WithSeal(header *Header) *Block {
 		header:       &cpy,
 		transactions: b.transactions,
 		uncles:       b.uncles,
+		evidences:    b.evidences,
+
+		crossShardReceipts: b.crossShardReceipts,
+		incomingReceipts:   b.incomingReceipts,
 	}
 }
 
-// WithBody returns a new block with the given transaction and uncle contents.
-func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
+// WithBody returns a new block with the given transaction, uncle, evidence and cross-shard
+// receipt contents.
+func (b *Block) WithBody(transactions []*Transaction, uncles []*Header, evidences []*DoubleSignEvidence, crossShardReceipts []*CrossShardReceipt, incomingReceipts []*CrossShardReceiptProof) *Block {
 	block := &Block{
 		header:       CopyHeader(b.header),
 		transactions: make([]*Transaction, len(transactions)),
 		uncles:       make([]*Header, len(uncles)),
+		evidences:    make([]*DoubleSignEvidence, len(evidences)),
+
+		crossShardReceipts: make(CrossShardReceipts, len(crossShardReceipts)),
+		incomingReceipts:   make(CrossShardReceiptProofs, len(incomingReceipts)),
 	}
 	copy(block.transactions, transactions)
+	copy(block.evidences, evidences)
+	copy(block.crossShardReceipts, crossShardReceipts)
+	copy(block.incomingReceipts, incomingReceipts)
 	for i := range uncles {
 		block.uncles[i] = CopyHeader(uncles[i])
 	}
@@ -452,11 +527,62 @@ func Number(b1, b2 *Block) bool {
 }
 
 // AddRandSeed add random seed into block header
-func (b *Block) AddRandSeed(randSeed int64) {
-	b.header.RandSeed = uint64(randSeed)
+func (b *Block) AddRandSeed(randSeed [32]byte) {
+	b.header.RandSeed = randSeed
+	b.hash = atomic.Value{}
+}
+
+// AddRandPreimage adds the encoded drand preimage of the epoch randomness into block header
+func (b *Block) AddRandPreimage(preimage []byte) {
+	b.header.RandPreimage = common.CopyBytes(preimage)
+	b.hash = atomic.Value{}
+}
+
+// AddVdf adds the encoded VDF output over pRnd and its proof into block header
+func (b *Block) AddVdf(vdf []byte) {
+	b.header.Vdf = common.CopyBytes(vdf)
+	b.hash = atomic.Value{}
+}
+
+// SetPrepareSig sets the aggregated prepare signature and the bitmap of its signers into block header
+func (b *Block) SetPrepareSig(sig []byte, signers []byte) {
+	copy(b.header.PrepareSignature[:], sig)
+	b.header.PrepareBitmap = append(signers[:0:0], signers...)
+	b.hash = atomic.Value{}
+}
+
+// SetCommitSig sets the aggregated commit signature and the bitmap of its signers into block header
+func (b *Block) SetCommitSig(sig []byte, signers []byte) {
+	copy(b.header.CommitSignature[:], sig)
+	b.header.CommitBitmap = append(signers[:0:0], signers...)
+	b.hash = atomic.Value{}
 }
 
 // AddShardStateHash add shardStateHash into block header
 func (b *Block) AddShardStateHash(shardStateHash common.Hash) {
 	b.header.ShardStateHash = shardStateHash
+	b.hash = atomic.Value{}
+}
+
+// AddEvidences adds double sign evidences into block body, and their root hash into block header
+func (b *Block) AddEvidences(evidences []*DoubleSignEvidence) {
+	b.evidences = append(b.evidences, evidences...)
+	b.header.EvidenceHash = DeriveSha(b.evidences)
+	b.hash = atomic.Value{}
+}
+
+// AddCrossShardReceipts adds the receipts of the transfers to other shards into block body,
+// and their root hash into block header
+func (b *Block) AddCrossShardReceipts(receipts []*CrossShardReceipt) {
+	b.crossShardReceipts = append(b.crossShardReceipts, receipts...)
+	b.header.CrossShardReceiptHash = DeriveSha(b.crossShardReceipts)
+	b.hash = atomic.Value{}
+}
+
+// AddIncomingReceipts adds the proven receipts of the transfers from other shards into
+// block body, and their root hash into block header
+func (b *Block) AddIncomingReceipts(proofs []*CrossShardReceiptProof) {
+	b.incomingReceipts = append(b.incomingReceipts, proofs...)
+	b.header.IncomingReceiptHash = DeriveSha(b.incomingReceipts)
+	b.hash = atomic.Value{}
 }
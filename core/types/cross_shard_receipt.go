@@ -0,0 +1,154 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Errors of the cross-shard receipt proofs.
+var (
+	ErrInvalidReceiptProof  = errors.New("invalid merkle proof of the cross-shard receipt")
+	ErrReceiptShardMismatch = errors.New("cross-shard receipt not from the shard of the proving block")
+)
+
+// CrossShardReceipt is the receipt of a transfer to another shard. The source shard emits
+// it when it debits the sender, and commits it in the block header; the destination shard
+// credits the recipient once it's proven to be in a block of the source shard.
+type CrossShardReceipt struct {
+	TxHash    common.Hash
+	From      common.Address
+	To        common.Address
+	ShardID   uint32
+	ToShardID uint32
+	Amount    *big.Int
+}
+
+// Hash returns the hash identifying the receipt.
+func (r *CrossShardReceipt) Hash() common.Hash {
+	return rlpHash(r)
+}
+
+// CrossShardReceipts is a list of cross-shard receipts.
+type CrossShardReceipts []*CrossShardReceipt
+
+// Len returns the length of s.
+func (s CrossShardReceipts) Len() int { return len(s) }
+
+// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+func (s CrossShardReceipts) GetRlp(i int) []byte {
+	enc, _ := rlp.EncodeToBytes(s[i])
+	return enc
+}
+
+// Prove returns the merkle proof of the i'th receipt in the trie whose root hash is
+// DeriveSha(s), as the encoded trie nodes on the path to the receipt.
+func (s CrossShardReceipts) Prove(i int) ([][]byte, error) {
+	keybuf := new(bytes.Buffer)
+	trie := new(trie.Trie)
+	for j := 0; j < s.Len(); j++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(j))
+		trie.Update(keybuf.Bytes(), s.GetRlp(j))
+	}
+	key, _ := rlp.EncodeToBytes(uint(i))
+	proof := &proofNodes{}
+	if err := trie.Prove(key, 0, proof); err != nil {
+		return nil, err
+	}
+	return proof.nodes, nil
+}
+
+// CrossShardReceiptProof proves that a receipt was committed by the source shard: the
+// receipt is in the cross-shard receipt trie of a block header, and the header is signed
+// by the committee of the source shard.
+type CrossShardReceiptProof struct {
+	Receipt     *CrossShardReceipt
+	Index       uint64   // the index of the receipt in the block
+	MerkleProof [][]byte // the encoded trie nodes on the path from the root to the receipt
+	Header      *Header  // the header of the block, with the commit signature of the committee
+}
+
+// Verify checks that the receipt is in the cross-shard receipts of the header. It
+// doesn't verify the signatures of the header.
+func (p *CrossShardReceiptProof) Verify() error {
+	if p.Receipt == nil || p.Header == nil {
+		return ErrInvalidReceiptProof
+	}
+	if EncodeShardID(p.Receipt.ShardID) != p.Header.ShardID {
+		return ErrReceiptShardMismatch
+	}
+	key, _ := rlp.EncodeToBytes(uint(p.Index))
+	value, _, err := trie.VerifyProof(p.Header.CrossShardReceiptHash, key, proofNodes{nodes: p.MerkleProof})
+	if err != nil {
+		return ErrInvalidReceiptProof
+	}
+	if receipt, _ := rlp.EncodeToBytes(p.Receipt); !bytes.Equal(value, receipt) {
+		return ErrInvalidReceiptProof
+	}
+	return nil
+}
+
+// CrossShardReceiptProofs returns the proofs of the cross-shard receipts of the block, by
+// the shards they are sent to. The block must be committed, so that its header carries the
+// commit signature.
+func (b *Block) CrossShardReceiptProofs() (map[uint32]CrossShardReceiptProofs, error) {
+	proofs := map[uint32]CrossShardReceiptProofs{}
+	for i, receipt := range b.crossShardReceipts {
+		merkleProof, err := b.crossShardReceipts.Prove(i)
+		if err != nil {
+			return nil, err
+		}
+		proofs[receipt.ToShardID] = append(proofs[receipt.ToShardID], &CrossShardReceiptProof{
+			Receipt:     receipt,
+			Index:       uint64(i),
+			MerkleProof: merkleProof,
+			Header:      b.Header(),
+		})
+	}
+	return proofs, nil
+}
+
+// CrossShardReceiptProofs is a list of cross-shard receipt proofs.
+type CrossShardReceiptProofs []*CrossShardReceiptProof
+
+// Len returns the length of s.
+func (s CrossShardReceiptProofs) Len() int { return len(s) }
+
+// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+func (s CrossShardReceiptProofs) GetRlp(i int) []byte {
+	enc, _ := rlp.EncodeToBytes(s[i])
+	return enc
+}
+
+// proofNodes is the database of the trie nodes of a merkle proof, keyed by their hashes.
+type proofNodes struct {
+	nodes [][]byte
+}
+
+// Put implements ethdb.Putter, collecting the nodes of a proof.
+func (p *proofNodes) Put(key []byte, value []byte) error {
+	p.nodes = append(p.nodes, common.CopyBytes(value))
+	return nil
+}
+
+// Get implements trie.DatabaseReader, returning the node of the given hash.
+func (p proofNodes) Get(key []byte) ([]byte, error) {
+	for _, node := range p.nodes {
+		if bytes.Equal(crypto.Keccak256(node), key) {
+			return node, nil
+		}
+	}
+	return nil, errors.New("missing trie node")
+}
+
+// Has implements trie.DatabaseReader.
+func (p proofNodes) Has(key []byte) (bool, error) {
+	_, err := p.Get(key)
+	return err == nil, nil
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func testCrossShardBlock() *Block {
+	block := NewBlockWithHeader(&Header{Number: big.NewInt(3), ShardID: EncodeShardID(1)})
+	receipts := []*CrossShardReceipt{}
+	for i := 0; i < 5; i++ {
+		receipts = append(receipts, &CrossShardReceipt{
+			TxHash:    common.BytesToHash([]byte{byte(i)}),
+			From:      common.BytesToAddress([]byte{1, byte(i)}),
+			To:        common.BytesToAddress([]byte{2, byte(i)}),
+			ShardID:   1,
+			ToShardID: uint32(i % 2),
+			Amount:    big.NewInt(int64(100 + i)),
+		})
+	}
+	block.AddCrossShardReceipts(receipts)
+	return block
+}
+
+func TestCrossShardReceiptProofs(t *testing.T) {
+	block := testCrossShardBlock()
+	if block.Header().CrossShardReceiptHash != DeriveSha(block.CrossShardReceipts()) {
+		t.Fatal("cross-shard receipt root hash not committed in the header")
+	}
+	proofs, err := block.CrossShardReceiptProofs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proofs[0]) != 3 || len(proofs[1]) != 2 {
+		t.Errorf("expected 3 proofs to shard 0 and 2 to shard 1, got %d and %d", len(proofs[0]), len(proofs[1]))
+	}
+	for shardID, shardProofs := range proofs {
+		for _, proof := range shardProofs {
+			if proof.Receipt.ToShardID != shardID {
+				t.Errorf("proof of a receipt to shard %d grouped with shard %d", proof.Receipt.ToShardID, shardID)
+			}
+			if err := proof.Verify(); err != nil {
+				t.Errorf("proof of receipt %d: %v", proof.Index, err)
+			}
+		}
+	}
+
+	// The proofs are still valid after being sent over the network
+	data, err := rlp.EncodeToBytes(proofs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := CrossShardReceiptProofs{}
+	if err := rlp.DecodeBytes(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, proof := range decoded {
+		if err := proof.Verify(); err != nil {
+			t.Errorf("decoded proof of receipt %d: %v", proof.Index, err)
+		}
+	}
+}
+
+func TestCrossShardReceiptProofInvalid(t *testing.T) {
+	proofs, err := testCrossShardBlock().CrossShardReceiptProofs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof := *proofs[0][0]
+	receipt := *proof.Receipt
+	receipt.Amount = big.NewInt(1000000)
+	proof.Receipt = &receipt
+	if err := proof.Verify(); err != ErrInvalidReceiptProof {
+		t.Errorf("a tampered receipt should fail the proof, got %v", err)
+	}
+
+	proof = *proofs[0][0]
+	proof.Index = proofs[0][1].Index
+	if err := proof.Verify(); err != ErrInvalidReceiptProof {
+		t.Errorf("a receipt at the wrong index should fail the proof, got %v", err)
+	}
+
+	proof = *proofs[0][0]
+	proof.Header = CopyHeader(proof.Header)
+	proof.Header.ShardID = EncodeShardID(2)
+	if err := proof.Verify(); err != ErrReceiptShardMismatch {
+		t.Errorf("a receipt proven by a block of another shard should fail, got %v", err)
+	}
+}
+
+func TestBlockCrossShardReceiptsRLP(t *testing.T) {
+	block := testCrossShardBlock()
+	proofs, _ := block.CrossShardReceiptProofs()
+	block.AddIncomingReceipts(proofs[0])
+
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Block{}
+	if err := rlp.DecodeBytes(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash() != block.Hash() {
+		t.Errorf("block hash changed after decoding: have %x, want %x", decoded.Hash(), block.Hash())
+	}
+	if DeriveSha(decoded.CrossShardReceipts()) != decoded.Header().CrossShardReceiptHash {
+		t.Error("decoded cross-shard receipts don't match the header")
+	}
+	if DeriveSha(decoded.IncomingReceipts()) != decoded.Header().IncomingReceiptHash {
+		t.Error("decoded incoming receipts don't match the header")
+	}
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+)
+
+// DoubleSignEvidence is the proof that a validator signed two different blocks
+// in the same phase of the same consensus round: two consensus messages which
+// differ only in the block hash, both signed by the offender's BLS key. The block
+// number tells the committee the offender must have been a member of.
+type DoubleSignEvidence struct {
+	BlockNumber uint64 // number of the block proposed in the double signed round
+	PubKey      []byte // serialized BLS public key of the offender
+	Message1    []byte // marshaled consensus message, including its signature
+	Message2    []byte // marshaled consensus message, including its signature
+}
+
+// DoubleSignOffense identifies a double signing: the offender and the phase of the
+// consensus round it signed twice. Any number of evidences prove the same offense,
+// but only one of them may get into the chain.
+type DoubleSignOffense struct {
+	PubKey      string
+	ConsensusID uint32
+	ViewID      uint32
+	MsgType     consensus_proto.MessageType
+}
+
+// Hash returns the hash identifying the evidence. The messages are hashed in
+// canonical order, so swapping them doesn't make another evidence.
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	message1, message2 := e.Message1, e.Message2
+	if bytes.Compare(message1, message2) > 0 {
+		message1, message2 = message2, message1
+	}
+	return rlpHash(&DoubleSignEvidence{
+		BlockNumber: e.BlockNumber,
+		PubKey:      e.PubKey,
+		Message1:    message1,
+		Message2:    message2,
+	})
+}
+
+// Offense returns the double signing the evidence is about, as told by its first
+// message. It only makes sense for a verified evidence.
+func (e *DoubleSignEvidence) Offense() (DoubleSignOffense, error) {
+	message := consensus_proto.Message{}
+	if err := protobuf.Unmarshal(e.Message1, &message); err != nil {
+		return DoubleSignOffense{}, err
+	}
+	return DoubleSignOffense{
+		PubKey:      string(e.PubKey),
+		ConsensusID: message.ConsensusId,
+		ViewID:      message.ViewId,
+		MsgType:     message.Type,
+	}, nil
+}
+
+// DoubleSignEvidences is a list of double sign evidences.
+type DoubleSignEvidences []*DoubleSignEvidence
+
+// Len returns the length of s.
+func (s DoubleSignEvidences) Len() int { return len(s) }
+
+// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+func (s DoubleSignEvidences) GetRlp(i int) []byte {
+	enc, _ := rlp.EncodeToBytes(s[i])
+	return enc
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	consensus_proto "github.com/harmony-one/harmony/api/consensus"
+)
+
+func TestDoubleSignEvidenceHash(t *testing.T) {
+	message1, _ := protobuf.Marshal(&consensus_proto.Message{Type: consensus_proto.MessageType_PREPARE, ConsensusId: 3, BlockHash: []byte{1}})
+	message2, _ := protobuf.Marshal(&consensus_proto.Message{Type: consensus_proto.MessageType_PREPARE, ConsensusId: 3, BlockHash: []byte{2}})
+	evidence := &DoubleSignEvidence{BlockNumber: 4, PubKey: []byte("offender"), Message1: message1, Message2: message2}
+	swapped := &DoubleSignEvidence{BlockNumber: 4, PubKey: []byte("offender"), Message1: message2, Message2: message1}
+
+	if evidence.Hash() != swapped.Hash() {
+		t.Error("swapping the messages changed the evidence hash")
+	}
+	offense, err := evidence.Offense()
+	if err != nil {
+		t.Fatalf("failed to read the offense: %v", err)
+	}
+	swappedOffense, _ := swapped.Offense()
+	if offense != swappedOffense {
+		t.Errorf("offense %v, swapped %v", offense, swappedOffense)
+	}
+	expected := DoubleSignOffense{PubKey: "offender", ConsensusID: 3, MsgType: consensus_proto.MessageType_PREPARE}
+	if offense != expected {
+		t.Errorf("offense %v, expected %v", offense, expected)
+	}
+}
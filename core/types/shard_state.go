@@ -1,14 +1,18 @@
 package types
 
 import (
+	"encoding/binary"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"golang.org/x/crypto/sha3"
 )
 
-// NodeID is a unique ID represent a node
-type NodeID string
+// NodeID represents a node in a committee by its BLS public key, along with its stake
+type NodeID struct {
+	BlsPublicKey string // hex string of the serialized BLS public key, which uniquely identifies the node
+	Stake        uint64 // the amount the node staked in the staking contract
+}
 
 // ShardState is the collection of all committees
 type ShardState []Committee
@@ -16,7 +20,7 @@ type ShardState []Committee
 // Committee contains the active nodes in one shard
 type Committee struct {
 	ShardID  uint32
-	NodeList []NodeID // a list of NodeID where NodeID is represented by a string
+	NodeList []NodeID // a list of NodeID where NodeID is represented by its BLS public key and stake
 }
 
 // GetHashFromNodeList will sort the list, then use Keccak256 to hash the list
@@ -51,18 +55,20 @@ func (ss ShardState) Hash() (h common.Hash) {
 	return h
 }
 
-// CompareNodeID compares two nodes by their ID; used to sort node list
+// CompareNodeID compares two nodes by their BLS public key; used to sort node list
 func CompareNodeID(n1 NodeID, n2 NodeID) int {
-	if n1 < n2 {
+	if n1.BlsPublicKey < n2.BlsPublicKey {
 		return -1
 	}
-	if n1 > n2 {
+	if n1.BlsPublicKey > n2.BlsPublicKey {
 		return 1
 	}
 	return 0
 }
 
-// Serialize serialize NodeID into bytes
+// Serialize serialize NodeID into bytes, the BLS public key followed by the big endian stake
 func (n NodeID) Serialize() []byte {
-	return []byte(n)
+	stake := make([]byte, 8)
+	binary.BigEndian.PutUint64(stake, n.Stake)
+	return append([]byte(n.BlsPublicKey), stake...)
 }
@@ -6,8 +6,8 @@ import (
 )
 
 func TestGetHashFromNodeList(t *testing.T) {
-	l1 := []NodeID{"node1", "node2", "node3"}
-	l2 := []NodeID{"node2", "node1", "node3"}
+	l1 := []NodeID{{BlsPublicKey: "node1"}, {BlsPublicKey: "node2"}, {BlsPublicKey: "node3"}}
+	l2 := []NodeID{{BlsPublicKey: "node2"}, {BlsPublicKey: "node1"}, {BlsPublicKey: "node3"}}
 	h1 := GetHashFromNodeList(l1)
 	h2 := GetHashFromNodeList(l2)
 
@@ -17,13 +17,13 @@ func TestGetHashFromNodeList(t *testing.T) {
 }
 
 func TestHash(t *testing.T) {
-	com1 := Committee{ShardID: 22, NodeList: []NodeID{"node11", "node22", "node1"}}
-	com2 := Committee{ShardID: 2, NodeList: []NodeID{"node4", "node5", "node6"}}
+	com1 := Committee{ShardID: 22, NodeList: []NodeID{{BlsPublicKey: "node11"}, {BlsPublicKey: "node22"}, {BlsPublicKey: "node1"}}}
+	com2 := Committee{ShardID: 2, NodeList: []NodeID{{BlsPublicKey: "node4"}, {BlsPublicKey: "node5"}, {BlsPublicKey: "node6"}}}
 	shardState1 := ShardState{com1, com2}
 	h1 := shardState1.Hash()
 
-	com3 := Committee{ShardID: 2, NodeList: []NodeID{"node6", "node5", "node4"}}
-	com4 := Committee{ShardID: 22, NodeList: []NodeID{"node1", "node11", "node22"}}
+	com3 := Committee{ShardID: 2, NodeList: []NodeID{{BlsPublicKey: "node6"}, {BlsPublicKey: "node5"}, {BlsPublicKey: "node4"}}}
+	com4 := Committee{ShardID: 22, NodeList: []NodeID{{BlsPublicKey: "node1"}, {BlsPublicKey: "node11"}, {BlsPublicKey: "node22"}}}
 	shardState2 := ShardState{com3, com4}
 	h2 := shardState2.Hash()
 
@@ -31,3 +31,14 @@ func TestHash(t *testing.T) {
 		t.Error("shardState1 and shardState2 should have equal hash")
 	}
 }
+
+func TestHashWithStake(t *testing.T) {
+	com1 := Committee{ShardID: 1, NodeList: []NodeID{{BlsPublicKey: "node1", Stake: 10}, {BlsPublicKey: "node2", Stake: 20}}}
+	com2 := Committee{ShardID: 1, NodeList: []NodeID{{BlsPublicKey: "node1", Stake: 10}, {BlsPublicKey: "node2", Stake: 21}}}
+	h1 := ShardState{com1}.Hash()
+	h2 := ShardState{com2}.Hash()
+
+	if bytes.Compare(h1[:], h2[:]) == 0 {
+		t.Error("shard states with different stakes should have different hashes")
+	}
+}
@@ -48,6 +48,7 @@ type Transaction struct {
 type txdata struct {
 	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
 	ShardID      uint32          `json:"shardID"  gencodec:"required"`
+	ToShardID    uint32          `json:"toShardID" gencodec:"required"` // the shard of the recipient, ShardID unless it's a cross-shard transfer
 	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
 	GasLimit     uint64          `json:"gas"      gencodec:"required"`
 	Recipient    *common.Address `json:"to"       rlp:"nil"` // nil means contract creation
@@ -79,6 +80,14 @@ func NewTransaction(nonce uint64, to common.Address, shardID uint32, amount *big
 	return newTransaction(nonce, &to, shardID, amount, gasLimit, gasPrice, data)
 }
 
+// NewCrossShardTransaction returns a transaction transferring the amount from the sender in
+// the shard of shardID to the recipient in the shard of toShardID.
+func NewCrossShardTransaction(nonce uint64, to common.Address, shardID uint32, toShardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+	tx := newTransaction(nonce, &to, shardID, amount, gasLimit, gasPrice, data)
+	tx.data.ToShardID = toShardID
+	return tx
+}
+
 // NewContractCreation returns contract transaction.
 func NewContractCreation(nonce uint64, shardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
 	return newTransaction(nonce, nil, shardID, amount, gasLimit, gasPrice, data)
@@ -92,6 +101,7 @@ func newTransaction(nonce uint64, to *common.Address, shardID uint32, amount *bi
 		AccountNonce: nonce,
 		Recipient:    to,
 		ShardID:      shardID,
+		ToShardID:    shardID,
 		Payload:      data,
 		Amount:       new(big.Int),
 		GasLimit:     gasLimit,
@@ -120,6 +130,16 @@ func (tx *Transaction) ShardID() uint32 {
 	return tx.data.ShardID
 }
 
+// ToShardID returns the shard id of the recipient of the transaction.
+func (tx *Transaction) ToShardID() uint32 {
+	return tx.data.ToShardID
+}
+
+// IsCrossShard returns whether the transaction transfers value to another shard.
+func (tx *Transaction) IsCrossShard() bool {
+	return tx.data.ToShardID != tx.data.ShardID
+}
+
 // Protected returns whether the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
 	return isProtectedV(tx.data.V)
@@ -260,6 +280,7 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		amount:     tx.data.Amount,
 		data:       tx.data.Payload,
 		checkNonce: true,
+		crossShard: tx.IsCrossShard(),
 	}
 
 	var err error
@@ -429,6 +450,7 @@ type Message struct {
 	gasPrice   *big.Int
 	data       []byte
 	checkNonce bool
+	crossShard bool
 }
 
 // NewMessage returns new message.
@@ -484,3 +506,8 @@ func (m Message) Data() []byte {
 func (m Message) CheckNonce() bool {
 	return m.checkNonce
 }
+
+// CrossShard returns whether the Message transfers value to another shard.
+func (m Message) CrossShard() bool {
+	return m.crossShard
+}
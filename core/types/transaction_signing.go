@@ -157,7 +157,7 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	return rlpHash(withCrossShardFields(tx, []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -165,7 +165,7 @@ func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 		tx.data.Amount,
 		tx.data.Payload,
 		s.chainID, uint(0), uint(0),
-	})
+	}))
 }
 
 // HomesteadSigner implements TransactionInterface using the
@@ -213,14 +213,24 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	return rlpHash(withCrossShardFields(tx, []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
 		tx.data.Recipient,
 		tx.data.Amount,
 		tx.data.Payload,
-	})
+	}))
+}
+
+// withCrossShardFields appends the shards of a cross-shard transaction to the fields signed
+// by the sender, so that its destination can't be changed. The signed fields of the
+// transactions within a shard are left as they are.
+func withCrossShardFields(tx *Transaction, fields []interface{}) []interface{} {
+	if !tx.IsCrossShard() {
+		return fields
+	}
+	return append(fields, tx.data.ShardID, tx.data.ToShardID)
 }
 
 // Sender returns the sender address of the given transaction.
@@ -96,3 +96,21 @@ func TestChainID(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestCrossShardSigningHash(t *testing.T) {
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	signer := NewEIP155Signer(big.NewInt(18))
+
+	tx := NewTransaction(0, addr, 1, big.NewInt(10), 21000, new(big.Int), nil)
+	crossShardTx := NewCrossShardTransaction(0, addr, 1, 2, big.NewInt(10), 21000, new(big.Int), nil)
+	otherShardTx := NewCrossShardTransaction(0, addr, 1, 3, big.NewInt(10), 21000, new(big.Int), nil)
+	if tx.IsCrossShard() || !crossShardTx.IsCrossShard() {
+		t.Fatal("only the transaction to another shard should be cross-shard")
+	}
+	if signer.Hash(tx) == signer.Hash(crossShardTx) {
+		t.Error("a cross-shard transaction should not share the signing hash of a transfer within the shard")
+	}
+	if signer.Hash(crossShardTx) == signer.Hash(otherShardTx) {
+		t.Error("the destination shard should be signed")
+	}
+}
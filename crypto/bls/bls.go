@@ -3,6 +3,7 @@ package bls
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/harmony-one/bls/ffi/go/bls"
 )
@@ -228,3 +229,62 @@ func NewThresholdPolicy(thold int) *ThresholdPolicy {
 func (p ThresholdPolicy) Check(m *Mask) bool {
 	return m.CountEnabled() >= p.thold
 }
+
+// FractionPolicy requires that more than the given fraction of the participants
+// have cosigned.
+type FractionPolicy struct {
+	numerator   uint64
+	denominator uint64
+}
+
+// NewFractionPolicy returns a new FractionPolicy requiring more than
+// numerator/denominator of the participants.
+func NewFractionPolicy(numerator, denominator uint64) Policy {
+	return &FractionPolicy{numerator: numerator, denominator: denominator}
+}
+
+// Check verifies that more than the fraction of the participants have
+// contributed to a collective signature.
+func (p FractionPolicy) Check(m *Mask) bool {
+	return uint64(m.CountEnabled())*p.denominator > uint64(m.CountTotal())*p.numerator
+}
+
+// StakeWeightedPolicy requires that the cosigners hold more than the given
+// fraction of the total stake of the participants, where the stake of each
+// participant is looked up by its public key. As long as some participant holds
+// no stake, e.g. in the committee of the genesis, the policy requires more than
+// the fraction of the participants instead, so that no few staked participants
+// make up a quorum on their own.
+type StakeWeightedPolicy struct {
+	stakeOf     func(*bls.PublicKey) int64
+	numerator   int64
+	denominator int64
+}
+
+// NewStakeWeightedPolicy returns a new StakeWeightedPolicy with the given stake
+// lookup, requiring more than numerator/denominator of the total stake.
+func NewStakeWeightedPolicy(stakeOf func(*bls.PublicKey) int64, numerator, denominator uint64) *StakeWeightedPolicy {
+	return &StakeWeightedPolicy{stakeOf: stakeOf, numerator: int64(numerator), denominator: int64(denominator)}
+}
+
+// Check verifies that the participants holding more than the fraction of the
+// total stake have contributed to a collective signature, or more than the
+// fraction of the participants if some of them hold no stake.
+func (p StakeWeightedPolicy) Check(m *Mask) bool {
+	total, signed := big.NewInt(0), big.NewInt(0)
+	for i, key := range m.publics {
+		stake := p.stakeOf(key)
+		if stake <= 0 {
+			return FractionPolicy{numerator: uint64(p.numerator), denominator: uint64(p.denominator)}.Check(m)
+		}
+		total.Add(total, big.NewInt(stake))
+		if enabled, err := m.IndexEnabled(i); err == nil && enabled {
+			signed.Add(signed, big.NewInt(stake))
+		}
+	}
+	if total.Sign() == 0 {
+		return false
+	}
+	// signed * denominator > total * numerator
+	return signed.Mul(signed, big.NewInt(p.denominator)).Cmp(total.Mul(total, big.NewInt(p.numerator))) > 0
+}
@@ -36,3 +36,63 @@ func TestNewMask(test *testing.T) {
 		test.Error("Should have a total of 3 keys")
 	}
 }
+
+func TestFractionPolicy(test *testing.T) {
+	_, pubKey1 := utils.GenKey("127.0.0.1", "5555")
+	_, pubKey2 := utils.GenKey("127.0.0.1", "6666")
+	_, pubKey3 := utils.GenKey("127.0.0.1", "7777")
+	_, pubKey4 := utils.GenKey("127.0.0.1", "8888")
+
+	mask, _ := NewMask([]*bls.PublicKey{pubKey1, pubKey2, pubKey3, pubKey4}, pubKey1)
+	mask.SetKey(pubKey2, true)
+	mask.SetKey(pubKey3, true)
+	if !NewFractionPolicy(2, 3).Check(mask) {
+		test.Error("3 of 4 participants should be more than two thirds")
+	}
+	if NewFractionPolicy(3, 4).Check(mask) {
+		test.Error("3 of 4 participants should not be more than three quarters")
+	}
+}
+
+func TestStakeWeightedPolicy(test *testing.T) {
+	_, pubKey1 := utils.GenKey("127.0.0.1", "5555")
+	_, pubKey2 := utils.GenKey("127.0.0.1", "6666")
+	_, pubKey3 := utils.GenKey("127.0.0.1", "7777")
+
+	stakes := map[string]int64{
+		pubKey1.SerializeToHexStr(): 70,
+		pubKey2.SerializeToHexStr(): 20,
+		pubKey3.SerializeToHexStr(): 10,
+	}
+	policy := NewStakeWeightedPolicy(func(pubKey *bls.PublicKey) int64 {
+		return stakes[pubKey.SerializeToHexStr()]
+	}, 2, 3)
+
+	mask, _ := NewMask([]*bls.PublicKey{pubKey1, pubKey2, pubKey3}, nil)
+	mask.SetKey(pubKey2, true)
+	mask.SetKey(pubKey3, true)
+	if policy.Check(mask) {
+		test.Error("30% of the stake should not be a quorum")
+	}
+	mask.SetKey(pubKey3, false)
+	mask.SetKey(pubKey1, true)
+	if !policy.Check(mask) {
+		test.Error("90% of the stake should be a quorum")
+	}
+
+	noStake := NewStakeWeightedPolicy(func(pubKey *bls.PublicKey) int64 { return 0 }, 2, 3)
+	if noStake.Check(mask) {
+		test.Error("2 of 3 unstaked participants should not be a quorum")
+	}
+	mask.SetKey(pubKey3, true)
+	if !noStake.Check(mask) {
+		test.Error("3 of 3 unstaked participants should be a quorum")
+	}
+
+	delete(stakes, pubKey2.SerializeToHexStr())
+	mask.SetKey(pubKey2, false)
+	mask.SetKey(pubKey3, false)
+	if policy.Check(mask) {
+		test.Error("The only signer should not be a quorum while a participant holds no stake")
+	}
+}
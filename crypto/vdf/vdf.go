@@ -0,0 +1,129 @@
+// Package vdf implements the verifiable delay function of Wesolowski
+// ("Efficient verifiable delay functions", 2018) over the RSA-2048 group.
+//
+// Evaluating the VDF takes a number of sequential squarings no parallelism can
+// speed up, while verifying its proof takes two small exponentiations. Since
+// nobody knows the factorization of the RSA-2048 modulus, nobody knows the
+// order of the group, which is what makes the squarings sequential.
+package vdf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// The size in bytes of the group elements, i.e. the outputs and proofs.
+const elementSize = 256
+
+// The RSA-2048 modulus of the RSA factoring challenge, whose factorization is unknown.
+var modulus, _ = new(big.Int).SetString("25195908475657893494027183240048398571429282126204032027777137836043662020707595556264018525880784406918290641249515082189298559149176184502808489120072844992687392807287776735971418347270261896375014971824691165077613379859095700097330459748808428401797429100642458691817195118746121515172654632282216869987549182422433637259085141865462043576798423387184774447920739934236584823824281198163815010674810451660377306056201619676256133844143603833904414952634432190114657544454178424020924616515723350778707749817125772467962926386356373289912154831438167899885040445364023527381951378636564391212010397122822120720357", 10)
+
+var (
+	one = big.NewInt(1)
+	two = big.NewInt(2)
+)
+
+// VDF is a verifiable delay function of a given difficulty.
+type VDF struct {
+	difficulty uint64
+}
+
+// New returns the VDF of the given number of sequential squarings.
+func New(difficulty uint64) *VDF {
+	return &VDF{difficulty: difficulty}
+}
+
+// Difficulty returns the number of sequential squarings of the VDF.
+func (vdf *VDF) Difficulty() uint64 {
+	return vdf.difficulty
+}
+
+// Evaluate returns the output y = x^(2^T) of the VDF on the input, where x is
+// the input hashed into the group and T the difficulty, and the proof of the
+// evaluation pi = x^floor(2^T / l), where l is a prime derived from x and y.
+func (vdf *VDF) Evaluate(input [32]byte) (output []byte, proof []byte) {
+	x := hashToGroup(input)
+
+	y := new(big.Int).Set(x)
+	for i := uint64(0); i < vdf.difficulty; i++ {
+		y.Mul(y, y).Mod(y, modulus)
+	}
+
+	// Long division of 2^T by l, raising x to the quotient bit by bit
+	l := hashToPrime(x, y)
+	pi := big.NewInt(1)
+	r := big.NewInt(1)
+	for i := uint64(0); i < vdf.difficulty; i++ {
+		r.Lsh(r, 1)
+		pi.Mul(pi, pi).Mod(pi, modulus)
+		if r.Cmp(l) >= 0 {
+			r.Sub(r, l)
+			pi.Mul(pi, x).Mod(pi, modulus)
+		}
+	}
+	return toBytes(y), toBytes(pi)
+}
+
+// Verify returns whether the output is the evaluation of the VDF on the input,
+// by checking pi^l * x^(2^T mod l) = y.
+func (vdf *VDF) Verify(input [32]byte, output []byte, proof []byte) bool {
+	if len(output) != elementSize || len(proof) != elementSize {
+		return false
+	}
+	y := new(big.Int).SetBytes(output)
+	pi := new(big.Int).SetBytes(proof)
+	if !inGroup(y) || !inGroup(pi) {
+		return false
+	}
+	x := hashToGroup(input)
+	l := hashToPrime(x, y)
+	r := new(big.Int).Exp(two, new(big.Int).SetUint64(vdf.difficulty), l)
+
+	expected := new(big.Int).Exp(pi, l, modulus)
+	expected.Mul(expected, new(big.Int).Exp(x, r, modulus)).Mod(expected, modulus)
+	return expected.Cmp(y) == 0
+}
+
+// hashToGroup hashes the input into an element of the group.
+func hashToGroup(input [32]byte) *big.Int {
+	data := make([]byte, 0, elementSize)
+	for counter := uint32(0); len(data) < elementSize; counter++ {
+		block := make([]byte, 4, 4+len(input))
+		binary.BigEndian.PutUint32(block, counter)
+		hash := sha256.Sum256(append(block, input[:]...))
+		data = append(data, hash[:]...)
+	}
+	x := new(big.Int).SetBytes(data)
+	x.Mod(x, modulus)
+	if !inGroup(x) {
+		x.SetInt64(2)
+	}
+	return x
+}
+
+// hashToPrime hashes the input and the output of an evaluation into a 256 bits
+// prime, the challenge the proof is made for.
+func hashToPrime(x *big.Int, y *big.Int) *big.Int {
+	hash := sha256.Sum256(append(toBytes(x), toBytes(y)...))
+	l := new(big.Int).SetBytes(hash[:])
+	l.SetBit(l, 255, 1)
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(20) {
+		l.Add(l, two)
+	}
+	return l
+}
+
+// inGroup returns whether the integer is an element of the group, other than 1.
+func inGroup(x *big.Int) bool {
+	return x.Cmp(one) > 0 && x.Cmp(modulus) < 0
+}
+
+// toBytes serializes a group element into its fixed size big endian bytes.
+func toBytes(x *big.Int) []byte {
+	data := make([]byte, elementSize)
+	bytes := x.Bytes()
+	copy(data[elementSize-len(bytes):], bytes)
+	return data
+}
@@ -0,0 +1,38 @@
+package vdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateAndVerify(t *testing.T) {
+	vdf := New(1000)
+	input := [32]byte{1, 2, 3}
+	output, proof := vdf.Evaluate(input)
+	assert.Equal(t, elementSize, len(output))
+	assert.Equal(t, elementSize, len(proof))
+	assert.True(t, vdf.Verify(input, output, proof))
+
+	// The evaluation is deterministic
+	sameOutput, _ := vdf.Evaluate(input)
+	assert.Equal(t, output, sameOutput)
+
+	// Another input, difficulty, output or proof doesn't verify
+	assert.False(t, vdf.Verify([32]byte{4, 5, 6}, output, proof))
+	assert.False(t, New(999).Verify(input, output, proof))
+	assert.False(t, vdf.Verify(input, proof, output))
+	tampered := append([]byte{}, output...)
+	tampered[elementSize-1] ^= 1
+	assert.False(t, vdf.Verify(input, tampered, proof))
+	assert.False(t, vdf.Verify(input, output[1:], proof))
+}
+
+func TestVerifyOutOfGroup(t *testing.T) {
+	vdf := New(10)
+	input := [32]byte{1}
+	_, proof := vdf.Evaluate(input)
+	assert.False(t, vdf.Verify(input, make([]byte, elementSize), proof))
+	assert.False(t, vdf.Verify(input, toBytes(modulus), proof))
+	assert.False(t, vdf.Verify(input, toBytes(one), toBytes(one)))
+}
@@ -0,0 +1,91 @@
+// Package bls implements a verifiable random function using BLS signatures.
+//
+// A BLS signature is unique for a key and a message, so the signature of the
+// message serves as the proof of the VRF, and its hash as the output. It lets
+// a node use its BLS signing key as its VRF key, which is already bound to its
+// identity in the committee.
+package bls
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/crypto/vrf"
+)
+
+var (
+	// ErrInvalidVRF occurs when the VRF proof doesn't verify.
+	ErrInvalidVRF = errors.New("invalid VRF proof")
+	// ErrNilKey occurs when the key of a signer or a verifier is nil.
+	ErrNilKey = errors.New("nil BLS key")
+)
+
+// PrivateKey holds a private VRF key, which is a BLS secret key.
+type PrivateKey struct {
+	*bls.SecretKey
+}
+
+// PublicKey holds a public VRF key, which is a BLS public key.
+type PublicKey struct {
+	*bls.PublicKey
+}
+
+// NewVRFSigner creates a signer object from a BLS secret key.
+func NewVRFSigner(key *bls.SecretKey) (vrf.PrivateKey, error) {
+	if key == nil {
+		return nil, ErrNilKey
+	}
+	return &PrivateKey{SecretKey: key}, nil
+}
+
+// NewVRFVerifier creates a verifier object from a BLS public key.
+func NewVRFVerifier(key *bls.PublicKey) (vrf.PublicKey, error) {
+	if key == nil {
+		return nil, ErrNilKey
+	}
+	return &PublicKey{PublicKey: key}, nil
+}
+
+// Evaluate returns the hash of the BLS signature of m as the output, and the
+// signature itself as the proof.
+func (k *PrivateKey) Evaluate(m []byte) (index [32]byte, proof []byte) {
+	hash := sha256.Sum256(m)
+	proof = k.SignHash(hash[:]).Serialize()
+	return sha256.Sum256(proof), proof
+}
+
+// Public returns the corresponding public key.
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return &PublicKey{PublicKey: k.GetPublicKey()}
+}
+
+// ProofToHash checks the proof is the BLS signature of m by the key, and
+// returns the output it is hashed into.
+func (pk *PublicKey) ProofToHash(m, proof []byte) (index [32]byte, err error) {
+	sig := bls.Sign{}
+	if err := sig.Deserialize(proof); err != nil {
+		return index, ErrInvalidVRF
+	}
+	hash := sha256.Sum256(m)
+	if !sig.VerifyHash(pk.PublicKey, hash[:]) {
+		return index, ErrInvalidVRF
+	}
+	// Hash the serialization of the signature rather than the given bytes, so
+	// that the output is unique even if the signature has several encodings.
+	return sha256.Sum256(sig.Serialize()), nil
+}
+
+// Serialize serializes the public key into bytes.
+func (pk *PublicKey) Serialize() []byte {
+	return pk.PublicKey.Serialize()
+}
+
+// Deserialize deserializes bytes into the public key.
+func (pk *PublicKey) Deserialize(data []byte) {
+	if pk.PublicKey == nil {
+		pk.PublicKey = &bls.PublicKey{}
+	}
+	pk.PublicKey.Deserialize(data)
+}
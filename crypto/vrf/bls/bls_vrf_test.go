@@ -0,0 +1,80 @@
+package bls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/harmony-one/harmony/crypto/vrf"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+func TestEvaluateAndProofToHash(t *testing.T) {
+	secretKey, publicKey := utils.GenKey("127.0.0.1", "9902")
+	signer, err := NewVRFSigner(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVRFVerifier(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ vrf.PrivateKey = signer
+	var _ vrf.PublicKey = verifier
+
+	m := []byte("block hash")
+	index, proof := signer.Evaluate(m)
+	got, err := verifier.ProofToHash(m, proof)
+	if err != nil {
+		t.Fatalf("ProofToHash(): %v", err)
+	}
+	if got != index {
+		t.Errorf("ProofToHash(): %x, want %x", got, index)
+	}
+
+	// The output is unique for the key and the message
+	sameIndex, sameProof := signer.Evaluate(m)
+	if sameIndex != index || !bytes.Equal(sameProof, proof) {
+		t.Error("Evaluate() is not deterministic")
+	}
+	if otherIndex, _ := signer.Evaluate([]byte("another block hash")); otherIndex == index {
+		t.Error("Evaluate() of different messages should differ")
+	}
+
+	// The public key of the signer verifies, once serialized too
+	public, ok := signer.Public().(*PublicKey)
+	if !ok || !bytes.Equal(public.Serialize(), verifier.Serialize()) {
+		t.Error("Public() doesn't match the verifier")
+	}
+	deserialized := &PublicKey{}
+	deserialized.Deserialize(verifier.Serialize())
+	if got, err := deserialized.ProofToHash(m, proof); err != nil || got != index {
+		t.Errorf("ProofToHash() with deserialized key: %x, %v", got, err)
+	}
+}
+
+func TestProofToHashInvalid(t *testing.T) {
+	secretKey, _ := utils.GenKey("127.0.0.1", "9902")
+	_, otherPublicKey := utils.GenKey("127.0.0.1", "9905")
+	signer, _ := NewVRFSigner(secretKey)
+	verifier, _ := NewVRFVerifier(otherPublicKey)
+
+	m := []byte("block hash")
+	_, proof := signer.Evaluate(m)
+	if _, err := verifier.ProofToHash(m, proof); err != ErrInvalidVRF {
+		t.Errorf("ProofToHash() with another key: %v, want %v", err, ErrInvalidVRF)
+	}
+	selfVerifier := signer.Public().(*PublicKey)
+	if _, err := selfVerifier.ProofToHash([]byte("another block hash"), proof); err != ErrInvalidVRF {
+		t.Errorf("ProofToHash() of another message: %v, want %v", err, ErrInvalidVRF)
+	}
+	if _, err := selfVerifier.ProofToHash(m, proof[1:]); err != ErrInvalidVRF {
+		t.Errorf("ProofToHash() of truncated proof: %v, want %v", err, ErrInvalidVRF)
+	}
+
+	if _, err := NewVRFSigner(nil); err != ErrNilKey {
+		t.Errorf("NewVRFSigner(nil): %v, want %v", err, ErrNilKey)
+	}
+	if _, err := NewVRFVerifier(nil); err != ErrNilKey {
+		t.Errorf("NewVRFVerifier(nil): %v, want %v", err, ErrNilKey)
+	}
+}
@@ -67,9 +67,14 @@ type PrivateKey struct {
 	*ecdsa.PrivateKey
 }
 
-// Serialize serialize the public key into bytes
+// Serialize serialize the public key into bytes, 32 bytes for each coordinate
 func (pk *PublicKey) Serialize() []byte {
-	return append(pk.PublicKey.X.Bytes(), pk.PublicKey.Y.Bytes()...)
+	byteLen := (params.BitSize + 7) >> 3
+	data := make([]byte, 2*byteLen)
+	xBytes, yBytes := pk.PublicKey.X.Bytes(), pk.PublicKey.Y.Bytes()
+	copy(data[byteLen-len(xBytes):byteLen], xBytes)
+	copy(data[2*byteLen-len(yBytes):], yBytes)
+	return data
 }
 
 // Deserialize de-serialize bytes into public key
@@ -182,6 +187,9 @@ func (pk *PublicKey) ProofToHash(m, proof []byte) (index [32]byte, err error) {
 	if got, want := len(proof), 64+65; got != want {
 		return nilIndex, ErrInvalidVRF
 	}
+	if !curve.IsOnCurve(pk.X, pk.Y) {
+		return nilIndex, ErrPointNotOnCurve
+	}
 
 	// Parse proof into s, t, and vrf.
 	s := proof[0:32]
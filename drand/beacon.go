@@ -0,0 +1,57 @@
+package drand
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
+)
+
+// ErrRandomnessNotFound is returned if the pRnd of the queried epoch isn't in the chain yet.
+var ErrRandomnessNotFound = errors.New("randomness of the epoch not found")
+
+// EpochRandomness is the randomness of an epoch as committed in the chain,
+// along with everything needed to verify it: the preimage with the vrf commits
+// of the committee, and the VDF evaluation over pRnd.
+type EpochRandomness struct {
+	Epoch           uint64
+	PRndBlockNumber uint64      // the first block of the epoch, committing the preimage
+	Preimage        *Preimage   // the preimage of the randomness
+	RandBlockNumber uint64      // the last block of the epoch, committing the final randomness
+	Randomness      *Randomness // the final randomness, nil until the last block of the epoch
+	VdfDifficulty   uint64      // the number of squarings of the VDF of the epoch
+}
+
+// GetEpochRandomness returns the randomness of the given epoch of the chain with the
+// given parameters, reading the block headers from getHeader, which returns nil for
+// unknown blocks.
+func GetEpochRandomness(config *chainparams.Config, epoch uint64, getHeader func(number uint64) *types.Header) (*EpochRandomness, error) {
+	result := &EpochRandomness{
+		Epoch:           epoch,
+		PRndBlockNumber: config.EpochBlockNumber(epoch),
+		RandBlockNumber: config.EpochBlockNumber(epoch+1) - 1,
+		VdfDifficulty:   config.VdfDifficulty(epoch),
+	}
+
+	header := getHeader(result.PRndBlockNumber)
+	if header == nil || len(header.RandPreimage) == 0 {
+		return nil, ErrRandomnessNotFound
+	}
+	preimage, err := DecodePreimage(header.RandPreimage)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %v", result.PRndBlockNumber, err)
+	}
+	result.Preimage = preimage
+
+	header = getHeader(result.RandBlockNumber)
+	if header == nil || len(header.Vdf) == 0 {
+		return result, nil
+	}
+	randomness, err := DecodeRandomness(header.Vdf)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %v", result.RandBlockNumber, err)
+	}
+	result.Randomness = randomness
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package drand
+
+import (
+	"testing"
+
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEpochRandomness(t *testing.T) {
+	preimage := &Preimage{PRnd: [32]byte{1, 2, 3}, Bitmap: []byte{1}, Commits: [][]byte{{4}}}
+	encodedPreimage, _ := preimage.Encode()
+	randomness := &Randomness{PRnd: preimage.PRnd, Output: []byte{5}, Proof: []byte{6}}
+	encodedRandomness, _ := randomness.Encode()
+
+	headers := map[uint64]*types.Header{
+		5:  {RandSeed: preimage.PRnd, RandPreimage: encodedPreimage},
+		9:  {RandSeed: randomness.Rand(), Vdf: encodedRandomness},
+		10: {RandPreimage: []byte("garbage")},
+	}
+	getHeader := func(number uint64) *types.Header { return headers[number] }
+
+	result, err := GetEpochRandomness(chainparams.DefaultConfig, 1, getHeader)
+	if assert.Nil(t, err) {
+		assert.Equal(t, uint64(5), result.PRndBlockNumber)
+		assert.Equal(t, uint64(9), result.RandBlockNumber)
+		assert.Equal(t, preimage, result.Preimage)
+		assert.Equal(t, randomness, result.Randomness)
+	}
+
+	// Before the last block of the epoch, only pRnd is known
+	delete(headers, 9)
+	result, err = GetEpochRandomness(chainparams.DefaultConfig, 1, getHeader)
+	if assert.Nil(t, err) {
+		assert.Equal(t, preimage, result.Preimage)
+		assert.Nil(t, result.Randomness)
+	}
+
+	_, err = GetEpochRandomness(chainparams.DefaultConfig, 0, getHeader)
+	assert.Equal(t, ErrRandomnessNotFound, err)
+	_, err = GetEpochRandomness(chainparams.DefaultConfig, 2, getHeader)
+	assert.NotNil(t, err)
+}
@@ -2,15 +2,16 @@ package drand
 
 import (
 	"crypto/sha256"
-	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"strconv"
 	"sync"
 
 	"github.com/harmony-one/harmony/crypto/vrf"
-	"github.com/harmony-one/harmony/crypto/vrf/p256"
+	vrf_bls "github.com/harmony-one/harmony/crypto/vrf/bls"
 
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chainparams"
 
 	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/bls/ffi/go/bls"
@@ -18,23 +19,25 @@ import (
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
 )
 
 // DRand is the main struct which contains state for the distributed randomness protocol.
 type DRand struct {
-	vrfs                  *map[uint32][]byte
+	vrfs                  *map[string][]byte
+	commits               *map[string][]byte // the marshaled commit messages of the vrfs
 	bitmap                *bls_cosi.Mask
 	pRand                 *[32]byte
 	rand                  *[32]byte
 	ConfirmedBlockChannel chan *types.Block // Channel to receive confirmed blocks
-	PRndChannel           chan []byte       // Channel to send pRnd (preimage of randomness resulting from combined vrf randomnesses) to consensus. The first 32 bytes are randomness, the rest is for bitmap.
+	PRndChannel           chan []byte       // Channel to send pRnd (preimage of randomness resulting from combined vrf randomnesses) to consensus, as an encoded Preimage.
+	RndChannel            chan []byte       // Channel to send the final randomness (the VDF output over pRnd) to consensus, as an encoded Randomness.
 
 	// global consensus mutex
 	mutex sync.Mutex
 
-	// map of nodeID to validator Peer object
-	// FIXME: should use PubKey of p2p.Peer as the hashkey
-	validators sync.Map // key is uint16, value is p2p.Peer
+	// map of the public keys of validators to validator Peer objects
+	validators sync.Map // key is the hex string of serialized public key, value is p2p.Peer
 
 	// Leader's address
 	leader p2p.Peer
@@ -46,25 +49,30 @@ type DRand struct {
 	// private/public keys of current node
 	priKey *bls.SecretKey
 	pubKey *bls.PublicKey
-	// VRF private and public key
-	// TODO: directly use signature signing key (BLS) for vrf
-	vrfPriKey *vrf.PrivateKey
-	vrfPubKey *vrf.PublicKey
+	// VRF private key, which is the BLS key of the node
+	vrfPriKey vrf.PrivateKey
 
 	// Whether I am leader. False means I am validator
 	IsLeader bool
 
-	// Leader or validator Id - 4 byte
-	nodeID uint32
-
 	// The p2p host used to send/receive p2p messages
 	host p2p.Host
 
 	// Shard Id which this node belongs to
 	ShardID uint32
 
+	// The protocol parameters of the chain, deciding where the epochs start
+	ChainParams *chainparams.Config
+
 	// Blockhash - 32 byte
 	blockHash [32]byte
+	// The last block of the epoch the vrfs are committed on, known by every member so
+	// that a new leader can run the protocol again
+	lastBlock *types.Block
+	// The epoch block committing pRnd of the current epoch, the input of the VDF
+	epochBlock *types.Block
+	// Whether the VDF is being evaluated
+	evaluating bool
 }
 
 // New creates a new dRand object
@@ -76,7 +84,9 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer, confi
 		dRand.ConfirmedBlockChannel = confirmedBlockChannel
 	}
 
-	dRand.PRndChannel = make(chan []byte)
+	dRand.ChainParams = chainparams.DefaultConfig
+	dRand.PRndChannel = make(chan []byte, 1)
+	dRand.RndChannel = make(chan []byte, 1)
 
 	selfPeer := host.GetSelfPeer()
 	if leader.Port == selfPeer.Port && leader.IP == selfPeer.IP {
@@ -87,10 +97,11 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer, confi
 
 	dRand.leader = leader
 	for _, peer := range peers {
-		dRand.validators.Store(utils.GetUniqueIDFromPeer(peer), peer)
+		dRand.validators.Store(getPeerKey(peer.PubKey), peer)
 	}
 
-	dRand.vrfs = &map[uint32][]byte{}
+	dRand.vrfs = &map[string][]byte{}
+	dRand.commits = &map[string][]byte{}
 
 	// Initialize cosign bitmap
 	allPublicKeys := make([]*bls.PublicKey, 0)
@@ -107,22 +118,11 @@ func New(host p2p.Host, ShardID string, peers []p2p.Peer, leader p2p.Peer, confi
 	dRand.pRand = nil
 	dRand.rand = nil
 
-	// For now use socket address as ID
-	// TODO: populate Id derived from address
-	dRand.nodeID = utils.GetUniqueIDFromPeer(selfPeer)
-
 	// Set private key for myself so that I can sign messages.
-	nodeIDBytes := make([]byte, 32)
-	binary.LittleEndian.PutUint32(nodeIDBytes, dRand.nodeID)
-	privateKey := bls.SecretKey{}
-	err := privateKey.SetLittleEndian(nodeIDBytes)
-	dRand.priKey = &privateKey
-	dRand.pubKey = privateKey.GetPublicKey()
-
-	// VRF keys
-	priKey, pubKey := p256.GenerateKey()
-	dRand.vrfPriKey = &priKey
-	dRand.vrfPubKey = &pubKey
+	dRand.priKey, dRand.pubKey = utils.GenKey(selfPeer.IP, selfPeer.Port)
+
+	// The VRF proofs are BLS signatures, verified against the public keys of the committee
+	dRand.vrfPriKey, _ = vrf_bls.NewVRFSigner(dRand.priKey)
 
 	myShardID, err := strconv.Atoi(ShardID)
 	if err != nil {
@@ -139,9 +139,9 @@ func (dRand *DRand) AddPeers(peers []*p2p.Peer) int {
 	count := 0
 
 	for _, peer := range peers {
-		_, ok := dRand.validators.Load(utils.GetUniqueIDFromPeer(*peer))
+		_, ok := dRand.validators.Load(getPeerKey(peer.PubKey))
 		if !ok {
-			dRand.validators.Store(utils.GetUniqueIDFromPeer(*peer), *peer)
+			dRand.validators.Store(getPeerKey(peer.PubKey), *peer)
 			dRand.pubKeyLock.Lock()
 			dRand.PublicKeys = append(dRand.PublicKeys, peer.PubKey)
 			dRand.pubKeyLock.Unlock()
@@ -151,6 +151,70 @@ func (dRand *DRand) AddPeers(peers []*p2p.Peer) int {
 	return count
 }
 
+// UpdateCommittee replaces the committee running the randomness protocol by the
+// given members and leader, when the committee of the shard was handed off at the
+// last block of an epoch. The public keys keep the order of the consensus committee, which
+// the bitmaps of the preimages are verified against.
+func (dRand *DRand) UpdateCommittee(publicKeys []*bls.PublicKey, leader p2p.Peer) {
+	dRand.mutex.Lock()
+	defer dRand.mutex.Unlock()
+
+	members := map[string]bool{}
+	for _, publicKey := range publicKeys {
+		members[getPeerKey(publicKey)] = true
+	}
+	dRand.validators.Range(func(key, value interface{}) bool {
+		if !members[key.(string)] || key.(string) == getPeerKey(leader.PubKey) {
+			dRand.validators.Delete(key)
+		}
+		return true
+	})
+	for _, publicKey := range publicKeys {
+		if publicKey.IsEqual(leader.PubKey) || publicKey.IsEqual(dRand.pubKey) {
+			continue
+		}
+		if _, ok := dRand.validators.Load(getPeerKey(publicKey)); !ok {
+			dRand.validators.Store(getPeerKey(publicKey), p2p.Peer{PubKey: publicKey})
+		}
+	}
+
+	dRand.pubKeyLock.Lock()
+	dRand.PublicKeys = append(publicKeys[:0:0], publicKeys...)
+	dRand.pubKeyLock.Unlock()
+
+	dRand.leader = leader
+	dRand.IsLeader = leader.PubKey.IsEqual(dRand.pubKey)
+	dRand.ResetState()
+}
+
+// StartEpoch starts the protocol of the next epoch on the last block of the epoch, once the
+// committee of the next epoch took over: its leader inits the protocol, and its members
+// commit their vrfs on the block, for the pRnd of the epoch block they sign.
+func (dRand *DRand) StartEpoch(lastBlock *types.Block) {
+	dRand.mutex.Lock()
+	dRand.lastBlock = lastBlock
+	isLeader := dRand.IsLeader
+	dRand.mutex.Unlock()
+	if isLeader {
+		dRand.init(lastBlock)
+	}
+}
+
+// SetLeader makes the given member the leader of the randomness protocol, after a view
+// change moved the leader of the consensus. The old leader becomes a validator.
+func (dRand *DRand) SetLeader(leader p2p.Peer) {
+	dRand.mutex.Lock()
+	defer dRand.mutex.Unlock()
+
+	oldLeader := dRand.leader
+	if oldLeader.PubKey != nil && !oldLeader.PubKey.IsEqual(leader.PubKey) && !oldLeader.PubKey.IsEqual(dRand.pubKey) {
+		dRand.validators.Store(getPeerKey(oldLeader.PubKey), oldLeader)
+	}
+	dRand.validators.Delete(getPeerKey(leader.PubKey))
+	dRand.leader = leader
+	dRand.IsLeader = leader.PubKey.IsEqual(dRand.pubKey)
+}
+
 // Sign on the drand message signature field.
 func (dRand *DRand) signDRandMessage(message *drand_proto.Message) error {
 	message.Signature = nil
@@ -181,8 +245,13 @@ func (dRand *DRand) signAndMarshalDRandMessage(message *drand_proto.Message) ([]
 	return marshaledMessage, nil
 }
 
+// SendMessageToGroup sends message thru p2p host to the drand group of the shard.
+func (dRand *DRand) SendMessageToGroup(message []byte) {
+	dRand.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(dRand.ShardID)}, host.ConstructP2pMessage(host.MessageTypeBroadcast, message))
+}
+
 func (dRand *DRand) vrf(blockHash [32]byte) (rand [32]byte, proof []byte) {
-	rand, proof = (*dRand.vrfPriKey).Evaluate(blockHash[:])
+	rand, proof = dRand.vrfPriKey.Evaluate(blockHash[:])
 	return
 }
 
@@ -222,16 +291,26 @@ func verifyMessageSig(signerPubKey *bls.PublicKey, message drand_proto.Message)
 	return nil
 }
 
-// Gets the validator peer based on validator ID.
-func (dRand *DRand) getValidatorPeerByID(validatorID uint32) *p2p.Peer {
-	v, ok := dRand.validators.Load(validatorID)
+// getPeerKey returns the key of a committee member in the maps of the dRand,
+// which is the hex string of its serialized public key.
+func getPeerKey(pubKey *bls.PublicKey) string {
+	if pubKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(pubKey.Serialize())
+}
+
+// Gets the validator peer based on the serialized public key of the validator.
+func (dRand *DRand) getValidatorPeerByPubKey(senderPubKey []byte) *p2p.Peer {
+	validatorKey := hex.EncodeToString(senderPubKey)
+	v, ok := dRand.validators.Load(validatorKey)
 	if !ok {
-		utils.GetLogInstance().Warn("Unrecognized validator", "validatorID", validatorID, "dRand", dRand)
+		utils.GetLogInstance().Warn("Unrecognized validator", "validatorKey", validatorKey, "dRand", dRand)
 		return nil
 	}
 	value, ok := v.(p2p.Peer)
 	if !ok {
-		utils.GetLogInstance().Warn("Invalid validator", "validatorID", validatorID, "dRand", dRand)
+		utils.GetLogInstance().Warn("Invalid validator", "validatorKey", validatorKey, "dRand", dRand)
 		return nil
 	}
 	return &value
@@ -239,7 +318,8 @@ func (dRand *DRand) getValidatorPeerByID(validatorID uint32) *p2p.Peer {
 
 // ResetState resets the state of the randomness protocol
 func (dRand *DRand) ResetState() {
-	dRand.vrfs = &map[uint32][]byte{}
+	dRand.vrfs = &map[string][]byte{}
+	dRand.commits = &map[string][]byte{}
 
 	bitmap, _ := bls_cosi.NewMask(dRand.PublicKeys, dRand.leader.PubKey)
 	dRand.bitmap = bitmap
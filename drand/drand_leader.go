@@ -1,13 +1,11 @@
 package drand
 
 import (
-	"bytes"
+	"encoding/hex"
 
 	protobuf "github.com/golang/protobuf/proto"
 	drand_proto "github.com/harmony-one/harmony/api/drand"
-	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
-	"github.com/harmony-one/harmony/crypto/vrf/p256"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p/host"
 )
@@ -21,8 +19,17 @@ func (dRand *DRand) WaitForEpochBlock(blockChannel chan *types.Block, stopChan c
 			default:
 				// keep waiting for epoch block
 				newBlock := <-blockChannel
-				if core.IsEpochLastBlock(newBlock) {
-					dRand.init(newBlock)
+				if dRand.ChainParams.IsEpochLastBlock(newBlock.NumberU64()) {
+					// The protocol of the next epoch is started by its committee, see StartEpoch.
+					dRand.mutex.Lock()
+					dRand.lastBlock = newBlock
+					dRand.mutex.Unlock()
+				}
+				if dRand.ChainParams.IsEpochBlock(newBlock.NumberU64()) && newBlock.NumberU64() > 0 {
+					dRand.mutex.Lock()
+					dRand.epochBlock = newBlock
+					dRand.mutex.Unlock()
+					go dRand.evaluateRandomness(newBlock)
 				}
 			case <-stopChan:
 				return
@@ -43,9 +50,80 @@ func (dRand *DRand) init(epochBlock *types.Block) {
 	// Leader commit vrf itself
 	rand, proof := dRand.vrf(dRand.blockHash)
 
-	(*dRand.vrfs)[dRand.nodeID] = append(rand[:], proof...)
+	dRand.mutex.Lock()
+	leaderKey := getPeerKey(dRand.pubKey)
+	(*dRand.vrfs)[leaderKey] = append(rand[:], proof...)
+	(*dRand.commits)[leaderKey] = dRand.signedCommitMessage(rand, proof)
+	dRand.bitmap.SetKey(dRand.pubKey, true)
+	dRand.mutex.Unlock()
+
+	if utils.UseLibP2P {
+		dRand.SendMessageToGroup(msgToSend)
+	} else {
+		host.BroadcastMessageFromLeader(dRand.host, dRand.GetValidatorPeers(), msgToSend, nil)
+	}
+}
 
-	host.BroadcastMessageFromLeader(dRand.host, dRand.GetValidatorPeers(), msgToSend, nil)
+// Rerun runs the protocol again for the block of the number, after consensus got an
+// invalid randomness for it: the leader collects the vrf commits again for an epoch
+// block, and the VDF is evaluated again over pRnd for the last block of an epoch.
+func (dRand *DRand) Rerun(number uint64) {
+	dRand.mutex.Lock()
+	lastBlock := dRand.lastBlock
+	epochBlock := dRand.epochBlock
+	isLeader := dRand.IsLeader
+	evaluating := dRand.evaluating
+	dRand.mutex.Unlock()
+
+	switch {
+	case dRand.ChainParams.IsEpochBlock(number):
+		if !isLeader || lastBlock == nil || lastBlock.NumberU64()+1 != number {
+			utils.GetLogInstance().Warn("[DRG] Can't rerun the randomness of the epoch block", "blockNum", number, "isLeader", isLeader)
+			return
+		}
+		utils.GetLogInstance().Info("[DRG] Rerunning the randomness of the epoch block", "blockNum", number)
+		dRand.init(lastBlock)
+	case dRand.ChainParams.IsEpochLastBlock(number) && epochBlock != nil:
+		if evaluating {
+			// The VDF runs for most of the epoch, the randomness comes once it's done.
+			return
+		}
+		utils.GetLogInstance().Info("[DRG] Evaluating the VDF again", "blockNum", number)
+		go dRand.evaluateRandomness(epochBlock)
+	}
+}
+
+// evaluateRandomness runs the VDF over pRnd committed in the epoch block into the
+// final randomness, and sends it to consensus to commit into the last block of the
+// epoch. The difficulty of the VDF is given by the chain params of the epoch.
+func (dRand *DRand) evaluateRandomness(epochBlock *types.Block) {
+	pRnd := epochBlock.Header().RandSeed
+	difficulty := dRand.ChainParams.VdfDifficulty(dRand.ChainParams.EpochOf(epochBlock.NumberU64()))
+	dRand.mutex.Lock()
+	dRand.evaluating = true
+	dRand.mutex.Unlock()
+
+	utils.GetLogInstance().Debug("[DRG] Evaluating the VDF", "pRnd", pRnd, "difficulty", difficulty)
+	randomness := EvaluateRandomness(pRnd, difficulty)
+	rand := randomness.Rand()
+
+	dRand.mutex.Lock()
+	dRand.evaluating = false
+	dRand.pRand = &pRnd
+	dRand.rand = &rand
+	dRand.mutex.Unlock()
+
+	encoded, err := randomness.Encode()
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to encode the randomness", "error", err)
+		return
+	}
+	// Only the randomness of the latest epoch is kept for consensus
+	select {
+	case <-dRand.RndChannel:
+	default:
+	}
+	dRand.RndChannel <- encoded
 }
 
 // ProcessMessageLeader dispatches messages for the leader to corresponding processors.
@@ -75,50 +153,65 @@ func (dRand *DRand) processCommitMessage(message drand_proto.Message) {
 	dRand.mutex.Lock()
 	defer dRand.mutex.Unlock()
 
-	validatorID := message.SenderId
-	validatorPeer := dRand.getValidatorPeerByID(validatorID)
+	validatorKey := hex.EncodeToString(message.SenderPubkey)
+	validatorPeer := dRand.getValidatorPeerByPubKey(message.SenderPubkey)
+	if validatorPeer == nil {
+		return
+	}
 	vrfs := dRand.vrfs
-	if len((*vrfs)) >= ((len(dRand.PublicKeys))/3 + 1) {
-		utils.GetLogInstance().Debug("Received additional randomness commit message", "validatorID", validatorID)
+	if len((*vrfs)) >= Threshold(len(dRand.PublicKeys)) {
+		utils.GetLogInstance().Debug("Received additional randomness commit message", "validatorKey", validatorKey)
 		return
 	}
 
-	// Verify message signature
-	err := verifyMessageSig(validatorPeer.PubKey, message)
+	// Verify message signature and the VRF
+	_, err := verifyCommit(message, dRand.blockHash, validatorPeer.PubKey)
 	if err != nil {
-		utils.GetLogInstance().Warn("Failed to verify the message signature", "Error", err)
+		utils.GetLogInstance().Warn("Failed to verify the commit", "error", err, "validatorKey", validatorKey)
 		return
 	}
-
-	rand := message.Payload[:32]
-	proof := message.Payload[32 : len(message.Payload)-64]
-	pubKeyBytes := message.Payload[len(message.Payload)-64:]
-	_, pubKey := p256.GenerateKey()
-	pubKey.Deserialize(pubKeyBytes)
-
-	expectedRand, err := pubKey.ProofToHash(dRand.blockHash[:], proof)
-
-	if err != nil || !bytes.Equal(expectedRand[:], rand) {
-		utils.GetLogInstance().Error("Failed to verify the VRF", "error", err, "validatorID", validatorID, "expectedRand", expectedRand, "receivedRand", rand)
+	commit, err := protobuf.Marshal(&message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to marshal the commit", "error", err)
 		return
 	}
 
-	utils.GetLogInstance().Debug("Received new commit", "numReceivedSoFar", len((*vrfs)), "validatorID", validatorID, "PublicKeys", len(dRand.PublicKeys))
+	utils.GetLogInstance().Debug("Received new commit", "numReceivedSoFar", len((*vrfs)), "validatorKey", validatorKey, "PublicKeys", len(dRand.PublicKeys))
 
-	(*vrfs)[validatorID] = message.Payload
+	(*vrfs)[validatorKey] = message.Payload
+	(*dRand.commits)[validatorKey] = commit
 	dRand.bitmap.SetKey(validatorPeer.PubKey, true) // Set the bitmap indicating that this validator signed.
 
-	if len((*vrfs)) >= ((len(dRand.PublicKeys))/3 + 1) {
+	if len((*vrfs)) >= Threshold(len(dRand.PublicKeys)) {
 		// Construct pRand and initiate consensus on it
-		utils.GetLogInstance().Debug("Received enough randomness commit", "numReceivedSoFar", len((*vrfs)), "validatorID", validatorID, "PublicKeys", len(dRand.PublicKeys))
+		utils.GetLogInstance().Debug("Received enough randomness commit", "numReceivedSoFar", len((*vrfs)), "validatorKey", validatorKey, "PublicKeys", len(dRand.PublicKeys))
 
-		pRnd := [32]byte{}
+		preimage := Preimage{Bitmap: append([]byte{}, dRand.bitmap.Bitmap...)}
 		// Bitwise XOR on all the submitted vrfs
 		for _, vrf := range *vrfs {
-			for i := 0; i < len(pRnd); i++ {
-				pRnd[i] = pRnd[i] ^ vrf[i]
+			for i := 0; i < len(preimage.PRnd); i++ {
+				preimage.PRnd[i] = preimage.PRnd[i] ^ vrf[i]
+			}
+		}
+		for _, pubKey := range dRand.PublicKeys {
+			if commit, ok := (*dRand.commits)[getPeerKey(pubKey)]; ok {
+				preimage.Commits = append(preimage.Commits, commit)
 			}
 		}
-		dRand.PRndChannel <- append(pRnd[:], dRand.bitmap.Bitmap...)
+		encoded, err := preimage.Encode()
+		if err != nil {
+			utils.GetLogInstance().Error("Failed to encode the randomness preimage", "error", err)
+			return
+		}
+		// Only the latest preimage is kept for consensus. The send mustn't block while
+		// holding the mutex, e.g. when the leader moved and nobody waits for the preimage.
+		select {
+		case <-dRand.PRndChannel:
+		default:
+		}
+		select {
+		case dRand.PRndChannel <- encoded:
+		default:
+		}
 	}
 }
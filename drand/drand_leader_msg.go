@@ -10,7 +10,7 @@ import (
 func (dRand *DRand) constructInitMessage() []byte {
 	message := drand_proto.Message{}
 	message.Type = drand_proto.MessageType_INIT
-	message.SenderId = dRand.nodeID
+	message.SenderPubkey = dRand.pubKey.Serialize()
 
 	message.BlockHash = dRand.blockHash[:]
 	// Don't need the payload in init message
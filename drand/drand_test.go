@@ -1,10 +1,16 @@
 package drand
 
 import (
+	"fmt"
+	"math/big"
 	"testing"
 
+	protobuf "github.com/golang/protobuf/proto"
+	drand_proto "github.com/harmony-one/harmony/api/drand"
+	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host/simulator"
 	"github.com/harmony-one/harmony/p2p/p2pimpl"
 )
 
@@ -22,3 +28,65 @@ func TestNew(test *testing.T) {
 		test.Error("dRand should belong to a leader")
 	}
 }
+
+func newTestDRands(size int) []*DRand {
+	network := simulator.NewNetwork(simulator.DefaultConfig(1))
+	peers := make([]p2p.Peer, size)
+	hosts := make([]*simulator.Host, size)
+	for i := range peers {
+		peers[i] = p2p.Peer{IP: "127.0.0.1", Port: fmt.Sprintf("%d", 9920+i)}
+		_, peers[i].PubKey = utils.GenKey(peers[i].IP, peers[i].Port)
+		hosts[i] = network.NewHost(&peers[i])
+	}
+	dRands := make([]*DRand, size)
+	for i := range peers {
+		dRands[i] = New(hosts[i], "0", peers[1:], peers[0], nil)
+	}
+	return dRands
+}
+
+func TestSetLeader(test *testing.T) {
+	dRands := newTestDRands(3)
+	newLeader := dRands[1].host.GetSelfPeer()
+	for _, dRand := range dRands {
+		dRand.SetLeader(newLeader)
+	}
+	if dRands[0].IsLeader || !dRands[1].IsLeader || dRands[2].IsLeader {
+		test.Error("the randomness protocol should follow the new leader")
+	}
+	// The old leader becomes a validator whose commits are accepted.
+	if dRands[1].getValidatorPeerByPubKey(dRands[0].pubKey.Serialize()) == nil {
+		test.Error("the old leader should be a validator of the new one")
+	}
+	if dRands[2].getValidatorPeerByPubKey(dRands[1].pubKey.Serialize()) != nil {
+		test.Error("the new leader should not be a validator")
+	}
+}
+
+func TestPreimageSentWithoutWaiting(test *testing.T) {
+	dRands := newTestDRands(3)
+	leader, validator := dRands[0], dRands[1]
+
+	// Nobody takes the preimages, e.g. the leader moved. The leader still handles the
+	// commits of every run, and only the latest preimage is kept.
+	for run := 0; run < 2; run++ {
+		leader.init(types.NewBlock(&types.Header{Number: big.NewInt(int64(10*run + 9))}, nil, nil))
+		validator.blockHash = leader.blockHash
+		rand, proof := validator.vrf(validator.blockHash)
+		message := drand_proto.Message{}
+		if err := protobuf.Unmarshal(validator.signedCommitMessage(rand, proof), &message); err != nil {
+			test.Fatal(err)
+		}
+		leader.processCommitMessage(message)
+	}
+	if len(leader.PRndChannel) != 1 {
+		test.Fatalf("one preimage should be kept, got %d", len(leader.PRndChannel))
+	}
+	preimage, err := DecodePreimage(<-leader.PRndChannel)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if err := preimage.Verify(leader.blockHash, leader.PublicKeys); err != nil {
+		test.Errorf("the preimage of the last run should be kept, got: %v", err)
+	}
+}
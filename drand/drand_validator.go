@@ -48,5 +48,9 @@ func (dRand *DRand) processInitMessage(message drand_proto.Message) {
 	msgToSend := dRand.constructCommitMessage(rand, proof)
 
 	// Send the commit message back to leader
-	host.SendMessage(dRand.host, dRand.leader, msgToSend, nil)
+	if utils.UseLibP2P {
+		dRand.SendMessageToGroup(msgToSend)
+	} else {
+		host.SendMessage(dRand.host, dRand.leader, msgToSend, nil)
+	}
 }
@@ -0,0 +1,135 @@
+package drand
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	drand_proto "github.com/harmony-one/harmony/api/drand"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	vrf_bls "github.com/harmony-one/harmony/crypto/vrf/bls"
+)
+
+// Errors of an invalid randomness preimage.
+var (
+	ErrNotEnoughCommits = errors.New("not enough vrf commits for the randomness preimage")
+	ErrWrongBitmap      = errors.New("bitmap doesn't match the vrf commits")
+	ErrWrongPRnd        = errors.New("pRnd doesn't match the vrf commits")
+)
+
+// Preimage is the preimage of the randomness of an epoch (pRnd), along with the
+// signed VRF commits it's combined from, so that anyone can recompute it.
+type Preimage struct {
+	PRnd    [32]byte
+	Bitmap  []byte   // the committee members who committed
+	Commits [][]byte // the marshaled commit messages, in the order of the committee
+}
+
+// Threshold returns the number of vrf commits needed for the randomness of a
+// committee of the given size.
+func Threshold(committeeSize int) int {
+	return committeeSize/3 + 1
+}
+
+// Encode encodes the preimage into bytes.
+func (preimage *Preimage) Encode() ([]byte, error) {
+	return rlp.EncodeToBytes(preimage)
+}
+
+// DecodePreimage decodes the preimage from bytes.
+func DecodePreimage(data []byte) (*Preimage, error) {
+	preimage := &Preimage{}
+	if err := rlp.DecodeBytes(data, preimage); err != nil {
+		return nil, err
+	}
+	return preimage, nil
+}
+
+// Verify checks that the preimage is combined from enough valid vrf commits on
+// the block hash from the committee of the given public keys. Each commit must
+// be signed by its committee member, and its vrf must verify against the BLS
+// public key of the member. pRnd and the bitmap are recomputed from the commits.
+func (preimage *Preimage) Verify(blockHash [32]byte, publicKeys []*bls.PublicKey) error {
+	mask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil {
+		return err
+	}
+	committee := map[string]*bls.PublicKey{}
+	for _, pubKey := range publicKeys {
+		committee[getPeerKey(pubKey)] = pubKey
+	}
+
+	pRnd := [32]byte{}
+	committed := map[string]bool{}
+	for i, commit := range preimage.Commits {
+		message := drand_proto.Message{}
+		if err := protobuf.Unmarshal(commit, &message); err != nil {
+			return fmt.Errorf("commit %d: %v", i, err)
+		}
+		senderKey := hex.EncodeToString(message.SenderPubkey)
+		rand, err := verifyCommit(message, blockHash, committee[senderKey])
+		if err != nil {
+			return fmt.Errorf("commit %d: %v", i, err)
+		}
+		if committed[senderKey] {
+			return fmt.Errorf("commit %d: duplicated commit of %s", i, senderKey)
+		}
+		committed[senderKey] = true
+		mask.SetKey(committee[senderKey], true)
+
+		// Bitwise XOR on all the committed vrfs
+		for j := range pRnd {
+			pRnd[j] ^= rand[j]
+		}
+	}
+
+	if len(committed) < Threshold(len(publicKeys)) {
+		return ErrNotEnoughCommits
+	}
+	if !bytes.Equal(mask.Bitmap, preimage.Bitmap) {
+		return ErrWrongBitmap
+	}
+	if pRnd != preimage.PRnd {
+		return ErrWrongPRnd
+	}
+	return nil
+}
+
+// verifyCommit checks the signature of a commit message from the committee
+// member of the given public key, and the VRF proof on the block hash in it.
+// It returns the verified vrf.
+func verifyCommit(message drand_proto.Message, blockHash [32]byte, senderPubKey *bls.PublicKey) (rand [32]byte, err error) {
+	if message.Type != drand_proto.MessageType_COMMIT {
+		return rand, fmt.Errorf("unexpected message type %s", message.Type)
+	}
+	if senderPubKey == nil {
+		return rand, errors.New("sender is not in the committee")
+	}
+	if !bytes.Equal(message.BlockHash, blockHash[:]) {
+		return rand, errors.New("commit on another block")
+	}
+	if err := verifyMessageSig(senderPubKey, message); err != nil {
+		return rand, err
+	}
+	if len(message.Payload) < len(rand) {
+		return rand, errors.New("commit payload too short")
+	}
+
+	payload := message.Payload
+	vrfPubKey, err := vrf_bls.NewVRFVerifier(senderPubKey)
+	if err != nil {
+		return rand, err
+	}
+	expectedRand, err := vrfPubKey.ProofToHash(blockHash[:], payload[len(rand):])
+	if err != nil {
+		return rand, err
+	}
+	if !bytes.Equal(expectedRand[:], payload[:len(rand)]) {
+		return rand, errors.New("vrf doesn't match its proof")
+	}
+	return expectedRand, nil
+}
@@ -0,0 +1,99 @@
+package drand
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	vrf_bls "github.com/harmony-one/harmony/crypto/vrf/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCommittee returns the dRand of each committee member, committing on the block hash.
+func newTestCommittee(size int, blockHash [32]byte) ([]*DRand, []*bls.PublicKey) {
+	members := make([]*DRand, size)
+	publicKeys := make([]*bls.PublicKey, size)
+	for i := range members {
+		dRand := &DRand{blockHash: blockHash}
+		dRand.priKey, dRand.pubKey = utils.GenKey("127.0.0.1", fmt.Sprintf("%d", 9500+i))
+		dRand.vrfPriKey, _ = vrf_bls.NewVRFSigner(dRand.priKey)
+		members[i], publicKeys[i] = dRand, dRand.pubKey
+	}
+	return members, publicKeys
+}
+
+// newTestPreimage combines the commits of the given committee members.
+func newTestPreimage(members []*DRand, publicKeys []*bls.PublicKey, committers ...int) *Preimage {
+	preimage := &Preimage{}
+	mask, _ := bls_cosi.NewMask(publicKeys, nil)
+	for _, i := range committers {
+		rand, proof := members[i].vrf(members[i].blockHash)
+		preimage.Commits = append(preimage.Commits, members[i].signedCommitMessage(rand, proof))
+		mask.SetKey(publicKeys[i], true)
+		for j := range preimage.PRnd {
+			preimage.PRnd[j] ^= rand[j]
+		}
+	}
+	preimage.Bitmap = mask.Bitmap
+	return preimage
+}
+
+func TestPreimageVerify(t *testing.T) {
+	blockHash := [32]byte{1, 2, 3}
+	members, publicKeys := newTestCommittee(4, blockHash)
+	assert.Equal(t, 2, Threshold(len(publicKeys)))
+
+	preimage := newTestPreimage(members, publicKeys, 0, 2)
+	assert.Nil(t, preimage.Verify(blockHash, publicKeys))
+
+	// The preimage is carried in blocks encoded
+	encoded, err := preimage.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodePreimage(encoded)
+	if assert.Nil(t, err) {
+		assert.Nil(t, decoded.Verify(blockHash, publicKeys))
+	}
+	_, err = DecodePreimage([]byte("garbage"))
+	assert.NotNil(t, err)
+
+	// Commits on another block
+	assert.NotNil(t, preimage.Verify([32]byte{4, 5, 6}, publicKeys))
+	// Commits from outside the committee
+	assert.NotNil(t, preimage.Verify(blockHash, publicKeys[1:]))
+
+	wrongPRnd := newTestPreimage(members, publicKeys, 0, 2)
+	wrongPRnd.PRnd[0] ^= 1
+	assert.Equal(t, ErrWrongPRnd, wrongPRnd.Verify(blockHash, publicKeys))
+
+	wrongBitmap := newTestPreimage(members, publicKeys, 0, 2)
+	wrongBitmap.Bitmap = newTestPreimage(members, publicKeys, 0, 1).Bitmap
+	assert.Equal(t, ErrWrongBitmap, wrongBitmap.Verify(blockHash, publicKeys))
+
+	notEnough := newTestPreimage(members, publicKeys, 3)
+	assert.Equal(t, ErrNotEnoughCommits, notEnough.Verify(blockHash, publicKeys))
+
+	// The same commit counted twice
+	duplicated := newTestPreimage(members, publicKeys, 1)
+	duplicated.Commits = append(duplicated.Commits, duplicated.Commits[0])
+	assert.NotNil(t, duplicated.Verify(blockHash, publicKeys))
+}
+
+func TestPreimageVerifyInvalidCommit(t *testing.T) {
+	blockHash := [32]byte{1, 2, 3}
+	members, publicKeys := newTestCommittee(4, blockHash)
+	preimage := newTestPreimage(members, publicKeys, 0, 1)
+
+	// A signed commit whose vrf doesn't match its proof
+	_, proof := members[1].vrf(blockHash)
+	preimage.Commits[1] = members[1].signedCommitMessage([32]byte{7}, proof)
+	assert.NotNil(t, preimage.Verify(blockHash, publicKeys))
+
+	// A commit signed by another member
+	preimage = newTestPreimage(members, publicKeys, 0, 1)
+	rand, proof := members[1].vrf(blockHash)
+	forger := &DRand{blockHash: blockHash, priKey: members[2].priKey, pubKey: members[1].pubKey}
+	preimage.Commits[1] = forger.signedCommitMessage(rand, proof)
+	assert.NotNil(t, preimage.Verify(blockHash, publicKeys))
+}
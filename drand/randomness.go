@@ -0,0 +1,56 @@
+package drand
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/crypto/vdf"
+)
+
+// ErrInvalidVdf is returned if the VDF output of a randomness doesn't verify.
+var ErrInvalidVdf = errors.New("invalid vdf output")
+
+// Randomness is the final randomness of an epoch: the output of the VDF over
+// its pRnd, with the proof of the evaluation. The last contributor of pRnd
+// can't predict it before the VDF finishes, so withholding its vrf can't bias it.
+type Randomness struct {
+	PRnd   [32]byte
+	Output []byte
+	Proof  []byte
+}
+
+// EvaluateRandomness runs the VDF of the given difficulty over pRnd into the final
+// randomness. The difficulty of an epoch is given by the chain params, see
+// chainparams.Config.VdfDifficulty.
+func EvaluateRandomness(pRnd [32]byte, difficulty uint64) *Randomness {
+	output, proof := vdf.New(difficulty).Evaluate(pRnd)
+	return &Randomness{PRnd: pRnd, Output: output, Proof: proof}
+}
+
+// Rand returns the final randomness, the hash of the VDF output.
+func (randomness *Randomness) Rand() [32]byte {
+	return sha256.Sum256(randomness.Output)
+}
+
+// Verify checks the VDF output is the evaluation of the given difficulty over pRnd.
+func (randomness *Randomness) Verify(difficulty uint64) error {
+	if !vdf.New(difficulty).Verify(randomness.PRnd, randomness.Output, randomness.Proof) {
+		return ErrInvalidVdf
+	}
+	return nil
+}
+
+// Encode encodes the randomness into bytes.
+func (randomness *Randomness) Encode() ([]byte, error) {
+	return rlp.EncodeToBytes(randomness)
+}
+
+// DecodeRandomness decodes the randomness from bytes.
+func DecodeRandomness(data []byte) (*Randomness, error) {
+	randomness := &Randomness{}
+	if err := rlp.DecodeBytes(data, randomness); err != nil {
+		return nil, err
+	}
+	return randomness, nil
+}
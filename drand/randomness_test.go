@@ -0,0 +1,27 @@
+package drand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomness(t *testing.T) {
+	pRnd := [32]byte{1, 2, 3}
+	randomness := EvaluateRandomness(pRnd, 100)
+	assert.Nil(t, randomness.Verify(100))
+	assert.Equal(t, ErrInvalidVdf, randomness.Verify(99))
+	assert.NotEqual(t, pRnd, randomness.Rand())
+
+	encoded, err := randomness.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeRandomness(encoded)
+	if assert.Nil(t, err) {
+		assert.Nil(t, decoded.Verify(100))
+		assert.Equal(t, randomness.Rand(), decoded.Rand())
+	}
+
+	// The output of the VDF over another pRnd
+	decoded.PRnd = [32]byte{4, 5, 6}
+	assert.Equal(t, ErrInvalidVdf, decoded.Verify(100))
+}
@@ -34,6 +34,7 @@ type Model struct {
 	attackType                Type
 	ConsensusIDThreshold      uint32
 	readyByConsensusThreshold bool
+	script                    *Script
 }
 
 var attackModel *Model
@@ -63,6 +64,16 @@ func (attack *Model) SetAttackEnabled(AttackEnabled bool) {
 	}
 }
 
+// SetScript sets the byzantine behaviors scripted for this node.
+func (attack *Model) SetScript(script *Script) {
+	attack.script = script
+}
+
+// Script returns the byzantine behaviors scripted for this node, or nil if there are none.
+func (attack *Model) Script() *Script {
+	return attack.script
+}
+
 // Run runs enabled attacks.
 func (attack *Model) Run() {
 	attack.NodeKilledByItSelf()
@@ -0,0 +1,130 @@
+package attack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+// Behavior is a byzantine behavior of a scripted adversary.
+type Behavior string
+
+// Byzantine behaviors of a scripted adversary.
+const (
+	// Equivocation makes a validator also vote for a conflicting block.
+	Equivocation Behavior = "equivocation"
+	// InvalidShare makes a validator send vote shares which don't verify.
+	InvalidShare Behavior = "invalid_share"
+	// Silence makes a node send nothing to the targeted peers.
+	Silence Behavior = "silence"
+	// SplitAnnounce makes a leader announce a conflicting block to the targeted peers.
+	SplitAnnounce Behavior = "split_announce"
+	// StaleReplay makes a node send its messages of the earlier rounds again.
+	StaleReplay Behavior = "stale_replay"
+)
+
+var behaviors = map[Behavior]bool{
+	Equivocation:  true,
+	InvalidShare:  true,
+	Silence:       true,
+	SplitAnnounce: true,
+	StaleReplay:   true,
+}
+
+// Rule enables a byzantine behavior for a range of consensus rounds.
+type Rule struct {
+	Behavior Behavior `json:"behavior"`
+	// The first and the last consensus ids the rule applies to. A last id of 0
+	// means the rule applies to all the rounds from the first one.
+	From uint32 `json:"from"`
+	To   uint32 `json:"to"`
+	// The addresses (ip:port) of the peers targeted by the rule. No peers means
+	// all of them.
+	Peers []string `json:"peers"`
+}
+
+// Script is the list of the byzantine behaviors of a node, e.g.
+//
+//	{"rules": [{"behavior": "silence", "from": 10, "to": 20, "peers": ["127.0.0.1:9001"]}]}
+type Script struct {
+	Rules []Rule `json:"rules"`
+}
+
+// ParseScript parses a script in json.
+func ParseScript(data []byte) (*Script, error) {
+	script := &Script{}
+	if err := json.Unmarshal(data, script); err != nil {
+		return nil, err
+	}
+	for i, rule := range script.Rules {
+		if !behaviors[rule.Behavior] {
+			return nil, fmt.Errorf("rule %d: unknown behavior %q", i, rule.Behavior)
+		}
+		if rule.To != 0 && rule.To < rule.From {
+			return nil, fmt.Errorf("rule %d: round range %d-%d is empty", i, rule.From, rule.To)
+		}
+	}
+	return script, nil
+}
+
+// LoadScript loads a script from either a json file, or the json itself if the
+// given value starts with '{'.
+func LoadScript(pathOrJSON string) (*Script, error) {
+	if strings.HasPrefix(strings.TrimSpace(pathOrJSON), "{") {
+		return ParseScript([]byte(pathOrJSON))
+	}
+	data, err := ioutil.ReadFile(pathOrJSON)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScript(data)
+}
+
+// Rule returns the first rule enabling the behavior in the given round, or nil
+// if the behavior is not enabled. A nil script enables nothing.
+func (script *Script) Rule(behavior Behavior, consensusID uint32) *Rule {
+	if script == nil {
+		return nil
+	}
+	for i := range script.Rules {
+		rule := &script.Rules[i]
+		if rule.Behavior == behavior && consensusID >= rule.From && (rule.To == 0 || consensusID <= rule.To) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Has returns whether the script enables the behavior in any round.
+func (script *Script) Has(behavior Behavior) bool {
+	if script == nil {
+		return false
+	}
+	for _, rule := range script.Rules {
+		if rule.Behavior == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns whether the rule targets the peer at the given address.
+func (rule *Rule) Targets(ip string, port string) bool {
+	if len(rule.Peers) == 0 {
+		return true
+	}
+	addr := net.JoinHostPort(ip, port)
+	for _, peer := range rule.Peers {
+		if peer == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// TargetsAll returns whether the rule targets all the peers.
+func (rule *Rule) TargetsAll() bool {
+	return len(rule.Peers) == 0
+}
@@ -0,0 +1,69 @@
+package attack
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScript(t *testing.T) {
+	script, err := ParseScript([]byte(`{"rules": [
+		{"behavior": "silence", "from": 10, "to": 20, "peers": ["127.0.0.1:9001"]},
+		{"behavior": "equivocation", "from": 5}
+	]}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Nil(t, script.Rule(Silence, 9))
+	rule := script.Rule(Silence, 10)
+	if assert.NotNil(t, rule) {
+		assert.True(t, rule.Targets("127.0.0.1", "9001"))
+		assert.False(t, rule.Targets("127.0.0.1", "9002"))
+		assert.False(t, rule.TargetsAll())
+	}
+	assert.Nil(t, script.Rule(Silence, 21))
+
+	// No last round
+	assert.NotNil(t, script.Rule(Equivocation, 1000))
+	assert.True(t, script.Rule(Equivocation, 5).Targets("127.0.0.1", "9002"))
+	assert.True(t, script.Has(Equivocation))
+	assert.False(t, script.Has(StaleReplay))
+
+	// A node without a script is honest
+	var honest *Script
+	assert.Nil(t, honest.Rule(Silence, 10))
+	assert.False(t, honest.Has(Silence))
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	_, err := ParseScript([]byte(`{"rules": [{"behavior": "teleport"}]}`))
+	assert.NotNil(t, err)
+	_, err = ParseScript([]byte(`{"rules": [{"behavior": "silence", "from": 5, "to": 4}]}`))
+	assert.NotNil(t, err)
+	_, err = ParseScript([]byte(`not json`))
+	assert.NotNil(t, err)
+}
+
+func TestLoadScript(t *testing.T) {
+	script, err := LoadScript(`{"rules": [{"behavior": "invalid_share"}]}`)
+	assert.Nil(t, err)
+	assert.NotNil(t, script.Rule(InvalidShare, 0))
+
+	file, err := ioutil.TempFile("", "attack_script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"rules": [{"behavior": "stale_replay", "from": 3}]}`)
+	file.Close()
+
+	script, err = LoadScript(file.Name())
+	assert.Nil(t, err)
+	assert.NotNil(t, script.Rule(StaleReplay, 3))
+
+	_, err = LoadScript(file.Name() + ".missing")
+	assert.NotNil(t, err)
+}
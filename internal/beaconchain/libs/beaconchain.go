@@ -1,17 +1,20 @@
 package beaconchain
 
 import (
+	"errors"
 	"math/rand"
 	"os"
 	"strconv"
 	"sync"
 
 	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
 	"github.com/harmony-one/harmony/api/proto/bcconn"
 	proto_identity "github.com/harmony-one/harmony/api/proto/identity"
 	"github.com/harmony-one/harmony/api/proto/node"
 	"github.com/harmony-one/harmony/crypto/pki"
 	beaconchain "github.com/harmony-one/harmony/internal/beaconchain/rpc"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	"github.com/harmony-one/harmony/p2p/host"
@@ -37,6 +40,9 @@ type BCInfo struct {
 	NumberOfNodesAdded int                `json:"numNodesAdded"`
 	IP                 string             `json:"ip"`
 	Port               string             `json:"port"`
+	// ShardCommittees are the serialized BLS public keys of the nodes registered to each
+	// shard, by the 0-indexed shard id, which sign the crosslinks of the shard.
+	ShardCommittees map[int][][]byte `json:"shardCommittees,omitempty"`
 }
 
 // BeaconChain (Blockchain) keeps Identities per epoch, currently centralized!
@@ -49,6 +55,17 @@ type BeaconChain struct {
 	rpcServer      *beaconchain.Server
 	Peer           p2p.Peer
 	Self           p2p.Peer // self Peer
+	// ChainParams are the protocol parameters of the shard chains, with the quorum the
+	// crosslinks are verified against. chainparams.DefaultConfig is used if it's nil.
+	ChainParams *chainparams.Config
+	// The verified headers of the shard chains, by shard id and block number, and the
+	// serialized BLS public keys of the committees they recorded, by shard id and epoch.
+	// They're appended to the crosslink file rather than saved with the info.
+	crosslinks map[uint32]map[uint64]*Crosslink
+	committees map[uint32]map[uint64][][]byte
+	// The crosslinks of the last blocks of epochs signed by a committee not known yet, by
+	// shard id and epoch, accepted once the committee is.
+	orphans map[uint32]map[uint64]*bcconn.Crosslink
 }
 
 //SaveFile is to store the file in which beaconchain info will be stored.
@@ -68,7 +85,7 @@ func (bc *BeaconChain) SupportRPC() {
 
 // InitRPCServer initializes Rpc server.
 func (bc *BeaconChain) InitRPCServer() {
-	bc.rpcServer = beaconchain.NewServer(bc.GetShardLeaderMap)
+	bc.rpcServer = beaconchain.NewServer(bc.GetShardLeaderMap, bc.fetchCrosslink)
 }
 
 // StartRPCServer starts Rpc server.
@@ -121,8 +138,18 @@ func (bc *BeaconChain) AcceptNodeInfo(b []byte) *node.Info {
 	bc.BCInfo.NumberOfNodesAdded = bc.BCInfo.NumberOfNodesAdded + 1
 	shardNum, isLeader := utils.AllocateShard(bc.BCInfo.NumberOfNodesAdded, bc.BCInfo.NumberOfShards)
 	if isLeader {
+		mutex.Lock()
 		bc.BCInfo.Leaders = append(bc.BCInfo.Leaders, Node)
 		bc.BCInfo.ShardLeaderMap[shardNum] = Node
+		mutex.Unlock()
+	}
+	if len(Node.PubKey) > 0 {
+		mutex.Lock()
+		if bc.BCInfo.ShardCommittees == nil {
+			bc.BCInfo.ShardCommittees = make(map[int][][]byte)
+		}
+		bc.BCInfo.ShardCommittees[shardNum-1] = append(bc.BCInfo.ShardCommittees[shardNum-1], Node.PubKey)
+		mutex.Unlock()
 	}
 	go SaveBeaconChainInfo(SaveFile, bc)
 	bc.state = NodeInfoReceived
@@ -149,6 +176,27 @@ func (bc *BeaconChain) AcceptConnections(b []byte) {
 	bc.RespondRandomness(node)
 }
 
+// RelayCrossShardReceipts forwards the node message of cross-shard receipts to the leader
+// of their destination shard, for the shards not connected over libp2p.
+func (bc *BeaconChain) RelayCrossShardReceipts(b []byte) error {
+	receipts, err := bcconn.DeserializeCrossShardReceipts(b)
+	if err != nil {
+		return err
+	}
+	if category, err := proto.GetMessageCategory(receipts.Message); err != nil || category != proto.Node {
+		return errors.New("not a node message")
+	}
+	// The beacon chain numbers the shards from 1
+	mutex.Lock()
+	leader, ok := bc.BCInfo.ShardLeaderMap[int(receipts.ToShardID)+1]
+	mutex.Unlock()
+	if !ok || leader == nil {
+		return ErrUnknownShard
+	}
+	host.SendMessage(bc.host, p2p.Peer{IP: leader.IP, Port: leader.Port, PeerID: leader.PeerID}, receipts.Message, nil)
+	return nil
+}
+
 //StartServer a server and process the request by a handler.
 func (bc *BeaconChain) StartServer() {
 	bc.host.BindHandlerAndServe(bc.BeaconChainHandler)
@@ -156,6 +204,8 @@ func (bc *BeaconChain) StartServer() {
 
 //SaveBeaconChainInfo to disk
 func SaveBeaconChainInfo(filePath string, bc *BeaconChain) error {
+	mutex.Lock()
+	defer mutex.Unlock()
 	bci := BCtoBCI(bc)
 	err := utils.Save(filePath, bci)
 	return err
@@ -174,12 +224,15 @@ func LoadBeaconChainInfo(path string) (*BeaconChain, error) {
 		return nil, err
 	}
 	bc = BCItoBC(bci)
+	if err := bc.loadCrosslinks(CrosslinkFile(path)); err != nil {
+		return nil, err
+	}
 	return bc, err
 }
 
 // BCtoBCI converts beaconchain into beaconchaininfo
 func BCtoBCI(bc *BeaconChain) *BCInfo {
-	bci := &BCInfo{Leaders: bc.BCInfo.Leaders, ShardLeaderMap: bc.BCInfo.ShardLeaderMap, NumberOfShards: bc.BCInfo.NumberOfShards, NumberOfNodesAdded: bc.BCInfo.NumberOfNodesAdded, IP: bc.BCInfo.IP, Port: bc.BCInfo.Port}
+	bci := &BCInfo{Leaders: bc.BCInfo.Leaders, ShardLeaderMap: bc.BCInfo.ShardLeaderMap, NumberOfShards: bc.BCInfo.NumberOfShards, NumberOfNodesAdded: bc.BCInfo.NumberOfNodesAdded, IP: bc.BCInfo.IP, Port: bc.BCInfo.Port, ShardCommittees: bc.BCInfo.ShardCommittees}
 	return bci
 }
 
@@ -48,6 +48,14 @@ func (bc *BeaconChain) BeaconChainHandler(s p2p.Stream) {
 			case proto_identity.Register:
 				utils.GetLogInstance().Info("Identity Message Type is of the type Register")
 				bc.AcceptConnections(identityMsgPayload)
+			case proto_identity.Crosslink:
+				if err := bc.AcceptCrosslink(identityMsgPayload); err != nil {
+					utils.GetLogInstance().Warn("Rejected crosslink", "err", err)
+				}
+			case proto_identity.CrossShardReceipts:
+				if err := bc.RelayCrossShardReceipts(identityMsgPayload); err != nil {
+					utils.GetLogInstance().Warn("Failed to relay cross-shard receipts", "err", err)
+				}
 			default:
 				utils.GetLogInstance().Error("Unrecognized identity message type", "type", idMsgType)
 			}
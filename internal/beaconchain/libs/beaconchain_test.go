@@ -67,7 +67,7 @@ func TestFetchLeaders(t *testing.T) {
 	priKey, _, _ := utils.GenKeyP2P(ip, beaconport)
 	bc := New(numshards, ip, beaconport, priKey)
 	bc.BCInfo.Leaders = leaders
-	bc.rpcServer = beaconchain.NewServer(bc.GetShardLeaderMap)
+	bc.rpcServer = beaconchain.NewServer(bc.GetShardLeaderMap, bc.fetchCrosslink)
 	bc.StartRPCServer()
 	port, _ := strconv.Atoi(beaconport)
 	bcClient := beaconchain.NewClient("127.0.0.1", strconv.Itoa(port+BeaconchainServicePortDiff))
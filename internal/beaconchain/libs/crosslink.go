@@ -0,0 +1,403 @@
+package beaconchain
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	proto "github.com/harmony-one/harmony/api/beaconchain"
+	"github.com/harmony-one/harmony/api/proto/bcconn"
+	proto_identity "github.com/harmony-one/harmony/api/proto/identity"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/chainparams"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// The number of crosslinks of the last blocks of epochs kept for each shard until the
+// committee which signed them is known.
+const maxOrphanCrosslinks = 16
+
+// Errors of the crosslinks.
+var (
+	ErrUnknownShard         = errors.New("no committee known for the shard")
+	ErrNotCommitteeMember   = errors.New("crosslink signed by a key not in the committee of the shard")
+	ErrConflictingCrosslink = errors.New("crosslink conflicting with the one at the same height")
+	ErrShardStateMismatch   = errors.New("shard state of the crosslink not matching its header")
+)
+
+// Crosslink is a committed block header of a shard chain, verified to be signed by the
+// committee of the shard.
+type Crosslink struct {
+	ShardID   uint32      `json:"shardID"`
+	BlockNum  uint64      `json:"blockNum"`
+	BlockHash common.Hash `json:"blockHash"`
+	Header    []byte      `json:"header"` // the RLP encoded header, with the signatures of the committee
+}
+
+// crosslinkRecord is a verified crosslink as appended to the crosslink file, with the
+// committee of the shard in the next epoch, if it's the last block of an epoch.
+type crosslinkRecord struct {
+	Crosslink *Crosslink
+	Epoch     uint64
+	Committee [][]byte // the serialized BLS public keys of the committee, if any
+}
+
+// CrosslinkFile returns the path of the file the crosslinks are appended to, next to the
+// file of the beacon chain info.
+func CrosslinkFile(savePath string) string {
+	return savePath + ".crosslinks"
+}
+
+// GetHeader returns the header of the crosslink.
+func (c *Crosslink) GetHeader() (*types.Header, error) {
+	header := &types.Header{}
+	if err := rlp.DecodeBytes(c.Header, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// committeeQuorum requires the signers to be more than the quorum fraction of the whole
+// registered committee of the shard. The consensus of the shard may not know every member
+// yet, so the bitmaps of the header can cover a part of the committee only.
+type committeeQuorum struct {
+	members     map[string]bool
+	numerator   uint64
+	denominator uint64
+}
+
+// Check verifies that more than the fraction of the committee have contributed to a
+// collective signature.
+func (q committeeQuorum) Check(m *bls_cosi.Mask) bool {
+	signers := 0
+	for _, key := range m.GetPubKeyFromMask(true) {
+		if q.members[string(key.Serialize())] {
+			signers++
+		}
+	}
+	return uint64(signers)*q.denominator > uint64(len(q.members))*q.numerator
+}
+
+// committeeAt returns the serialized BLS public keys of the committee of the shard in the
+// epoch. It's the committee recorded in the shard state of the verified last block of the
+// previous epoch of the shard, or in the genesis epoch, the nodes registered to the shard. The caller must hold
+// the mutex.
+func (bc *BeaconChain) committeeAt(shardID uint32, epoch uint64) [][]byte {
+	if committee, ok := bc.committees[shardID][epoch]; ok {
+		return committee
+	}
+	if epoch == 0 {
+		return bc.BCInfo.ShardCommittees[int(shardID)]
+	}
+	return nil
+}
+
+// chainParams returns the params of the shard chains.
+func (bc *BeaconChain) chainParams() *chainparams.Config {
+	if bc.ChainParams == nil {
+		return chainparams.DefaultConfig
+	}
+	return bc.ChainParams
+}
+
+// VerifyCrosslink checks that the header of the crosslink is signed by a quorum of the
+// committee of its shard in the epoch of the header.
+func (bc *BeaconChain) VerifyCrosslink(crosslink *bcconn.Crosslink) error {
+	header := crosslink.Header
+	if header == nil || header.Number == nil {
+		return errors.New("crosslink without header")
+	}
+	shardID := binary.BigEndian.Uint32(header.ShardID[:])
+	chainParams := bc.chainParams()
+
+	mutex.Lock()
+	committee := bc.committeeAt(shardID, chainParams.EpochOf(header.Number.Uint64()))
+	mutex.Unlock()
+	if len(committee) == 0 {
+		return ErrUnknownShard
+	}
+	members := map[string]bool{}
+	for _, key := range committee {
+		members[string(key)] = true
+	}
+
+	// The keys are in the order of the bitmaps, each one a distinct member of the committee
+	publicKeys := []*bls.PublicKey{}
+	seen := map[string]bool{}
+	for _, key := range crosslink.PublicKeys {
+		if !members[string(key)] || seen[string(key)] {
+			return ErrNotCommitteeMember
+		}
+		seen[string(key)] = true
+		publicKey := &bls.PublicKey{}
+		if err := publicKey.Deserialize(key); err != nil {
+			return err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	params := chainParams.At(header.Number.Uint64())
+	quorum := committeeQuorum{members: members, numerator: params.QuorumNumerator, denominator: params.QuorumDenominator}
+	return consensus.VerifyHeaderSeal(header, publicKeys, quorum)
+}
+
+// nextCommittee returns the serialized BLS public keys of the committee of the shard which
+// takes over after the last block of an epoch of the crosslink, from the shard state sent
+// along. It returns nil if the crosslink isn't of the last block of an epoch, or the shard
+// state doesn't record the BLS keys of the shard.
+func nextCommittee(crosslink *bcconn.Crosslink, chainParams *chainparams.Config) ([][]byte, error) {
+	header := crosslink.Header
+	number := header.Number.Uint64()
+	if !chainParams.IsEpochLastBlock(number) || header.ShardStateHash == (common.Hash{}) {
+		return nil, nil
+	}
+	if crosslink.ShardState.Hash() != header.ShardStateHash {
+		return nil, ErrShardStateMismatch
+	}
+	shardID := binary.BigEndian.Uint32(header.ShardID[:])
+	for _, committee := range crosslink.ShardState {
+		if committee.ShardID != shardID {
+			continue
+		}
+		keys := [][]byte{}
+		for _, nodeID := range committee.NodeList {
+			publicKey := &bls.PublicKey{}
+			if err := publicKey.DeserializeHexStr(nodeID.BlsPublicKey); err != nil {
+				return nil, nil
+			}
+			keys = append(keys, publicKey.Serialize())
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// AcceptCrosslink verifies the crosslink submitted by the leader of a shard, and stores it
+// if it's signed by the committee of the shard. The crosslink of the last block of an epoch
+// records the committee of the shard in the next epoch.
+func (bc *BeaconChain) AcceptCrosslink(b []byte) error {
+	crosslink, err := bcconn.DeserializeCrosslink(b)
+	if err != nil {
+		return err
+	}
+	return bc.acceptCrosslink(crosslink)
+}
+
+// acceptCrosslink verifies and stores the crosslink. Once it records the committee of an
+// epoch, the crosslink of the last block of the epoch kept until then is accepted too.
+func (bc *BeaconChain) acceptCrosslink(crosslink *bcconn.Crosslink) error {
+	if err := bc.VerifyCrosslink(crosslink); err != nil {
+		if err == ErrUnknownShard {
+			bc.requestCommittee(crosslink)
+		}
+		return err
+	}
+	committee, err := nextCommittee(crosslink, bc.chainParams())
+	if err != nil {
+		return err
+	}
+	header := crosslink.Header
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return err
+	}
+	shardID := binary.BigEndian.Uint32(header.ShardID[:])
+	blockNum := header.Number.Uint64()
+	record := &crosslinkRecord{
+		Crosslink: &Crosslink{ShardID: shardID, BlockNum: blockNum, BlockHash: header.Hash(), Header: data},
+		Epoch:     bc.chainParams().EpochOf(blockNum) + 1,
+		Committee: committee,
+	}
+
+	mutex.Lock()
+	if existing, ok := bc.crosslinks[shardID][blockNum]; ok {
+		mutex.Unlock()
+		if existing.BlockHash != header.Hash() {
+			utils.GetLogInstance().Warn("Conflicting crosslinks", "shardID", shardID, "blockNum", blockNum, "hash", existing.BlockHash, "newHash", header.Hash())
+			return ErrConflictingCrosslink
+		}
+		return nil
+	}
+	bc.addCrosslink(record)
+	if SaveFile != "" {
+		if err := appendCrosslink(CrosslinkFile(SaveFile), record); err != nil {
+			utils.GetLogInstance().Error("Failed to save crosslink", "shardID", shardID, "blockNum", blockNum, "err", err)
+		}
+	}
+	var orphan *bcconn.Crosslink
+	if len(committee) > 0 {
+		orphan = bc.orphans[shardID][record.Epoch]
+		delete(bc.orphans[shardID], record.Epoch)
+	}
+	mutex.Unlock()
+
+	utils.GetLogInstance().Info("New crosslink", "shardID", shardID, "blockNum", blockNum, "blockHash", header.Hash())
+	if orphan != nil {
+		if err := bc.acceptCrosslink(orphan); err != nil {
+			utils.GetLogInstance().Warn("Rejected the kept crosslink", "shardID", shardID, "blockNum", orphan.Header.Number, "err", err)
+		}
+	}
+	return nil
+}
+
+// requestCommittee asks the leader of the shard for the crosslink recording the committee
+// which signed the given crosslink: the one of the last block before its epoch. That one
+// may be signed by an unknown committee too, so the beacon chain walks back one epoch at a
+// time to a known committee. The crosslinks of the last blocks of epochs are kept on the
+// way, to walk forward again once their committees are known. A crosslink which can't be
+// kept is requested again by the next unverifiable crosslink of the shard.
+func (bc *BeaconChain) requestCommittee(crosslink *bcconn.Crosslink) {
+	header := crosslink.Header
+	shardID := binary.BigEndian.Uint32(header.ShardID[:])
+	number := header.Number.Uint64()
+	epoch := bc.chainParams().EpochOf(number)
+	// The committee of the genesis epoch is only known from the registered nodes
+	if epoch == 0 {
+		return
+	}
+
+	mutex.Lock()
+	if bc.chainParams().IsEpochLastBlock(number) {
+		if bc.orphans == nil {
+			bc.orphans = make(map[uint32]map[uint64]*bcconn.Crosslink)
+		}
+		if bc.orphans[shardID] == nil {
+			bc.orphans[shardID] = make(map[uint64]*bcconn.Crosslink)
+		}
+		if _, ok := bc.orphans[shardID][epoch]; ok || len(bc.orphans[shardID]) < maxOrphanCrosslinks {
+			bc.orphans[shardID][epoch] = crosslink
+		}
+	}
+	// The beacon chain numbers the shards from 1
+	leader, ok := bc.BCInfo.ShardLeaderMap[int(shardID)+1]
+	mutex.Unlock()
+	if !ok || leader == nil || bc.host == nil {
+		return
+	}
+	request := bcconn.SerializeCommitteeRequest(&bcconn.CommitteeRequest{ShardID: shardID, Epoch: epoch})
+	msg := proto_identity.ConstructIdentityMessage(proto_identity.CommitteeRequest, request)
+	utils.GetLogInstance().Info("Requesting the committee of the shard", "shardID", shardID, "epoch", epoch)
+	host.SendMessage(bc.host, p2p.Peer{IP: leader.IP, Port: leader.Port, PeerID: leader.PeerID}, msg, nil)
+}
+
+// addCrosslink stores the crosslink of the record, and the committee it records. The
+// caller must hold the mutex.
+func (bc *BeaconChain) addCrosslink(record *crosslinkRecord) {
+	crosslink := record.Crosslink
+	if bc.crosslinks == nil {
+		bc.crosslinks = make(map[uint32]map[uint64]*Crosslink)
+	}
+	if bc.crosslinks[crosslink.ShardID] == nil {
+		bc.crosslinks[crosslink.ShardID] = make(map[uint64]*Crosslink)
+	}
+	bc.crosslinks[crosslink.ShardID][crosslink.BlockNum] = crosslink
+	if len(record.Committee) == 0 {
+		return
+	}
+	if bc.committees == nil {
+		bc.committees = make(map[uint32]map[uint64][][]byte)
+	}
+	if bc.committees[crosslink.ShardID] == nil {
+		bc.committees[crosslink.ShardID] = make(map[uint64][][]byte)
+	}
+	bc.committees[crosslink.ShardID][record.Epoch] = record.Committee
+}
+
+// appendCrosslink appends the record of a verified crosslink to the crosslink file, so that
+// the crosslinks are persisted one at a time.
+func appendCrosslink(path string, record *crosslinkRecord) error {
+	data, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadCrosslinks reads the crosslinks appended to the crosslink file back. A record cut
+// short by a crash while it was written is dropped.
+func (bc *BeaconChain) loadCrosslinks(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	stream := rlp.NewStream(file, 0)
+	for {
+		record := &crosslinkRecord{}
+		err := stream.Decode(record)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			utils.GetLogInstance().Warn("Dropping the truncated crosslink at the end of the file", "path", path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		bc.addCrosslink(record)
+	}
+}
+
+// GetCrosslink returns the crosslink of the shard at the block number, or nil if there's none.
+func (bc *BeaconChain) GetCrosslink(shardID uint32, blockNum uint64) *Crosslink {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return bc.crosslinks[shardID][blockNum]
+}
+
+// GetLatestCrosslink returns the crosslink of the shard at the highest block, or nil if
+// there's none.
+func (bc *BeaconChain) GetLatestCrosslink(shardID uint32) *Crosslink {
+	mutex.Lock()
+	defer mutex.Unlock()
+	var latest *Crosslink
+	for _, crosslink := range bc.crosslinks[shardID] {
+		if latest == nil || crosslink.BlockNum > latest.BlockNum {
+			latest = crosslink
+		}
+	}
+	return latest
+}
+
+// fetchCrosslink returns the crosslink of the shard at the block number for the rpc
+// service, or the latest one of the shard if the block number is 0.
+func (bc *BeaconChain) fetchCrosslink(shardID uint32, blockNum uint64) *proto.FetchCrosslinkResponse {
+	var crosslink *Crosslink
+	if blockNum == 0 {
+		crosslink = bc.GetLatestCrosslink(shardID)
+	} else {
+		crosslink = bc.GetCrosslink(shardID, blockNum)
+	}
+	if crosslink == nil {
+		return nil
+	}
+	return &proto.FetchCrosslinkResponse{
+		ShardId:     crosslink.ShardID,
+		BlockNumber: crosslink.BlockNum,
+		BlockHash:   crosslink.BlockHash[:],
+		Header:      crosslink.Header,
+	}
+}
@@ -0,0 +1,198 @@
+package beaconchain
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto/bcconn"
+	"github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// signedCrosslink returns the crosslink of a header of shard 0 at the block number, signed
+// by the given keys of the committee in both prepare and commit phases.
+func signedCrosslink(blockNum int64, publicKeys []*bls.PublicKey, signers []*bls.SecretKey) []byte {
+	return signedEpochCrosslink(blockNum, nil, publicKeys, signers)
+}
+
+// signedEpochCrosslink returns the signed crosslink of a header of shard 0 at the block
+// number recording the shard state, if any.
+func signedEpochCrosslink(blockNum int64, shardState types.ShardState, publicKeys []*bls.PublicKey, signers []*bls.SecretKey) []byte {
+	header := &types.Header{Number: big.NewInt(blockNum), Time: big.NewInt(0), Difficulty: big.NewInt(0), ShardID: types.EncodeShardID(0)}
+	if shardState != nil {
+		header.ShardStateHash = shardState.Hash()
+	}
+	mask, _ := bls_cosi.NewMask(publicKeys, nil)
+	for _, signer := range signers {
+		mask.SetKey(signer.GetPublicKey(), true)
+	}
+	block := types.NewBlockWithHeader(header)
+	sealHash := consensus.NewFaker().SealHash(header)
+	prepareSigs := []*bls.Sign{}
+	for _, signer := range signers {
+		prepareSigs = append(prepareSigs, signer.SignHash(sealHash[:]))
+	}
+	prepareSig := bls_cosi.AggregateSig(prepareSigs)
+	block.SetPrepareSig(prepareSig.Serialize(), mask.Bitmap)
+	prepareMultiSigAndBitmap := append(prepareSig.Serialize(), mask.Bitmap...)
+	commitSigs := []*bls.Sign{}
+	for _, signer := range signers {
+		commitSigs = append(commitSigs, signer.SignHash(prepareMultiSigAndBitmap))
+	}
+	block.SetCommitSig(bls_cosi.AggregateSig(commitSigs).Serialize(), mask.Bitmap)
+
+	keys := [][]byte{}
+	for _, publicKey := range publicKeys {
+		keys = append(keys, publicKey.Serialize())
+	}
+	return bcconn.SerializeCrosslink(&bcconn.Crosslink{Header: block.Header(), PublicKeys: keys, ShardState: shardState})
+}
+
+func TestAcceptCrosslink(t *testing.T) {
+	SetSaveFile(filepath.Join(os.TempDir(), "beaconchain_crosslink_test.json"))
+	os.Remove(CrosslinkFile(SaveFile))
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "7523")
+	bc := New(1, "127.0.0.1", "7523", priKey)
+
+	priKeys := []*bls.SecretKey{}
+	publicKeys := []*bls.PublicKey{}
+	for i := 0; i < 3; i++ {
+		blsPriKey, blsPubKey := utils.GenKey("127.0.0.1", strconv.Itoa(9000+i))
+		priKeys = append(priKeys, blsPriKey)
+		publicKeys = append(publicKeys, blsPubKey)
+		bc.AcceptNodeInfo(bcconn.SerializeNodeInfo(&node.Info{IP: "127.0.0.1", Port: strconv.Itoa(9000 + i), PubKey: blsPubKey.Serialize()}))
+	}
+	assert.Len(t, bc.BCInfo.ShardCommittees[0], 3, "registered nodes should make the committee of the shard")
+
+	assert.Equal(t, ErrUnknownShard, (&BeaconChain{}).AcceptCrosslink(signedCrosslink(1, publicKeys, priKeys)))
+
+	assert.Nil(t, bc.AcceptCrosslink(signedCrosslink(1, publicKeys, priKeys)))
+	crosslink := bc.GetCrosslink(0, 1)
+	if assert.NotNil(t, crosslink) {
+		header, err := crosslink.GetHeader()
+		assert.Nil(t, err)
+		assert.Equal(t, crosslink.BlockHash, header.Hash())
+	}
+
+	// The bitmaps cover 2 of the 3 members only, which is not more than 2/3 of the committee
+	assert.NotNil(t, bc.AcceptCrosslink(signedCrosslink(2, publicKeys[:2], priKeys[:2])))
+	assert.Nil(t, bc.GetCrosslink(0, 2))
+
+	// A key not registered to the shard
+	otherPriKey, otherPubKey := utils.GenKey("127.0.0.1", "9100")
+	assert.Equal(t, ErrNotCommitteeMember, bc.AcceptCrosslink(signedCrosslink(3, append(publicKeys[:2:2], otherPubKey), append(priKeys[:2:2], otherPriKey))))
+
+	assert.Nil(t, bc.AcceptCrosslink(signedCrosslink(4, publicKeys, priKeys)))
+	assert.Equal(t, uint64(4), bc.GetLatestCrosslink(0).BlockNum)
+	assert.Equal(t, uint64(4), bc.fetchCrosslink(0, 0).BlockNumber)
+	assert.Equal(t, uint64(1), bc.fetchCrosslink(0, 1).BlockNumber)
+	assert.Nil(t, bc.fetchCrosslink(1, 0))
+}
+
+func TestCrosslinkCommitteeOfEpoch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beaconchain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetSaveFile(filepath.Join(dir, "beaconchain.json"))
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "7524")
+	bc := New(1, "127.0.0.1", "7524", priKey)
+
+	// The nodes registered to the shard sign the genesis epoch, the shard state of its last
+	// block records the committee of the next epoch, which signs the epoch block
+	priKeys := []*bls.SecretKey{}
+	publicKeys := []*bls.PublicKey{}
+	newPriKeys := []*bls.SecretKey{}
+	newPublicKeys := []*bls.PublicKey{}
+	shardState := types.ShardState{{ShardID: 0}}
+	for i := 0; i < 3; i++ {
+		blsPriKey, blsPubKey := utils.GenKey("127.0.0.1", strconv.Itoa(9000+i))
+		priKeys = append(priKeys, blsPriKey)
+		publicKeys = append(publicKeys, blsPubKey)
+		bc.AcceptNodeInfo(bcconn.SerializeNodeInfo(&node.Info{IP: "127.0.0.1", Port: strconv.Itoa(9000 + i), PubKey: blsPubKey.Serialize()}))
+
+		newPriKey, newPubKey := utils.GenKey("127.0.0.1", strconv.Itoa(9200+i))
+		newPriKeys = append(newPriKeys, newPriKey)
+		newPublicKeys = append(newPublicKeys, newPubKey)
+		shardState[0].NodeList = append(shardState[0].NodeList, types.NodeID{BlsPublicKey: newPubKey.SerializeToHexStr()})
+	}
+	epochBlock := int64(bc.chainParams().EpochBlockNumber(1))
+	lastBlock := epochBlock - 1
+
+	// The shard state sent along must be the one of the header
+	forged, _ := bcconn.DeserializeCrosslink(signedEpochCrosslink(lastBlock, shardState, publicKeys, priKeys))
+	forged.ShardState = types.ShardState{{ShardID: 0, NodeList: shardState[0].NodeList[:1]}}
+	assert.Equal(t, ErrShardStateMismatch, bc.AcceptCrosslink(bcconn.SerializeCrosslink(forged)))
+
+	assert.Nil(t, bc.AcceptCrosslink(signedEpochCrosslink(lastBlock, shardState, publicKeys, priKeys)))
+	assert.Equal(t, ErrNotCommitteeMember, bc.AcceptCrosslink(signedCrosslink(epochBlock, publicKeys, priKeys)))
+	assert.Nil(t, bc.AcceptCrosslink(signedCrosslink(epochBlock, newPublicKeys, newPriKeys)))
+
+	// No last block of the epoch recorded the committee of the next epoch
+	nextEpochBlock := int64(bc.chainParams().EpochBlockNumber(2))
+	assert.Equal(t, ErrUnknownShard, bc.AcceptCrosslink(signedCrosslink(nextEpochBlock, newPublicKeys, newPriKeys)))
+
+	// The crosslinks and committees are loaded back from the crosslink file
+	assert.Nil(t, SaveBeaconChainInfo(SaveFile, bc))
+	loaded, err := LoadBeaconChainInfo(SaveFile)
+	if assert.Nil(t, err) {
+		assert.NotNil(t, loaded.GetCrosslink(0, uint64(lastBlock)))
+		assert.Equal(t, uint64(epochBlock), loaded.GetLatestCrosslink(0).BlockNum)
+		assert.Nil(t, loaded.AcceptCrosslink(signedCrosslink(epochBlock+1, newPublicKeys, newPriKeys)))
+	}
+}
+
+func TestCrosslinkCommitteeBackfill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beaconchain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetSaveFile(filepath.Join(dir, "beaconchain.json"))
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "7525")
+	bc := New(1, "127.0.0.1", "7525", priKey)
+
+	// The registered nodes sign the genesis epoch, then each epoch has a new committee
+	committee := func(port int) ([]*bls.SecretKey, []*bls.PublicKey, types.ShardState) {
+		priKeys := []*bls.SecretKey{}
+		publicKeys := []*bls.PublicKey{}
+		shardState := types.ShardState{{ShardID: 0}}
+		for i := 0; i < 3; i++ {
+			blsPriKey, blsPubKey := utils.GenKey("127.0.0.1", strconv.Itoa(port+i))
+			priKeys = append(priKeys, blsPriKey)
+			publicKeys = append(publicKeys, blsPubKey)
+			shardState[0].NodeList = append(shardState[0].NodeList, types.NodeID{BlsPublicKey: blsPubKey.SerializeToHexStr()})
+		}
+		return priKeys, publicKeys, shardState
+	}
+	priKeys0, publicKeys0, _ := committee(9000)
+	for i, publicKey := range publicKeys0 {
+		bc.AcceptNodeInfo(bcconn.SerializeNodeInfo(&node.Info{IP: "127.0.0.1", Port: strconv.Itoa(9000 + i), PubKey: publicKey.Serialize()}))
+	}
+	priKeys1, publicKeys1, shardState1 := committee(9300)
+	priKeys2, publicKeys2, shardState2 := committee(9400)
+	priKeys3, publicKeys3, shardState3 := committee(9500)
+	lastBlock := func(epoch uint64) int64 { return int64(bc.chainParams().EpochBlockNumber(epoch+1)) - 1 }
+
+	// The crosslinks recording the committees of epochs 1 to 3 are received backwards
+	assert.Equal(t, ErrUnknownShard, bc.AcceptCrosslink(signedEpochCrosslink(lastBlock(2), shardState3, publicKeys2, priKeys2)))
+	assert.Equal(t, ErrUnknownShard, bc.AcceptCrosslink(signedEpochCrosslink(lastBlock(1), shardState2, publicKeys1, priKeys1)))
+	assert.Nil(t, bc.GetCrosslink(0, uint64(lastBlock(1))))
+	assert.Nil(t, bc.AcceptCrosslink(signedEpochCrosslink(lastBlock(0), shardState1, publicKeys0, priKeys0)))
+
+	// The kept crosslinks are accepted as their committees get known
+	assert.NotNil(t, bc.GetCrosslink(0, uint64(lastBlock(1))))
+	assert.NotNil(t, bc.GetCrosslink(0, uint64(lastBlock(2))))
+	assert.Empty(t, bc.orphans[0])
+	assert.Nil(t, bc.AcceptCrosslink(signedCrosslink(lastBlock(2)+1, publicKeys3, priKeys3)))
+}
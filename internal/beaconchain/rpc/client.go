@@ -49,3 +49,12 @@ func (client *Client) GetLeaders() *proto.FetchLeadersResponse {
 	}
 	return response
 }
+
+// GetCrosslink gets the crosslink of a shard at a block number from beacon chain, or the
+// latest one of the shard if the block number is 0.
+func (client *Client) GetCrosslink(shardID uint32, blockNum uint64) (*proto.FetchCrosslinkResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &proto.FetchCrosslinkRequest{ShardId: shardID, BlockNumber: blockNum}
+	return client.beaconChainServiceClient.FetchCrosslink(ctx, request)
+}
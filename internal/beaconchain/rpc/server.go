@@ -2,6 +2,7 @@ package beaconchain
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 
@@ -15,6 +16,7 @@ import (
 // Server is the Server struct for beacon chain package.
 type Server struct {
 	shardLeaderMap func() map[int]*node.Info
+	getCrosslink   func(shardID uint32, blockNum uint64) *proto.FetchCrosslinkResponse
 }
 
 // FetchLeaders implements the FetchLeaders interface to return current leaders.
@@ -29,6 +31,16 @@ func (s *Server) FetchLeaders(ctx context.Context, request *proto.FetchLeadersRe
 	return &proto.FetchLeadersResponse{Leaders: leaders}, nil
 }
 
+// FetchCrosslink implements the FetchCrosslink interface to return the crosslink of a shard
+// at a block number, or the latest one of the shard if the block number is 0.
+func (s *Server) FetchCrosslink(ctx context.Context, request *proto.FetchCrosslinkRequest) (*proto.FetchCrosslinkResponse, error) {
+	response := s.getCrosslink(request.ShardId, request.BlockNumber)
+	if response == nil {
+		return nil, fmt.Errorf("no crosslink of shard %d at block %d", request.ShardId, request.BlockNumber)
+	}
+	return response, nil
+}
+
 // Start starts the Server on given ip and port.
 func (s *Server) Start(ip, port string) (*grpc.Server, error) {
 	// TODO(minhdoan): Currently not using ip. Fix it later.
@@ -45,7 +57,7 @@ func (s *Server) Start(ip, port string) (*grpc.Server, error) {
 }
 
 // NewServer creates new Server which implements BeaconChainServiceServer interface.
-func NewServer(shardLeaderMap func() map[int]*node.Info) *Server {
-	s := &Server{shardLeaderMap}
+func NewServer(shardLeaderMap func() map[int]*node.Info, getCrosslink func(shardID uint32, blockNum uint64) *proto.FetchCrosslinkResponse) *Server {
+	s := &Server{shardLeaderMap, getCrosslink}
 	return s
 }
@@ -0,0 +1,294 @@
+// Package chainparams defines the protocol parameters of a Harmony chain. They are configured
+// in the Harmony section of the genesis chain config, persisted with the genesis block, and
+// can be changed from scheduled upgrade blocks on.
+package chainparams
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Params are the protocol parameters in effect at a block.
+type Params struct {
+	// The number of blocks in one epoch
+	BlocksPerEpoch uint64 `json:"blocksPerEpoch,omitempty"`
+	// The max number of transactions in a block
+	MaxTxsPerBlock int `json:"maxTxsPerBlock,omitempty"`
+	// The signers of a block must be more than QuorumNumerator/QuorumDenominator of the committee
+	QuorumNumerator   uint64 `json:"quorumNumerator,omitempty"`
+	QuorumDenominator uint64 `json:"quorumDenominator,omitempty"`
+	// The expected time between two blocks, in milliseconds
+	BlockTimeMs uint64 `json:"blockTimeMs,omitempty"`
+	// The number of sequential squarings of the VDF a node runs per second
+	VdfSquaringsPerSecond uint64 `json:"vdfSquaringsPerSecond,omitempty"`
+}
+
+// Upgrade changes the parameters from its block on, which must be the first block of an
+// epoch. The parameters left zero keep their values.
+type Upgrade struct {
+	Block uint64 `json:"block"`
+	Params
+}
+
+// Config is the Harmony section of the chain config.
+type Config struct {
+	// The parameters from the genesis block on
+	Params
+	// The initial fund of the faucet in the genesis block, in ether
+	TotalInitFund uint64 `json:"totalInitFund"`
+	// The hex serialized BLS public keys of the committees of epoch 0 by shard id, which
+	// the genesis block records as its shard state
+	GenesisCommittees map[uint32][]string `json:"genesisCommittees,omitempty"`
+	// The scheduled changes of the parameters, in the order of their blocks
+	Upgrades []Upgrade `json:"upgrades,omitempty"`
+}
+
+// RandomnessCommitBlocks is the length in blocks of the commit window of drand: the
+// members commit their vrfs on the last block of an epoch, and pRnd is only fixed once
+// the next epoch block is committed.
+const RandomnessCommitBlocks = 2
+
+// DefaultConfig is the config of the chains whose genesis doesn't specify one.
+var DefaultConfig = &Config{
+	Params: Params{
+		BlocksPerEpoch:        5,
+		MaxTxsPerBlock:        8000,
+		QuorumNumerator:       2,
+		QuorumDenominator:     3,
+		BlockTimeMs:           10000,
+		VdfSquaringsPerSecond: 200000,
+	},
+	TotalInitFund: 9000000,
+}
+
+// ParseConfig parses a config in json, in which the parameters left out keep their
+// default values, e.g.
+//
+//	{"blocksPerEpoch": 100, "upgrades": [{"block": 1000, "maxTxsPerBlock": 10000}]}
+func ParseConfig(data []byte) (*Config, error) {
+	config := *DefaultConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadConfig loads a config from a json file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// Validate checks the parameters are usable and every upgrade is scheduled at the first
+// block of an epoch, after the previous upgrade.
+func (c *Config) Validate() error {
+	if c.BlocksPerEpoch == 0 {
+		return errors.New("the number of blocks per epoch must be positive")
+	}
+	if c.BlockTimeMs == 0 || c.VdfSquaringsPerSecond == 0 {
+		return errors.New("the block time and the speed of the VDF must be positive")
+	}
+	if err := c.Params.validate(); err != nil {
+		return err
+	}
+	for shardID, publicKeys := range c.GenesisCommittees {
+		if len(publicKeys) == 0 {
+			return fmt.Errorf("the genesis committee of shard %d has no member", shardID)
+		}
+	}
+	for i, upgrade := range c.Upgrades {
+		if err := upgrade.Params.validate(); err != nil {
+			return fmt.Errorf("upgrade at block %d: %v", upgrade.Block, err)
+		}
+		if i > 0 && upgrade.Block <= c.Upgrades[i-1].Block {
+			return fmt.Errorf("upgrade at block %d is not after the previous one", upgrade.Block)
+		}
+		scheduled := &Config{Params: c.Params, Upgrades: c.Upgrades[:i]}
+		if upgrade.Block == 0 || !scheduled.IsEpochBlock(upgrade.Block) {
+			return fmt.Errorf("upgrade at block %d is not at the first block of an epoch", upgrade.Block)
+		}
+	}
+	return nil
+}
+
+// GenesisHash returns the hash of the parameters the chain starts with, which the genesis
+// block commits to. The upgrades are left out, so that the ones scheduled later can be added
+// to the config of a running chain; CheckCompatible checks them on restart instead.
+func (c *Config) GenesisHash() common.Hash {
+	data, _ := json.Marshal(&Config{Params: c.Params, TotalInitFund: c.TotalInitFund})
+	return crypto.Keccak256Hash(data)
+}
+
+// CheckCompatible checks the new config of a chain whose head is at the given block only
+// appends upgrades to the stored config, after the head, so that the blocks already in the
+// chain keep their parameters.
+func (c *Config) CheckCompatible(newConfig *Config, head uint64) error {
+	if newConfig.Params != c.Params || newConfig.TotalInitFund != c.TotalInitFund {
+		return errors.New("the parameters of the genesis differ from the stored ones")
+	}
+	if len(newConfig.Upgrades) < len(c.Upgrades) {
+		return errors.New("the stored upgrades are missing")
+	}
+	for i, upgrade := range c.Upgrades {
+		if newConfig.Upgrades[i] != upgrade {
+			return fmt.Errorf("upgrade at block %d differs from the stored one", upgrade.Block)
+		}
+	}
+	for _, upgrade := range newConfig.Upgrades[len(c.Upgrades):] {
+		if upgrade.Block <= head {
+			return fmt.Errorf("upgrade at block %d is added after the chain passed it at block %d", upgrade.Block, head)
+		}
+	}
+	return nil
+}
+
+// validate checks the parameters which are set are usable.
+func (p Params) validate() error {
+	if p.MaxTxsPerBlock < 0 {
+		return errors.New("the max number of transactions per block must not be negative")
+	}
+	if (p.QuorumNumerator == 0) != (p.QuorumDenominator == 0) {
+		return errors.New("the quorum needs both its numerator and denominator")
+	}
+	if p.QuorumNumerator >= p.QuorumDenominator && p.QuorumDenominator != 0 {
+		return errors.New("the quorum must be less than the whole committee")
+	}
+	if p.BlocksPerEpoch != 0 && vdfDelayBlocks(p.BlocksPerEpoch) <= RandomnessCommitBlocks {
+		return fmt.Errorf("an epoch must have more than %d blocks for the VDF to outlast the commit window of drand", RandomnessCommitBlocks+2)
+	}
+	return nil
+}
+
+// vdfDelayBlocks returns the number of blocks the VDF of an epoch of the given length runs
+// for: from the epoch block committing pRnd to the last block of the epoch, which commits
+// the output of the VDF. The leader proposes the last block once the block before it is
+// committed, so the VDF has the time of BlocksPerEpoch-2 blocks.
+func vdfDelayBlocks(blocksPerEpoch uint64) uint64 {
+	if blocksPerEpoch < 2 {
+		return 0
+	}
+	return blocksPerEpoch - 2
+}
+
+// update returns the parameters changed by the non-zero ones of the given changes.
+func (p Params) update(changes Params) Params {
+	if changes.BlocksPerEpoch != 0 {
+		p.BlocksPerEpoch = changes.BlocksPerEpoch
+	}
+	if changes.MaxTxsPerBlock != 0 {
+		p.MaxTxsPerBlock = changes.MaxTxsPerBlock
+	}
+	if changes.QuorumDenominator != 0 {
+		p.QuorumNumerator = changes.QuorumNumerator
+		p.QuorumDenominator = changes.QuorumDenominator
+	}
+	if changes.BlockTimeMs != 0 {
+		p.BlockTimeMs = changes.BlockTimeMs
+	}
+	if changes.VdfSquaringsPerSecond != 0 {
+		p.VdfSquaringsPerSecond = changes.VdfSquaringsPerSecond
+	}
+	return p
+}
+
+// At returns the parameters in effect at the block of the given number.
+func (c *Config) At(number uint64) Params {
+	params := c.Params
+	for _, upgrade := range c.Upgrades {
+		if upgrade.Block > number {
+			break
+		}
+		params = params.update(upgrade.Params)
+	}
+	return params
+}
+
+// era is a range of epochs of the same length, starting at the given block and epoch.
+type era struct {
+	block          uint64
+	epoch          uint64
+	blocksPerEpoch uint64
+}
+
+// eras returns the eras of the chain, in the order of their blocks.
+func (c *Config) eras() []era {
+	eras := []era{{block: 0, epoch: 0, blocksPerEpoch: c.BlocksPerEpoch}}
+	for _, upgrade := range c.Upgrades {
+		last := eras[len(eras)-1]
+		if upgrade.BlocksPerEpoch == 0 || upgrade.BlocksPerEpoch == last.blocksPerEpoch {
+			continue
+		}
+		eras = append(eras, era{
+			block:          upgrade.Block,
+			epoch:          last.epoch + (upgrade.Block-last.block)/last.blocksPerEpoch,
+			blocksPerEpoch: upgrade.BlocksPerEpoch,
+		})
+	}
+	return eras
+}
+
+// EpochOf returns the epoch the block of the given number belongs to.
+func (c *Config) EpochOf(number uint64) uint64 {
+	eras := c.eras()
+	i := len(eras) - 1
+	for eras[i].block > number {
+		i--
+	}
+	return eras[i].epoch + (number-eras[i].block)/eras[i].blocksPerEpoch
+}
+
+// EpochBlockNumber returns the number of the first block of the epoch, where the shard
+// state of the epoch is stored.
+func (c *Config) EpochBlockNumber(epoch uint64) uint64 {
+	eras := c.eras()
+	i := len(eras) - 1
+	for eras[i].epoch > epoch {
+		i--
+	}
+	return eras[i].block + (epoch-eras[i].epoch)*eras[i].blocksPerEpoch
+}
+
+// IsEpochBlock returns whether the block of the given number is the first block of an epoch.
+func (c *Config) IsEpochBlock(number uint64) bool {
+	return c.EpochBlockNumber(c.EpochOf(number)) == number
+}
+
+// IsEpochLastBlock returns whether the block of the given number is the last block of an epoch.
+func (c *Config) IsEpochLastBlock(number uint64) bool {
+	return c.IsEpochBlock(number + 1)
+}
+
+// ShardStateBlockNumber returns the number of the block recording the shard state of the
+// epoch: the last block of the previous epoch, signed by the committee handing off to the
+// one of the epoch, or the genesis block for epoch 0.
+func (c *Config) ShardStateBlockNumber(epoch uint64) uint64 {
+	if epoch == 0 {
+		return 0
+	}
+	return c.EpochBlockNumber(epoch) - 1
+}
+
+// VdfDifficulty returns the number of sequential squarings of the VDF over the pRnd of the
+// epoch, so that the VDF runs until the last block of the epoch on the nodes.
+//
+// The delay of the VDF must exceed the commit window of drand: otherwise the last member
+// to commit its vrf could evaluate the final randomness before pRnd is fixed, and bias it
+// by withholding its vrf. Validate requires the VDF to run for more blocks than the commit
+// window, so an adversary evaluating the VDF up to vdfDelayBlocks/RandomnessCommitBlocks
+// times faster than the nodes still can't bias the randomness.
+func (c *Config) VdfDifficulty(epoch uint64) uint64 {
+	params := c.At(c.EpochBlockNumber(epoch))
+	delayMs := vdfDelayBlocks(params.BlocksPerEpoch) * params.BlockTimeMs
+	return delayMs * params.VdfSquaringsPerSecond / 1000
+}
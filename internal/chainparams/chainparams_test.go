@@ -0,0 +1,126 @@
+package chainparams
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testConfig has epochs of 5 blocks up to block 20, then of 10 blocks.
+var testConfig = &Config{
+	Params: DefaultConfig.Params,
+	Upgrades: []Upgrade{
+		{Block: 10, Params: Params{MaxTxsPerBlock: 100}},
+		{Block: 20, Params: Params{BlocksPerEpoch: 10, QuorumNumerator: 3, QuorumDenominator: 4}},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, DefaultConfig.Validate())
+	assert.Nil(t, testConfig.Validate())
+
+	for _, config := range []*Config{
+		{},
+		{Params: Params{BlocksPerEpoch: 5, QuorumNumerator: 2}},
+		{Params: Params{BlocksPerEpoch: 5, QuorumNumerator: 3, QuorumDenominator: 3}},
+		{Params: Params{BlocksPerEpoch: 5, MaxTxsPerBlock: -1}},
+		{Params: Params{BlocksPerEpoch: 5}, Upgrades: []Upgrade{{Block: 12}}},
+		{Params: Params{BlocksPerEpoch: 5}, Upgrades: []Upgrade{{Block: 0}}},
+		{Params: Params{BlocksPerEpoch: 5}, Upgrades: []Upgrade{{Block: 10}, {Block: 10}}},
+		{Params: Params{BlocksPerEpoch: 5}, Upgrades: []Upgrade{{Block: 10, Params: Params{BlocksPerEpoch: 10}}, {Block: 15}}},
+		{Params: Params{BlocksPerEpoch: 5}},
+		{Params: Params{BlocksPerEpoch: 4, BlockTimeMs: 1000, VdfSquaringsPerSecond: 1000}},
+		{Params: Params{BlocksPerEpoch: 5, BlockTimeMs: 1000, VdfSquaringsPerSecond: 1000}, Upgrades: []Upgrade{{Block: 10, Params: Params{BlocksPerEpoch: 3}}}},
+	} {
+		assert.NotNil(t, config.Validate(), "config %+v should be invalid", config)
+	}
+}
+
+func TestAt(t *testing.T) {
+	assert.Equal(t, DefaultConfig.Params, testConfig.At(9))
+
+	params := testConfig.At(10)
+	assert.Equal(t, 100, params.MaxTxsPerBlock)
+	assert.Equal(t, uint64(5), params.BlocksPerEpoch)
+
+	params = testConfig.At(25)
+	assert.Equal(t, Params{BlocksPerEpoch: 10, MaxTxsPerBlock: 100, QuorumNumerator: 3, QuorumDenominator: 4, BlockTimeMs: 10000, VdfSquaringsPerSecond: 200000}, params)
+}
+
+func TestEpochs(t *testing.T) {
+	for number, epoch := range map[uint64]uint64{0: 0, 4: 0, 5: 1, 19: 3, 20: 4, 29: 4, 30: 5, 45: 6} {
+		if got := testConfig.EpochOf(number); got != epoch {
+			t.Errorf("block %d: expected epoch %d, got %d", number, epoch, got)
+		}
+	}
+	for epoch, number := range map[uint64]uint64{0: 0, 1: 5, 3: 15, 4: 20, 5: 30, 6: 40} {
+		if got := testConfig.EpochBlockNumber(epoch); got != number {
+			t.Errorf("epoch %d: expected block %d, got %d", epoch, number, got)
+		}
+	}
+	assert.True(t, testConfig.IsEpochBlock(20))
+	assert.False(t, testConfig.IsEpochBlock(25))
+	assert.True(t, testConfig.IsEpochLastBlock(19))
+	assert.True(t, testConfig.IsEpochLastBlock(29))
+	assert.False(t, testConfig.IsEpochLastBlock(24))
+}
+
+func TestShardStateBlockNumber(t *testing.T) {
+	for epoch, number := range map[uint64]uint64{0: 0, 1: 4, 2: 9} {
+		assert.Equal(t, number, DefaultConfig.ShardStateBlockNumber(epoch), "block recording the shard state of epoch %d", epoch)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"blocksPerEpoch": 100, "upgrades": [{"block": 1000, "maxTxsPerBlock": 10000}]}`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, uint64(100), config.BlocksPerEpoch)
+		assert.Equal(t, DefaultConfig.MaxTxsPerBlock, config.MaxTxsPerBlock)
+		assert.Equal(t, DefaultConfig.TotalInitFund, config.TotalInitFund)
+		assert.Equal(t, 10000, config.At(1000).MaxTxsPerBlock)
+	}
+
+	_, err = ParseConfig([]byte(`{"upgrades": [{"block": 7}]}`))
+	assert.NotNil(t, err, "an upgrade must be at an epoch block")
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(testConfig)
+	if !assert.Nil(t, err) {
+		return
+	}
+	config := &Config{}
+	assert.Nil(t, json.Unmarshal(data, config))
+	assert.Equal(t, testConfig, config)
+}
+
+func TestVdfDifficulty(t *testing.T) {
+	// The VDF runs for 3 blocks of 10 seconds in the epochs of 5 blocks, and for 8 blocks in
+	// the epochs of 10 blocks: longer than the commit window of drand.
+	assert.Equal(t, uint64(3*10*200000), testConfig.VdfDifficulty(1))
+	assert.Equal(t, uint64(8*10*200000), testConfig.VdfDifficulty(4))
+	assert.True(t, vdfDelayBlocks(DefaultConfig.BlocksPerEpoch) > RandomnessCommitBlocks)
+}
+
+func TestGenesisHash(t *testing.T) {
+	// The upgrades added later keep the hash the genesis commits to
+	config := *DefaultConfig
+	config.Upgrades = testConfig.Upgrades
+	assert.Equal(t, DefaultConfig.GenesisHash(), config.GenesisHash())
+	config.BlocksPerEpoch = 10
+	assert.NotEqual(t, DefaultConfig.GenesisHash(), config.GenesisHash())
+}
+
+func TestCheckCompatible(t *testing.T) {
+	stored := &Config{Params: testConfig.Params, Upgrades: testConfig.Upgrades[:1]}
+	assert.Nil(t, stored.CheckCompatible(stored, 15))
+	assert.Nil(t, stored.CheckCompatible(testConfig, 15), "an upgrade after the head can be added")
+	assert.NotNil(t, stored.CheckCompatible(testConfig, 20), "an upgrade the chain passed can't be added")
+	assert.NotNil(t, testConfig.CheckCompatible(stored, 15), "a stored upgrade can't be dropped")
+	assert.NotNil(t, stored.CheckCompatible(&Config{Params: testConfig.Params, Upgrades: testConfig.Upgrades[1:]}, 5))
+
+	config := *stored
+	config.MaxTxsPerBlock = 10
+	assert.NotNil(t, stored.CheckCompatible(&config, 0))
+}
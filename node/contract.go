@@ -27,7 +27,7 @@ const (
 func (node *Node) AddStakingContractToPendingTransactions() {
 	// Add a contract deployment transaction
 	//Generate contract key and associate funds with the smart contract
-	priKey, _ := ecdsa.GenerateKey(crypto.S256(), strings.NewReader("Deposit Smart Contract Key"))
+	priKey := stakingContractKey()
 	contractAddress := crypto.PubkeyToAddress(priKey.PublicKey)
 	//Initially the smart contract should have minimal funds.
 	contractFunds := big.NewInt(0)
@@ -40,6 +40,13 @@ func (node *Node) AddStakingContractToPendingTransactions() {
 	node.addPendingTransactions(types.Transactions{mycontracttx})
 }
 
+// stakingContractKey returns the fixed key deploying the staking contract, so that every
+// node knows the address of the contract.
+func stakingContractKey() *ecdsa.PrivateKey {
+	priKey, _ := ecdsa.GenerateKey(crypto.S256(), strings.NewReader("Deposit Smart Contract Key"))
+	return priKey
+}
+
 //CreateStakingWithdrawTransaction creates a new withdraw stake transaction
 func (node *Node) CreateStakingWithdrawTransaction(stake string) (*types.Transaction, error) {
 	//These should be read from somewhere.
@@ -20,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/api/client"
 	clientService "github.com/harmony-one/harmony/api/client/service"
 	proto_discovery "github.com/harmony-one/harmony/api/proto/discovery"
@@ -37,11 +38,13 @@ import (
 	"github.com/harmony-one/harmony/api/service/syncing"
 	"github.com/harmony-one/harmony/api/service/syncing/downloader"
 	downloader_pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+	"github.com/harmony-one/harmony/api/service/tracing"
 	bft "github.com/harmony-one/harmony/consensus"
 	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/core/vm"
 	"github.com/harmony-one/harmony/crypto/pki"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/node/worker"
 	"github.com/harmony-one/harmony/p2p"
@@ -100,9 +103,6 @@ const (
 	inSyncThreshold   = 1
 )
 
-// TotalInitFund is the initial total fund to the faucet.
-const TotalInitFund = 9000000
-
 const (
 	waitBeforeJoinShard = time.Second * 3
 	timeOutToJoinShard  = time.Minute * 10
@@ -137,7 +137,11 @@ type Node struct {
 	pendingTransactions    types.Transactions   // All the transactions received but not yet processed for Consensus
 	transactionInConsensus []*types.Transaction // The transactions selected into the new block and under Consensus process
 	pendingTxMutex         sync.Mutex
-	DRand                  *drand.DRand // The instance for distributed randomness protocol
+	pendingTxSignal        chan struct{} // Notified when new transactions are added to the pending list
+	DRand                  *drand.DRand  // The instance for distributed randomness protocol
+
+	incomingReceipts      map[common.Hash]*incomingReceipt // The proven receipts from other shards not credited yet, by their hashes
+	incomingReceiptsMutex sync.Mutex
 
 	blockchain *core.BlockChain   // The blockchain for the shard where this node belongs
 	db         *ethdb.LDBDatabase // LevelDB to store blockchain.
@@ -179,7 +183,15 @@ type Node struct {
 	serviceManager *service_manager.Manager
 
 	//Staked Accounts and Contract
-	CurrentStakes          map[common.Address]int64 //This will save the latest information about staked nodes.
+	CurrentStakes          map[common.Address]int64    //This will save the latest information about staked nodes.
+	stakeMutex             sync.RWMutex                // mutex for CurrentStakes, which is read by the stake-weighted quorum policy
+	stakingBlsKeys         map[common.Address]string   // hex strings of the BLS public keys registered by the deposits of staked nodes
+	blsKeyStakers          map[string]common.Address   // the staked nodes by the hex strings of the BLS public keys they registered
+	epochStakes            map[uint64]map[string]int64 // the stakes at the start of each epoch by BLS public key, weighing the votes of its committee
+	newStakers             []common.Address            // the nodes which staked for the first time during stakingEpoch, in order
+	stakingEpoch           uint64                      // the epoch of the blocks newStakers were collected from, the last block of an epoch counting in the next one
+	epochNewNodes          map[uint64][]types.NodeID   // the nodes joining the committees in the resharding of each epoch
+	nextStakingBlock       uint64                      // the number of the next block of the chain to apply to the stakes
 	StakingContractAddress common.Address
 	WithdrawStakeFunc      []byte
 
@@ -187,14 +199,27 @@ type Node struct {
 	AccountKey *ecdsa.PrivateKey
 	Address    common.Address
 
+	// The allocation of the genesis block, shared by the chains of all the shards
+	genesisAlloc core.GenesisAlloc
+	// The protocol parameters in the genesis chain config, shared by the chains of all the shards
+	chainParams *chainparams.Config
+	// The database of the chain of the node's shard
+	chainDB ethdb.Database
+	// Opens the database of the chain of the given shard when the node moves to the shard,
+	// an in-memory one is used if nil
+	OpenShardDatabase func(shardID uint32) (ethdb.Database, error)
+
 	// For test only
 	TestBankKeys      []*ecdsa.PrivateKey
 	ContractKeys      []*ecdsa.PrivateKey
 	ContractAddresses []common.Address
 
-	// Group Message Receiver
+	// Group Message Receiver of the shard
 	groupReceiver p2p.GroupReceiver
 
+	// Group Message Receiver of the global group of all the shards
+	globalGroupReceiver p2p.GroupReceiver
+
 	// Duplicated Ping Message Received
 	duplicatedPing map[string]bool
 }
@@ -209,6 +234,10 @@ func (node *Node) addPendingTransactions(newTxs types.Transactions) {
 	node.pendingTxMutex.Lock()
 	node.pendingTransactions = append(node.pendingTransactions, newTxs...)
 	node.pendingTxMutex.Unlock()
+	select {
+	case node.pendingTxSignal <- struct{}{}:
+	default:
+	}
 	utils.GetLogInstance().Debug("Got more transactions", "num", len(newTxs), "totalPending", len(node.pendingTransactions))
 }
 
@@ -245,9 +274,16 @@ func (node *Node) countNumTransactionsInBlockchain() int {
 	return count
 }
 
-// New creates a new node.
-func New(host p2p.Host, consensus *bft.Consensus, db ethdb.Database) *Node {
+// New creates a new node. The chain params configure the genesis of the chain, the
+// default ones are used if nil.
+func New(host p2p.Host, consensus *bft.Consensus, db ethdb.Database, chainParams *chainparams.Config) *Node {
 	node := Node{}
+	node.pendingTxSignal = make(chan struct{}, 1)
+	node.incomingReceipts = make(map[common.Hash]*incomingReceipt)
+	node.chainParams = chainParams
+	if node.chainParams == nil {
+		node.chainParams = chainparams.DefaultConfig
+	}
 
 	if host != nil {
 		node.host = host
@@ -262,40 +298,29 @@ func New(host p2p.Host, consensus *bft.Consensus, db ethdb.Database) *Node {
 		genesisAlloc := node.CreateGenesisAllocWithTestingAddresses(100)
 		contractKey, _ := ecdsa.GenerateKey(crypto.S256(), strings.NewReader("Test contract key string stream that is fixed so that generated test key are deterministic every time"))
 		contractAddress := crypto.PubkeyToAddress(contractKey.PublicKey)
-		contractFunds := big.NewInt(TotalInitFund)
+		contractFunds := new(big.Int).SetUint64(node.chainParams.TotalInitFund)
 		contractFunds = contractFunds.Mul(contractFunds, big.NewInt(params.Ether))
 		genesisAlloc[contractAddress] = core.GenesisAccount{Balance: contractFunds}
 		node.ContractKeys = append(node.ContractKeys, contractKey)
 
+		node.genesisAlloc = genesisAlloc
+
 		database := db
 		if database == nil {
 			database = ethdb.NewMemDatabase()
 		}
-
-		chainConfig := params.TestChainConfig
-		chainConfig.ChainID = big.NewInt(int64(node.Consensus.ShardID)) // Use ChainID as piggybacked ShardID
-		gspec := core.Genesis{
-			Config:  chainConfig,
-			Alloc:   genesisAlloc,
-			ShardID: uint32(node.Consensus.ShardID),
-		}
-
-		_ = gspec.MustCommit(database)
-		chain, _ := core.NewBlockChain(database, nil, gspec.Config, node.Consensus, vm.Config{}, nil)
-		node.blockchain = chain
+		node.initBlockchain(database)
 		node.BlockChannel = make(chan *types.Block)
 		node.ConfirmedBlockChannel = make(chan *types.Block)
 
-		node.TxPool = core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, chain)
-		node.Worker = worker.New(params.TestChainConfig, chain, node.Consensus, pki.GetAddressFromPublicKey(node.SelfPeer.PubKey), node.Consensus.ShardID)
 		node.AddFaucetContractToPendingTransactions()
 		if node.Role == BeaconLeader {
 			node.AddStakingContractToPendingTransactions() //This will save the latest information about staked nodes in current staked
 			node.DepositToFakeAccounts()
 		}
-		if node.Role == BeaconLeader || node.Role == BeaconValidator {
-			node.CurrentStakes = make(map[common.Address]int64)
-		}
+		// Every node derives the stakes from the staking transactions of its chain.
+		node.CurrentStakes = make(map[common.Address]int64)
+		node.StakingContractAddress = node.generateDeployedStakingContractAddress(nil, crypto.PubkeyToAddress(stakingContractKey().PublicKey))
 		node.Consensus.ConsensusBlock = make(chan *bft.BFTBlockInfo)
 		node.Consensus.VerifiedNewBlock = make(chan *types.Block)
 	}
@@ -313,18 +338,59 @@ func New(host p2p.Host, consensus *bft.Consensus, db ethdb.Database) *Node {
 	node.OfflinePeers = make(chan p2p.Peer)
 	go node.RemovePeersHandler()
 
-	// start the goroutine to receive group message
+	// start the goroutines to receive group message
 	go node.ReceiveGroupMessage()
+	go node.ReceiveGlobalMessage()
 
 	node.duplicatedPing = make(map[string]bool)
 
 	return &node
 }
 
+// initBlockchain sets up the blockchain of the shard of the consensus on the database,
+// from the genesis block or from the chain already in the database, along with the tx
+// pool and the worker building on top of it.
+func (node *Node) initBlockchain(database ethdb.Database) {
+	chainConfig := params.TestChainConfig
+	chainConfig.ChainID = big.NewInt(int64(node.Consensus.ShardID)) // Use ChainID as piggybacked ShardID
+	gspec := core.Genesis{
+		Config:      chainConfig,
+		Alloc:       node.genesisAlloc,
+		ShardID:     uint32(node.Consensus.ShardID),
+		ChainParams: node.chainParams,
+	}
+
+	// A database holding a chain already must hold the chain of the same genesis and params.
+	if _, _, err := core.SetupGenesisBlock(database, &gspec); err != nil {
+		panic(fmt.Sprintf("unable to set up the genesis block: %v", err))
+	}
+	chain, _ := core.NewBlockChain(database, nil, gspec.Config, node.Consensus, vm.Config{}, nil)
+	chain.SetNewNodeListReader(node.NewNodeList)
+	// The nodes which synced the last block of an epoch instead of committing it hand off too.
+	chain.SetEpochLastBlockHandler(node.Consensus.HandOff)
+	node.chainDB = database
+	node.blockchain = chain
+	node.Consensus.ChainReader = chain
+	node.Consensus.SetChainParams(chain.ChainParams())
+
+	node.TxPool = core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, chain)
+	node.Worker = worker.New(params.TestChainConfig, chain, node.Consensus, pki.GetAddressFromPublicKey(node.SelfPeer.PubKey), node.Consensus.ShardID)
+}
+
 func (node *Node) getDeployedStakingContract() common.Address {
 	return node.StakingContractAddress
 }
 
+// getEpochRandomness returns the randomness of the epoch committed in the blockchain.
+func (node *Node) getEpochRandomness(epoch uint64) (*drand.EpochRandomness, error) {
+	return drand.GetEpochRandomness(node.blockchain.ChainParams(), epoch, node.blockchain.GetHeaderByNumber)
+}
+
+// epochOf returns the epoch of the block of the given number in the blockchain.
+func (node *Node) epochOf(number uint64) uint64 {
+	return node.blockchain.ChainParams().EpochOf(number)
+}
+
 //In order to get the deployed contract address of a contract, we need to find the nonce of the address that created it.
 //(Refer: https://solidity.readthedocs.io/en/v0.5.3/introduction-to-smart-contracts.html#index-8)
 // Then we can (re)create the deployed address. Trivially, this is 0 for us.
@@ -642,13 +708,58 @@ func (node *Node) RemovePeersHandler() {
 }
 
 //UpdateStakingList updates the stakes of every node.
+//The nodes staking for the first time are collected per epoch, along with the BLS public key
+//their deposit registers, to join the committees at the next resharding.
 func (node *Node) UpdateStakingList(block *types.Block) error {
+	node.stakeMutex.Lock()
+	defer node.stakeMutex.Unlock()
+
+	node.applyStakingTxs(block)
+	return nil
+}
+
+// syncStakingList applies the staking transactions of the blocks of the chain up to the
+// given number which aren't applied yet. The stakes thus only depend on the chain, whether
+// the node ran consensus on the blocks, synced them or restarted on them.
+func (node *Node) syncStakingList(number uint64) {
+	node.stakeMutex.Lock()
+	defer node.stakeMutex.Unlock()
+
+	for node.nextStakingBlock <= number {
+		block := node.blockchain.GetBlockByNumber(node.nextStakingBlock)
+		if block == nil {
+			return
+		}
+		node.applyStakingTxs(block)
+	}
+}
+
+// applyStakingTxs applies the staking transactions of the block to the stakes, and records
+// the new nodes of the next resharding once the parent of the last block of an epoch is
+// applied, and the stakes of the next epoch once the last block is. The caller must hold stakeMutex.
+func (node *Node) applyStakingTxs(block *types.Block) {
+	if node.stakingBlsKeys == nil {
+		node.stakingBlsKeys = make(map[common.Address]string)
+		node.blsKeyStakers = make(map[string]common.Address)
+		node.epochStakes = make(map[uint64]map[string]int64)
+		node.epochNewNodes = make(map[uint64][]types.NodeID)
+	}
+	config := node.blockchain.ChainParams()
+	epoch := config.EpochOf(block.NumberU64())
+	// The resharding in the last block of an epoch is calculated from the stakers before it,
+	// so the stakers in the last block join at the resharding of the next epoch.
+	if stakingEpoch := config.EpochOf(block.NumberU64() + 1); stakingEpoch != node.stakingEpoch {
+		node.stakingEpoch = stakingEpoch
+		node.newStakers = nil
+	}
+	node.nextStakingBlock = block.NumberU64() + 1
+
 	signerType := types.HomesteadSigner{}
 	txns := block.Transactions()
 	for i := range txns {
 		txn := txns[i]
 		toAddress := txn.To()
-		if *toAddress != node.StakingContractAddress { //Not a address aimed at the staking contract.
+		if toAddress == nil || *toAddress != node.StakingContractAddress { //Not a address aimed at the staking contract.
 			continue
 		}
 		currentSender, _ := types.Sender(signerType, txn)
@@ -658,12 +769,16 @@ func (node *Node) UpdateStakingList(block *types.Block) error {
 		case depositFuncSignature: //deposit, currently: 0xd0e30db0
 			amount := txn.Value()
 			value := amount.Int64()
+			if blsPubKey := decodeDepositBlsKey(data); blsPubKey != "" {
+				node.registerBlsKey(currentSender, blsPubKey)
+			}
 			if isPresent {
 				//This means the node has increased its stake.
 				node.CurrentStakes[currentSender] += value
 			} else {
 				//This means its a new node that is staking the first time.
 				node.CurrentStakes[currentSender] = value
+				node.newStakers = append(node.newStakers, currentSender)
 			}
 		case withdrawFuncSignature: //withdaw, currently: 0x2e1a7d4d
 			value := decodeStakeCall(data)
@@ -682,7 +797,86 @@ func (node *Node) UpdateStakingList(block *types.Block) error {
 			continue //no-op if its not deposit or withdaw
 		}
 	}
-	return nil
+	if config.IsEpochLastBlock(block.NumberU64() + 1) {
+		node.epochNewNodes[epoch+1] = node.collectNewNodes()
+	}
+	if config.IsEpochLastBlock(block.NumberU64()) {
+		node.recordEpochStakes(epoch + 1)
+	}
+}
+
+// NewNodeList returns the nodes joining the committees in the resharding of the given epoch:
+// the nodes which staked for the first time during the previous epoch, registered a BLS
+// public key and are still staked before its last block, with their stakes. The list is
+// derived from the blocks of the previous epoch in the chain up to the parent of its last
+// block, which records the resharding, so all the nodes of the chain agree on it.
+func (node *Node) NewNodeList(epoch uint64) []types.NodeID {
+	if epoch == 0 {
+		return nil
+	}
+	node.syncStakingList(node.blockchain.ChainParams().ShardStateBlockNumber(epoch) - 1)
+
+	node.stakeMutex.RLock()
+	defer node.stakeMutex.RUnlock()
+	return node.epochNewNodes[epoch]
+}
+
+// collectNewNodes returns the nodes which staked for the first time during the current
+// staking epoch, registered a BLS public key and are still staked, with their current
+// stakes. The caller must hold stakeMutex.
+func (node *Node) collectNewNodes() []types.NodeID {
+	newNodeList := []types.NodeID{}
+	for _, staker := range node.newStakers {
+		stake, isPresent := node.CurrentStakes[staker]
+		blsPubKey, hasKey := node.stakingBlsKeys[staker]
+		if !isPresent || stake <= 0 || !hasKey {
+			continue
+		}
+		newNodeList = append(newNodeList, types.NodeID{BlsPublicKey: blsPubKey, Stake: uint64(stake)})
+	}
+	return newNodeList
+}
+
+// registerBlsKey records the BLS public key registered by the deposit of the staker.
+// A key already registered by another staker is ignored, so that no one weighs with
+// the stake of someone else. The caller must hold stakeMutex.
+func (node *Node) registerBlsKey(staker common.Address, blsPubKey string) {
+	if owner, isPresent := node.blsKeyStakers[blsPubKey]; isPresent && owner != staker {
+		return
+	}
+	if oldKey, hasKey := node.stakingBlsKeys[staker]; hasKey {
+		delete(node.blsKeyStakers, oldKey)
+	}
+	node.stakingBlsKeys[staker] = blsPubKey
+	node.blsKeyStakers[blsPubKey] = staker
+}
+
+// recordEpochStakes records the current stakes by BLS public key as the stakes of the
+// given epoch, once the last block of the previous epoch is applied. The caller must hold stakeMutex.
+func (node *Node) recordEpochStakes(epoch uint64) {
+	stakes := make(map[string]int64)
+	for blsPubKey, staker := range node.blsKeyStakers {
+		if stake := node.CurrentStakes[staker]; stake > 0 {
+			stakes[blsPubKey] = stake
+		}
+	}
+	node.epochStakes[epoch] = stakes
+}
+
+// StakesAt returns the lookup of the stakes weighing the votes on the block of the given
+// number, which are the stakes at the start of the epoch of the block, signed by its committee.
+// It is used by the stake-weighted quorum policy of the consensus.
+func (node *Node) StakesAt(number uint64) func(pubKey *bls.PublicKey) int64 {
+	epoch := node.blockchain.ChainParams().EpochOf(number)
+	if number > 0 {
+		// The stakes of the epoch are recorded once the last block of the previous epoch, the parent at the latest, is applied.
+		node.syncStakingList(number - 1)
+	}
+	return func(pubKey *bls.PublicKey) int64 {
+		node.stakeMutex.RLock()
+		defer node.stakeMutex.RUnlock()
+		return node.epochStakes[epoch][pubKey.SerializeToHexStr()]
+	}
 }
 
 //The first four bytes of the call data for a function call specifies the function to be called.
@@ -694,6 +888,19 @@ func decodeStakeCall(getData []byte) int64 {
 	return value.Int64()
 }
 
+//decodeDepositBlsKey gets the BLS public key registered by a deposit, appended to the call data
+//after the function signature. It returns the hex string of the key, or "" if there is no valid key.
+func decodeDepositBlsKey(data []byte) string {
+	if len(data) <= funcSingatureBytes {
+		return ""
+	}
+	pubKey := &bls.PublicKey{}
+	if err := pubKey.Deserialize(data[funcSingatureBytes:]); err != nil {
+		return ""
+	}
+	return pubKey.SerializeToHexStr()
+}
+
 //The first four bytes of the call data for a function call specifies the function to be called.
 //It is the first (left, high-order in big-endian) four bytes of the Keccak-256 (SHA-3)
 //Refer: https://solidity.readthedocs.io/en/develop/abi-spec.html
@@ -703,28 +910,47 @@ func decodeFuncSign(data []byte) string {
 	return funcSign
 }
 
+// setupGroupReceivers subscribes the node to the given group and to the global group.
+func (node *Node) setupGroupReceivers(groupID p2p.GroupID) error {
+	var err error
+	node.groupReceiver, err = node.host.GroupReceiver(groupID)
+	if err != nil {
+		return err
+	}
+	node.globalGroupReceiver, err = node.host.GroupReceiver(p2p.GroupIDGlobal)
+	return err
+}
+
 func (node *Node) setupForShardLeader() {
+	if err := node.setupGroupReceivers(p2p.NewGroupIDByShardID(node.Consensus.ShardID)); err != nil {
+		utils.GetLogInstance().Error("create group receiver error", "msg", err)
+		return
+	}
+
 	// Register explorer service.
-	node.serviceManager.RegisterService(service_manager.SupportExplorer, explorer.New(&node.SelfPeer))
+	node.serviceManager.RegisterService(service_manager.SupportExplorer, explorer.New(&node.SelfPeer, node.blockchain.ChainParams()))
 	// Register consensus service.
 	node.serviceManager.RegisterService(service_manager.Consensus, consensus_service.New(node.BlockChannel, node.Consensus))
 	// Register new block service.
 	node.serviceManager.RegisterService(service_manager.BlockProposal, blockproposal.New(node.Consensus.ReadySignal, node.WaitForConsensusReady))
 	// Register client support service.
-	node.serviceManager.RegisterService(service_manager.ClientSupport, clientsupport.New(node.blockchain.State, node.CallFaucetContract, node.getDeployedStakingContract, node.SelfPeer.IP, node.SelfPeer.Port))
+	node.serviceManager.RegisterService(service_manager.ClientSupport, clientsupport.New(node.blockchain.State, node.CallFaucetContract, node.getDeployedStakingContract, node.getEpochRandomness, node.epochOf, node.SelfPeer.IP, node.SelfPeer.Port))
 	// Register randomness service
 	node.serviceManager.RegisterService(service_manager.Randomness, randomness_service.New(node.DRand))
+	// Register consensus tracing service.
+	node.serviceManager.RegisterService(service_manager.ConsensusTracing, tracing.New(&node.SelfPeer, node.Consensus.Tracer))
 }
 
 func (node *Node) setupForShardValidator() {
+	if err := node.setupGroupReceivers(p2p.NewGroupIDByShardID(node.Consensus.ShardID)); err != nil {
+		utils.GetLogInstance().Error("create group receiver error", "msg", err)
+	}
 }
 
 func (node *Node) setupForBeaconLeader() {
 	chanPeer := make(chan p2p.Peer)
 
-	var err error
-	node.groupReceiver, err = node.host.GroupReceiver(p2p.GroupIDBeacon)
-	if err != nil {
+	if err := node.setupGroupReceivers(p2p.NewGroupIDByShardID(node.Consensus.ShardID)); err != nil {
 		utils.GetLogInstance().Error("create group receiver error", "msg", err)
 		return
 	}
@@ -739,18 +965,18 @@ func (node *Node) setupForBeaconLeader() {
 	// Register new block service.
 	node.serviceManager.RegisterService(service_manager.BlockProposal, blockproposal.New(node.Consensus.ReadySignal, node.WaitForConsensusReady))
 	// Register client support service.
-	node.serviceManager.RegisterService(service_manager.ClientSupport, clientsupport.New(node.blockchain.State, node.CallFaucetContract, node.getDeployedStakingContract, node.SelfPeer.IP, node.SelfPeer.Port))
+	node.serviceManager.RegisterService(service_manager.ClientSupport, clientsupport.New(node.blockchain.State, node.CallFaucetContract, node.getDeployedStakingContract, node.getEpochRandomness, node.epochOf, node.SelfPeer.IP, node.SelfPeer.Port))
 	// Register randomness service
 	node.serviceManager.RegisterService(service_manager.Randomness, randomness_service.New(node.DRand))
+	// Register consensus tracing service.
+	node.serviceManager.RegisterService(service_manager.ConsensusTracing, tracing.New(&node.SelfPeer, node.Consensus.Tracer))
 
 }
 
 func (node *Node) setupForBeaconValidator() {
 	chanPeer := make(chan p2p.Peer)
 
-	var err error
-	node.groupReceiver, err = node.host.GroupReceiver(p2p.GroupIDBeacon)
-	if err != nil {
+	if err := node.setupGroupReceivers(p2p.NewGroupIDByShardID(node.Consensus.ShardID)); err != nil {
 		utils.GetLogInstance().Error("create group receiver error", "msg", err)
 		return
 	}
@@ -767,15 +993,14 @@ func (node *Node) setupForNewNode() {
 	chanPeer := make(chan p2p.Peer)
 	stakingPeer := make(chan p2p.Peer)
 
-	var err error
-	node.groupReceiver, err = node.host.GroupReceiver(p2p.GroupIDBeacon)
-	if err != nil {
+	// The new node joins the beacon group until it is assigned to a shard.
+	if err := node.setupGroupReceivers(p2p.GroupIDBeacon); err != nil {
 		utils.GetLogInstance().Error("create group receiver error", "msg", err)
 		return
 	}
 
 	// Register staking service.
-	node.serviceManager.RegisterService(service_manager.Staking, staking.New(node.AccountKey, 0, stakingPeer))
+	node.serviceManager.RegisterService(service_manager.Staking, staking.New(node.AccountKey, node.SelfPeer.PubKey, 0, stakingPeer))
 	// Register peer discovery service. "0" is the beacon shard ID
 	node.serviceManager.RegisterService(service_manager.PeerDiscovery, discovery.New(node.host, "0", chanPeer, stakingPeer))
 	// Register networkinfo service. "0" is the beacon shard ID
@@ -784,6 +1009,55 @@ func (node *Node) setupForNewNode() {
 	// TODO: how to restart networkinfo and discovery service after receiving shard id info from beacon chain?
 }
 
+// OnBlockPrepared is called by consensus on the leader once a block is prepared.
+// The next block is built on top of it while its commits are collected.
+func (node *Node) OnBlockPrepared(block *types.Block) {
+	node.pendingTxMutex.Lock()
+	defer node.pendingTxMutex.Unlock()
+	if err := node.Worker.SetPending(block); err != nil {
+		utils.GetLogInstance().Debug("Failed to build on the prepared block", "blockHash", block.Hash(), "Error", err)
+	}
+}
+
+// OnViewChange is called by consensus when the shard moved to a new leader.
+// The node starts proposing blocks once it becomes the leader.
+func (node *Node) OnViewChange(isLeader bool) {
+	// The prepared blocks of the old view are left to the new leader.
+	if node.Worker != nil {
+		node.pendingTxMutex.Lock()
+		node.Worker.ClearPending()
+		node.pendingTxMutex.Unlock()
+	}
+	// The randomness protocol follows the leader of the consensus.
+	if node.DRand != nil {
+		node.DRand.SetLeader(node.Consensus.GetLeader())
+	}
+	if !isLeader {
+		node.State = NodeReadyForConsensus
+		return
+	}
+	node.State = NodeLeader
+	if node.serviceManager == nil {
+		return
+	}
+	if _, ok := node.serviceManager.GetServices()[service_manager.Consensus]; ok {
+		// Services of the leader are already running, e.g. the node was the leader before.
+		return
+	}
+	utils.GetLogInstance().Info("Became the leader of the shard, starting block proposal", "node", node)
+	// Register consensus service.
+	node.serviceManager.RegisterService(service_manager.Consensus, consensus_service.New(node.BlockChannel, node.Consensus))
+	// Register new block service.
+	node.serviceManager.RegisterService(service_manager.BlockProposal, blockproposal.New(node.Consensus.ReadySignal, node.WaitForConsensusReady))
+	node.serviceManager.TakeAction(&service_manager.Action{Action: service_manager.Start, ServiceType: service_manager.Consensus})
+	node.serviceManager.TakeAction(&service_manager.Action{Action: service_manager.Start, ServiceType: service_manager.BlockProposal})
+	if _, ok := node.serviceManager.GetServices()[service_manager.Randomness]; !ok && node.DRand != nil {
+		// Register randomness service, so that the new leader inits the randomness protocol.
+		node.serviceManager.RegisterService(service_manager.Randomness, randomness_service.New(node.DRand))
+		node.serviceManager.TakeAction(&service_manager.Action{Action: service_manager.Start, ServiceType: service_manager.Randomness})
+	}
+}
+
 // ServiceManagerSetup setups service store.
 func (node *Node) ServiceManagerSetup() {
 	node.serviceManager = &service_manager.Manager{}
@@ -0,0 +1,167 @@
+package node
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/api/proto/bcconn"
+	proto_identity "github.com/harmony-one/harmony/api/proto/identity"
+	proto_node "github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// incomingReceiptEpochs is the number of epochs a proven receipt from another shard is kept
+// for if it doesn't get credited.
+const incomingReceiptEpochs = 2
+
+// outgoingReceiptEpochs is the number of epochs the receipts of a committed block are sent
+// again for, until the destination shard credits them.
+const outgoingReceiptEpochs = 2
+
+// incomingReceipt is a proven receipt from another shard waiting to be credited.
+type incomingReceipt struct {
+	proof    *types.CrossShardReceiptProof
+	blockNum uint64 // the number of the last block in the chain when the receipt arrived
+}
+
+// BroadcastCrossShardReceipts sends the proofs of the cross-shard receipts of the committed
+// block to the shards the transfers are sent to, whose leaders credit them. The receipts of
+// the blocks 1, 2, 4, ... blocks before are sent again, up to outgoingReceiptEpochs back, as
+// a destination shard may have missed them or not known the committee of this shard yet;
+// it skips the ones already credited.
+func (node *Node) BroadcastCrossShardReceipts(block *types.Block) {
+	node.sendCrossShardReceipts(block)
+	maxAge := outgoingReceiptEpochs * node.blockchain.ChainParams().At(block.NumberU64()).BlocksPerEpoch
+	for age := uint64(1); age <= maxAge && age <= block.NumberU64(); age *= 2 {
+		if previous := node.blockchain.GetBlockByNumber(block.NumberU64() - age); previous != nil {
+			node.sendCrossShardReceipts(previous)
+		}
+	}
+}
+
+// sendCrossShardReceipts sends the proofs of the cross-shard receipts of the block to their
+// shards, over libp2p, or relayed by the beacon chain to the leaders of the shards otherwise.
+func (node *Node) sendCrossShardReceipts(block *types.Block) {
+	if len(block.CrossShardReceipts()) == 0 {
+		return
+	}
+	proofs, err := block.CrossShardReceiptProofs()
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to prove cross-shard receipts", "blockNum", block.NumberU64(), "err", err)
+		return
+	}
+	for shardID, shardProofs := range proofs {
+		utils.GetLogInstance().Info("Sending cross-shard receipts", "blockNum", block.NumberU64(), "toShardID", shardID, "num", len(shardProofs))
+		msg := proto_node.ConstructCrossShardReceiptsMessage(shardProofs)
+		if !utils.UseLibP2P {
+			node.relayCrossShardReceipts(shardID, msg)
+			continue
+		}
+		if err := node.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(shardID)}, host.ConstructP2pMessage(host.MessageTypeBroadcast, msg)); err != nil {
+			utils.GetLogInstance().Error("Failed to send cross-shard receipts", "toShardID", shardID, "err", err)
+		}
+	}
+}
+
+// relayCrossShardReceipts sends the message of the cross-shard receipts to the beacon chain,
+// which forwards it to the leader of the destination shard.
+func (node *Node) relayCrossShardReceipts(shardID uint32, msg []byte) {
+	if len(node.BCPeers) == 0 {
+		utils.GetLogInstance().Warn("No beacon chain to relay cross-shard receipts", "toShardID", shardID)
+		return
+	}
+	relay := bcconn.SerializeCrossShardReceipts(&bcconn.CrossShardReceipts{ToShardID: shardID, Message: msg})
+	relayMsg := proto_identity.ConstructIdentityMessage(proto_identity.CrossShardReceipts, relay)
+	for _, peer := range node.BCPeers {
+		host.SendMessage(node.host, peer, relayMsg, nil)
+	}
+}
+
+// crossShardMessageHandler adds the proven receipts of the transfers to this shard to the
+// ones waiting to be credited. The receipts not proven or already credited are dropped,
+// as are the ones of shards whose committee isn't known yet, which their shard sends again.
+func (node *Node) crossShardMessageHandler(msgPayload []byte) {
+	proofs := types.CrossShardReceiptProofs{}
+	if err := rlp.DecodeBytes(msgPayload, &proofs); err != nil {
+		utils.GetLogInstance().Error("Failed to decode cross-shard receipts", "err", err)
+		return
+	}
+	statedb, err := node.blockchain.State()
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to get the state of the chain", "err", err)
+		return
+	}
+	blockNum := node.blockchain.CurrentBlock().NumberU64()
+	added := 0
+
+	node.incomingReceiptsMutex.Lock()
+	for _, proof := range proofs {
+		if err := core.VerifyIncomingReceipt(node.blockchain, node.blockchain.Engine(), node.Consensus.ShardID, proof); err == core.ErrCrossShardCommitteeUnknown {
+			utils.GetLogInstance().Info("Cross-shard receipt of an unknown committee, waiting for it to be sent again", "shardID", proof.Receipt.ShardID, "blockNum", proof.Header.Number)
+			continue
+		} else if err != nil {
+			utils.GetLogInstance().Debug("Invalid cross-shard receipt", "err", err)
+			continue
+		}
+		hash := proof.Receipt.Hash()
+		if _, ok := node.incomingReceipts[hash]; ok || core.IsReceiptSpent(statedb, proof.Receipt) {
+			continue
+		}
+		node.incomingReceipts[hash] = &incomingReceipt{proof: proof, blockNum: blockNum}
+		added++
+	}
+	totalPending := len(node.incomingReceipts)
+	node.incomingReceiptsMutex.Unlock()
+
+	utils.GetLogInstance().Debug("Got cross-shard receipts", "num", added, "totalPending", totalPending)
+	if added > 0 {
+		select {
+		case node.pendingTxSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// pendingIncomingReceipts returns the proven receipts waiting to be credited, in the
+// order of their hashes.
+func (node *Node) pendingIncomingReceipts() []*types.CrossShardReceiptProof {
+	node.incomingReceiptsMutex.Lock()
+	defer node.incomingReceiptsMutex.Unlock()
+
+	hashes := make([]common.Hash, 0, len(node.incomingReceipts))
+	for hash := range node.incomingReceipts {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	proofs := make([]*types.CrossShardReceiptProof, len(hashes))
+	for i, hash := range hashes {
+		proofs[i] = node.incomingReceipts[hash].proof
+	}
+	return proofs
+}
+
+// removeIncomingReceipts drops the receipts credited by the new block, and the ones which
+// waited for too long.
+func (node *Node) removeIncomingReceipts(block *types.Block) {
+	node.incomingReceiptsMutex.Lock()
+	defer node.incomingReceiptsMutex.Unlock()
+
+	for _, proof := range block.IncomingReceipts() {
+		delete(node.incomingReceipts, proof.Receipt.Hash())
+	}
+	maxAge := incomingReceiptEpochs * node.blockchain.ChainParams().At(block.NumberU64()).BlocksPerEpoch
+	for hash, receipt := range node.incomingReceipts {
+		if receipt.blockNum+maxAge < block.NumberU64() {
+			utils.GetLogInstance().Debug("Dropping cross-shard receipt not credited", "receiptHash", hash, "txHash", receipt.proof.Receipt.TxHash)
+			delete(node.incomingReceipts, hash)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/api/proto/bcconn"
+	proto_identity "github.com/harmony-one/harmony/api/proto/identity"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// SubmitCrosslink sends the header of the committed block to the beacon chain, along with
+// the public keys of the committee which signed it, so that the beacon chain keeps track
+// of the blocks of the shard. The shard state of the last block of an epoch is sent too, for
+// the beacon chain to verify the crosslinks of the next epoch against the new committee.
+func (node *Node) SubmitCrosslink(block *types.Block) {
+	var shardState types.ShardState
+	if block.Header().ShardStateHash != (common.Hash{}) {
+		shardState = node.blockchain.GetNewShardState(block)
+	}
+	node.sendCrosslink(block, shardState)
+}
+
+// submitCommittee resends the crosslink recording the committee of the epoch the beacon chain
+// asks for, the one of the last block before the epoch, when the beacon chain missed it.
+func (node *Node) submitCommittee(payload []byte) {
+	request, err := bcconn.DeserializeCommitteeRequest(payload)
+	if err != nil {
+		utils.GetLogInstance().Warn("Unparseable committee request", "err", err)
+		return
+	}
+	if request.ShardID != node.Consensus.ShardID || request.Epoch == 0 {
+		return
+	}
+	block := node.blockchain.GetBlockByNumber(node.blockchain.ChainParams().ShardStateBlockNumber(request.Epoch))
+	if block == nil {
+		utils.GetLogInstance().Debug("No block recording the requested committee", "epoch", request.Epoch)
+		return
+	}
+	node.sendCrosslink(block, node.blockchain.ReadShardState(request.Epoch))
+}
+
+// sendCrosslink sends the crosslink of the block with the shard state it records, if any.
+func (node *Node) sendCrosslink(block *types.Block, shardState types.ShardState) {
+	if len(node.BCPeers) == 0 {
+		return
+	}
+	publicKeys := [][]byte{}
+	for _, publicKey := range node.Consensus.CommitteeKeys(node.blockchain, block.Header()) {
+		publicKeys = append(publicKeys, publicKey.Serialize())
+	}
+	crosslink := bcconn.SerializeCrosslink(&bcconn.Crosslink{Header: block.Header(), PublicKeys: publicKeys, ShardState: shardState})
+	msg := proto_identity.ConstructIdentityMessage(proto_identity.Crosslink, crosslink)
+	utils.GetLogInstance().Debug("Submitting crosslink", "blockNum", block.NumberU64(), "blockHash", block.Hash())
+	for _, peer := range node.BCPeers {
+		host.SendMessage(node.host, peer, msg, nil)
+	}
+}
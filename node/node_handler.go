@@ -22,11 +22,6 @@ import (
 	"github.com/harmony-one/harmony/p2p/host"
 )
 
-const (
-	// MaxNumberOfTransactionsPerBlock is the max number of transaction per a block.
-	MaxNumberOfTransactionsPerBlock = 8000
-)
-
 // MaybeBroadcastAsValidator returns if the node is a validator node.
 func (node *Node) MaybeBroadcastAsValidator(content []byte) {
 	// TODO: this is tree-based broadcasting. this needs to be replaced by p2p gossiping.
@@ -50,15 +45,25 @@ func (node *Node) StreamHandler(s p2p.Stream) {
 	node.messageHandler(content, "")
 }
 
-// ReceiveGroupMessage use libp2p pubsub mechanism to receive broadcast messages
+// ReceiveGroupMessage use libp2p pubsub mechanism to receive broadcast messages of the shard
 func (node *Node) ReceiveGroupMessage() {
+	node.receiveGroupMessage(func() p2p.GroupReceiver { return node.groupReceiver })
+}
+
+// ReceiveGlobalMessage use libp2p pubsub mechanism to receive broadcast messages of all the shards
+func (node *Node) ReceiveGlobalMessage() {
+	node.receiveGroupMessage(func() p2p.GroupReceiver { return node.globalGroupReceiver })
+}
+
+func (node *Node) receiveGroupMessage(groupReceiver func() p2p.GroupReceiver) {
 	ctx := context.Background()
 	for {
-		if node.groupReceiver == nil {
+		receiver := groupReceiver()
+		if receiver == nil {
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
-		msg, sender, err := node.groupReceiver.Receive(ctx)
+		msg, sender, err := receiver.Receive(ctx)
 		if sender != node.host.GetID() {
 			//			utils.GetLogInstance().Info("[PUBSUB]", "received group msg", len(msg), "sender", sender)
 			if err == nil {
@@ -103,6 +108,13 @@ func (node *Node) messageHandler(content []byte, sender string) {
 			case proto_identity.Register:
 				fmt.Println("received a identity message")
 				utils.GetLogInstance().Info("NET: received message: IDENTITY/REGISTER")
+			case proto_identity.CommitteeRequest:
+				payload, err := proto_identity.GetIdentityMessagePayload(msgPayload)
+				if err != nil {
+					utils.GetLogInstance().Error("Read committee request failed", "err", err)
+					return
+				}
+				node.submitCommittee(payload)
 			default:
 				utils.GetLogInstance().Error("Announce message should be sent to IdentityChain")
 			}
@@ -182,6 +194,9 @@ func (node *Node) messageHandler(content []byte, sender string) {
 			node.pingMessageHandler(msgPayload, sender)
 		case proto_node.PONG:
 			node.pongMessageHandler(msgPayload)
+		case proto_node.CrossShard:
+			utils.GetLogInstance().Info("NET: received message: Node/CrossShard")
+			node.crossShardMessageHandler(msgPayload)
 		}
 	default:
 		utils.GetLogInstance().Error("Unknown", "MsgCategory", msgCategory)
@@ -231,7 +246,7 @@ func (node *Node) BroadcastNewBlock(newBlock *types.Block) {
 	if node.ClientPeer != nil {
 		utils.GetLogInstance().Debug("Sending new block to client", "client", node.ClientPeer)
 		if utils.UseLibP2P {
-			node.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, proto_node.ConstructBlocksSyncMessage([]*types.Block{newBlock}))
+			node.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDGlobal}, host.ConstructP2pMessage(host.MessageTypeBroadcast, proto_node.ConstructBlocksSyncMessage([]*types.Block{newBlock})))
 		} else {
 			node.SendMessage(*node.ClientPeer, proto_node.ConstructBlocksSyncMessage([]*types.Block{newBlock}))
 		}
@@ -240,6 +255,12 @@ func (node *Node) BroadcastNewBlock(newBlock *types.Block) {
 
 // VerifyNewBlock is called by consensus participants to verify the block (account model) they are running consensus on
 func (node *Node) VerifyNewBlock(newBlock *types.Block) bool {
+	// Check the header against the chain, including the randomness committed into it
+	if err := node.blockchain.Engine().VerifyHeader(node.blockchain, newBlock.Header(), false); err != nil {
+		utils.GetLogInstance().Debug("Failed verifying new block header", "Error", err)
+		return false
+	}
+
 	err := node.blockchain.ValidateNewBlock(newBlock, pki.GetAddressFromPublicKey(node.SelfPeer.PubKey))
 	if err != nil {
 		utils.GetLogInstance().Debug("Failed verifying new block", "Error", err, "tx", newBlock.Transactions()[0])
@@ -250,20 +271,47 @@ func (node *Node) VerifyNewBlock(newBlock *types.Block) bool {
 	if err != nil {
 		utils.GetLogInstance().Debug("Failed to verify new sharding state", "err", err)
 	}
+
+	if types.DeriveSha(newBlock.CrossShardReceipts()) != newBlock.Header().CrossShardReceiptHash {
+		utils.GetLogInstance().Debug("Cross-shard receipt root hash mismatch", "blockHash", newBlock.Hash())
+		return false
+	}
+	if types.DeriveSha(newBlock.IncomingReceipts()) != newBlock.Header().IncomingReceiptHash {
+		utils.GetLogInstance().Debug("Incoming receipt root hash mismatch", "blockHash", newBlock.Hash())
+		return false
+	}
+	if types.DeriveSha(newBlock.Evidences()) != newBlock.Header().EvidenceHash {
+		utils.GetLogInstance().Debug("Evidence root hash mismatch", "blockHash", newBlock.Hash())
+		return false
+	}
+	for _, evidence := range newBlock.Evidences() {
+		if err := node.Consensus.VerifyEvidence(node.blockchain, newBlock.Header(), evidence); err != nil {
+			utils.GetLogInstance().Debug("Failed to verify double sign evidence", "evidenceHash", evidence.Hash(), "err", err)
+			return false
+		}
+	}
+	if err := node.blockchain.ValidateEvidences(newBlock); err != nil {
+		utils.GetLogInstance().Debug("Failed to verify double sign evidences", "err", err)
+		return false
+	}
 	return true
 }
 
 // PostConsensusProcessing is called by consensus participants, after consensus is done, to:
 // 1. add the new block to blockchain
 // 2. [leader] send new block to the client
+// 3. [leader] send the proven cross-shard receipts to their shards
 func (node *Node) PostConsensusProcessing(newBlock *types.Block) {
-	if node.Role == BeaconLeader || node.Role == BeaconValidator {
-		node.UpdateStakingList(newBlock)
-	}
 	if node.Consensus.IsLeader {
 		node.BroadcastNewBlock(newBlock)
+		node.BroadcastCrossShardReceipts(newBlock)
+		node.SubmitCrosslink(newBlock)
 	}
 	node.AddNewBlock(newBlock)
+	node.removeIncomingReceipts(newBlock)
+	// The stakes are updated after the block is added, as the new shard state of the last
+	// block of an epoch is calculated from the nodes which staked during the epoch.
+	node.syncStakingList(newBlock.NumberU64())
 
 	// TODO: enable drand only for beacon chain
 	// ConfirmedBlockChannel which is listened by drand leader who will initiate DRG if its a epoch block (first block of a epoch)
@@ -324,7 +372,7 @@ func (node *Node) pingMessageHandler(msgPayload []byte, sender string) int {
 	}
 
 	if ping.Node.Role == proto_node.ClientRole {
-		utils.GetLogInstance().Info("Add Client Peer to Node", "Node", node.Consensus.GetNodeID(), "Client", peer)
+		utils.GetLogInstance().Info("Add Client Peer to Node", "Node", node.SelfPeer, "Client", peer)
 		node.ClientPeer = peer
 		return 0
 	}
@@ -385,13 +433,14 @@ func (node *Node) SendPongMessage() {
 				if !sentMessage {
 					pong := proto_discovery.NewPongMessage(peers, node.Consensus.PublicKeys)
 					buffer := pong.ConstructPongMessage()
-					content := host.ConstructP2pMessage(byte(0), buffer)
-					err := node.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, content)
+					content := host.ConstructP2pMessage(host.MessageTypeDirect, buffer)
+					groupID := p2p.NewGroupIDByShardID(node.Consensus.ShardID)
+					err := node.host.SendMessageToGroups([]p2p.GroupID{groupID}, content)
 					if err != nil {
-						utils.GetLogInstance().Error("[PONG] failed to send pong message", "group", p2p.GroupIDBeacon)
+						utils.GetLogInstance().Error("[PONG] failed to send pong message", "group", groupID)
 						continue
 					} else {
-						utils.GetLogInstance().Info("[PONG] sent pong message to", "group", p2p.GroupIDBeacon)
+						utils.GetLogInstance().Info("[PONG] sent pong message to", "group", groupID)
 					}
 					sentMessage = true
 					// stop sending ping message
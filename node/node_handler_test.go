@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/consensus"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/chainparams"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	"github.com/harmony-one/harmony/p2p/p2pimpl"
@@ -20,7 +23,7 @@ func TestNodeStreamHandler(t *testing.T) {
 		t.Fatalf("newhost failure: %v", err)
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -35,21 +38,39 @@ func TestNodeStreamHandler(t *testing.T) {
 }
 
 func TestAddNewBlock(t *testing.T) {
-	_, pubKey := utils.GenKey("1", "2")
-	leader := p2p.Peer{IP: "127.0.0.1", Port: "9882", PubKey: pubKey}
-	validator := p2p.Peer{IP: "127.0.0.1", Port: "9885"}
+	leaderPriKey, leaderPubKey := utils.GenKey("127.0.0.1", "9882")
+	validatorPriKey, validatorPubKey := utils.GenKey("127.0.0.1", "9885")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9882", PubKey: leaderPubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "9885", PubKey: validatorPubKey}
 	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
 	host, err := p2pimpl.NewHost(&leader, priKey)
 	if err != nil {
 		t.Fatalf("newhost failure: %v", err)
 	}
-	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
-	node := New(host, consensus, nil)
+	consensus := consensus.New(host, "0", []p2p.Peer{validator}, leader)
+	node := New(host, consensus, nil, nil)
 
-	selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
+	selectedTxs := node.getTransactionsForNewBlock(chainparams.DefaultConfig.MaxTxsPerBlock)
 	node.Worker.CommitTransactions(selectedTxs)
 	block, _ := node.Worker.Commit()
 
+	// A block without the committee's signatures is rejected.
+	node.AddNewBlock(block)
+	if node.blockchain.CurrentBlock().NumberU64() != 0 {
+		t.Error("New block is added without being sealed")
+	}
+
+	// Both the validator and the leader sign the block.
+	mask, _ := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	mask.SetKey(leaderPubKey, true)
+	mask.SetKey(validatorPubKey, true)
+	blockHash := block.Hash()
+	prepareSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(blockHash[:]), validatorPriKey.SignHash(blockHash[:])})
+	block.SetPrepareSig(prepareSig.Serialize(), mask.Bitmap)
+	prepareMultiSigAndBitmap := append(prepareSig.Serialize(), mask.Bitmap...)
+	commitSig := bls_cosi.AggregateSig([]*bls.Sign{leaderPriKey.SignHash(prepareMultiSigAndBitmap), validatorPriKey.SignHash(prepareMultiSigAndBitmap)})
+	block.SetCommitSig(commitSig.Serialize(), mask.Bitmap)
+
 	node.AddNewBlock(block)
 
 	if node.blockchain.CurrentBlock().NumberU64() != 1 {
@@ -67,9 +88,9 @@ func TestVerifyNewBlock(t *testing.T) {
 		t.Fatalf("newhost failure: %v", err)
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 
-	selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
+	selectedTxs := node.getTransactionsForNewBlock(chainparams.DefaultConfig.MaxTxsPerBlock)
 	node.Worker.CommitTransactions(selectedTxs)
 	block, _ := node.Worker.Commit()
 
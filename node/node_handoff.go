@@ -0,0 +1,142 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	service_manager "github.com/harmony-one/harmony/api/service"
+	"github.com/harmony-one/harmony/api/service/clientsupport"
+	"github.com/harmony-one/harmony/api/service/explorer"
+	"github.com/harmony-one/harmony/api/service/tracing"
+	bft "github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// ShardCommittee returns the public keys of the committee of the node's shard recorded in
+// the shard state of the epoch. It returns nil if the shard state doesn't record the BLS
+// keys of the committee, e.g. on a chain whose genesis records no committees.
+func (node *Node) ShardCommittee(epoch uint64) []*bls.PublicKey {
+	for _, committee := range node.blockchain.ReadShardState(epoch) {
+		if committee.ShardID == node.Consensus.ShardID {
+			return bft.CommitteePublicKeys(committee)
+		}
+	}
+	return nil
+}
+
+// OnHandOff is called by consensus when the last block of an epoch is committed and the
+// committee of the next epoch took over the shard. The randomness protocol of the next epoch
+// is started by the new committee, and the node moves to the shard it was assigned to if it
+// left the committee.
+func (node *Node) OnHandOff(block *types.Block, publicKeys []*bls.PublicKey, leader p2p.Peer) {
+	if node.DRand != nil {
+		node.DRand.UpdateCommittee(publicKeys, leader)
+		node.DRand.StartEpoch(block)
+	}
+
+	myKey := node.Consensus.GetPublicKey()
+	if hasKey(publicKeys, myKey) {
+		return
+	}
+	// The last block of the epoch records the shard state of the next epoch.
+	for _, committee := range node.blockchain.ReadShardState(node.blockchain.ChainParams().EpochOf(block.NumberU64()) + 1) {
+		if committee.ShardID == node.Consensus.ShardID {
+			continue
+		}
+		if newKeys := bft.CommitteePublicKeys(committee); hasKey(newKeys, myKey) {
+			// The consensus mutex is held by the caller, the move waits for the handoff to finish.
+			go node.moveToShard(committee.ShardID, newKeys)
+			return
+		}
+	}
+	utils.GetLogInstance().Info("Left the committee of the shard", "shardID", node.Consensus.ShardID)
+}
+
+// moveToShard makes the node join the committee of another shard: it subscribes to the
+// group of the shard, and syncs the chain of the shard into the database of the shard
+// before it takes part in the consensus.
+func (node *Node) moveToShard(shardID uint32, publicKeys []*bls.PublicKey) {
+	utils.GetLogInstance().Info("Moving to another shard", "fromShardID", node.Consensus.ShardID, "toShardID", shardID)
+	var database ethdb.Database = ethdb.NewMemDatabase()
+	if node.OpenShardDatabase != nil {
+		var err error
+		if database, err = node.OpenShardDatabase(shardID); err != nil {
+			utils.GetLogInstance().Error("Failed to open the database of the shard", "shardID", shardID, "error", err)
+			return
+		}
+	}
+	if err := node.setupGroupReceivers(p2p.NewGroupIDByShardID(shardID)); err != nil {
+		utils.GetLogInstance().Error("create group receiver error", "msg", err)
+		database.Close()
+		return
+	}
+	node.Consensus.JoinCommittee(shardID, publicKeys)
+
+	node.stateMutex.Lock()
+	defer node.stateMutex.Unlock()
+	oldChain, oldDB := node.blockchain, node.chainDB
+	node.initBlockchain(database)
+	node.resetStakingList()
+	node.restartShardServices()
+	oldChain.Stop()
+	oldDB.Close()
+
+	node.pendingTxMutex.Lock()
+	node.pendingTransactions = types.Transactions{}
+	node.pendingTxMutex.Unlock()
+	if node.stateSync != nil {
+		node.stateSync.CloseConnections()
+		node.stateSync = nil
+	}
+	node.State = NodeNotInSync
+}
+
+// resetStakingList drops the stakes derived from the chain the node left, so that they're
+// derived from the chain of the new shard.
+func (node *Node) resetStakingList() {
+	node.stakeMutex.Lock()
+	defer node.stakeMutex.Unlock()
+
+	node.CurrentStakes = make(map[common.Address]int64)
+	node.stakingBlsKeys = nil
+	node.newStakers = nil
+	node.stakingEpoch = 0
+	node.nextStakingBlock = 0
+}
+
+// restartShardServices restarts the running services serving the chain of the shard, so
+// that they serve the chain of the shard the node moved to. The caller must hold stateMutex.
+func (node *Node) restartShardServices() {
+	if node.serviceManager == nil {
+		return
+	}
+	restart := func(serviceType service_manager.Type, newService func() service_manager.Interface) {
+		if _, ok := node.serviceManager.GetServices()[serviceType]; !ok {
+			return
+		}
+		node.serviceManager.TakeAction(&service_manager.Action{Action: service_manager.Stop, ServiceType: serviceType})
+		node.serviceManager.RegisterService(serviceType, newService())
+		node.serviceManager.TakeAction(&service_manager.Action{Action: service_manager.Start, ServiceType: serviceType})
+	}
+	restart(service_manager.ClientSupport, func() service_manager.Interface {
+		return clientsupport.New(node.blockchain.State, node.CallFaucetContract, node.getDeployedStakingContract, node.getEpochRandomness, node.epochOf, node.SelfPeer.IP, node.SelfPeer.Port)
+	})
+	restart(service_manager.SupportExplorer, func() service_manager.Interface {
+		return explorer.New(&node.SelfPeer, node.blockchain.ChainParams())
+	})
+	restart(service_manager.ConsensusTracing, func() service_manager.Interface {
+		return tracing.New(&node.SelfPeer, node.Consensus.Tracer)
+	})
+}
+
+// hasKey returns whether the public key is one of the keys.
+func hasKey(publicKeys []*bls.PublicKey, publicKey *bls.PublicKey) bool {
+	for _, key := range publicKeys {
+		if key.IsEqual(publicKey) {
+			return true
+		}
+	}
+	return false
+}
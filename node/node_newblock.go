@@ -3,7 +3,6 @@ package node
 import (
 	"time"
 
-	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/internal/utils"
 )
@@ -24,8 +23,11 @@ func (node *Node) WaitForConsensusReady(readySignal chan struct{}, stopChan chan
 			// keep waiting for Consensus ready
 			select {
 			case <-readySignal:
-				time.Sleep(100 * time.Millisecond) // Delay a bit so validator is catched up (test-only).
 			case <-time.After(200 * time.Second):
+				if !node.Consensus.IsLeader {
+					// The shard moved to another leader through view change.
+					continue
+				}
 				node.Consensus.ResetState()
 				timeoutCount++
 				utils.GetLogInstance().Debug("Consensus timeout, retry!", "count", timeoutCount, "node", node)
@@ -40,26 +42,42 @@ func (node *Node) WaitForConsensusReady(readySignal chan struct{}, stopChan chan
 					threshold = 2
 					firstTime = false
 				}
-				utils.GetLogInstance().Debug("STARTING BLOCK", "threshold", threshold, "pendingTransactions", len(node.pendingTransactions))
-				if len(node.pendingTransactions) >= threshold {
+				incomingReceipts := node.pendingIncomingReceipts()
+				utils.GetLogInstance().Debug("STARTING BLOCK", "threshold", threshold, "pendingTransactions", len(node.pendingTransactions), "incomingReceipts", len(incomingReceipts))
+				if len(node.pendingTransactions) >= threshold || len(incomingReceipts) != 0 {
 					// Normal tx block consensus
-					selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
-					if len(selectedTxs) != 0 {
-						node.Worker.CommitTransactions(selectedTxs)
+					maxNumTxs := node.blockchain.ChainParams().At(node.Worker.GetNewBlockNumber()).MaxTxsPerBlock
+					selectedTxs := node.getTransactionsForNewBlock(maxNumTxs)
+					node.Worker.CommitTransactions(selectedTxs)
+					// credit the proven transfers from other shards
+					creditedReceipts := node.Worker.CommitIncomingReceipts(incomingReceipts)
+					if len(selectedTxs) != 0 || len(creditedReceipts) != 0 {
 						block, err := node.Worker.Commit()
 						if err != nil {
 							utils.GetLogInstance().Debug("Failed commiting new block", "Error", err)
 						} else {
-							// add new shard state if it's epoch block
-							node.addNewShardState(block)
+							// add the double sign evidences not in the chain yet, the block may follow a prepared one
+							head := node.blockchain.CurrentBlock()
+							evidences := []*types.DoubleSignEvidence{}
+							for _, evidence := range node.Consensus.PendingEvidences(block.NumberU64()) {
+								if !node.blockchain.HasEvidence(evidence, head.Hash(), head.NumberU64()) {
+									evidences = append(evidences, evidence)
+								}
+							}
+							block.AddEvidences(evidences)
 							newBlock = block
 							break
 						}
 					}
 				}
 				// If not enough transactions to run Consensus,
-				// periodically check whether we have enough transactions to package into block.
-				time.Sleep(1 * time.Second)
+				// wait for new transactions, or check again periodically for the ones left over.
+				select {
+				case <-node.pendingTxSignal:
+				case <-time.After(1 * time.Second):
+				case <-stopChan:
+					return
+				}
 			}
 			// Send the new block to Consensus so it can be confirmed.
 			if newBlock != nil {
@@ -69,7 +87,9 @@ func (node *Node) WaitForConsensusReady(readySignal chan struct{}, stopChan chan
 	}()
 }
 
-func (node *Node) addNewShardState(block *types.Block) {
+// AddNewShardState adds the hash of the new shard state into the last block of an epoch.
+// It's called by consensus once the final randomness of the epoch is added into the block.
+func (node *Node) AddNewShardState(block *types.Block) {
 	shardState := node.blockchain.GetNewShardState(block)
 	if shardState != nil {
 		shardHash := shardState.Hash()
@@ -80,21 +100,3 @@ func (node *Node) addNewShardState(block *types.Block) {
 		block.AddShardStateHash(shardHash)
 	}
 }
-
-func (node *Node) addNewRandSeed(block *types.Block) {
-	if !core.IsEpochBlock(block) {
-		return
-	}
-
-	var rnd int64
-	blockNumber := block.NumberU64()
-	epoch := core.GetEpochFromBlockNumber(blockNumber)
-	if epoch == 1 {
-		rnd = core.InitialSeed
-	} else {
-		number := core.GetPreviousEpochBlockNumber(blockNumber)
-		oldrnd := node.blockchain.GetRandSeedByNumber(number)
-		rnd = core.FakeGenRandSeed(oldrnd)
-	}
-	block.AddRandSeed(rnd)
-}
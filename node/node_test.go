@@ -1,9 +1,11 @@
 package node
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -11,13 +13,18 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/bls/ffi/go/bls"
 	proto_discovery "github.com/harmony-one/harmony/api/proto/discovery"
 	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/rawdb"
 	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/crypto/pki"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host/simulator"
 	"github.com/harmony-one/harmony/p2p/p2pimpl"
 	"golang.org/x/crypto/sha3"
 )
@@ -32,7 +39,7 @@ func TestNewNode(t *testing.T) {
 		t.Fatalf("newhost failure: %v", err)
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	if node.Consensus == nil {
 		t.Error("Consensus is not initialized for the node")
 	}
@@ -58,7 +65,7 @@ func TestGetSyncingPeers(t *testing.T) {
 
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
 
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	peer := p2p.Peer{IP: "127.0.0.1", Port: "8000"}
 	peer2 := p2p.Peer{IP: "127.0.0.1", Port: "8001"}
 	node.Neighbors.Store("minh", peer)
@@ -101,7 +108,7 @@ func TestAddPeers(t *testing.T) {
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
 	dRand := drand.New(host, "0", []p2p.Peer{leader, validator}, leader, nil)
 
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	node.DRand = dRand
 	r1 := node.AddPeers(peers1)
 	e1 := 2
@@ -175,7 +182,7 @@ func TestPingPongHandler(t *testing.T) {
 		t.Fatalf("newhost failure: %v", err)
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader}, leader)
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	//go sendPingMessage(leader)
 	go sendPongMessage(node, leader)
 	go exitServer()
@@ -193,7 +200,7 @@ func TestUpdateStakingDeposit(t *testing.T) {
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
 
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	node.CurrentStakes = make(map[common.Address]int64)
 
 	DepositContractPriKey, _ := crypto.GenerateKey()                                  //DepositContractPriKey is pk for contract
@@ -235,7 +242,7 @@ func TestUpdateStakingWithdrawal(t *testing.T) {
 	}
 	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
 
-	node := New(host, consensus, nil)
+	node := New(host, consensus, nil, nil)
 	node.CurrentStakes = make(map[common.Address]int64)
 
 	DepositContractPriKey, _ := crypto.GenerateKey()                                  //DepositContractPriKey is pk for contract
@@ -277,3 +284,214 @@ func TestUpdateStakingWithdrawal(t *testing.T) {
 	}
 
 }
+
+func TestNewNodeList(t *testing.T) {
+	_, pubKey := utils.GenKey("1", "2")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "8882", PubKey: pubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "8885"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
+
+	node := New(host, consensus, nil, nil)
+	node.CurrentStakes = make(map[common.Address]int64)
+	DepositContractPriKey, _ := crypto.GenerateKey()
+	node.StakingContractAddress = crypto.PubkeyToAddress(DepositContractPriKey.PublicKey)
+
+	// deposit returns a block of the given number with a deposit registering the BLS public key
+	deposit := func(number int64, accountKey *ecdsa.PrivateKey, blsPubKey []byte) *types.Block {
+		dataEnc := append(common.FromHex("0xd0e30db0"), blsPubKey...)
+		tx, _ := types.SignTx(types.NewTransaction(0, node.StakingContractAddress, node.Consensus.ShardID, big.NewInt(10), params.TxGasContractCreation*10, nil, dataEnc), types.HomesteadSigner{}, accountKey)
+		return types.NewBlock(&types.Header{Number: big.NewInt(number)}, []*types.Transaction{tx}, nil)
+	}
+	accountKey1, _ := crypto.GenerateKey()
+	accountKey2, _ := crypto.GenerateKey()
+	accountKey3, _ := crypto.GenerateKey()
+	_, blsPubKey1 := utils.GenKey("127.0.0.1", "9000")
+	_, blsPubKey2 := utils.GenKey("127.0.0.1", "9001")
+	_, blsPubKey3 := utils.GenKey("127.0.0.1", "9002")
+
+	// Deposits during epoch 1, the second one without a BLS public key
+	node.UpdateStakingList(deposit(5, accountKey1, blsPubKey1.Serialize()))
+	node.UpdateStakingList(deposit(7, accountKey1, nil))
+	node.UpdateStakingList(deposit(8, accountKey2, blsPubKey2.Serialize()))
+	// The deposit in the last block of the epoch is after the resharding it records
+	node.UpdateStakingList(deposit(9, accountKey3, blsPubKey3.Serialize()))
+
+	newNodeList := node.NewNodeList(2)
+	if len(newNodeList) != 2 {
+		t.Fatalf("expected 2 new nodes, got %d", len(newNodeList))
+	}
+	if newNodeList[0].BlsPublicKey != blsPubKey1.SerializeToHexStr() || newNodeList[0].Stake != 20 {
+		t.Error("The first new node has a wrong BLS public key or stake")
+	}
+	if newNodeList[1].BlsPublicKey != blsPubKey2.SerializeToHexStr() || newNodeList[1].Stake != 10 {
+		t.Error("The second new node has a wrong BLS public key or stake")
+	}
+	if node.NewNodeList(3) != nil {
+		t.Error("No new nodes are known for epoch 3 yet")
+	}
+
+	// The new nodes are collected again in the next epoch
+	node.UpdateStakingList(deposit(10, accountKey1, nil))
+	node.UpdateStakingList(deposit(13, accountKey2, nil))
+	newNodeList = node.NewNodeList(3)
+	if len(newNodeList) != 1 || newNodeList[0].BlsPublicKey != blsPubKey3.SerializeToHexStr() {
+		t.Errorf("expected only the staker of the last block of epoch 1 to join in epoch 3, got %v", newNodeList)
+	}
+}
+
+func TestNewNodeListOnSyncedChain(t *testing.T) {
+	_, pubKey := utils.GenKey("1", "2")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "8882", PubKey: pubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "8885"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	database := ethdb.NewMemDatabase()
+	// A node which synced its chain saw no consensus round and holds no stakes in memory
+	node := New(host, consensus.New(host, "0", []p2p.Peer{leader, validator}, leader), database, nil)
+
+	accountKey1, _ := crypto.GenerateKey()
+	accountKey2, _ := crypto.GenerateKey()
+	_, blsPubKey1 := utils.GenKey("127.0.0.1", "9000")
+	_, blsPubKey2 := utils.GenKey("127.0.0.1", "9001")
+	deposit := func(accountKey *ecdsa.PrivateKey, blsPubKey []byte, amount int64) *types.Transaction {
+		dataEnc := append(common.FromHex("0xd0e30db0"), blsPubKey...)
+		tx, _ := types.SignTx(types.NewTransaction(0, node.StakingContractAddress, node.Consensus.ShardID, big.NewInt(amount), params.TxGasContractCreation*10, nil, dataEnc), types.HomesteadSigner{}, accountKey)
+		return tx
+	}
+	// The blocks of epoch 0 and 1 written by the sync, the stakers of epoch 1 join in epoch 2
+	txs := map[uint64][]*types.Transaction{
+		3: {deposit(accountKey1, blsPubKey1.Serialize(), 30)},
+		6: {deposit(accountKey2, blsPubKey2.Serialize(), 10)},
+		8: {deposit(accountKey2, nil, 15)},
+	}
+	blocks := []*types.Block{}
+	for number := uint64(1); number < 10; number++ {
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(number)}, txs[number], nil)
+		rawdb.WriteBlock(database, block)
+		rawdb.WriteCanonicalHash(database, block.Hash(), number)
+		blocks = append(blocks, block)
+	}
+
+	newNodeList := node.NewNodeList(2)
+	if len(newNodeList) != 1 || newNodeList[0].BlsPublicKey != blsPubKey2.SerializeToHexStr() || newNodeList[0].Stake != 25 {
+		t.Errorf("expected the second staker to join in epoch 2, got %v", newNodeList)
+	}
+	if stake := node.StakesAt(6)(blsPubKey1); stake != 30 {
+		t.Errorf("expected the stake of the first staker in epoch 1, got %d", stake)
+	}
+
+	// A node which ran consensus on the same blocks agrees on the list
+	other := New(host, consensus.New(host, "0", []p2p.Peer{leader, validator}, leader), nil, nil)
+	for _, block := range blocks {
+		other.UpdateStakingList(block)
+	}
+	if !reflect.DeepEqual(other.NewNodeList(2), newNodeList) {
+		t.Errorf("the nodes disagree on the new nodes: %v != %v", other.NewNodeList(2), newNodeList)
+	}
+}
+
+func TestStakesAt(t *testing.T) {
+	_, pubKey := utils.GenKey("1", "2")
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "8882", PubKey: pubKey}
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "8885"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus := consensus.New(host, "0", []p2p.Peer{leader, validator}, leader)
+
+	node := New(host, consensus, nil, nil)
+	node.CurrentStakes = make(map[common.Address]int64)
+	DepositContractPriKey, _ := crypto.GenerateKey()
+	node.StakingContractAddress = crypto.PubkeyToAddress(DepositContractPriKey.PublicKey)
+
+	accountKey1, _ := crypto.GenerateKey()
+	accountKey2, _ := crypto.GenerateKey()
+	_, blsPubKey1 := utils.GenKey("127.0.0.1", "9000")
+	_, blsPubKey2 := utils.GenKey("127.0.0.1", "9001")
+	deposit := func(accountKey *ecdsa.PrivateKey, blsPubKey []byte, amount int64) *types.Transaction {
+		dataEnc := append(common.FromHex("0xd0e30db0"), blsPubKey...)
+		tx, _ := types.SignTx(types.NewTransaction(0, node.StakingContractAddress, node.Consensus.ShardID, big.NewInt(amount), params.TxGasContractCreation*10, nil, dataEnc), types.HomesteadSigner{}, accountKey)
+		return tx
+	}
+
+	// Both deposit during epoch 0, the second one registering the key of the first one
+	node.UpdateStakingList(types.NewBlock(&types.Header{Number: big.NewInt(3)}, []*types.Transaction{
+		deposit(accountKey1, blsPubKey1.Serialize(), 30),
+		deposit(accountKey2, blsPubKey1.Serialize(), 50),
+	}, nil))
+	// The last block of epoch 0 is signed by the committee of epoch 0, which had no stakes
+	if stake := node.StakesAt(4)(blsPubKey1); stake != 0 {
+		t.Errorf("expected no stake for the last block of epoch 0, got %d", stake)
+	}
+	node.UpdateStakingList(types.NewBlock(&types.Header{Number: big.NewInt(4)}, nil, nil))
+	node.UpdateStakingList(types.NewBlock(&types.Header{Number: big.NewInt(5)}, []*types.Transaction{
+		deposit(accountKey2, blsPubKey2.Serialize(), 10),
+	}, nil))
+
+	// The deposits up to the last block of epoch 0 count from the epoch block on
+	stakeOf := node.StakesAt(5)
+	if stake := stakeOf(blsPubKey1); stake != 30 {
+		t.Errorf("expected the stake of the first staker, got %d", stake)
+	}
+	if stake := stakeOf(blsPubKey2); stake != 0 {
+		t.Errorf("expected no stake for the key registered in epoch 1, got %d", stake)
+	}
+
+	mask, _ := bls_cosi.NewMask([]*bls.PublicKey{blsPubKey1, blsPubKey2, pubKey}, nil)
+	mask.SetKey(blsPubKey1, true)
+	if bls_cosi.NewStakeWeightedPolicy(stakeOf, 2, 3).Check(mask) {
+		t.Error("The only staked signer should not form a quorum of a committee with unstaked members")
+	}
+	node.UpdateStakingList(types.NewBlock(&types.Header{Number: big.NewInt(9)}, nil, nil))
+	if stake := node.StakesAt(10)(blsPubKey2); stake != 60 {
+		t.Errorf("expected the stakes of the second staker in epoch 2, got %d", stake)
+	}
+}
+
+func TestShardValidatorReceivesAnnounce(t *testing.T) {
+	utils.UseLibP2P = true
+	defer func() { utils.UseLibP2P = false }()
+
+	network := simulator.NewNetwork(simulator.DefaultConfig(1))
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9010"}
+	_, leader.PubKey = utils.GenKey(leader.IP, leader.Port)
+	validator := p2p.Peer{IP: "127.0.0.1", Port: "9011", ValidatorID: 1}
+	_, validator.PubKey = utils.GenKey(validator.IP, validator.Port)
+	leaderHost := network.NewHost(&leader)
+	validatorHost := network.NewHost(&validator)
+
+	leaderConsensus := consensus.New(leaderHost, "1", []p2p.Peer{validator}, leader)
+	leaderConsensus.SetClock(network.Clock())
+	validatorConsensus := consensus.New(validatorHost, "1", []p2p.Peer{validator}, leader)
+	validatorConsensus.SetClock(network.Clock())
+	announced := make(chan *types.Block, 1)
+	validatorConsensus.BlockVerifier = func(block *types.Block) bool {
+		announced <- block
+		return false
+	}
+	node := New(validatorHost, validatorConsensus, nil, nil)
+	node.Role = ShardValidator
+	node.ServiceManagerSetup()
+
+	// The network waits for the node to handle the announce sent to the group of the shard.
+	network.AfterFunc(0, func() {
+		leaderConsensus.ProposeBlock(types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil))
+	})
+	if network.RunUntil(func() bool { return len(announced) > 0 }, time.Minute) {
+		if block := <-announced; block.NumberU64() != 1 {
+			t.Errorf("announced block %v, expected 1", block.NumberU64())
+		}
+		return
+	}
+	t.Error("the validator of shard 1 didn't receive the announce")
+}
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"errors"
 	"math/big"
 	"time"
 
@@ -23,6 +24,15 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+
+	crossShardReceipts []*types.CrossShardReceipt      // the receipts of the transfers to other shards
+	incomingReceipts   []*types.CrossShardReceiptProof // the proven receipts credited from other shards
+}
+
+// pendingBlock is a block still in consensus with the state after it.
+type pendingBlock struct {
+	block *types.Block
+	state *state.DB
 }
 
 // Worker is the main object which takes care of submitting new work to consensus engine
@@ -30,7 +40,8 @@ type environment struct {
 type Worker struct {
 	config  *params.ChainConfig
 	chain   *core.BlockChain
-	current *environment // An environment for current running cycle.
+	current *environment  // An environment for current running cycle.
+	pending *pendingBlock // The prepared block not in the chain yet, if any.
 
 	coinbase common.Address
 	engine   consensus_engine.Engine
@@ -52,6 +63,7 @@ func (w *Worker) SelectTransactionsForNewBlock(txs types.Transactions, maxNumTxs
 	for _, tx := range txs {
 		if tx.ShardID() != w.shardID {
 			invalid = append(invalid, tx)
+			continue
 		}
 		snap := w.current.state.Snapshot()
 		_, err := w.commitTransaction(tx, w.coinbase)
@@ -77,13 +89,16 @@ func (w *Worker) SelectTransactionsForNewBlock(txs types.Transactions, maxNumTxs
 func (w *Worker) commitTransaction(tx *types.Transaction, coinbase common.Address) ([]*types.Log, error) {
 	snap := w.current.state.Snapshot()
 
-	receipt, _, err := core.ApplyTransaction(w.config, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, &w.current.header.GasUsed, vm.Config{})
+	receipt, crossShardReceipt, _, err := core.ApplyTransaction(w.config, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, &w.current.header.GasUsed, vm.Config{})
 	if err != nil {
 		w.current.state.RevertToSnapshot(snap)
 		return nil, err
 	}
 	w.current.txs = append(w.current.txs, tx)
 	w.current.receipts = append(w.current.receipts, receipt)
+	if crossShardReceipt != nil {
+		w.current.crossShardReceipts = append(w.current.crossShardReceipts, crossShardReceipt)
+	}
 
 	return receipt.Logs, nil
 }
@@ -105,18 +120,47 @@ func (w *Worker) CommitTransactions(txs types.Transactions) error {
 	return nil
 }
 
+// CommitIncomingReceipts credits the proven receipts of the transfers from other shards,
+// and returns the ones credited. The invalid and already credited receipts are skipped.
+func (w *Worker) CommitIncomingReceipts(proofs []*types.CrossShardReceiptProof) []*types.CrossShardReceiptProof {
+	applied := []*types.CrossShardReceiptProof{}
+	for _, proof := range proofs {
+		snap := w.current.state.Snapshot()
+		if err := core.ApplyIncomingReceipt(w.chain, w.engine, w.current.state, w.current.header, proof); err != nil {
+			w.current.state.RevertToSnapshot(snap)
+			log.Debug("Invalid cross-shard receipt", "Error", err)
+			continue
+		}
+		applied = append(applied, proof)
+	}
+	w.current.incomingReceipts = append(w.current.incomingReceipts, applied...)
+	return applied
+}
+
 // UpdateCurrent updates the current environment with the current state and header.
+// The new block is built on top of the pending block if it extends the chain.
 func (w *Worker) UpdateCurrent() error {
 	parent := w.chain.CurrentBlock()
+	if w.pending != nil {
+		if w.pending.block.ParentHash() == parent.Hash() {
+			return w.makeCurrentOnPending()
+		}
+		// The pending block is in the chain or abandoned.
+		w.pending = nil
+	}
 	num := parent.Number()
 	timestamp := time.Now().Unix()
 	header := &types.Header{
 		ParentHash: parent.Hash(),
+		Coinbase:   w.coinbase,
 		Number:     num.Add(num, common.Big1),
 		GasLimit:   core.CalcGasLimit(parent, w.gasFloor, w.gasCeil),
 		Time:       big.NewInt(timestamp),
 		ShardID:    types.EncodeShardID(w.chain.ShardID()),
 	}
+	if err := w.engine.Prepare(w.chain, header); err != nil {
+		return err
+	}
 	return w.makeCurrent(parent, header)
 }
 
@@ -135,11 +179,61 @@ func (w *Worker) makeCurrent(parent *types.Block, header *types.Header) error {
 	return nil
 }
 
+// makeCurrentOnPending creates a new environment on top of the pending block.
+func (w *Worker) makeCurrentOnPending() error {
+	parent := w.pending.block
+	num := parent.Number()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   w.coinbase,
+		Number:     num.Add(num, common.Big1),
+		GasLimit:   core.CalcGasLimit(parent, w.gasFloor, w.gasCeil),
+		Time:       big.NewInt(time.Now().Unix()),
+		ShardID:    types.EncodeShardID(w.chain.ShardID()),
+	}
+	if err := w.engine.Prepare(w.chain, header); err != nil {
+		return err
+	}
+	w.current = &environment{
+		state:  w.pending.state.Copy(),
+		header: header,
+	}
+	return nil
+}
+
+// SetPending sets the block which is prepared but not committed yet, so that
+// the next block can be built on top of it before it's added to the chain.
+func (w *Worker) SetPending(block *types.Block) error {
+	parent := w.chain.GetBlockByHash(block.ParentHash())
+	if parent == nil {
+		return errors.New("parent of the pending block is not in the chain")
+	}
+	state, err := w.chain.StateAt(parent.Root())
+	if err != nil {
+		return err
+	}
+	if _, _, _, err := w.chain.Processor().Process(block, state, vm.Config{}); err != nil {
+		return err
+	}
+	w.pending = &pendingBlock{block: block, state: state}
+	return w.UpdateCurrent()
+}
+
+// ClearPending drops the pending block, e.g. when it won't be committed.
+func (w *Worker) ClearPending() {
+	w.pending = nil
+}
+
 // GetCurrentState gets the current state.
 func (w *Worker) GetCurrentState() *state.DB {
 	return w.current.state
 }
 
+// GetNewBlockNumber gets the number of the block being built.
+func (w *Worker) GetNewBlockNumber() uint64 {
+	return w.current.header.Number.Uint64()
+}
+
 // GetCurrentReceipts get the receipts generated starting from the last state.
 func (w *Worker) GetCurrentReceipts() []*types.Receipt {
 	return w.current.receipts
@@ -152,6 +246,8 @@ func (w *Worker) Commit() (*types.Block, error) {
 	if err != nil {
 		return nil, err
 	}
+	block.AddCrossShardReceipts(w.current.crossShardReceipts)
+	block.AddIncomingReceipts(w.current.incomingReceipts)
 	return block, nil
 }
 
@@ -167,17 +263,7 @@ func New(config *params.ChainConfig, chain *core.BlockChain, engine consensus_en
 	worker.coinbase = coinbase
 	worker.shardID = shardID
 
-	parent := worker.chain.CurrentBlock()
-	num := parent.Number()
-	timestamp := time.Now().Unix()
-	header := &types.Header{
-		ParentHash: parent.Hash(),
-		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent, worker.gasFloor, worker.gasCeil),
-		Time:       big.NewInt(timestamp),
-		ShardID:    types.EncodeShardID(worker.chain.ShardID()),
-	}
-	worker.makeCurrent(parent, header)
+	worker.UpdateCurrent()
 
 	return worker
 }
@@ -82,3 +82,53 @@ func TestCommitTransactions(t *testing.T) {
 		t.Error("Transaction is not committed")
 	}
 }
+
+func TestBuildOnPendingBlock(t *testing.T) {
+	// Setup a new blockchain with genesis block containing test token on test address
+	var (
+		database = ethdb.NewMemDatabase()
+		gspec    = core.Genesis{
+			Config:  chainConfig,
+			Alloc:   core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+			ShardID: 10,
+		}
+	)
+
+	gspec.MustCommit(database)
+	chain, _ := core.NewBlockChain(database, nil, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+
+	// Create a new worker
+	worker := New(params.TestChainConfig, chain, consensus.NewFaker(), testBankAddress, 0)
+
+	baseNonce := worker.GetCurrentState().GetNonce(testBankAddress)
+	tx, _ := types.SignTx(types.NewTransaction(baseNonce, testBankAddress, uint32(0), big.NewInt(1), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	if err := worker.CommitTransactions(types.Transactions{tx}); err != nil {
+		t.Fatal(err)
+	}
+	block, err := worker.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next block is built on the pending block before it's in the chain
+	if err := worker.SetPending(block); err != nil {
+		t.Fatal(err)
+	}
+	if worker.current.header.ParentHash != block.Hash() || worker.current.header.Number.Uint64() != 2 {
+		t.Error("Next block is not built on the pending block")
+	}
+	if worker.GetCurrentState().GetNonce(testBankAddress) != baseNonce+1 {
+		t.Error("Worker state doesn't include the pending block")
+	}
+
+	// The pending block is dropped once the chain moves on
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.UpdateCurrent(); err != nil {
+		t.Fatal(err)
+	}
+	if worker.pending != nil || worker.current.header.ParentHash != block.Hash() {
+		t.Error("Next block is not built on the chain head")
+	}
+}
@@ -28,6 +28,16 @@ const (
 	GroupIDGlobal GroupID = "harmony/0.0.1/global"
 )
 
+// NewGroupIDByShardID returns the group ID of the consensus and drand traffic of
+// the shard, so that the nodes only receive the votes of their own shard.
+// Shard 0 is the beacon chain.
+func NewGroupIDByShardID(shardID uint32) GroupID {
+	if shardID == 0 {
+		return GroupIDBeacon
+	}
+	return GroupID(fmt.Sprintf("harmony/0.0.1/shard/%d", shardID))
+}
+
 // GroupReceiver is a multicast group message receiver interface.
 type GroupReceiver interface {
 	// Close closes this receiver.
@@ -20,3 +20,22 @@ func TestGroupID_String(t *testing.T) {
 		})
 	}
 }
+
+func TestNewGroupIDByShardID(t *testing.T) {
+	tests := []struct {
+		name    string
+		shardID uint32
+		want    GroupID
+	}{
+		{"beacon", 0, GroupIDBeacon},
+		{"shard1", 1, GroupID("harmony/0.0.1/shard/1")},
+		{"shard2", 2, GroupID("harmony/0.0.1/shard/2")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGroupIDByShardID(tt.shardID); got != tt.want {
+				t.Errorf("NewGroupIDByShardID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
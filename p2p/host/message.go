@@ -10,11 +10,17 @@ import (
 	"github.com/harmony-one/harmony/p2p"
 )
 
+// Types of the p2p messages.
+const (
+	MessageTypeDirect    byte = 0
+	MessageTypeBroadcast byte = 17 // 0x11
+)
+
 // SendMessage is to connect a socket given a port and send the given message.
 // TODO(minhdoan, rj): need to check if a peer is reachable or not.
 func SendMessage(host p2p.Host, p p2p.Peer, message []byte, lostPeer chan p2p.Peer) {
 	// Construct normal p2p message
-	content := ConstructP2pMessage(byte(0), message)
+	content := ConstructP2pMessage(MessageTypeDirect, message)
 	go send(host, p, content, lostPeer)
 }
 
@@ -24,7 +30,7 @@ func BroadcastMessage(h p2p.Host, peers []p2p.Peer, msg []byte, lostPeer chan p2
 		return
 	}
 	// Construct broadcast p2p message
-	content := ConstructP2pMessage(byte(17), msg)
+	content := ConstructP2pMessage(MessageTypeBroadcast, msg)
 	length := len(content)
 
 	log.Info("Start Broadcasting", "gomaxprocs", runtime.GOMAXPROCS(0), "Size", length)
@@ -52,7 +58,7 @@ func BroadcastMessageFromLeader(h p2p.Host, peers []p2p.Peer, msg []byte, lostPe
 // ConstructP2pMessage constructs the p2p message as [messageType, contentSize, content]
 func ConstructP2pMessage(msgType byte, content []byte) []byte {
 	message := make([]byte, 5+len(content))
-	message[0] = 17 // messageType 0x11
+	message[0] = msgType
 	binary.BigEndian.PutUint32(message[1:5], uint32(len(content)))
 	copy(message[5:], content)
 	return message
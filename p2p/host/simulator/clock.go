@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the virtual clock of a simulated network. It only advances when the
+// network delivers the next message or fires the next timer, so a simulated run
+// doesn't depend on the speed of the machine running it.
+type Clock struct {
+	network *Network
+
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// newClock creates a clock of the network starting at the given time.
+func newClock(network *Network, start time.Time) *Clock {
+	return &Clock{network: network, now: start}
+}
+
+// Now returns the current virtual time.
+func (clock *Clock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// Since returns the virtual time elapsed since t.
+func (clock *Clock) Since(t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
+
+// AfterFunc calls f in the simulation loop once the virtual clock reaches d from
+// now. Calling stop cancels the call, it returns false if f was already called.
+func (clock *Clock) AfterFunc(d time.Duration, f func()) (stop func() bool) {
+	return clock.network.AfterFunc(d, f)
+}
+
+// advance moves the clock forward to t. The clock never goes back.
+func (clock *Clock) advance(t time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	if t.After(clock.now) {
+		clock.now = t
+	}
+}
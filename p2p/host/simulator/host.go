@@ -0,0 +1,252 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	libp2p_host "github.com/libp2p/go-libp2p-host"
+	libp2p_peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// receiverQueueSize is the number of messages a group receiver keeps before
+// dropping the new ones.
+const receiverQueueSize = 1024
+
+// ErrReceiverClosed is returned when receiving from a closed group receiver.
+var ErrReceiverClosed = errors.New("[SIMULATOR]: group receiver is closed")
+
+// Host is an in-memory p2p host in a simulated network.
+type Host struct {
+	network *Network
+	self    p2p.Peer
+
+	mutex     sync.Mutex
+	handler   p2p.StreamHandler
+	receivers map[p2p.GroupID][]*groupReceiver
+	closed    bool
+}
+
+// GetSelfPeer gets self peer
+func (host *Host) GetSelfPeer() p2p.Peer {
+	return host.self
+}
+
+// GetID returns the peer ID of the host.
+func (host *Host) GetID() libp2p_peer.ID {
+	return host.self.PeerID
+}
+
+// GetP2PHost returns nil, as there is no libp2p host behind a simulated host.
+func (host *Host) GetP2PHost() libp2p_host.Host {
+	return nil
+}
+
+// AddPeer does nothing, as all the hosts in the network know each other.
+func (host *Host) AddPeer(*p2p.Peer) error {
+	return nil
+}
+
+// ConnectHostPeer does nothing, as all the hosts in the network are connected.
+func (host *Host) ConnectHostPeer(p2p.Peer) {
+}
+
+// BindHandlerAndServe binds the handler of the messages sent to the host.
+// Unlike the real hosts, it returns right away.
+func (host *Host) BindHandlerAndServe(handler p2p.StreamHandler) {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+	host.handler = handler
+}
+
+// Close takes the host off the network. The messages in flight to it are dropped.
+func (host *Host) Close() error {
+	host.mutex.Lock()
+	host.closed = true
+	receivers := host.receivers
+	host.receivers = make(map[p2p.GroupID][]*groupReceiver)
+	host.mutex.Unlock()
+
+	for _, groupReceivers := range receivers {
+		for _, receiver := range groupReceivers {
+			receiver.Close()
+		}
+	}
+	return nil
+}
+
+// SendMessage sends the message to the peer, which gets it in the handler of its stream.
+func (host *Host) SendMessage(peer p2p.Peer, message []byte) error {
+	target := host.network.hostOf(peer)
+	if target == nil {
+		return p2p.ErrNewStream
+	}
+	content := append([]byte{}, message...)
+	host.network.send(host.GetID(), target.GetID(), func() {
+		target.handleStream(content)
+	})
+	return nil
+}
+
+// SendMessageToGroups sends a message to one or more multicast groups.
+// Like libp2p pubsub, the sender gets its own message if it's in the group.
+func (host *Host) SendMessageToGroups(groups []p2p.GroupID, msg []byte) error {
+	content := append([]byte{}, msg...)
+	sender := host.GetID()
+	for _, group := range groups {
+		group := group
+		for _, target := range host.network.subscribers(group) {
+			target := target
+			host.network.send(sender, target.GetID(), func() {
+				target.handleGroupMessage(group, content, sender)
+			})
+		}
+	}
+	return nil
+}
+
+// GroupReceiver returns a receiver of messages sent to a multicast group.
+// See the GroupReceiver interface for details.
+func (host *Host) GroupReceiver(group p2p.GroupID) (p2p.GroupReceiver, error) {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+
+	receiver := &groupReceiver{
+		messages: make(chan groupMessage, receiverQueueSize),
+		closing:  make(chan struct{}),
+	}
+	host.receivers[group] = append(host.receivers[group], receiver)
+	return receiver, nil
+}
+
+// subscribed returns whether the host has a receiver of the group.
+func (host *Host) subscribed(group p2p.GroupID) bool {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+	return !host.closed && len(host.receivers[group]) > 0
+}
+
+// handleStream passes a direct message to the handler of the host.
+func (host *Host) handleStream(content []byte) {
+	host.mutex.Lock()
+	handler := host.handler
+	closed := host.closed
+	host.mutex.Unlock()
+
+	if handler == nil || closed {
+		return
+	}
+	handler(&stream{Reader: bytes.NewReader(content)})
+}
+
+// handleGroupMessage passes a group message to the receivers of the group, one
+// after the other once each one handled it.
+func (host *Host) handleGroupMessage(group p2p.GroupID, content []byte, sender libp2p_peer.ID) {
+	host.mutex.Lock()
+	receivers := append([]*groupReceiver{}, host.receivers[group]...)
+	host.mutex.Unlock()
+
+	for _, receiver := range receivers {
+		receiver.push(content, sender)
+	}
+}
+
+// groupMessage is a message queued in a group receiver.
+type groupMessage struct {
+	content []byte
+	sender  libp2p_peer.ID
+	// Closed once the message is handled
+	handled chan struct{}
+}
+
+// groupReceiver is a multicast group receiver of a simulated host.
+type groupReceiver struct {
+	messages chan groupMessage
+	closing  chan struct{}
+
+	mutex sync.Mutex
+	// The message returned by the last Receive, which is handled until the next call
+	handling *groupMessage
+	closed   bool
+}
+
+// push queues a message in the receiver, and waits until the message is handled
+// or the receiver is closed.
+func (receiver *groupReceiver) push(content []byte, sender libp2p_peer.ID) {
+	receiver.mutex.Lock()
+	if receiver.closed {
+		receiver.mutex.Unlock()
+		return
+	}
+	message := groupMessage{content: content, sender: sender, handled: make(chan struct{})}
+	select {
+	case receiver.messages <- message:
+	default:
+		receiver.mutex.Unlock()
+		utils.GetLogInstance().Warn("Simulated group receiver is full, dropping the message")
+		return
+	}
+	receiver.mutex.Unlock()
+
+	select {
+	case <-message.handled:
+	case <-receiver.closing:
+	}
+}
+
+// Receive receives a message. Calling it again means the previous message is handled.
+func (receiver *groupReceiver) Receive(ctx context.Context) (msg []byte, sender libp2p_peer.ID, err error) {
+	receiver.mutex.Lock()
+	if receiver.handling != nil {
+		close(receiver.handling.handled)
+		receiver.handling = nil
+	}
+	receiver.mutex.Unlock()
+
+	select {
+	case message := <-receiver.messages:
+		receiver.mutex.Lock()
+		receiver.handling = &message
+		receiver.mutex.Unlock()
+		return message.content, message.sender, nil
+	case <-receiver.closing:
+		return nil, "", ErrReceiverClosed
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
+// Close closes this receiver. The messages it didn't handle are dropped.
+func (receiver *groupReceiver) Close() error {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	if receiver.closed {
+		return nil
+	}
+	receiver.closed = true
+	close(receiver.closing)
+	return nil
+}
+
+// stream is the stream of a direct message. It can only be read.
+type stream struct {
+	*bytes.Reader
+}
+
+func (s *stream) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func (s *stream) Close() error {
+	return nil
+}
+
+func (s *stream) SetReadDeadline(time.Time) error {
+	return nil
+}
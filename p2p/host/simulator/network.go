@@ -0,0 +1,330 @@
+package simulator
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/harmony-one/harmony/p2p"
+	libp2p_peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Config is the configuration of a simulated network.
+type Config struct {
+	// Seed of the latencies and drops of the messages. Runs with the same seed
+	// deliver the same messages in the same order.
+	Seed int64
+	// The latency of a message is drawn uniformly from [MinLatency, MaxLatency].
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	// Probability of dropping a message, from 0 to 1.
+	DropRate float64
+	// Whether the messages between two hosts may be delivered out of order.
+	Reorder bool
+}
+
+// DefaultConfig returns the configuration of a reliable network with the given seed.
+func DefaultConfig(seed int64) Config {
+	return Config{
+		Seed:       seed,
+		MinLatency: 10 * time.Millisecond,
+		MaxLatency: 100 * time.Millisecond,
+	}
+}
+
+// Stats are the counters of the messages in a simulated network.
+type Stats struct {
+	Sent      int
+	Delivered int
+	Dropped   int
+}
+
+// Network is a simulated network of in-memory hosts. The messages sent by the
+// hosts are delivered one at a time in the order of their virtual delivery
+// time, when the network is stepped.
+//
+// The hosts handle a message before the next one is delivered: the handler of
+// the host is called in the simulation loop, and the delivery of a group message
+// waits until its receivers come back for the next message. So the hosts must
+// serve every group receiver they create.
+//
+// Latencies and drops are derived from the seed and the sender and receiver of
+// each message, so the hosts may send the messages from different goroutines
+// and still get the same run for the same seed.
+type Network struct {
+	config Config
+	clock  *Clock
+
+	mutex      sync.Mutex
+	hosts      map[string]*Host // by ip:port
+	partitions map[libp2p_peer.ID]int
+	links      map[string]*link
+	events     eventQueue
+	timers     uint64
+	stats      Stats
+}
+
+// link is the state of the messages from one host to another.
+type link struct {
+	sent         uint64
+	lastDelivery time.Time
+}
+
+// NewNetwork creates a simulated network.
+func NewNetwork(config Config) *Network {
+	if config.MaxLatency < config.MinLatency {
+		config.MaxLatency = config.MinLatency
+	}
+	network := &Network{
+		config:     config,
+		hosts:      make(map[string]*Host),
+		partitions: make(map[libp2p_peer.ID]int),
+		links:      make(map[string]*link),
+	}
+	network.clock = newClock(network, time.Unix(0, 0).UTC())
+	return network
+}
+
+// Clock returns the virtual clock of the network.
+func (network *Network) Clock() *Clock {
+	return network.clock
+}
+
+// Stats returns the counters of the messages so far.
+func (network *Network) Stats() Stats {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.stats
+}
+
+// NewHost creates a host of the given peer in the network. The peer ID of the
+// host is derived from the address of the peer.
+func (network *Network) NewHost(self *p2p.Peer) *Host {
+	addr := net.JoinHostPort(self.IP, self.Port)
+	self.PeerID = libp2p_peer.ID(addr)
+	host := &Host{
+		network:   network,
+		self:      *self,
+		receivers: make(map[p2p.GroupID][]*groupReceiver),
+	}
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.hosts[addr] = host
+	return host
+}
+
+// Partition splits the network so that only the hosts in the same group can
+// talk to each other. The hosts which aren't in any group form one more group.
+// Messages in flight between two groups are dropped.
+func (network *Network) Partition(groups ...[]*Host) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.partitions = make(map[libp2p_peer.ID]int)
+	for i, group := range groups {
+		for _, host := range group {
+			network.partitions[host.GetID()] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions of the network.
+func (network *Network) Heal() {
+	network.Partition()
+}
+
+// AfterFunc calls f in the simulation loop once the virtual clock reaches d from
+// now. Calling stop cancels the call, it returns false if f was already called.
+func (network *Network) AfterFunc(d time.Duration, f func()) (stop func() bool) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.timers++
+	e := &event{at: network.clock.Now().Add(d), seq: network.timers, run: f}
+	heap.Push(&network.events, e)
+	return func() bool {
+		network.mutex.Lock()
+		defer network.mutex.Unlock()
+		if e.done {
+			return false
+		}
+		e.done = true
+		return true
+	}
+}
+
+// Step delivers the next message. It returns false if there is none.
+func (network *Network) Step() bool {
+	return network.step(time.Time{})
+}
+
+// RunFor delivers the messages due in the next d of virtual time, and moves the
+// clock forward by d.
+func (network *Network) RunFor(d time.Duration) {
+	deadline := network.clock.Now().Add(d)
+	for network.step(deadline) {
+	}
+	network.clock.advance(deadline)
+}
+
+// RunUntil delivers the messages until done returns true, and gives up after
+// limit of virtual time. It returns whether done returned true.
+func (network *Network) RunUntil(done func() bool, limit time.Duration) bool {
+	deadline := network.clock.Now().Add(limit)
+	for !done() {
+		if !network.step(deadline) {
+			return done()
+		}
+	}
+	return true
+}
+
+// step delivers the next message due before the deadline, if any.
+func (network *Network) step(deadline time.Time) bool {
+	network.mutex.Lock()
+	// The stopped timers are dropped once they are due.
+	for len(network.events) > 0 && network.events[0].done {
+		heap.Pop(&network.events)
+	}
+	if len(network.events) == 0 || (!deadline.IsZero() && network.events[0].at.After(deadline)) {
+		network.mutex.Unlock()
+		return false
+	}
+	e := heap.Pop(&network.events).(*event)
+	e.done = true
+	network.clock.advance(e.at)
+	connected := e.from == "" || network.partitions[e.from] == network.partitions[e.to]
+	if connected {
+		network.stats.Delivered++
+	} else {
+		network.stats.Dropped++
+	}
+	network.mutex.Unlock()
+
+	if connected {
+		e.run()
+	}
+	return true
+}
+
+// send schedules the delivery of a message from one host to another.
+func (network *Network) send(from libp2p_peer.ID, to libp2p_peer.ID, deliver func()) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.stats.Sent++
+
+	key := string(from) + "/" + string(to)
+	l, ok := network.links[key]
+	if !ok {
+		l = &link{}
+		network.links[key] = l
+	}
+	l.sent++
+
+	random := network.linkRand(from, to, l.sent)
+	if random.Float64() < network.config.DropRate {
+		network.stats.Dropped++
+		return
+	}
+	latency := network.config.MinLatency
+	if spread := network.config.MaxLatency - network.config.MinLatency; spread > 0 {
+		latency += time.Duration(random.Int63n(int64(spread) + 1))
+	}
+	at := network.clock.Now().Add(latency)
+	if !network.config.Reorder && at.Before(l.lastDelivery) {
+		at = l.lastDelivery
+	}
+	l.lastDelivery = at
+
+	heap.Push(&network.events, &event{at: at, from: from, to: to, seq: l.sent, run: deliver})
+}
+
+// linkRand returns the source of the latency and drop of the seq-th message
+// from one host to another.
+func (network *Network) linkRand(from libp2p_peer.ID, to libp2p_peer.ID, seq uint64) *rand.Rand {
+	hash := fnv.New64a()
+	binary.Write(hash, binary.BigEndian, network.config.Seed)
+	hash.Write([]byte(from))
+	hash.Write([]byte{0})
+	hash.Write([]byte(to))
+	binary.Write(hash, binary.BigEndian, seq)
+	return rand.New(rand.NewSource(int64(hash.Sum64())))
+}
+
+// hostOf returns the host of the peer, or nil if it isn't in the network.
+func (network *Network) hostOf(peer p2p.Peer) *Host {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	if peer.PeerID != "" {
+		if host, ok := network.hosts[string(peer.PeerID)]; ok {
+			return host
+		}
+	}
+	return network.hosts[net.JoinHostPort(peer.IP, peer.Port)]
+}
+
+// subscribers returns the hosts with a receiver of the group.
+func (network *Network) subscribers(group p2p.GroupID) []*Host {
+	network.mutex.Lock()
+	hosts := make([]*Host, 0, len(network.hosts))
+	for _, host := range network.hosts {
+		hosts = append(hosts, host)
+	}
+	network.mutex.Unlock()
+
+	subscribers := []*Host{}
+	for _, host := range hosts {
+		if host.subscribed(group) {
+			subscribers = append(subscribers, host)
+		}
+	}
+	return subscribers
+}
+
+// event is the delivery of a message, or a timer.
+type event struct {
+	at   time.Time
+	from libp2p_peer.ID
+	to   libp2p_peer.ID
+	seq  uint64
+	run  func()
+	// Whether the event was run, or the timer stopped
+	done bool
+}
+
+// before orders the events by time. The ties are broken by the sender, the
+// receiver and the order of the messages on the link, which don't depend on
+// the order the hosts sent the messages in.
+func (e *event) before(other *event) bool {
+	if !e.at.Equal(other.at) {
+		return e.at.Before(other.at)
+	}
+	if e.from != other.from {
+		return e.from < other.from
+	}
+	if e.to != other.to {
+		return e.to < other.to
+	}
+	return e.seq < other.seq
+}
+
+// eventQueue is a heap of events, the earliest first.
+type eventQueue []*event
+
+func (queue eventQueue) Len() int            { return len(queue) }
+func (queue eventQueue) Less(i, j int) bool  { return queue[i].before(queue[j]) }
+func (queue eventQueue) Swap(i, j int)       { queue[i], queue[j] = queue[j], queue[i] }
+func (queue *eventQueue) Push(x interface{}) { *queue = append(*queue, x.(*event)) }
+func (queue *eventQueue) Pop() interface{} {
+	old := *queue
+	e := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return e
+}
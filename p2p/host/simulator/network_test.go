@@ -0,0 +1,183 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHosts(network *Network, count int) []*Host {
+	hosts := make([]*Host, count)
+	for i := range hosts {
+		hosts[i] = network.NewHost(&p2p.Peer{IP: "127.0.0.1", Port: fmt.Sprintf("%d", 9000+i)})
+	}
+	return hosts
+}
+
+// record binds a handler to each host which records the messages it gets as "receiver:message".
+func record(hosts []*Host) *[]string {
+	received := []string{}
+	for i, host := range hosts {
+		i := i
+		host.BindHandlerAndServe(func(s p2p.Stream) {
+			content, _ := ioutil.ReadAll(s)
+			received = append(received, fmt.Sprintf("%d:%s", i, content))
+		})
+	}
+	return &received
+}
+
+func TestSendMessage(t *testing.T) {
+	config := DefaultConfig(1)
+	network := NewNetwork(config)
+	hosts := newTestHosts(network, 2)
+	received := record(hosts)
+
+	var _ p2p.Host = hosts[0]
+	start := network.Clock().Now()
+	assert.Nil(t, hosts[0].SendMessage(hosts[1].GetSelfPeer(), []byte("hello")))
+	// The peer can also be found by address only
+	assert.Nil(t, hosts[1].SendMessage(p2p.Peer{IP: "127.0.0.1", Port: "9000"}, []byte("world")))
+	assert.Equal(t, p2p.ErrNewStream, hosts[0].SendMessage(p2p.Peer{IP: "127.0.0.1", Port: "1"}, []byte("lost")))
+
+	assert.True(t, network.Step())
+	elapsed := network.Clock().Since(start)
+	assert.True(t, elapsed >= config.MinLatency && elapsed <= config.MaxLatency, "latency %v out of range", elapsed)
+	assert.True(t, network.Step())
+	assert.False(t, network.Step())
+	assert.ElementsMatch(t, []string{"1:hello", "0:world"}, *received)
+	assert.Equal(t, Stats{Sent: 2, Delivered: 2}, network.Stats())
+}
+
+func TestSameSeedSameRun(t *testing.T) {
+	run := func(seed int64, reverse bool) []string {
+		config := DefaultConfig(seed)
+		config.Reorder = true
+		config.DropRate = 0.2
+		network := NewNetwork(config)
+		hosts := newTestHosts(network, 4)
+		received := record(hosts)
+		// The order the hosts send in mustn't change the run.
+		for n := range hosts {
+			i := n
+			if reverse {
+				i = len(hosts) - 1 - n
+			}
+			for j := range hosts {
+				for k := 0; k < 5; k++ {
+					hosts[i].SendMessage(hosts[j].GetSelfPeer(), []byte(fmt.Sprintf("%d-%d", i, k)))
+				}
+			}
+		}
+		for network.Step() {
+		}
+		return *received
+	}
+	first := run(42, false)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, run(42, false))
+	assert.Equal(t, first, run(42, true))
+}
+
+func TestNoReorder(t *testing.T) {
+	config := DefaultConfig(7)
+	network := NewNetwork(config)
+	hosts := newTestHosts(network, 2)
+	received := record(hosts)
+
+	expected := []string{}
+	for k := 0; k < 20; k++ {
+		hosts[0].SendMessage(hosts[1].GetSelfPeer(), []byte(fmt.Sprintf("%d", k)))
+		expected = append(expected, fmt.Sprintf("1:%d", k))
+	}
+	network.RunFor(time.Second)
+	assert.Equal(t, expected, *received)
+}
+
+func TestDropAndPartition(t *testing.T) {
+	config := DefaultConfig(3)
+	config.DropRate = 1
+	network := NewNetwork(config)
+	hosts := newTestHosts(network, 3)
+	received := record(hosts)
+
+	hosts[0].SendMessage(hosts[1].GetSelfPeer(), []byte("dropped"))
+	assert.False(t, network.Step())
+	assert.Equal(t, 1, network.Stats().Dropped)
+
+	network.config.DropRate = 0
+	network.Partition([]*Host{hosts[0]}, []*Host{hosts[1], hosts[2]})
+	hosts[0].SendMessage(hosts[1].GetSelfPeer(), []byte("across"))
+	hosts[1].SendMessage(hosts[2].GetSelfPeer(), []byte("within"))
+	network.RunFor(time.Second)
+	assert.Equal(t, []string{"2:within"}, *received)
+
+	network.Heal()
+	hosts[0].SendMessage(hosts[1].GetSelfPeer(), []byte("healed"))
+	network.RunFor(time.Second)
+	assert.Equal(t, []string{"2:within", "1:healed"}, *received)
+}
+
+func TestGroupReceiver(t *testing.T) {
+	config := DefaultConfig(5)
+	network := NewNetwork(config)
+	hosts := newTestHosts(network, 3)
+	group := p2p.NewGroupIDByShardID(1)
+
+	handled := make(chan string, 10)
+	for i := 0; i < 2; i++ {
+		receiver, err := hosts[i].GroupReceiver(group)
+		assert.Nil(t, err)
+		i := i
+		go func() {
+			for {
+				msg, sender, err := receiver.Receive(context.Background())
+				if err != nil {
+					return
+				}
+				// A slow handler, which the network waits for
+				time.Sleep(10 * time.Millisecond)
+				handled <- fmt.Sprintf("%d:%s:%s", i, msg, string(sender))
+			}
+		}()
+	}
+
+	assert.Nil(t, hosts[0].SendMessageToGroups([]p2p.GroupID{group}, []byte("vote")))
+	network.RunFor(time.Second)
+	// The network waited for both receivers to handle the message.
+	assert.Equal(t, 2, len(handled))
+	<-handled
+	<-handled
+
+	// The host not in the group gets nothing, and closed hosts leave the group.
+	assert.False(t, hosts[2].subscribed(group))
+	hosts[1].Close()
+	assert.Nil(t, hosts[0].SendMessageToGroups([]p2p.GroupID{group}, []byte("again")))
+	network.RunFor(time.Second)
+	if assert.Equal(t, 1, len(handled)) {
+		assert.Equal(t, "0:again:127.0.0.1:9000", <-handled)
+	}
+}
+
+func TestAfterFunc(t *testing.T) {
+	network := NewNetwork(DefaultConfig(9))
+	start := network.Clock().Now()
+	fired := []time.Duration{}
+	network.Clock().AfterFunc(2*time.Second, func() {
+		fired = append(fired, network.Clock().Since(start))
+	})
+	stop := network.Clock().AfterFunc(time.Second, func() {
+		fired = append(fired, network.Clock().Since(start))
+	})
+	assert.True(t, stop())
+	assert.False(t, stop(), "the timer is stopped already")
+
+	network.RunFor(time.Minute)
+	assert.Equal(t, []time.Duration{2 * time.Second}, fired)
+	assert.Equal(t, time.Minute, network.Clock().Since(start))
+}
@@ -5,6 +5,8 @@ SRC[txgen]=cmd/client/txgen/main.go
 SRC[beacon]=cmd/beaconchain/main.go
 SRC[wallet]=cmd/client/wallet/main.go
 SRC[bootnode]=cmd/bootnode/main.go
+SRC[shardsim]=cmd/shardsim/main.go
+SRC[prune]=cmd/prune/main.go
 
 BINDIR=bin
 BUCKET=unique-bucket-bin